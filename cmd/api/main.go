@@ -2,40 +2,108 @@ package main
 
 import (
 	"context"
+	"log"
 	"net/http"
 	"os"
 	"os/signal"
 	"syscall"
 	"time"
+	// Scheduled sends resolve IANA time zones even where the host has no
+	// zoneinfo.
+	_ "time/tzdata"
 
+	"golang.org/x/oauth2"
 	"ai-assistant/internal/app/config"
 	"ai-assistant/internal/handlers"
+	"ai-assistant/internal/httperr"
+	internalMiddleware "ai-assistant/internal/middleware"
 	"ai-assistant/internal/repository"
 	"ai-assistant/internal/routes"
 	"ai-assistant/internal/usecase"
 	"ai-assistant/internal/services/ai/claude"
+	"ai-assistant/internal/services/ai/embedding"
 	"ai-assistant/internal/services/ai/gemini"
 	"ai-assistant/internal/services/auth"
+	"ai-assistant/internal/services/blob"
+	"ai-assistant/internal/services/blob/filesystem"
+	"ai-assistant/internal/services/blob/s3"
+	"ai-assistant/internal/services/email"
+	"ai-assistant/internal/services/email/gmail"
+	"ai-assistant/internal/services/email/imap"
+	"ai-assistant/internal/services/email/resend"
+	"ai-assistant/internal/services/email/smtp"
+	"ai-assistant/internal/services/imageproxy"
+	"ai-assistant/internal/services/inlineimage"
+	"ai-assistant/migrations"
 	"ai-assistant/pkg/cache"
 	"ai-assistant/pkg/database"
+	"ai-assistant/pkg/health"
 	"ai-assistant/pkg/logger"
+	"ai-assistant/pkg/metrics"
+	"ai-assistant/pkg/migrate"
+	"ai-assistant/pkg/tracing"
 )
 
+// configPollInterval is how often the config file is checked for changes.
+const configPollInterval = 10 * time.Second
+
 func main() {
-	cfg := config.Load()
+	cfg, err := config.Load(os.Args[1:])
+	if err != nil {
+		log.Fatalf("Invalid configuration:\n%v", err)
+	}
+	logCloser, err := logger.Setup(logger.Options{
+		Level:    cfg.Logging.Level,
+		Format:   cfg.Logging.Format,
+		Output:   cfg.Logging.Output,
+		FilePath: cfg.Logging.FilePath,
+	})
+	if err != nil {
+		log.Fatalf("Failed to set up logging: %v", err)
+	}
+	defer logCloser.Close()
 	appLogger := logger.New()
 
+	shutdownTracing, err := tracing.Setup(context.Background(), tracing.Options{
+		Exporter:    cfg.Tracing.Exporter,
+		Endpoint:    cfg.Tracing.OTLPEndpoint,
+		ServiceName: cfg.Tracing.ServiceName,
+		SampleRatio: cfg.Tracing.SampleRatio,
+	})
+	if err != nil {
+		appLogger.Error("Failed to set up tracing", "error", err)
+		os.Exit(1)
+	}
+	defer func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		if err := shutdownTracing(ctx); err != nil {
+			appLogger.Error("Failed to flush traces", "error", err)
+		}
+	}()
+	httperr.SetProduction(cfg.Server.Production())
+
 	db, err := database.New(cfg)
 	if err != nil {
-		appLogger.Error("Failed to connect to database:", err)
+		appLogger.Error("Failed to connect to database", "error", err)
 		os.Exit(1)
 	}
 	defer db.Close()
+	if err := metrics.RegisterDB(db.DB, "postgres"); err != nil {
+		appLogger.Warn("Failed to export database pool metrics", "error", err)
+	}
+
+	if cfg.Database.AutoMigrate {
+		if err := migrateDatabase(db, appLogger); err != nil {
+			appLogger.Error("Failed to migrate database", "error", err)
+			os.Exit(1)
+		}
+	}
 
 	redisService := cache.NewRedisService(cfg)
 	if redisService != nil {
 		if err := redisService.Connect(); err != nil {
-			appLogger.Warn("Failed to connect to Redis, continuing without cache:", err)
+			appLogger.Warn("Failed to connect to Redis, continuing without cache", "error", err)
 		} else {
 			defer redisService.Disconnect()
 		}
@@ -43,7 +111,7 @@ func main() {
 
 	geminiService, err := gemini.NewGeminiService(cfg)
 	if err != nil {
-		appLogger.Error("Failed to initialize Gemini service:", err)
+		appLogger.Error("Failed to initialize Gemini service", "error", err)
 		os.Exit(1)
 	}
 	defer geminiService.Close()
@@ -53,34 +121,100 @@ func main() {
 		appLogger.Warn("Claude service not configured (API key missing)")
 	}
 
+
 	userRepo := repository.NewUserRepository(db)
+	emailRepo := repository.NewEmailRepository(db)
+	draftRepo := repository.NewDraftRepository(db)
+	labelRepo := repository.NewLabelRepository(db)
+	ruleRepo := repository.NewRuleRepository(db)
+	accountRepo := repository.NewAccountRepository(db)
+	watchRepo := repository.NewGmailWatchRepository(db)
+	outboundRepo := repository.NewOutboundRepository(db)
+	scheduledRepo := repository.NewScheduledEmailRepository(db)
+	templateRepo := repository.NewTemplateRepository(db)
+
+	gmailAccounts := gmailProviders(cfg, accountRepo)
+	mailboxes, senders := newMailProviders(cfg, gmailAccounts)
 
-	aiUsecase := usecase.NewAIUsecase(geminiService, claudeService, redisService)
+	retrievalUsecase := usecase.NewRetrievalUsecase(emailRepo, newEmbedder(cfg, geminiService), newVectorStore(cfg, db), appLogger)
+	aiUsecase := usecase.NewAIUsecase(geminiService, claudeService, redisService, retrievalUsecase)
 	authUsecase := usecase.NewAuthUsecase(userRepo)
+	emailUsecase := usecase.NewEmailUsecase(emailRepo, outboundRepo, mailboxes, senders, newBlobStore(cfg, appLogger), retrievalUsecase)
+	draftUsecase := usecase.NewDraftUsecase(draftRepo, emailRepo, aiUsecase, emailUsecase)
+	threadUsecase := usecase.NewThreadUsecase(emailRepo, mailboxes)
+	labelUsecase := usecase.NewLabelUsecase(labelRepo, emailRepo, mailboxes)
+	ruleUsecase := usecase.NewRuleUsecase(ruleRepo, emailRepo, userRepo, labelUsecase, emailUsecase, aiUsecase)
+	emailUsecase.SetRuleRunner(ruleUsecase)
+	templateUsecase := usecase.NewTemplateUsecase(templateRepo)
+	// The proxy is passed on as an untyped nil when remote images are
+	// blocked, so the nil checks behind the interfaces work.
+	imageProxy := newImageProxy(cfg, appLogger)
+	var remoteImages usecase.ImageProxy
+	if imageProxy != nil {
+		remoteImages = imageProxy
+	}
+	inlineImages := inlineimage.New(imageSecret(cfg), cfg.Server.BaseURL)
+	sanitizeUsecase := usecase.NewSanitizeUsecase(emailRepo, inlineImages, remoteImages)
+	scheduleUsecase := usecase.NewScheduleUsecase(scheduledRepo, emailUsecase, templateUsecase, cfg.Email.UndoSendDelay, appLogger)
+	pushUsecase := usecase.NewPushUsecase(watchRepo, emailUsecase, gmailPushMailboxes(gmailAccounts), cfg.Google.PubSubTopic, appLogger)
+
+	workerCtx, stopWorkers := context.WithCancel(context.Background())
+	defer stopWorkers()
+	pushUsecase.Start(workerCtx)
+	retrievalUsecase.Start(workerCtx)
+	scheduleUsecase.Start(workerCtx)
+
+	aiUsecase.SetProviders(cfg.AI.DefaultProvider, cfg.AI.AllowedProviders)
+	aiUsecase.SetFeatures(cfg.Features.MailboxGrounding, cfg.Features.AIClassification)
+	aiRateLimiter := internalMiddleware.NewRateLimiter(cfg.RateLimit.AIRequestsPerMinute, cfg.RateLimit.AIBurst)
+	settings := config.NewReloader(cfg, os.Args[1:], appLogger)
+	settings.OnReload(func(next *config.Config) {
+		if err := logger.SetLevel(next.Logging.Level); err != nil {
+			appLogger.Error("Failed to change log level", "error", err)
+		}
+		aiUsecase.SetProviders(next.AI.DefaultProvider, next.AI.AllowedProviders)
+		aiUsecase.SetFeatures(next.Features.MailboxGrounding, next.Features.AIClassification)
+		aiRateLimiter.SetLimit(next.RateLimit.AIRequestsPerMinute, next.RateLimit.AIBurst)
+	})
+	settings.Start(workerCtx, configPollInterval)
 
 	authService := auth.NewAuthService(cfg)
 
 	aiHandler := handlers.NewAIHandler(aiUsecase)
-	authHandler := handlers.NewAuthHandler(authService)
-	emailHandler := handlers.NewEmailHandler()
+	authHandler := handlers.NewAuthHandler(authService, cfg)
+	emailHandler := handlers.NewEmailHandler(emailUsecase)
+	scheduleHandler := handlers.NewScheduleHandler(scheduleUsecase)
+	sanitizeHandler := handlers.NewSanitizeHandler(sanitizeUsecase)
+	draftHandler := handlers.NewDraftHandler(draftUsecase)
+	threadHandler := handlers.NewThreadHandler(threadUsecase)
+	labelHandler := handlers.NewLabelHandler(labelUsecase)
+	ruleHandler := handlers.NewRuleHandler(ruleUsecase)
+	templateHandler := handlers.NewTemplateHandler(templateUsecase)
+	imageProxyHandler := handlers.NewImageProxyHandler(nil)
+	if imageProxy != nil {
+		imageProxyHandler = handlers.NewImageProxyHandler(imageProxy)
+	}
+	inlineImageHandler := handlers.NewInlineImageHandler(emailUsecase, inlineImages)
+	pushVerifier := gmail.NewPushVerifier(gmail.NewJWKS(cfg.Google.PushCertsURL, nil), cfg.Google.PushAudience, cfg.Google.PushServiceAccount)
+	pushHandler := handlers.NewPushHandler(pushUsecase, pushVerifier)
+	webhookHandler := handlers.NewWebhookHandler(emailUsecase, newResendWebhookVerifier(cfg, appLogger))
 
 	// Setup routes
-	router := routes.SetupRoutes(authHandler, aiHandler, emailHandler, authService, redisService)
+	router := routes.SetupRoutes(authHandler, aiHandler, emailHandler, scheduleHandler, sanitizeHandler, draftHandler, threadHandler, labelHandler, ruleHandler, templateHandler, imageProxyHandler, inlineImageHandler, pushHandler, webhookHandler, authService, newHealthChecker(db, redisService, geminiService, claudeService), settings, aiRateLimiter)
 
 	server := &http.Server{
-		Addr:         ":" + cfg.Server.Port,
+		Addr:         cfg.Server.Addr(),
 		Handler:      router,
-		ReadTimeout:  10 * time.Second,
-		WriteTimeout: 10 * time.Second,
-		IdleTimeout:  15 * time.Second,
+		ReadTimeout:  cfg.Server.ReadTimeout,
+		WriteTimeout: cfg.Server.WriteTimeout,
+		IdleTimeout:  cfg.Server.IdleTimeout,
 	}
 
 	go func() {
-		appLogger.Infof("Server starting on port %s", cfg.Server.Port)
-		appLogger.Infof("Server URL: %s", cfg.Server.BaseURL)
+		appLogger.Info("Server starting", "port", cfg.Server.Port, "url", cfg.Server.BaseURL)
 		
 		if err := server.ListenAndServe(); err != nil && err != http.ErrServerClosed {
-			appLogger.Error("Failed to start server:", err)
+			appLogger.Error("Failed to start server", "error", err)
 			os.Exit(1)
 		}
 	}()
@@ -97,9 +231,209 @@ func main() {
 	defer cancel()
 
 	if err := server.Shutdown(ctx); err != nil {
-		appLogger.Error("Server forced to shutdown:", err)
+		appLogger.Error("Server forced to shutdown", "error", err)
 		os.Exit(1)
 	}
 
 	appLogger.Info("Server exited")
-}
\ No newline at end of file
+}
+// newHealthChecker registers the dependencies /readyz checks. Only the
+// database is critical: without Redis or a model provider the API still
+// serves mail.
+func newHealthChecker(db *database.DB, redisService *cache.RedisService, geminiService *gemini.GeminiService, claudeService *claude.ClaudeService) *health.Checker {
+	checker := health.New(5 * time.Second)
+	checker.Register("database", true, 2*time.Second, db.PingContext)
+	if redisService != nil {
+		checker.Register("redis", false, time.Second, redisService.Ping)
+	}
+	checker.Register("gemini", false, 3*time.Second, geminiService.Ping)
+	if claudeService != nil {
+		checker.Register("claude", false, 3*time.Second, claudeService.Ping)
+	}
+	return checker
+}
+
+// migrateDatabase applies pending migrations. Replicas starting together
+// wait for each other on the migration lock.
+func migrateDatabase(db *database.DB, appLogger *logger.Logger) error {
+	migrator, err := migrate.New(db.DB, migrations.FS)
+	if err != nil {
+		return err
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Minute)
+	defer cancel()
+
+	applied, err := migrator.Up(ctx, 0)
+	for _, m := range applied {
+		appLogger.Info("Applied migration", "version", m.Version, "name", m.Name)
+	}
+	return err
+}
+
+// newMailProviders picks the mailbox and sender named in the config, which
+// Config.Validate has checked are known and fully configured. Gmail clients
+// are per-user OAuth sessions, opened through gmailAccounts for each
+// request; the other providers are shared by every user.
+func newMailProviders(cfg *config.Config, gmailAccounts gmailProviderFactory) (email.MailboxFactory, email.SenderFactory) {
+	var mailboxes email.MailboxFactory
+	switch cfg.Email.MailboxProvider {
+	case "imap":
+		mailboxes = email.SharedMailbox(imap.NewIMAPService(cfg))
+	case "gmail":
+		mailboxes = func(ctx context.Context, userID string) (email.MailboxProvider, error) {
+			provider, err := gmailAccounts(ctx, userID)
+			if provider == nil || err != nil {
+				return nil, err
+			}
+			return provider, nil
+		}
+	}
+
+	var senders email.SenderFactory
+	switch cfg.Email.Sender {
+	case "resend":
+		senders = email.SharedSender(resend.NewResendService(cfg))
+	case "smtp":
+		senders = email.SharedSender(smtp.NewSMTPService(cfg))
+	case "gmail":
+		senders = func(ctx context.Context, userID string) (email.Sender, error) {
+			provider, err := gmailAccounts(ctx, userID)
+			if provider == nil || err != nil {
+				return nil, err
+			}
+			return provider, nil
+		}
+	}
+
+	return mailboxes, senders
+}
+
+// newBlobStore opens the configured attachment storage. It returns an
+// untyped nil if that fails, which leaves attachments unstored.
+func newBlobStore(cfg *config.Config, appLogger *logger.Logger) blob.Store {
+	switch cfg.Storage.Backend {
+	case "s3":
+		store, err := s3.NewStore(s3.Config{
+			Endpoint:        cfg.Storage.S3.Endpoint,
+			Region:          cfg.Storage.S3.Region,
+			Bucket:          cfg.Storage.S3.Bucket,
+			AccessKeyID:     cfg.Storage.S3.AccessKeyID,
+			SecretAccessKey: cfg.Storage.S3.SecretAccessKey,
+			PathStyle:       cfg.Storage.S3.PathStyle,
+		}, nil)
+		if err != nil {
+			appLogger.Warn("S3 storage not configured", "error", err)
+			return nil
+		}
+		return store
+	case "filesystem":
+		store, err := filesystem.NewStore(cfg.Storage.Dir)
+		if err != nil {
+			appLogger.Warn("Filesystem storage not available", "error", err)
+			return nil
+		}
+		return store
+	default:
+		appLogger.Warn("Unknown storage backend", "backend", cfg.Storage.Backend)
+		return nil
+	}
+}
+
+// imageSecret signs the URLs of the images in sanitized emails.
+func imageSecret(cfg *config.Config) string {
+	if cfg.Email.ImageProxySecret != "" {
+		return cfg.Email.ImageProxySecret
+	}
+	return cfg.Auth.JWTSecret
+}
+
+// newImageProxy returns nil when remote images are blocked.
+func newImageProxy(cfg *config.Config, appLogger *logger.Logger) *imageproxy.Proxy {
+	switch cfg.Email.RemoteImages {
+	case "proxy":
+		return imageproxy.New(imageSecret(cfg), cfg.Server.BaseURL, nil)
+	case "block":
+		return nil
+	default:
+		appLogger.Warn("Unknown remote image mode, blocking remote images", "mode", cfg.Email.RemoteImages)
+		return nil
+	}
+}
+
+func newResendWebhookVerifier(cfg *config.Config, appLogger *logger.Logger) *resend.WebhookVerifier {
+	if cfg.Email.ResendWebhookSecret == "" {
+		return nil
+	}
+	verifier, err := resend.NewWebhookVerifier(cfg.Email.ResendWebhookSecret)
+	if err != nil {
+		appLogger.Warn("Ignoring RESEND_WEBHOOK_SECRET", "error", err)
+		return nil
+	}
+	return verifier
+}
+
+// gmailProviderFactory opens a user's Gmail mailbox. It returns nil if the
+// user has not linked a Google account.
+type gmailProviderFactory func(ctx context.Context, userID string) (*gmail.Provider, error)
+
+// gmailProviders opens users' Gmail mailboxes with the OAuth tokens of their
+// linked Google accounts.
+func gmailProviders(cfg *config.Config, accountRepo *repository.AccountRepository) gmailProviderFactory {
+	return func(ctx context.Context, userID string) (*gmail.Provider, error) {
+		account, err := accountRepo.GetByUserAndProvider(ctx, userID, "google")
+		if err != nil {
+			return nil, err
+		}
+		if account == nil || (account.AccessToken == nil && account.RefreshToken == nil) {
+			return nil, nil
+		}
+
+		token := &oauth2.Token{
+			AccessToken:  derefString(account.AccessToken),
+			RefreshToken: derefString(account.RefreshToken),
+			TokenType:    derefString(account.TokenType),
+		}
+		if account.ExpiresAt != nil {
+			token.Expiry = time.Unix(int64(*account.ExpiresAt), 0)
+		}
+
+		gmailService, err := gmail.NewGmailService(cfg, token)
+		if err != nil {
+			return nil, err
+		}
+		return gmail.NewProvider(gmailService, "me"), nil
+	}
+}
+
+// gmailPushMailboxes adapts gmailAccounts to push sync. Users without a
+// Google account get an untyped nil, so the usecase's nil checks work.
+func gmailPushMailboxes(gmailAccounts gmailProviderFactory) usecase.PushMailboxFactory {
+	return func(ctx context.Context, userID string) (usecase.PushMailbox, error) {
+		provider, err := gmailAccounts(ctx, userID)
+		if provider == nil || err != nil {
+			return nil, err
+		}
+		return provider, nil
+	}
+}
+
+func derefString(s *string) string {
+	if s == nil {
+		return ""
+	}
+	return *s
+}
+
+func newEmbedder(cfg *config.Config, geminiService *gemini.GeminiService) embedding.Embedder {
+	if cfg.AI.EmbeddingProvider == "local" {
+		return embedding.NewLocalEmbedder(0)
+	}
+	return geminiService.Embedder()
+}
+
+func newVectorStore(cfg *config.Config, db *database.DB) embedding.Store {
+	if cfg.AI.VectorStore == "memory" {
+		return embedding.NewMemoryIndex()
+	}
+	return repository.NewEmbeddingRepository(db)
+}
@@ -0,0 +1,133 @@
+// Command migrate manages the database schema:
+//
+//	migrate up [-to VERSION]     apply pending migrations
+//	migrate down [-steps N]      revert the latest N migrations (default 1)
+//	migrate status               list migrations and when they were applied
+//	migrate baseline -to VERSION record migrations as applied without running them
+//
+// The database is configured as for the API: -database-url, DATABASE_URL
+// (also read from .env) or the config file named by -config or CONFIG_FILE,
+// in the section chosen by -env or APP_ENV.
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"ai-assistant/internal/app/config"
+	"ai-assistant/migrations"
+	"ai-assistant/pkg/database"
+	"ai-assistant/pkg/migrate"
+)
+
+// configFlags are passed on to config.LoadDatabase.
+var configFlags = map[string]bool{"config": true, "env": true, "database-url": true}
+
+func main() {
+	if len(os.Args) < 2 {
+		usage()
+	}
+	command := os.Args[1]
+
+	flags := flag.NewFlagSet(command, flag.ExitOnError)
+	flags.String("config", "", "YAML config file (CONFIG_FILE)")
+	flags.String("env", "", "section of the config file to use (APP_ENV)")
+	flags.String("database-url", "", "PostgreSQL connection URL (DATABASE_URL)")
+	to := flags.Int64("to", 0, "target version for up and baseline")
+	steps := flags.Int("steps", 1, "how many migrations down reverts")
+	flags.Parse(os.Args[2:])
+
+	var configArgs []string
+	flags.Visit(func(f *flag.Flag) {
+		if configFlags[f.Name] {
+			configArgs = append(configArgs, "-"+f.Name+"="+f.Value.String())
+		}
+	})
+	cfg, err := config.LoadDatabase(configArgs)
+	if err != nil {
+		fail(err)
+	}
+
+	db, err := database.New(cfg)
+	if err != nil {
+		fail(err)
+	}
+	defer db.Close()
+
+	migrator, err := migrate.New(db.DB, migrations.FS)
+	if err != nil {
+		fail(err)
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	switch command {
+	case "up":
+		applied, err := migrator.Up(ctx, *to)
+		report("applied", applied)
+		if err != nil {
+			fail(err)
+		}
+		if len(applied) == 0 {
+			fmt.Println("schema is up to date")
+		}
+	case "down":
+		if *steps < 1 {
+			fail(fmt.Errorf("-steps must be at least 1"))
+		}
+		reverted, err := migrator.Down(ctx, *steps)
+		report("reverted", reverted)
+		if err != nil {
+			fail(err)
+		}
+	case "baseline":
+		if *to <= 0 {
+			fail(fmt.Errorf("baseline needs -to VERSION"))
+		}
+		recorded, err := migrator.Baseline(ctx, *to)
+		report("baselined", recorded)
+		if err != nil {
+			fail(err)
+		}
+	case "status":
+		statuses, err := migrator.Status(ctx)
+		if err != nil {
+			fail(err)
+		}
+		for _, s := range statuses {
+			state := "pending"
+			switch {
+			case s.AppliedAt != nil && s.Up == "":
+				state = "applied " + s.AppliedAt.Format("2006-01-02 15:04:05") + " (unknown to this binary)"
+			case s.AppliedAt != nil && s.Modified:
+				state = "applied " + s.AppliedAt.Format("2006-01-02 15:04:05") + " (modified since)"
+			case s.AppliedAt != nil:
+				state = "applied " + s.AppliedAt.Format("2006-01-02 15:04:05")
+			}
+			fmt.Printf("%04d  %-30s %s\n", s.Version, s.Name, state)
+		}
+	default:
+		usage()
+	}
+}
+
+func report(verb string, list []*migrate.Migration) {
+	for _, m := range list {
+		fmt.Printf("%s %04d_%s\n", verb, m.Version, m.Name)
+	}
+}
+
+func usage() {
+	fmt.Fprintln(os.Stderr, "usage: migrate up|down|status|baseline [-config FILE] [-env ENV] [-database-url URL] [-to VERSION] [-steps N]")
+	os.Exit(2)
+}
+
+func fail(err error) {
+	fmt.Fprintln(os.Stderr, "migrate:", err)
+	os.Exit(1)
+}
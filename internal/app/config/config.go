@@ -1,103 +1,271 @@
 package config
 
-import (
-	"log"
-	"os"
-
-	"github.com/joho/godotenv"
-)
+import "time"
 
 type Config struct {
-	Server   ServerConfig
-	Database DatabaseConfig
-	Redis    RedisConfig
-	Google   GoogleConfig
-	AI       AIConfig
-	Email    EmailConfig
-	Auth     AuthConfig
+	Server   ServerConfig   `yaml:"server"`
+	Database DatabaseConfig `yaml:"database"`
+	Redis    RedisConfig    `yaml:"redis"`
+	Google   GoogleConfig   `yaml:"google"`
+	AI       AIConfig       `yaml:"ai"`
+	Email    EmailConfig    `yaml:"email"`
+	Storage  StorageConfig  `yaml:"storage"`
+	Auth     AuthConfig     `yaml:"auth"`
+	Logging  LoggingConfig  `yaml:"logging"`
+	Tracing  TracingConfig  `yaml:"tracing"`
+	CORS     CORSConfig     `yaml:"cors"`
+	// RateLimit holds per-user request limits.
+	RateLimit RateLimitConfig `yaml:"rate_limit"`
+	Features  FeaturesConfig  `yaml:"features"`
 }
 
 type ServerConfig struct {
-	Port    string
-	BaseURL string
-	GinMode string
+	Port string `yaml:"port"`
+	// Host is the interface to listen on; empty listens on all of them.
+	Host    string `yaml:"host"`
+	BaseURL string `yaml:"base_url"`
+	GinMode string `yaml:"gin_mode"`
+
+	ReadTimeout  time.Duration `yaml:"read_timeout"`
+	WriteTimeout time.Duration `yaml:"write_timeout"`
+	IdleTimeout  time.Duration `yaml:"idle_timeout"`
+}
+
+// Production reports whether the server runs in release mode, where error
+// responses leave out internal details.
+func (s ServerConfig) Production() bool {
+	return s.GinMode == "release"
+}
+
+// Addr is the address to listen on.
+func (s ServerConfig) Addr() string {
+	return s.Host + ":" + s.Port
 }
 
 type DatabaseConfig struct {
-	URL string
+	URL string `yaml:"url"`
+	// AutoMigrate applies pending migrations when the API starts.
+	AutoMigrate bool `yaml:"auto_migrate"`
+
+	MaxOpenConns    int           `yaml:"max_open_conns"`
+	MaxIdleConns    int           `yaml:"max_idle_conns"`
+	ConnMaxLifetime time.Duration `yaml:"conn_max_lifetime"`
 }
 
 type RedisConfig struct {
-	URL string
+	URL          string `yaml:"url"`
+	MaxRetries   int    `yaml:"max_retries"`
+	PoolSize     int    `yaml:"pool_size"`
+	MinIdleConns int    `yaml:"min_idle_conns"`
 }
 
 type GoogleConfig struct {
-	ClientID     string
-	ClientSecret string
+	ClientID     string `yaml:"client_id"`
+	ClientSecret string `yaml:"client_secret"`
+	// PubSubTopic is the topic Gmail publishes mailbox changes to, as
+	// projects/<project>/topics/<topic>. Push sync is off when empty.
+	PubSubTopic string `yaml:"pubsub_topic"`
+	// PushAudience is the audience of the push subscription's OIDC tokens.
+	PushAudience string `yaml:"push_audience"`
+	// PushServiceAccount, if set, is the only account pushes are accepted from.
+	PushServiceAccount string `yaml:"push_service_account"`
+	// PushCertsURL serves the keys push tokens are verified with.
+	PushCertsURL string `yaml:"push_certs_url"`
 }
 
 type AIConfig struct {
-	GeminiAPIKey string
-	ClaudeAPIKey string
+	GeminiAPIKey string `yaml:"gemini_api_key"`
+	ClaudeAPIKey string `yaml:"claude_api_key"`
+	// EmbeddingProvider is "gemini" or "local" (deterministic, offline).
+	EmbeddingProvider string `yaml:"embedding_provider"`
+	// VectorStore is "pgvector" or "memory".
+	VectorStore string `yaml:"vector_store"`
+	// DefaultProvider answers AI requests that name no provider.
+	DefaultProvider string `yaml:"default_provider"`
+	// AllowedProviders are the providers AI requests may name.
+	AllowedProviders []string `yaml:"allowed_providers"`
 }
 
 type EmailConfig struct {
-	ResendAPIKey   string
-	ResendFromEmail string
+	ResendAPIKey    string `yaml:"resend_api_key"`
+	ResendFromEmail string `yaml:"resend_from_email"`
+	// ResendWebhookSecret is the "whsec_..." signing secret of the webhook
+	// endpoint; delivery events are rejected without it.
+	ResendWebhookSecret string `yaml:"resend_webhook_secret"`
+	// UndoSendDelay holds immediate sends in the queue so they can still be
+	// cancelled.
+	UndoSendDelay time.Duration `yaml:"undo_send_delay"`
+	// MailboxProvider selects where mail is read from: "gmail" or "imap".
+	MailboxProvider string `yaml:"mailbox_provider"`
+	// Sender selects how mail is sent: "resend", "smtp" or "gmail".
+	Sender string `yaml:"sender"`
+	// RemoteImages is "proxy" to load received mail's remote images through
+	// the image proxy, or "block" to remove them.
+	RemoteImages string `yaml:"remote_images"`
+	// ImageProxySecret signs image proxy and inline image URLs; JWT_SECRET
+	// is used if empty.
+	ImageProxySecret string     `yaml:"image_proxy_secret"`
+	SMTP             SMTPConfig `yaml:"smtp"`
+	IMAP             IMAPConfig `yaml:"imap"`
+}
+
+type SMTPConfig struct {
+	Host     string `yaml:"host"`
+	Port     string `yaml:"port"`
+	Username string `yaml:"username"`
+	Password string `yaml:"password"`
+	From     string `yaml:"from"`
+	// TLSMode is "starttls", "tls" (implicit) or "none".
+	TLSMode string `yaml:"tls_mode"`
+}
+
+type IMAPConfig struct {
+	Addr     string `yaml:"addr"`
+	Username string `yaml:"username"`
+	Password string `yaml:"password"`
+	Mailbox  string `yaml:"mailbox"`
+	TLS      bool   `yaml:"tls"`
+}
+
+// StorageConfig selects where attachment content is kept.
+type StorageConfig struct {
+	// Backend is "filesystem" or "s3".
+	Backend string   `yaml:"backend"`
+	Dir     string   `yaml:"dir"`
+	S3      S3Config `yaml:"s3"`
+}
+
+type S3Config struct {
+	Endpoint        string `yaml:"endpoint"`
+	Region          string `yaml:"region"`
+	Bucket          string `yaml:"bucket"`
+	AccessKeyID     string `yaml:"access_key_id"`
+	SecretAccessKey string `yaml:"secret_access_key"`
+	PathStyle       bool   `yaml:"path_style"`
 }
 
 type AuthConfig struct {
-	JWTSecret string
+	JWTSecret string `yaml:"jwt_secret"`
 }
 
-func Load() *Config {
-	if err := godotenv.Load(); err != nil {
-		log.Println("No .env file found, using environment variables")
-	}
+// LoggingConfig configures the structured logger.
+type LoggingConfig struct {
+	// Level is debug, info, warn or error.
+	Level string `yaml:"level"`
+	// Format is json or text.
+	Format string `yaml:"format"`
+	// Output is stdout, stderr or file, which appends to FilePath.
+	Output   string `yaml:"output"`
+	FilePath string `yaml:"file_path"`
+}
+
+// TracingConfig configures OpenTelemetry tracing.
+type TracingConfig struct {
+	// Exporter is otlp, stdout or none.
+	Exporter string `yaml:"exporter"`
+	// OTLPEndpoint is the collector's OTLP/HTTP URL. When empty, the
+	// standard OTEL_EXPORTER_OTLP_* variables apply.
+	OTLPEndpoint string `yaml:"otlp_endpoint"`
+	ServiceName  string `yaml:"service_name"`
+	// SampleRatio is the share of new traces recorded, from 0 to 1.
+	SampleRatio float64 `yaml:"sample_ratio"`
+}
+
+// CORSConfig lists the origins browsers may call the API from.
+type CORSConfig struct {
+	// AllowedOrigins are origins like https://app.example.com, or "*" for
+	// any origin.
+	AllowedOrigins []string `yaml:"allowed_origins"`
+}
+
+type RateLimitConfig struct {
+	// AIRequestsPerMinute is how many AI requests a user may make per
+	// minute, on average. Zero turns the limit off.
+	AIRequestsPerMinute int `yaml:"ai_requests_per_minute"`
+	// AIBurst is how many AI requests a user may make at once.
+	AIBurst int `yaml:"ai_burst"`
+}
 
-	config := &Config{
+// FeaturesConfig switches optional features on and off.
+type FeaturesConfig struct {
+	// MailboxGrounding lets AI requests be answered from the user's emails.
+	MailboxGrounding bool `yaml:"mailbox_grounding"`
+	// AIClassification lets mail rules sort emails into categories with the
+	// AI model.
+	AIClassification bool `yaml:"ai_classification"`
+}
+
+// Default returns the settings used where no layer sets a value.
+func Default() *Config {
+	return &Config{
 		Server: ServerConfig{
-			Port:    getEnv("PORT", "8080"),
-			BaseURL: getEnv("BASE_URL", "http://localhost:8080"),
-			GinMode: getEnv("GIN_MODE", "debug"),
+			Port:         "8080",
+			BaseURL:      "http://localhost:8080",
+			GinMode:      "debug",
+			ReadTimeout:  10 * time.Second,
+			WriteTimeout: 10 * time.Second,
+			IdleTimeout:  15 * time.Second,
 		},
 		Database: DatabaseConfig{
-			URL: mustGetEnv("DATABASE_URL"),
+			MaxOpenConns:    25,
+			MaxIdleConns:    25,
+			ConnMaxLifetime: 5 * time.Minute,
 		},
 		Redis: RedisConfig{
-			URL: getEnv("REDIS_URL", "redis://localhost:6379"),
+			URL:        "redis://localhost:6379",
+			MaxRetries: 3,
+			PoolSize:   10,
 		},
 		Google: GoogleConfig{
-			ClientID:     mustGetEnv("GOOGLE_CLIENT_ID"),
-			ClientSecret: mustGetEnv("GOOGLE_CLIENT_SECRET"),
+			PushCertsURL: "https://www.googleapis.com/oauth2/v3/certs",
 		},
 		AI: AIConfig{
-			GeminiAPIKey: mustGetEnv("GEMINI_API_KEY"),
-			ClaudeAPIKey: getEnv("CLAUDE_API_KEY", ""),
+			EmbeddingProvider: "gemini",
+			VectorStore:       "pgvector",
+			DefaultProvider:   "gemini",
+			AllowedProviders:  []string{"gemini", "claude"},
 		},
 		Email: EmailConfig{
-			ResendAPIKey:   mustGetEnv("RESEND_API_KEY"),
-			ResendFromEmail: mustGetEnv("RESEND_FROM_EMAIL"),
+			UndoSendDelay:   10 * time.Second,
+			MailboxProvider: "gmail",
+			Sender:          "resend",
+			RemoteImages:    "proxy",
+			SMTP: SMTPConfig{
+				Port:    "587",
+				TLSMode: "starttls",
+			},
+			IMAP: IMAPConfig{
+				Mailbox: "INBOX",
+				TLS:     true,
+			},
 		},
-		Auth: AuthConfig{
-			JWTSecret: mustGetEnv("JWT_SECRET"),
+		Storage: StorageConfig{
+			Backend: "filesystem",
+			Dir:     "./data/blobs",
+			S3: S3Config{
+				Endpoint: "https://s3.amazonaws.com",
+				Region:   "us-east-1",
+			},
+		},
+		Logging: LoggingConfig{
+			Level:  "info",
+			Format: "json",
+			Output: "stdout",
+		},
+		Tracing: TracingConfig{
+			Exporter:    "none",
+			ServiceName: "ai-assistant",
+			SampleRatio: 1,
+		},
+		CORS: CORSConfig{
+			AllowedOrigins: []string{"*"},
+		},
+		RateLimit: RateLimitConfig{
+			AIBurst: 5,
+		},
+		Features: FeaturesConfig{
+			MailboxGrounding: true,
+			AIClassification: true,
 		},
 	}
-
-	return config
-}
-
-func getEnv(key, defaultValue string) string {
-	if value := os.Getenv(key); value != "" {
-		return value
-	}
-	return defaultValue
 }
-
-func mustGetEnv(key string) string {
-	value := os.Getenv(key)
-	if value == "" {
-		log.Fatalf("Environment variable %s is required", key)
-	}
-	return value
-}
\ No newline at end of file
@@ -0,0 +1,353 @@
+package config
+
+import (
+	"bytes"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/joho/godotenv"
+	"gopkg.in/yaml.v3"
+)
+
+// DefaultEnvironment is the section of the config file used when neither
+// -env nor APP_ENV names one.
+const DefaultEnvironment = "development"
+
+// Load builds the configuration in layers, each overriding the one before:
+//
+//  1. the defaults,
+//  2. the environment's section of the YAML config file (-config or
+//     CONFIG_FILE; none by default), chosen by -env or APP_ENV,
+//  3. environment variables, also read from a .env file,
+//  4. command-line flags in args.
+//
+// It then validates the result. The error lists every problem found, not
+// just the first.
+func Load(args []string) (*Config, error) {
+	return load(args, (*Config).Validate)
+}
+
+// LoadDatabase loads the configuration like Load but only validates the
+// database settings, for tools such as cmd/migrate that use nothing else.
+func LoadDatabase(args []string) (*Config, error) {
+	return load(args, (*Config).ValidateDatabase)
+}
+
+func load(args []string, validate func(*Config) error) (*Config, error) {
+	// A missing .env file is normal outside development.
+	godotenv.Load()
+
+	flags, err := parseFlags(args)
+	if err != nil {
+		return nil, err
+	}
+
+	cfg := Default()
+	var errs []error
+
+	file := flags.file()
+	environment := firstNonEmpty(flags.environment, os.Getenv("APP_ENV"), DefaultEnvironment)
+	if file != "" {
+		if err := loadFile(cfg, file, environment); err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	errs = append(errs, loadEnv(cfg)...)
+	flags.apply(cfg)
+
+	if cfg.Google.PushAudience == "" {
+		// Pub/Sub uses the push endpoint URL when no audience is configured.
+		cfg.Google.PushAudience = cfg.Server.BaseURL + "/api/gmail/push"
+	}
+
+	errs = append(errs, validate(cfg))
+	if err := errors.Join(errs...); err != nil {
+		return nil, err
+	}
+	return cfg, nil
+}
+
+// loadFile applies the environment's section of a YAML file whose top-level
+// keys are environment names. Keys it does not know are errors, so that a
+// misspelt setting does not go unnoticed.
+func loadFile(cfg *Config, path, environment string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("config file: %w", err)
+	}
+
+	var sections map[string]yaml.Node
+	if err := yaml.Unmarshal(data, &sections); err != nil {
+		return fmt.Errorf("config file %s: %w", path, err)
+	}
+	section, ok := sections[environment]
+	if !ok {
+		return fmt.Errorf("config file %s: no %q section", path, environment)
+	}
+
+	// Node.Decode cannot reject unknown keys, so the section is decoded
+	// again from its own text.
+	sectionData, err := yaml.Marshal(&section)
+	if err != nil {
+		return fmt.Errorf("config file %s: %w", path, err)
+	}
+	decoder := yaml.NewDecoder(bytes.NewReader(sectionData))
+	decoder.KnownFields(true)
+	if err := decoder.Decode(cfg); err != nil {
+		return fmt.Errorf("config file %s, section %s: %w", path, environment, err)
+	}
+	return nil
+}
+
+// loadEnv applies the environment variables that are set.
+func loadEnv(cfg *Config) []error {
+	e := &envReader{}
+
+	e.str(&cfg.Server.Port, "PORT")
+	e.str(&cfg.Server.Host, "HOST")
+	e.str(&cfg.Server.BaseURL, "BASE_URL")
+	e.str(&cfg.Server.GinMode, "GIN_MODE")
+	e.duration(&cfg.Server.ReadTimeout, "SERVER_READ_TIMEOUT")
+	e.duration(&cfg.Server.WriteTimeout, "SERVER_WRITE_TIMEOUT")
+	e.duration(&cfg.Server.IdleTimeout, "SERVER_IDLE_TIMEOUT")
+
+	e.str(&cfg.Database.URL, "DATABASE_URL")
+	e.boolean(&cfg.Database.AutoMigrate, "AUTO_MIGRATE")
+	e.integer(&cfg.Database.MaxOpenConns, "DATABASE_MAX_OPEN_CONNS")
+	e.integer(&cfg.Database.MaxIdleConns, "DATABASE_MAX_IDLE_CONNS")
+	e.duration(&cfg.Database.ConnMaxLifetime, "DATABASE_CONN_MAX_LIFETIME")
+
+	e.str(&cfg.Redis.URL, "REDIS_URL")
+	e.integer(&cfg.Redis.MaxRetries, "REDIS_MAX_RETRIES")
+	e.integer(&cfg.Redis.PoolSize, "REDIS_POOL_SIZE")
+	e.integer(&cfg.Redis.MinIdleConns, "REDIS_MIN_IDLE_CONNS")
+
+	e.str(&cfg.Google.ClientID, "GOOGLE_CLIENT_ID")
+	e.str(&cfg.Google.ClientSecret, "GOOGLE_CLIENT_SECRET")
+	e.str(&cfg.Google.PubSubTopic, "GMAIL_PUBSUB_TOPIC")
+	e.str(&cfg.Google.PushAudience, "GMAIL_PUSH_AUDIENCE")
+	e.str(&cfg.Google.PushServiceAccount, "GMAIL_PUSH_SERVICE_ACCOUNT")
+	e.str(&cfg.Google.PushCertsURL, "GMAIL_PUSH_CERTS_URL")
+
+	e.str(&cfg.AI.GeminiAPIKey, "GEMINI_API_KEY")
+	e.str(&cfg.AI.ClaudeAPIKey, "CLAUDE_API_KEY")
+	e.str(&cfg.AI.EmbeddingProvider, "EMBEDDING_PROVIDER")
+	e.str(&cfg.AI.VectorStore, "VECTOR_STORE")
+	e.str(&cfg.AI.DefaultProvider, "AI_DEFAULT_PROVIDER")
+	e.list(&cfg.AI.AllowedProviders, "AI_ALLOWED_PROVIDERS")
+
+	e.str(&cfg.Email.ResendAPIKey, "RESEND_API_KEY")
+	e.str(&cfg.Email.ResendFromEmail, "RESEND_FROM_EMAIL")
+	e.str(&cfg.Email.ResendWebhookSecret, "RESEND_WEBHOOK_SECRET")
+	e.duration(&cfg.Email.UndoSendDelay, "UNDO_SEND_DELAY")
+	e.str(&cfg.Email.MailboxProvider, "MAIL_PROVIDER")
+	e.str(&cfg.Email.Sender, "MAIL_SENDER")
+	e.str(&cfg.Email.RemoteImages, "REMOTE_IMAGES")
+	e.str(&cfg.Email.ImageProxySecret, "IMAGE_PROXY_SECRET")
+	e.str(&cfg.Email.SMTP.Host, "SMTP_HOST")
+	e.str(&cfg.Email.SMTP.Port, "SMTP_PORT")
+	e.str(&cfg.Email.SMTP.Username, "SMTP_USERNAME")
+	e.str(&cfg.Email.SMTP.Password, "SMTP_PASSWORD")
+	e.str(&cfg.Email.SMTP.From, "SMTP_FROM")
+	e.str(&cfg.Email.SMTP.TLSMode, "SMTP_TLS")
+	e.str(&cfg.Email.IMAP.Addr, "IMAP_ADDR")
+	e.str(&cfg.Email.IMAP.Username, "IMAP_USERNAME")
+	e.str(&cfg.Email.IMAP.Password, "IMAP_PASSWORD")
+	e.str(&cfg.Email.IMAP.Mailbox, "IMAP_MAILBOX")
+	e.boolean(&cfg.Email.IMAP.TLS, "IMAP_TLS")
+
+	e.str(&cfg.Storage.Backend, "STORAGE_BACKEND")
+	e.str(&cfg.Storage.Dir, "STORAGE_DIR")
+	e.str(&cfg.Storage.S3.Endpoint, "S3_ENDPOINT")
+	e.str(&cfg.Storage.S3.Region, "S3_REGION")
+	e.str(&cfg.Storage.S3.Bucket, "S3_BUCKET")
+	e.str(&cfg.Storage.S3.AccessKeyID, "S3_ACCESS_KEY_ID")
+	e.str(&cfg.Storage.S3.SecretAccessKey, "S3_SECRET_ACCESS_KEY")
+	e.boolean(&cfg.Storage.S3.PathStyle, "S3_PATH_STYLE")
+
+	e.str(&cfg.Auth.JWTSecret, "JWT_SECRET")
+
+	e.str(&cfg.Logging.Level, "LOG_LEVEL")
+	e.str(&cfg.Logging.Format, "LOG_FORMAT")
+	e.str(&cfg.Logging.Output, "LOG_OUTPUT")
+	e.str(&cfg.Logging.FilePath, "LOG_FILE")
+
+	e.str(&cfg.Tracing.Exporter, "TRACING_EXPORTER")
+	e.str(&cfg.Tracing.OTLPEndpoint, "TRACING_OTLP_ENDPOINT")
+	e.str(&cfg.Tracing.ServiceName, "TRACING_SERVICE_NAME")
+	e.float(&cfg.Tracing.SampleRatio, "TRACING_SAMPLE_RATIO")
+
+	e.list(&cfg.CORS.AllowedOrigins, "CORS_ALLOWED_ORIGINS")
+
+	e.integer(&cfg.RateLimit.AIRequestsPerMinute, "RATE_LIMIT_AI_PER_MINUTE")
+	e.integer(&cfg.RateLimit.AIBurst, "RATE_LIMIT_AI_BURST")
+
+	e.boolean(&cfg.Features.MailboxGrounding, "FEATURE_MAILBOX_GROUNDING")
+	e.boolean(&cfg.Features.AIClassification, "FEATURE_AI_CLASSIFICATION")
+
+	return e.errs
+}
+
+// envReader sets fields from the environment variables that are set,
+// collecting the values it cannot parse.
+type envReader struct {
+	errs []error
+}
+
+func (e *envReader) str(dst *string, key string) {
+	if value := os.Getenv(key); value != "" {
+		*dst = value
+	}
+}
+
+// list reads a comma-separated list, such as "gemini, claude".
+func (e *envReader) list(dst *[]string, key string) {
+	value := os.Getenv(key)
+	if value == "" {
+		return
+	}
+	var items []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	*dst = items
+}
+
+func (e *envReader) boolean(dst *bool, key string) {
+	value := os.Getenv(key)
+	if value == "" {
+		return
+	}
+	b, err := strconv.ParseBool(value)
+	if err != nil {
+		e.errs = append(e.errs, fmt.Errorf("%s: %q is not true or false", key, value))
+		return
+	}
+	*dst = b
+}
+
+func (e *envReader) integer(dst *int, key string) {
+	value := os.Getenv(key)
+	if value == "" {
+		return
+	}
+	n, err := strconv.Atoi(value)
+	if err != nil {
+		e.errs = append(e.errs, fmt.Errorf("%s: %q is not a whole number", key, value))
+		return
+	}
+	*dst = n
+}
+
+func (e *envReader) float(dst *float64, key string) {
+	value := os.Getenv(key)
+	if value == "" {
+		return
+	}
+	f, err := strconv.ParseFloat(value, 64)
+	if err != nil {
+		e.errs = append(e.errs, fmt.Errorf("%s: %q is not a number", key, value))
+		return
+	}
+	*dst = f
+}
+
+func (e *envReader) duration(dst *time.Duration, key string) {
+	value := os.Getenv(key)
+	if value == "" {
+		return
+	}
+	d, err := time.ParseDuration(value)
+	if err != nil {
+		e.errs = append(e.errs, fmt.Errorf("%s: %q is not a duration like 10s", key, value))
+		return
+	}
+	*dst = d
+}
+
+// cliFlags holds the command-line flags. Only flags given on the command
+// line override the other layers.
+type cliFlags struct {
+	configFile  string
+	environment string
+	set         map[string]bool
+
+	port         string
+	baseURL      string
+	databaseURL  string
+	autoMigrate  bool
+	logLevel     string
+	logFormat    string
+	mailProvider string
+	mailSender   string
+}
+
+func parseFlags(args []string) (*cliFlags, error) {
+	f := &cliFlags{set: map[string]bool{}}
+	fs := flag.NewFlagSet("ai-assistant", flag.ContinueOnError)
+	fs.StringVar(&f.configFile, "config", "", "YAML config file (CONFIG_FILE)")
+	fs.StringVar(&f.environment, "env", "", "section of the config file to use (APP_ENV, default "+DefaultEnvironment+")")
+	fs.StringVar(&f.port, "port", "", "port to listen on (PORT)")
+	fs.StringVar(&f.baseURL, "base-url", "", "public URL of the API (BASE_URL)")
+	fs.StringVar(&f.databaseURL, "database-url", "", "PostgreSQL connection URL (DATABASE_URL)")
+	fs.BoolVar(&f.autoMigrate, "auto-migrate", false, "apply pending migrations on startup (AUTO_MIGRATE)")
+	fs.StringVar(&f.logLevel, "log-level", "", "debug, info, warn or error (LOG_LEVEL)")
+	fs.StringVar(&f.logFormat, "log-format", "", "json or text (LOG_FORMAT)")
+	fs.StringVar(&f.mailProvider, "mail-provider", "", "gmail or imap (MAIL_PROVIDER)")
+	fs.StringVar(&f.mailSender, "mail-sender", "", "resend, smtp or gmail (MAIL_SENDER)")
+
+	if err := fs.Parse(args); err != nil {
+		return nil, err
+	}
+	fs.Visit(func(fl *flag.Flag) { f.set[fl.Name] = true })
+	return f, nil
+}
+
+// file is the config file to read, if any.
+func (f *cliFlags) file() string {
+	return firstNonEmpty(f.configFile, os.Getenv("CONFIG_FILE"))
+}
+
+func (f *cliFlags) apply(cfg *Config) {
+	if f.set["port"] {
+		cfg.Server.Port = f.port
+	}
+	if f.set["base-url"] {
+		cfg.Server.BaseURL = f.baseURL
+	}
+	if f.set["database-url"] {
+		cfg.Database.URL = f.databaseURL
+	}
+	if f.set["auto-migrate"] {
+		cfg.Database.AutoMigrate = f.autoMigrate
+	}
+	if f.set["log-level"] {
+		cfg.Logging.Level = f.logLevel
+	}
+	if f.set["log-format"] {
+		cfg.Logging.Format = f.logFormat
+	}
+	if f.set["mail-provider"] {
+		cfg.Email.MailboxProvider = f.mailProvider
+	}
+	if f.set["mail-sender"] {
+		cfg.Email.Sender = f.mailSender
+	}
+}
+
+func firstNonEmpty(values ...string) string {
+	for _, v := range values {
+		if v != "" {
+			return v
+		}
+	}
+	return ""
+}
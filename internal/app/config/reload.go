@@ -0,0 +1,171 @@
+package config
+
+import (
+	"context"
+	"os"
+	"os/signal"
+	"reflect"
+	"sync"
+	"sync/atomic"
+	"syscall"
+	"time"
+
+	"ai-assistant/pkg/logger"
+)
+
+// Reloader holds the running configuration and loads it again on SIGHUP or
+// when the config file changes. Only the settings read while serving
+// requests change without a restart: the log level, CORS origins, AI
+// provider defaults, rate limits and feature flags. Changes to the others
+// are logged as needing a restart and otherwise ignored.
+type Reloader struct {
+	args    []string
+	file    string
+	logger  *logger.Logger
+	current atomic.Pointer[Config]
+
+	// mu serializes reloads and guards listeners.
+	mu        sync.Mutex
+	listeners []func(*Config)
+}
+
+// NewReloader returns a Reloader running cfg, which Load returned for args.
+func NewReloader(cfg *Config, args []string, appLogger *logger.Logger) *Reloader {
+	r := &Reloader{args: args, logger: appLogger}
+	if flags, err := parseFlags(args); err == nil {
+		r.file = flags.file()
+	}
+	r.current.Store(cfg)
+	return r
+}
+
+// Current returns the running configuration. It must not be modified.
+func (r *Reloader) Current() *Config {
+	return r.current.Load()
+}
+
+// OnReload registers fn to be called with the new configuration after each
+// reload that changed it.
+func (r *Reloader) OnReload(fn func(*Config)) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.listeners = append(r.listeners, fn)
+}
+
+// Reload loads the configuration like Load does and, if it is valid, swaps
+// its reloadable settings in. The running configuration is kept when the
+// new one is invalid.
+func (r *Reloader) Reload() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	loaded, err := Load(r.args)
+	if err != nil {
+		r.logger.Error("Configuration reload rejected, keeping the running configuration", "error", err)
+		return err
+	}
+	current := r.current.Load()
+	next := current.withReloadable(loaded)
+	if err := next.Validate(); err != nil {
+		r.logger.Error("Configuration reload rejected, keeping the running configuration", "error", err)
+		return err
+	}
+
+	if restart := Diff(next, loaded); len(restart) > 0 {
+		r.logger.Warn("Configuration changes need a restart to apply", "keys", restart)
+	}
+	changed := Diff(current, next)
+	if len(changed) == 0 {
+		r.logger.Info("Configuration reloaded, nothing changed")
+		return nil
+	}
+
+	r.current.Store(next)
+	r.logger.Info("Configuration reloaded", "changed", changed)
+	for _, fn := range r.listeners {
+		fn(next)
+	}
+	return nil
+}
+
+// Start reloads the configuration on SIGHUP and, when there is a config
+// file, whenever its modification time changes, checked every interval. It
+// stops when ctx is done.
+func (r *Reloader) Start(ctx context.Context, interval time.Duration) {
+	hup := make(chan os.Signal, 1)
+	signal.Notify(hup, syscall.SIGHUP)
+
+	var tick <-chan time.Time
+	if r.file != "" {
+		ticker := time.NewTicker(interval)
+		tick = ticker.C
+		context.AfterFunc(ctx, ticker.Stop)
+	}
+
+	go func() {
+		defer signal.Stop(hup)
+		modified := r.fileModTime()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-hup:
+				r.logger.Info("Reloading configuration", "trigger", "SIGHUP")
+				r.Reload()
+			case <-tick:
+				if m := r.fileModTime(); !m.Equal(modified) {
+					modified = m
+					r.logger.Info("Reloading configuration", "trigger", "file", "file", r.file)
+					r.Reload()
+				}
+			}
+		}
+	}()
+}
+
+// fileModTime is the zero time when the file cannot be read, so that the
+// file coming back also triggers a reload.
+func (r *Reloader) fileModTime() time.Time {
+	info, err := os.Stat(r.file)
+	if err != nil {
+		return time.Time{}
+	}
+	return info.ModTime()
+}
+
+// withReloadable returns a copy of c with the settings that can change while
+// the server runs taken from next.
+func (c *Config) withReloadable(next *Config) *Config {
+	out := *c
+	out.Logging.Level = next.Logging.Level
+	out.AI.DefaultProvider = next.AI.DefaultProvider
+	out.AI.AllowedProviders = next.AI.AllowedProviders
+	out.CORS = next.CORS
+	out.RateLimit = next.RateLimit
+	out.Features = next.Features
+	return &out
+}
+
+// Diff returns the keys, like logging.level, whose values differ between a
+// and b.
+func Diff(a, b *Config) []string {
+	var keys []string
+	diffValues(reflect.ValueOf(a).Elem(), reflect.ValueOf(b).Elem(), "", &keys)
+	return keys
+}
+
+func diffValues(a, b reflect.Value, prefix string, keys *[]string) {
+	if a.Kind() != reflect.Struct {
+		if !reflect.DeepEqual(a.Interface(), b.Interface()) {
+			*keys = append(*keys, prefix)
+		}
+		return
+	}
+	for i := 0; i < a.NumField(); i++ {
+		key := a.Type().Field(i).Tag.Get("yaml")
+		if prefix != "" {
+			key = prefix + "." + key
+		}
+		diffValues(a.Field(i), b.Field(i), key, keys)
+	}
+}
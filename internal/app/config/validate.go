@@ -0,0 +1,172 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// Validate checks the configuration and returns every problem it finds,
+// joined. Settings of a feature are only required when it is enabled, such
+// as the Resend API key when mail is sent through Resend.
+func (c *Config) Validate() error {
+	v := &validator{}
+
+	v.required(c.Auth.JWTSecret, "JWT_SECRET", "auth.jwt_secret")
+	// Gemini answers AI requests by default, so it is always needed.
+	v.required(c.AI.GeminiAPIKey, "GEMINI_API_KEY", "ai.gemini_api_key")
+
+	if port, err := strconv.Atoi(c.Server.Port); err != nil || port < 1 || port > 65535 {
+		v.problem("PORT (server.port) must be a port number, not %q", c.Server.Port)
+	}
+	if u, err := url.Parse(c.Server.BaseURL); err != nil || u.Scheme == "" || u.Host == "" {
+		v.problem("BASE_URL (server.base_url) must be an absolute URL, not %q", c.Server.BaseURL)
+	}
+	v.positive(int64(c.Server.ReadTimeout), "SERVER_READ_TIMEOUT", "server.read_timeout")
+	v.positive(int64(c.Server.WriteTimeout), "SERVER_WRITE_TIMEOUT", "server.write_timeout")
+	v.positive(int64(c.Server.IdleTimeout), "SERVER_IDLE_TIMEOUT", "server.idle_timeout")
+
+	c.Database.validate(v)
+
+	v.positive(int64(c.Redis.PoolSize), "REDIS_POOL_SIZE", "redis.pool_size")
+	v.nonNegative(int64(c.Redis.MaxRetries), "REDIS_MAX_RETRIES", "redis.max_retries")
+	v.nonNegative(int64(c.Redis.MinIdleConns), "REDIS_MIN_IDLE_CONNS", "redis.min_idle_conns")
+	if c.Redis.MinIdleConns > c.Redis.PoolSize {
+		v.problem("REDIS_MIN_IDLE_CONNS (redis.min_idle_conns) must not exceed REDIS_POOL_SIZE (redis.pool_size)")
+	}
+
+	v.oneOf(c.AI.EmbeddingProvider, "EMBEDDING_PROVIDER", "ai.embedding_provider", "gemini", "local")
+	v.oneOf(c.AI.VectorStore, "VECTOR_STORE", "ai.vector_store", "pgvector", "memory")
+	v.oneOf(c.AI.DefaultProvider, "AI_DEFAULT_PROVIDER", "ai.default_provider", "gemini", "claude")
+	for _, provider := range c.AI.AllowedProviders {
+		v.oneOf(provider, "AI_ALLOWED_PROVIDERS", "ai.allowed_providers", "gemini", "claude")
+	}
+	if !slices.Contains(c.AI.AllowedProviders, c.AI.DefaultProvider) {
+		v.problem("AI_DEFAULT_PROVIDER (ai.default_provider) %q must be one of AI_ALLOWED_PROVIDERS (ai.allowed_providers)", c.AI.DefaultProvider)
+	}
+
+	v.nonNegative(int64(c.Email.UndoSendDelay), "UNDO_SEND_DELAY", "email.undo_send_delay")
+	v.oneOf(c.Email.RemoteImages, "REMOTE_IMAGES", "email.remote_images", "proxy", "block")
+	v.oneOf(c.Email.MailboxProvider, "MAIL_PROVIDER", "email.mailbox_provider", "gmail", "imap")
+	v.oneOf(c.Email.Sender, "MAIL_SENDER", "email.sender", "resend", "smtp", "gmail")
+
+	if c.Email.MailboxProvider == "gmail" || c.Email.Sender == "gmail" || c.Google.PubSubTopic != "" {
+		const feature = "with Gmail"
+		v.requiredFor(c.Google.ClientID, "GOOGLE_CLIENT_ID", "google.client_id", feature)
+		v.requiredFor(c.Google.ClientSecret, "GOOGLE_CLIENT_SECRET", "google.client_secret", feature)
+	}
+	switch c.Email.Sender {
+	case "resend":
+		const feature = "with MAIL_SENDER=resend"
+		v.requiredFor(c.Email.ResendAPIKey, "RESEND_API_KEY", "email.resend_api_key", feature)
+		v.requiredFor(c.Email.ResendFromEmail, "RESEND_FROM_EMAIL", "email.resend_from_email", feature)
+	case "smtp":
+		const feature = "with MAIL_SENDER=smtp"
+		v.requiredFor(c.Email.SMTP.Host, "SMTP_HOST", "email.smtp.host", feature)
+		v.requiredFor(c.Email.SMTP.From, "SMTP_FROM", "email.smtp.from", feature)
+		v.oneOf(c.Email.SMTP.TLSMode, "SMTP_TLS", "email.smtp.tls_mode", "starttls", "tls", "none")
+	}
+	if c.Email.MailboxProvider == "imap" {
+		const feature = "with MAIL_PROVIDER=imap"
+		v.requiredFor(c.Email.IMAP.Addr, "IMAP_ADDR", "email.imap.addr", feature)
+		v.requiredFor(c.Email.IMAP.Username, "IMAP_USERNAME", "email.imap.username", feature)
+	}
+
+	v.oneOf(c.Storage.Backend, "STORAGE_BACKEND", "storage.backend", "filesystem", "s3")
+	switch c.Storage.Backend {
+	case "filesystem":
+		v.requiredFor(c.Storage.Dir, "STORAGE_DIR", "storage.dir", "with STORAGE_BACKEND=filesystem")
+	case "s3":
+		const feature = "with STORAGE_BACKEND=s3"
+		v.requiredFor(c.Storage.S3.Bucket, "S3_BUCKET", "storage.s3.bucket", feature)
+		v.requiredFor(c.Storage.S3.AccessKeyID, "S3_ACCESS_KEY_ID", "storage.s3.access_key_id", feature)
+		v.requiredFor(c.Storage.S3.SecretAccessKey, "S3_SECRET_ACCESS_KEY", "storage.s3.secret_access_key", feature)
+	}
+
+	v.oneOf(c.Logging.Level, "LOG_LEVEL", "logging.level", "debug", "info", "warn", "error")
+	v.oneOf(c.Logging.Format, "LOG_FORMAT", "logging.format", "json", "text")
+	v.oneOf(c.Logging.Output, "LOG_OUTPUT", "logging.output", "stdout", "stderr", "file")
+	if c.Logging.Output == "file" {
+		v.requiredFor(c.Logging.FilePath, "LOG_FILE", "logging.file_path", "with LOG_OUTPUT=file")
+	}
+
+	v.oneOf(c.Tracing.Exporter, "TRACING_EXPORTER", "tracing.exporter", "otlp", "stdout", "none")
+	if c.Tracing.SampleRatio < 0 || c.Tracing.SampleRatio > 1 {
+		v.problem("TRACING_SAMPLE_RATIO (tracing.sample_ratio) must be between 0 and 1, not %v", c.Tracing.SampleRatio)
+	}
+
+	if len(c.CORS.AllowedOrigins) == 0 {
+		v.problem("CORS_ALLOWED_ORIGINS (cors.allowed_origins) is required")
+	}
+	for _, origin := range c.CORS.AllowedOrigins {
+		if u, err := url.Parse(origin); origin != "*" && (err != nil || u.Scheme == "" || u.Host == "" || u.Path != "") {
+			v.problem("CORS_ALLOWED_ORIGINS (cors.allowed_origins) must list origins like https://app.example.com or *, not %q", origin)
+		}
+	}
+
+	v.nonNegative(int64(c.RateLimit.AIRequestsPerMinute), "RATE_LIMIT_AI_PER_MINUTE", "rate_limit.ai_requests_per_minute")
+	if c.RateLimit.AIRequestsPerMinute > 0 {
+		v.positive(int64(c.RateLimit.AIBurst), "RATE_LIMIT_AI_BURST", "rate_limit.ai_burst")
+	}
+
+	return errors.Join(v.problems...)
+}
+
+// ValidateDatabase checks only the database settings, for tools that need
+// nothing else.
+func (c *Config) ValidateDatabase() error {
+	v := &validator{}
+	c.Database.validate(v)
+	return errors.Join(v.problems...)
+}
+
+func (d *DatabaseConfig) validate(v *validator) {
+	v.required(d.URL, "DATABASE_URL", "database.url")
+	v.positive(int64(d.MaxOpenConns), "DATABASE_MAX_OPEN_CONNS", "database.max_open_conns")
+	v.nonNegative(int64(d.MaxIdleConns), "DATABASE_MAX_IDLE_CONNS", "database.max_idle_conns")
+	if d.MaxIdleConns > d.MaxOpenConns {
+		v.problem("DATABASE_MAX_IDLE_CONNS (database.max_idle_conns) must not exceed DATABASE_MAX_OPEN_CONNS (database.max_open_conns)")
+	}
+	v.nonNegative(int64(d.ConnMaxLifetime), "DATABASE_CONN_MAX_LIFETIME", "database.conn_max_lifetime")
+}
+
+type validator struct {
+	problems []error
+}
+
+func (v *validator) problem(format string, args ...interface{}) {
+	v.problems = append(v.problems, fmt.Errorf(format, args...))
+}
+
+func (v *validator) required(value, env, key string) {
+	if value == "" {
+		v.problem("%s (%s) is required", env, key)
+	}
+}
+
+func (v *validator) requiredFor(value, env, key, feature string) {
+	if value == "" {
+		v.problem("%s (%s) is required %s", env, key, feature)
+	}
+}
+
+func (v *validator) positive(n int64, env, key string) {
+	if n <= 0 {
+		v.problem("%s (%s) must be positive", env, key)
+	}
+}
+
+func (v *validator) nonNegative(n int64, env, key string) {
+	if n < 0 {
+		v.problem("%s (%s) must not be negative", env, key)
+	}
+}
+
+func (v *validator) oneOf(value, env, key string, allowed ...string) {
+	if !slices.Contains(allowed, value) {
+		v.problem("%s (%s) must be %s, not %q", env, key, strings.Join(allowed, ", "), value)
+	}
+}
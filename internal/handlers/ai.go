@@ -28,28 +28,19 @@ func NewAIHandler(aiUsecase AIUsecaseInterface) *AIHandler {
 func (h *AIHandler) Ask(w http.ResponseWriter, r *http.Request) {
 	user, err := auth.GetCurrentUser(r)
 	if err != nil {
-		w.Header().Set("Content-Type", "application/json")
-		w.WriteHeader(http.StatusUnauthorized)
-		response := map[string]interface{}{"error": "Unauthorized"}
-		json.NewEncoder(w).Encode(response)
+		writeJSONError(w, r, http.StatusUnauthorized, "Unauthorized")
 		return
 	}
 
 	var req models.AIRequest
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		w.Header().Set("Content-Type", "application/json")
-		w.WriteHeader(http.StatusBadRequest)
-		response := map[string]interface{}{"error": "Invalid JSON"}
-		json.NewEncoder(w).Encode(response)
+		writeJSONError(w, r, http.StatusBadRequest, "Invalid JSON")
 		return
 	}
 
 	response, err := h.aiUsecase.ProcessAIRequest(r.Context(), user.ID, &req)
 	if err != nil {
-		w.Header().Set("Content-Type", "application/json")
-		w.WriteHeader(http.StatusInternalServerError)
-		errorResponse := map[string]interface{}{"error": err.Error()}
-		json.NewEncoder(w).Encode(errorResponse)
+		writeUsecaseError(w, r, err)
 		return
 	}
 
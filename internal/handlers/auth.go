@@ -6,39 +6,39 @@ import (
 	"encoding/json"
 	"net/http"
 	"net/url"
-	"os"
+	"strings"
 
 	"github.com/go-chi/chi/v5"
+	"ai-assistant/internal/app/config"
 	"ai-assistant/internal/models"
 	"ai-assistant/internal/services/auth"
 )
 
 type AuthHandler struct {
 	authService *auth.AuthService
+	clientID    string
+	baseURL     string
 }
 
-func NewAuthHandler(authService *auth.AuthService) *AuthHandler {
+func NewAuthHandler(authService *auth.AuthService, cfg *config.Config) *AuthHandler {
 	return &AuthHandler{
 		authService: authService,
+		clientID:    cfg.Google.ClientID,
+		baseURL:     strings.TrimSuffix(cfg.Server.BaseURL, "/"),
 	}
 }
 
 func (h *AuthHandler) GoogleOAuth(w http.ResponseWriter, r *http.Request) {
-	clientID := os.Getenv("GOOGLE_CLIENT_ID")
-	if clientID == "" {
-		w.Header().Set("Content-Type", "application/json")
-		w.WriteHeader(http.StatusInternalServerError)
-		response := map[string]interface{}{"error": "Google OAuth not configured"}
-		json.NewEncoder(w).Encode(response)
+	if h.clientID == "" {
+		writeJSONError(w, r, http.StatusInternalServerError, "Google OAuth not configured")
 		return
 	}
 
 	state := generateRandomState()
-	baseURL := os.Getenv("BASE_URL")
-	redirectURI := baseURL + "/api/auth/google/callback"
+	redirectURI := h.baseURL + "/api/auth/google/callback"
 
 	params := url.Values{}
-	params.Add("client_id", clientID)
+	params.Add("client_id", h.clientID)
 	params.Add("redirect_uri", redirectURI)
 	params.Add("response_type", "code")
 	params.Add("scope", "openid email profile")
@@ -60,18 +60,12 @@ func (h *AuthHandler) GoogleCallback(w http.ResponseWriter, r *http.Request) {
 	errorParam := r.URL.Query().Get("error")
 
 	if errorParam != "" {
-		w.Header().Set("Content-Type", "application/json")
-		w.WriteHeader(http.StatusBadRequest)
-		response := map[string]interface{}{"error": "OAuth error: " + errorParam}
-		json.NewEncoder(w).Encode(response)
+		writeJSONError(w, r, http.StatusBadRequest, "OAuth error: " + errorParam)
 		return
 	}
 
 	if code == "" {
-		w.Header().Set("Content-Type", "application/json")
-		w.WriteHeader(http.StatusBadRequest)
-		response := map[string]interface{}{"error": "No authorization code provided"}
-		json.NewEncoder(w).Encode(response)
+		writeJSONError(w, r, http.StatusBadRequest, "No authorization code provided")
 		return
 	}
 
@@ -84,10 +78,7 @@ func (h *AuthHandler) GoogleCallback(w http.ResponseWriter, r *http.Request) {
 
 	token, err := h.authService.GenerateToken(mockUser)
 	if err != nil {
-		w.Header().Set("Content-Type", "application/json")
-		w.WriteHeader(http.StatusInternalServerError)
-		response := map[string]interface{}{"error": "Failed to generate token"}
-		json.NewEncoder(w).Encode(response)
+		writeJSONError(w, r, http.StatusInternalServerError, "Failed to generate token")
 		return
 	}
 
@@ -109,10 +100,7 @@ func (h *AuthHandler) GoogleCallback(w http.ResponseWriter, r *http.Request) {
 func (h *AuthHandler) Me(w http.ResponseWriter, r *http.Request) {
 	user, err := auth.GetCurrentUser(r)
 	if err != nil {
-		w.Header().Set("Content-Type", "application/json")
-		w.WriteHeader(http.StatusUnauthorized)
-		response := map[string]interface{}{"error": "Unauthorized"}
-		json.NewEncoder(w).Encode(response)
+		writeJSONError(w, r, http.StatusUnauthorized, "Unauthorized")
 		return
 	}
 
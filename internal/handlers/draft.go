@@ -0,0 +1,180 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"strconv"
+
+	"github.com/go-chi/chi/v5"
+	"ai-assistant/internal/models"
+	"ai-assistant/internal/services/auth"
+)
+
+// DraftUsecaseInterface defines the interface for draft usecase
+type DraftUsecaseInterface interface {
+	GenerateDraft(ctx context.Context, userID, userEmail string, req *models.GenerateDraftRequest) (*models.Draft, error)
+	GetDraft(ctx context.Context, userID, draftID string) (*models.Draft, error)
+	ListDrafts(ctx context.Context, userID string, status models.DraftStatus, limit, offset int) ([]*models.Draft, error)
+	UpdateDraft(ctx context.Context, userID, userEmail, draftID string, req *models.UpdateDraftRequest) (*models.Draft, error)
+	ApproveDraft(ctx context.Context, userID, draftID string) (*models.Draft, error)
+	DiscardDraft(ctx context.Context, userID, draftID string) (*models.Draft, error)
+	SendDraft(ctx context.Context, userID, userEmail, draftID string) (*models.Draft, error)
+}
+
+type DraftHandler struct {
+	draftUsecase DraftUsecaseInterface
+}
+
+func NewDraftHandler(draftUsecase DraftUsecaseInterface) *DraftHandler {
+	return &DraftHandler{
+		draftUsecase: draftUsecase,
+	}
+}
+
+func (h *DraftHandler) Generate(w http.ResponseWriter, r *http.Request) {
+	user, err := auth.GetCurrentUser(r)
+	if err != nil {
+		writeJSONError(w, r, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+
+	var req models.GenerateDraftRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeJSONError(w, r, http.StatusBadRequest, "Invalid JSON")
+		return
+	}
+
+	draft, err := h.draftUsecase.GenerateDraft(r.Context(), user.ID, user.Email, &req)
+	if err != nil {
+		writeUsecaseError(w, r, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(draft)
+}
+
+func (h *DraftHandler) List(w http.ResponseWriter, r *http.Request) {
+	user, err := auth.GetCurrentUser(r)
+	if err != nil {
+		writeJSONError(w, r, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+
+	status := models.DraftStatus(r.URL.Query().Get("status"))
+	limit, _ := strconv.Atoi(r.URL.Query().Get("limit"))
+	offset, _ := strconv.Atoi(r.URL.Query().Get("offset"))
+
+	drafts, err := h.draftUsecase.ListDrafts(r.Context(), user.ID, status, limit, offset)
+	if err != nil {
+		writeUsecaseError(w, r, err)
+		return
+	}
+
+	response := map[string]interface{}{"drafts": drafts}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
+
+func (h *DraftHandler) Get(w http.ResponseWriter, r *http.Request) {
+	user, err := auth.GetCurrentUser(r)
+	if err != nil {
+		writeJSONError(w, r, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+
+	draft, err := h.draftUsecase.GetDraft(r.Context(), user.ID, chi.URLParam(r, "id"))
+	if err != nil {
+		writeUsecaseError(w, r, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(draft)
+}
+
+func (h *DraftHandler) Update(w http.ResponseWriter, r *http.Request) {
+	user, err := auth.GetCurrentUser(r)
+	if err != nil {
+		writeJSONError(w, r, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+
+	var req models.UpdateDraftRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeJSONError(w, r, http.StatusBadRequest, "Invalid JSON")
+		return
+	}
+
+	draft, err := h.draftUsecase.UpdateDraft(r.Context(), user.ID, user.Email, chi.URLParam(r, "id"), &req)
+	if err != nil {
+		writeUsecaseError(w, r, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(draft)
+}
+
+func (h *DraftHandler) Approve(w http.ResponseWriter, r *http.Request) {
+	user, err := auth.GetCurrentUser(r)
+	if err != nil {
+		writeJSONError(w, r, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+
+	draft, err := h.draftUsecase.ApproveDraft(r.Context(), user.ID, chi.URLParam(r, "id"))
+	if err != nil {
+		writeUsecaseError(w, r, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(draft)
+}
+
+func (h *DraftHandler) Discard(w http.ResponseWriter, r *http.Request) {
+	user, err := auth.GetCurrentUser(r)
+	if err != nil {
+		writeJSONError(w, r, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+
+	draft, err := h.draftUsecase.DiscardDraft(r.Context(), user.ID, chi.URLParam(r, "id"))
+	if err != nil {
+		writeUsecaseError(w, r, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(draft)
+}
+
+func (h *DraftHandler) Send(w http.ResponseWriter, r *http.Request) {
+	user, err := auth.GetCurrentUser(r)
+	if err != nil {
+		writeJSONError(w, r, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+
+	draft, err := h.draftUsecase.SendDraft(r.Context(), user.ID, user.Email, chi.URLParam(r, "id"))
+	if err != nil {
+		writeUsecaseError(w, r, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(draft)
+}
+
+func (h *DraftHandler) RegisterRoutes(router chi.Router) {
+	router.Post("/", h.Generate)
+	router.Get("/", h.List)
+	router.Get("/{id}", h.Get)
+	router.Put("/{id}", h.Update)
+	router.Post("/{id}/approve", h.Approve)
+	router.Post("/{id}/discard", h.Discard)
+	router.Post("/{id}/send", h.Send)
+}
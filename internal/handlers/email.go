@@ -1,62 +1,267 @@
 package handlers
 
 import (
+	"context"
 	"encoding/json"
+	"io"
+	"mime"
 	"net/http"
+	"strconv"
 
 	"github.com/go-chi/chi/v5"
+	"ai-assistant/internal/httperr"
+	"ai-assistant/internal/models"
 	"ai-assistant/internal/services/auth"
+	"ai-assistant/pkg/errors"
 )
 
+// EmailUsecaseInterface defines the interface for email usecase
+type EmailUsecaseInterface interface {
+	GetUserEmails(ctx context.Context, userID string, limit, offset int) ([]*models.Email, error)
+	SearchEmails(ctx context.Context, userID, query string, limit, offset int) ([]*models.EmailSearchResult, error)
+	ListSentEmails(ctx context.Context, userID, status string, limit, offset int) ([]*models.OutboundEmail, error)
+	ReplyToEmail(ctx context.Context, userID, userEmail, emailID string, req *models.ReplyRequest) (*models.SentEmail, error)
+	ForwardEmail(ctx context.Context, userID, userEmail, emailID string, req *models.ForwardRequest) (*models.SentEmail, error)
+	ModifyEmailLabels(ctx context.Context, userID, emailID string, add, remove []string) (*models.Email, error)
+	ListAttachments(ctx context.Context, userID, emailID string) ([]*models.EmailAttachment, error)
+	OpenAttachment(ctx context.Context, userID, emailID, attachmentID string) (*models.EmailAttachment, io.ReadCloser, int64, error)
+}
+
 type EmailHandler struct {
+	emailUsecase EmailUsecaseInterface
 }
 
-func NewEmailHandler() *EmailHandler {
-	return &EmailHandler{}
+func NewEmailHandler(emailUsecase EmailUsecaseInterface) *EmailHandler {
+	return &EmailHandler{
+		emailUsecase: emailUsecase,
+	}
 }
 
 func (h *EmailHandler) GetEmails(w http.ResponseWriter, r *http.Request) {
 	user, err := auth.GetCurrentUser(r)
 	if err != nil {
-		w.Header().Set("Content-Type", "application/json")
-		w.WriteHeader(http.StatusUnauthorized)
-		response := map[string]interface{}{"error": "Unauthorized"}
-		json.NewEncoder(w).Encode(response)
+		writeJSONError(w, r, http.StatusUnauthorized, "Unauthorized")
 		return
 	}
 
-	_ = user
+	limit, _ := strconv.Atoi(r.URL.Query().Get("limit"))
+	offset, _ := strconv.Atoi(r.URL.Query().Get("offset"))
+
+	emails, err := h.emailUsecase.GetUserEmails(r.Context(), user.ID, limit, offset)
+	if err != nil {
+		writeUsecaseError(w, r, err)
+		return
+	}
 
 	response := map[string]interface{}{
-		"message": "Email fetching not implemented yet",
-		"user_id": user.ID,
+		"emails": emails,
+		"limit":  limit,
+		"offset": offset,
 	}
 	w.Header().Set("Content-Type", "application/json")
-	w.WriteHeader(http.StatusNotImplemented)
 	json.NewEncoder(w).Encode(response)
 }
 
-func (h *EmailHandler) SendEmail(w http.ResponseWriter, r *http.Request) {
+func (h *EmailHandler) SearchEmails(w http.ResponseWriter, r *http.Request) {
 	user, err := auth.GetCurrentUser(r)
 	if err != nil {
-		w.Header().Set("Content-Type", "application/json")
-		w.WriteHeader(http.StatusUnauthorized)
-		response := map[string]interface{}{"error": "Unauthorized"}
-		json.NewEncoder(w).Encode(response)
+		writeJSONError(w, r, http.StatusUnauthorized, "Unauthorized")
 		return
 	}
 
-	_ = user
+	query := r.URL.Query().Get("q")
+	limit, _ := strconv.Atoi(r.URL.Query().Get("limit"))
+	offset, _ := strconv.Atoi(r.URL.Query().Get("offset"))
+
+	results, err := h.emailUsecase.SearchEmails(r.Context(), user.ID, query, limit, offset)
+	if err != nil {
+		writeUsecaseError(w, r, err)
+		return
+	}
+
+	response := map[string]interface{}{
+		"query":   query,
+		"results": results,
+		"limit":   limit,
+		"offset":  offset,
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
+
+// GetSent lists sent emails with their delivery status, optionally filtered
+// by ?status=.
+func (h *EmailHandler) GetSent(w http.ResponseWriter, r *http.Request) {
+	user, err := auth.GetCurrentUser(r)
+	if err != nil {
+		writeJSONError(w, r, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+
+	status := r.URL.Query().Get("status")
+	limit, _ := strconv.Atoi(r.URL.Query().Get("limit"))
+	offset, _ := strconv.Atoi(r.URL.Query().Get("offset"))
+
+	sent, err := h.emailUsecase.ListSentEmails(r.Context(), user.ID, status, limit, offset)
+	if err != nil {
+		writeUsecaseError(w, r, err)
+		return
+	}
 
 	response := map[string]interface{}{
-		"message": "Email sending not implemented yet",
+		"emails": sent,
+		"limit":  limit,
+		"offset": offset,
 	}
 	w.Header().Set("Content-Type", "application/json")
-	w.WriteHeader(http.StatusNotImplemented)
 	json.NewEncoder(w).Encode(response)
 }
 
+func (h *EmailHandler) Reply(w http.ResponseWriter, r *http.Request) {
+	user, err := auth.GetCurrentUser(r)
+	if err != nil {
+		writeJSONError(w, r, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+
+	var req models.ReplyRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeJSONError(w, r, http.StatusBadRequest, "Invalid JSON")
+		return
+	}
+
+	sent, err := h.emailUsecase.ReplyToEmail(r.Context(), user.ID, user.Email, chi.URLParam(r, "id"), &req)
+	if err != nil {
+		writeUsecaseError(w, r, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(sent)
+}
+
+func (h *EmailHandler) Forward(w http.ResponseWriter, r *http.Request) {
+	user, err := auth.GetCurrentUser(r)
+	if err != nil {
+		writeJSONError(w, r, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+
+	var req models.ForwardRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeJSONError(w, r, http.StatusBadRequest, "Invalid JSON")
+		return
+	}
+
+	sent, err := h.emailUsecase.ForwardEmail(r.Context(), user.ID, user.Email, chi.URLParam(r, "id"), &req)
+	if err != nil {
+		writeUsecaseError(w, r, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(sent)
+}
+
+func (h *EmailHandler) ModifyLabels(w http.ResponseWriter, r *http.Request) {
+	user, err := auth.GetCurrentUser(r)
+	if err != nil {
+		writeJSONError(w, r, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+
+	var req models.ModifyLabelsRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeJSONError(w, r, http.StatusBadRequest, "Invalid JSON")
+		return
+	}
+
+	email, err := h.emailUsecase.ModifyEmailLabels(r.Context(), user.ID, chi.URLParam(r, "id"), req.Add, req.Remove)
+	if err != nil {
+		writeUsecaseError(w, r, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(email)
+}
+
+func (h *EmailHandler) ListAttachments(w http.ResponseWriter, r *http.Request) {
+	user, err := auth.GetCurrentUser(r)
+	if err != nil {
+		writeJSONError(w, r, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+
+	attachments, err := h.emailUsecase.ListAttachments(r.Context(), user.ID, chi.URLParam(r, "id"))
+	if err != nil {
+		writeUsecaseError(w, r, err)
+		return
+	}
+	if attachments == nil {
+		attachments = []*models.EmailAttachment{}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{"attachments": attachments})
+}
+
+// DownloadAttachment streams an attachment's content. It is always served
+// as a download, with sniffing off, so that an HTML attachment cannot run
+// in the API's origin.
+func (h *EmailHandler) DownloadAttachment(w http.ResponseWriter, r *http.Request) {
+	user, err := auth.GetCurrentUser(r)
+	if err != nil {
+		writeJSONError(w, r, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+
+	attachment, content, size, err := h.emailUsecase.OpenAttachment(r.Context(), user.ID, chi.URLParam(r, "id"), chi.URLParam(r, "attId"))
+	if err != nil {
+		writeUsecaseError(w, r, err)
+		return
+	}
+	defer content.Close()
+
+	contentType := attachment.MimeType
+	if _, _, err := mime.ParseMediaType(contentType); err != nil {
+		contentType = "application/octet-stream"
+	}
+	disposition := mime.FormatMediaType("attachment", map[string]string{"filename": attachment.Filename})
+	if disposition == "" {
+		disposition = "attachment"
+	}
+
+	w.Header().Set("Content-Type", contentType)
+	w.Header().Set("Content-Disposition", disposition)
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	w.Header().Set("Cache-Control", "private, max-age=3600")
+	if size >= 0 {
+		w.Header().Set("Content-Length", strconv.FormatInt(size, 10))
+	}
+	io.Copy(w, content)
+}
+
 func (h *EmailHandler) RegisterRoutes(router chi.Router) {
 	router.Get("/", h.GetEmails)
-	router.Post("/send", h.SendEmail)
-}
\ No newline at end of file
+	router.Get("/search", h.SearchEmails)
+	router.Get("/sent", h.GetSent)
+	router.Post("/{id}/reply", h.Reply)
+	router.Post("/{id}/forward", h.Forward)
+	router.Post("/{id}/labels", h.ModifyLabels)
+	router.Get("/{id}/attachments", h.ListAttachments)
+	router.Get("/{id}/attachments/{attId}", h.DownloadAttachment)
+}
+
+// writeJSONError responds with an error of the given status.
+func writeJSONError(w http.ResponseWriter, r *http.Request, status int, message string) {
+	httperr.Write(w, r, errors.NewAppError(status, message, ""))
+}
+
+// writeUsecaseError responds with err's AppError, or the status its
+// sentinel error maps to.
+func writeUsecaseError(w http.ResponseWriter, r *http.Request, err error) {
+	httperr.Write(w, r, err)
+}
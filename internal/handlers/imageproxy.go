@@ -0,0 +1,67 @@
+package handlers
+
+import (
+	"context"
+	stderrors "errors"
+	"net/http"
+	"strconv"
+
+	"github.com/go-chi/chi/v5"
+	"ai-assistant/internal/services/imageproxy"
+)
+
+// ImageProxyInterface defines the interface for the remote image proxy
+type ImageProxyInterface interface {
+	Verify(src, sig string) bool
+	Fetch(ctx context.Context, src string) (*imageproxy.Image, error)
+}
+
+type ImageProxyHandler struct {
+	proxy ImageProxyInterface
+}
+
+func NewImageProxyHandler(proxy ImageProxyInterface) *ImageProxyHandler {
+	return &ImageProxyHandler{
+		proxy: proxy,
+	}
+}
+
+// Proxy serves a remote image of a sanitized email. It is loaded by <img>
+// tags, which cannot send the API token, so the URL's signature is what
+// authorizes it. The response is locked down in case it is opened directly.
+func (h *ImageProxyHandler) Proxy(w http.ResponseWriter, r *http.Request) {
+	if h.proxy == nil {
+		writeJSONError(w, r, http.StatusNotFound, "Image proxy is disabled")
+		return
+	}
+
+	src := r.URL.Query().Get("url")
+	if src == "" || !h.proxy.Verify(src, r.URL.Query().Get("sig")) {
+		writeJSONError(w, r, http.StatusForbidden, "Invalid image signature")
+		return
+	}
+
+	image, err := h.proxy.Fetch(r.Context(), src)
+	if err != nil {
+		switch {
+		case stderrors.Is(err, imageproxy.ErrNotImage):
+			writeJSONError(w, r, http.StatusUnsupportedMediaType, "Not an image")
+		case stderrors.Is(err, imageproxy.ErrTooLarge):
+			writeJSONError(w, r, http.StatusRequestEntityTooLarge, "Image too large")
+		default:
+			writeJSONError(w, r, http.StatusBadGateway, "Failed to fetch image")
+		}
+		return
+	}
+
+	w.Header().Set("Content-Type", image.ContentType)
+	w.Header().Set("Content-Length", strconv.Itoa(len(image.Data)))
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	w.Header().Set("Content-Security-Policy", "default-src 'none'; sandbox")
+	w.Header().Set("Cache-Control", "public, max-age=86400")
+	w.Write(image.Data)
+}
+
+func (h *ImageProxyHandler) RegisterRoutes(router chi.Router) {
+	router.Get("/proxy", h.Proxy)
+}
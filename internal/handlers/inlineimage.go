@@ -0,0 +1,66 @@
+package handlers
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"strconv"
+
+	"github.com/go-chi/chi/v5"
+	"ai-assistant/internal/models"
+)
+
+// InlineImageUsecaseInterface defines the interface for serving the inline
+// images of sanitized emails
+type InlineImageUsecaseInterface interface {
+	OpenInlineImage(ctx context.Context, emailID, attachmentID string) (*models.EmailAttachment, io.ReadCloser, int64, error)
+}
+
+// InlineImageVerifier checks the signatures of inline image URLs
+type InlineImageVerifier interface {
+	Verify(emailID, attachmentID, exp, sig string) bool
+}
+
+type InlineImageHandler struct {
+	usecase  InlineImageUsecaseInterface
+	verifier InlineImageVerifier
+}
+
+func NewInlineImageHandler(usecase InlineImageUsecaseInterface, verifier InlineImageVerifier) *InlineImageHandler {
+	return &InlineImageHandler{
+		usecase:  usecase,
+		verifier: verifier,
+	}
+}
+
+// Serve streams an inline image of a sanitized email. Like the image proxy
+// it is loaded by <img> tags, so the URL's signature and expiry authorize
+// it rather than the API token.
+func (h *InlineImageHandler) Serve(w http.ResponseWriter, r *http.Request) {
+	emailID, attachmentID := chi.URLParam(r, "emailId"), chi.URLParam(r, "attId")
+	query := r.URL.Query()
+	if !h.verifier.Verify(emailID, attachmentID, query.Get("exp"), query.Get("sig")) {
+		writeJSONError(w, r, http.StatusForbidden, "Invalid or expired image signature")
+		return
+	}
+
+	attachment, content, size, err := h.usecase.OpenInlineImage(r.Context(), emailID, attachmentID)
+	if err != nil {
+		writeUsecaseError(w, r, err)
+		return
+	}
+	defer content.Close()
+
+	w.Header().Set("Content-Type", attachment.MimeType)
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	w.Header().Set("Content-Security-Policy", "default-src 'none'; sandbox")
+	w.Header().Set("Cache-Control", "private, max-age=3600")
+	if size >= 0 {
+		w.Header().Set("Content-Length", strconv.FormatInt(size, 10))
+	}
+	io.Copy(w, content)
+}
+
+func (h *InlineImageHandler) RegisterRoutes(router chi.Router) {
+	router.Get("/inline/{emailId}/{attId}", h.Serve)
+}
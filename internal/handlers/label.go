@@ -0,0 +1,135 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+	"ai-assistant/internal/models"
+	"ai-assistant/internal/services/auth"
+)
+
+// LabelUsecaseInterface defines the interface for label usecase
+type LabelUsecaseInterface interface {
+	ListLabels(ctx context.Context, userID string) ([]*models.Label, error)
+	CreateLabel(ctx context.Context, userID string, req *models.LabelRequest) (*models.Label, error)
+	UpdateLabel(ctx context.Context, userID, labelID string, req *models.LabelRequest) (*models.Label, error)
+	DeleteLabel(ctx context.Context, userID, labelID string) error
+	SyncLabels(ctx context.Context, userID string) ([]*models.Label, error)
+}
+
+type LabelHandler struct {
+	labelUsecase LabelUsecaseInterface
+}
+
+func NewLabelHandler(labelUsecase LabelUsecaseInterface) *LabelHandler {
+	return &LabelHandler{
+		labelUsecase: labelUsecase,
+	}
+}
+
+func (h *LabelHandler) List(w http.ResponseWriter, r *http.Request) {
+	user, err := auth.GetCurrentUser(r)
+	if err != nil {
+		writeJSONError(w, r, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+
+	labels, err := h.labelUsecase.ListLabels(r.Context(), user.ID)
+	writeLabels(w, r, labels, err)
+}
+
+func (h *LabelHandler) Create(w http.ResponseWriter, r *http.Request) {
+	user, err := auth.GetCurrentUser(r)
+	if err != nil {
+		writeJSONError(w, r, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+
+	var req models.LabelRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeJSONError(w, r, http.StatusBadRequest, "Invalid JSON")
+		return
+	}
+
+	label, err := h.labelUsecase.CreateLabel(r.Context(), user.ID, &req)
+	if err != nil {
+		writeUsecaseError(w, r, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(label)
+}
+
+func (h *LabelHandler) Update(w http.ResponseWriter, r *http.Request) {
+	user, err := auth.GetCurrentUser(r)
+	if err != nil {
+		writeJSONError(w, r, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+
+	var req models.LabelRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeJSONError(w, r, http.StatusBadRequest, "Invalid JSON")
+		return
+	}
+
+	label, err := h.labelUsecase.UpdateLabel(r.Context(), user.ID, chi.URLParam(r, "id"), &req)
+	if err != nil {
+		writeUsecaseError(w, r, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(label)
+}
+
+func (h *LabelHandler) Delete(w http.ResponseWriter, r *http.Request) {
+	user, err := auth.GetCurrentUser(r)
+	if err != nil {
+		writeJSONError(w, r, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+
+	if err := h.labelUsecase.DeleteLabel(r.Context(), user.ID, chi.URLParam(r, "id")); err != nil {
+		writeUsecaseError(w, r, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (h *LabelHandler) Sync(w http.ResponseWriter, r *http.Request) {
+	user, err := auth.GetCurrentUser(r)
+	if err != nil {
+		writeJSONError(w, r, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+
+	labels, err := h.labelUsecase.SyncLabels(r.Context(), user.ID)
+	writeLabels(w, r, labels, err)
+}
+
+func writeLabels(w http.ResponseWriter, r *http.Request, labels []*models.Label, err error) {
+	if err != nil {
+		writeUsecaseError(w, r, err)
+		return
+	}
+	if labels == nil {
+		labels = []*models.Label{}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{"labels": labels})
+}
+
+func (h *LabelHandler) RegisterRoutes(router chi.Router) {
+	router.Get("/", h.List)
+	router.Post("/", h.Create)
+	router.Post("/sync", h.Sync)
+	router.Put("/{id}", h.Update)
+	router.Delete("/{id}", h.Delete)
+}
@@ -0,0 +1,108 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+	"ai-assistant/internal/models"
+	"ai-assistant/internal/services/auth"
+	"ai-assistant/internal/services/email/gmail"
+)
+
+// maxPushBody bounds Pub/Sub push bodies; Gmail notifications are tiny.
+const maxPushBody = 64 << 10
+
+// PushUsecaseInterface defines the interface for Gmail push usecase
+type PushUsecaseInterface interface {
+	Watch(ctx context.Context, userID string) (*models.GmailWatch, error)
+	StopWatch(ctx context.Context, userID string) error
+	HandleNotification(ctx context.Context, n *gmail.Notification) error
+}
+
+// PushVerifier authenticates Pub/Sub push requests from their Authorization
+// header.
+type PushVerifier interface {
+	Verify(ctx context.Context, authorization string) error
+}
+
+type PushHandler struct {
+	pushUsecase PushUsecaseInterface
+	verifier    PushVerifier
+}
+
+func NewPushHandler(pushUsecase PushUsecaseInterface, verifier PushVerifier) *PushHandler {
+	return &PushHandler{
+		pushUsecase: pushUsecase,
+		verifier:    verifier,
+	}
+}
+
+// Push receives Gmail notifications from a Pub/Sub push subscription. Any
+// 2xx response acknowledges the message; errors make Pub/Sub retry.
+func (h *PushHandler) Push(w http.ResponseWriter, r *http.Request) {
+	if err := h.verifier.Verify(r.Context(), r.Header.Get("Authorization")); err != nil {
+		writeJSONError(w, r, http.StatusUnauthorized, "Invalid push token")
+		return
+	}
+
+	body, err := io.ReadAll(io.LimitReader(r.Body, maxPushBody))
+	if err != nil {
+		writeJSONError(w, r, http.StatusBadRequest, "Failed to read body")
+		return
+	}
+
+	notification, err := gmail.ParsePush(body)
+	if err != nil {
+		writeJSONError(w, r, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	if err := h.pushUsecase.HandleNotification(r.Context(), notification); err != nil {
+		writeUsecaseError(w, r, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (h *PushHandler) Watch(w http.ResponseWriter, r *http.Request) {
+	user, err := auth.GetCurrentUser(r)
+	if err != nil {
+		writeJSONError(w, r, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+
+	watch, err := h.pushUsecase.Watch(r.Context(), user.ID)
+	if err != nil {
+		writeUsecaseError(w, r, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(watch)
+}
+
+func (h *PushHandler) StopWatch(w http.ResponseWriter, r *http.Request) {
+	user, err := auth.GetCurrentUser(r)
+	if err != nil {
+		writeJSONError(w, r, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+
+	if err := h.pushUsecase.StopWatch(r.Context(), user.ID); err != nil {
+		writeUsecaseError(w, r, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// RegisterRoutes registers the user's watch routes; the push endpoint is
+// registered separately because Pub/Sub does not carry a user token.
+func (h *PushHandler) RegisterRoutes(router chi.Router) {
+	router.Post("/watch", h.Watch)
+	router.Delete("/watch", h.StopWatch)
+}
@@ -0,0 +1,176 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"strconv"
+
+	"github.com/go-chi/chi/v5"
+	"ai-assistant/internal/models"
+	"ai-assistant/internal/services/auth"
+)
+
+// RuleUsecaseInterface defines the interface for rule usecase
+type RuleUsecaseInterface interface {
+	ListRules(ctx context.Context, userID string) ([]*models.Rule, error)
+	GetRule(ctx context.Context, userID, ruleID string) (*models.Rule, error)
+	CreateRule(ctx context.Context, userID string, req *models.RuleRequest) (*models.Rule, error)
+	UpdateRule(ctx context.Context, userID, ruleID string, req *models.RuleRequest) (*models.Rule, error)
+	DeleteRule(ctx context.Context, userID, ruleID string) error
+	DryRun(ctx context.Context, userID string, req *models.RuleRequest, limit int) (*models.DryRunResult, error)
+	DryRunRule(ctx context.Context, userID, ruleID string, limit int) (*models.DryRunResult, error)
+}
+
+type RuleHandler struct {
+	ruleUsecase RuleUsecaseInterface
+}
+
+func NewRuleHandler(ruleUsecase RuleUsecaseInterface) *RuleHandler {
+	return &RuleHandler{
+		ruleUsecase: ruleUsecase,
+	}
+}
+
+func (h *RuleHandler) List(w http.ResponseWriter, r *http.Request) {
+	user, err := auth.GetCurrentUser(r)
+	if err != nil {
+		writeJSONError(w, r, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+
+	list, err := h.ruleUsecase.ListRules(r.Context(), user.ID)
+	if err != nil {
+		writeUsecaseError(w, r, err)
+		return
+	}
+	if list == nil {
+		list = []*models.Rule{}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{"rules": list})
+}
+
+func (h *RuleHandler) Get(w http.ResponseWriter, r *http.Request) {
+	user, err := auth.GetCurrentUser(r)
+	if err != nil {
+		writeJSONError(w, r, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+
+	rule, err := h.ruleUsecase.GetRule(r.Context(), user.ID, chi.URLParam(r, "id"))
+	writeRule(w, r, http.StatusOK, rule, err)
+}
+
+func (h *RuleHandler) Create(w http.ResponseWriter, r *http.Request) {
+	user, err := auth.GetCurrentUser(r)
+	if err != nil {
+		writeJSONError(w, r, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+
+	var req models.RuleRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeJSONError(w, r, http.StatusBadRequest, "Invalid JSON")
+		return
+	}
+
+	rule, err := h.ruleUsecase.CreateRule(r.Context(), user.ID, &req)
+	writeRule(w, r, http.StatusCreated, rule, err)
+}
+
+func (h *RuleHandler) Update(w http.ResponseWriter, r *http.Request) {
+	user, err := auth.GetCurrentUser(r)
+	if err != nil {
+		writeJSONError(w, r, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+
+	var req models.RuleRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeJSONError(w, r, http.StatusBadRequest, "Invalid JSON")
+		return
+	}
+
+	rule, err := h.ruleUsecase.UpdateRule(r.Context(), user.ID, chi.URLParam(r, "id"), &req)
+	writeRule(w, r, http.StatusOK, rule, err)
+}
+
+func (h *RuleHandler) Delete(w http.ResponseWriter, r *http.Request) {
+	user, err := auth.GetCurrentUser(r)
+	if err != nil {
+		writeJSONError(w, r, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+
+	if err := h.ruleUsecase.DeleteRule(r.Context(), user.ID, chi.URLParam(r, "id")); err != nil {
+		writeUsecaseError(w, r, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// DryRun tests an unsaved rule from the request body against recent mail.
+func (h *RuleHandler) DryRun(w http.ResponseWriter, r *http.Request) {
+	user, err := auth.GetCurrentUser(r)
+	if err != nil {
+		writeJSONError(w, r, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+
+	var req models.RuleRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeJSONError(w, r, http.StatusBadRequest, "Invalid JSON")
+		return
+	}
+
+	limit, _ := strconv.Atoi(r.URL.Query().Get("limit"))
+	result, err := h.ruleUsecase.DryRun(r.Context(), user.ID, &req, limit)
+	writeDryRun(w, r, result, err)
+}
+
+// DryRunSaved tests a saved rule against recent mail.
+func (h *RuleHandler) DryRunSaved(w http.ResponseWriter, r *http.Request) {
+	user, err := auth.GetCurrentUser(r)
+	if err != nil {
+		writeJSONError(w, r, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+
+	limit, _ := strconv.Atoi(r.URL.Query().Get("limit"))
+	result, err := h.ruleUsecase.DryRunRule(r.Context(), user.ID, chi.URLParam(r, "id"), limit)
+	writeDryRun(w, r, result, err)
+}
+
+func writeRule(w http.ResponseWriter, r *http.Request, status int, rule *models.Rule, err error) {
+	if err != nil {
+		writeUsecaseError(w, r, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(rule)
+}
+
+func writeDryRun(w http.ResponseWriter, r *http.Request, result *models.DryRunResult, err error) {
+	if err != nil {
+		writeUsecaseError(w, r, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(result)
+}
+
+func (h *RuleHandler) RegisterRoutes(router chi.Router) {
+	router.Get("/", h.List)
+	router.Post("/", h.Create)
+	router.Post("/dry-run", h.DryRun)
+	router.Get("/{id}", h.Get)
+	router.Put("/{id}", h.Update)
+	router.Delete("/{id}", h.Delete)
+	router.Post("/{id}/dry-run", h.DryRunSaved)
+}
@@ -0,0 +1,57 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+	"ai-assistant/internal/models"
+	"ai-assistant/internal/services/auth"
+)
+
+// SanitizeUsecaseInterface defines the interface for the safe rendering usecase
+type SanitizeUsecaseInterface interface {
+	GetSanitizedEmail(ctx context.Context, userID, emailID string, blockImages bool) (*models.SanitizedEmail, error)
+}
+
+type SanitizeHandler struct {
+	sanitizeUsecase SanitizeUsecaseInterface
+}
+
+func NewSanitizeHandler(sanitizeUsecase SanitizeUsecaseInterface) *SanitizeHandler {
+	return &SanitizeHandler{
+		sanitizeUsecase: sanitizeUsecase,
+	}
+}
+
+// GetHTML returns an email's sanitized HTML and plain-text fallback.
+// ?images=block removes remote images even when the image proxy is on.
+func (h *SanitizeHandler) GetHTML(w http.ResponseWriter, r *http.Request) {
+	user, err := auth.GetCurrentUser(r)
+	if err != nil {
+		writeJSONError(w, r, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+
+	images := r.URL.Query().Get("images")
+	if images != "" && images != "block" && images != "proxy" {
+		writeJSONError(w, r, http.StatusBadRequest, "images must be proxy or block")
+		return
+	}
+
+	rendering, err := h.sanitizeUsecase.GetSanitizedEmail(r.Context(), user.ID, chi.URLParam(r, "id"), images == "block")
+	if err != nil {
+		writeUsecaseError(w, r, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "private, no-cache")
+	json.NewEncoder(w).Encode(rendering)
+}
+
+// RegisterRoutes mounts the rendering endpoint under the email routes.
+func (h *SanitizeHandler) RegisterRoutes(router chi.Router) {
+	router.Get("/{id}/html", h.GetHTML)
+}
@@ -0,0 +1,149 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"strconv"
+
+	"github.com/go-chi/chi/v5"
+	"ai-assistant/internal/models"
+	"ai-assistant/internal/services/auth"
+)
+
+// ScheduleUsecaseInterface defines the interface for the send queue usecase
+type ScheduleUsecaseInterface interface {
+	QueueEmail(ctx context.Context, userID, from string, req *models.SendEmailRequest) (*models.ScheduledEmail, error)
+	ListScheduled(ctx context.Context, userID, status string, limit, offset int) ([]*models.ScheduledEmail, error)
+	GetScheduled(ctx context.Context, userID, id string) (*models.ScheduledEmail, error)
+	UpdateScheduled(ctx context.Context, userID, id string, req *models.SendEmailRequest) (*models.ScheduledEmail, error)
+	CancelScheduled(ctx context.Context, userID, id string) (*models.ScheduledEmail, error)
+}
+
+type ScheduleHandler struct {
+	scheduleUsecase ScheduleUsecaseInterface
+}
+
+func NewScheduleHandler(scheduleUsecase ScheduleUsecaseInterface) *ScheduleHandler {
+	return &ScheduleHandler{
+		scheduleUsecase: scheduleUsecase,
+	}
+}
+
+// SendEmail queues a message. It is accepted rather than sent: until it is
+// dispatched it can be edited or cancelled under /scheduled/{id}.
+func (h *ScheduleHandler) SendEmail(w http.ResponseWriter, r *http.Request) {
+	user, err := auth.GetCurrentUser(r)
+	if err != nil {
+		writeJSONError(w, r, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+
+	var req models.SendEmailRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeJSONError(w, r, http.StatusBadRequest, "Invalid JSON")
+		return
+	}
+
+	scheduled, err := h.scheduleUsecase.QueueEmail(r.Context(), user.ID, "", &req)
+	if err != nil {
+		writeUsecaseError(w, r, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusAccepted)
+	json.NewEncoder(w).Encode(scheduled)
+}
+
+func (h *ScheduleHandler) ListScheduled(w http.ResponseWriter, r *http.Request) {
+	user, err := auth.GetCurrentUser(r)
+	if err != nil {
+		writeJSONError(w, r, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+
+	status := r.URL.Query().Get("status")
+	limit, _ := strconv.Atoi(r.URL.Query().Get("limit"))
+	offset, _ := strconv.Atoi(r.URL.Query().Get("offset"))
+
+	emails, err := h.scheduleUsecase.ListScheduled(r.Context(), user.ID, status, limit, offset)
+	if err != nil {
+		writeUsecaseError(w, r, err)
+		return
+	}
+
+	response := map[string]interface{}{
+		"emails": emails,
+		"limit":  limit,
+		"offset": offset,
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
+
+func (h *ScheduleHandler) GetScheduled(w http.ResponseWriter, r *http.Request) {
+	user, err := auth.GetCurrentUser(r)
+	if err != nil {
+		writeJSONError(w, r, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+
+	scheduled, err := h.scheduleUsecase.GetScheduled(r.Context(), user.ID, chi.URLParam(r, "id"))
+	if err != nil {
+		writeUsecaseError(w, r, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(scheduled)
+}
+
+func (h *ScheduleHandler) UpdateScheduled(w http.ResponseWriter, r *http.Request) {
+	user, err := auth.GetCurrentUser(r)
+	if err != nil {
+		writeJSONError(w, r, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+
+	var req models.SendEmailRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeJSONError(w, r, http.StatusBadRequest, "Invalid JSON")
+		return
+	}
+
+	scheduled, err := h.scheduleUsecase.UpdateScheduled(r.Context(), user.ID, chi.URLParam(r, "id"), &req)
+	if err != nil {
+		writeUsecaseError(w, r, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(scheduled)
+}
+
+func (h *ScheduleHandler) CancelScheduled(w http.ResponseWriter, r *http.Request) {
+	user, err := auth.GetCurrentUser(r)
+	if err != nil {
+		writeJSONError(w, r, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+
+	scheduled, err := h.scheduleUsecase.CancelScheduled(r.Context(), user.ID, chi.URLParam(r, "id"))
+	if err != nil {
+		writeUsecaseError(w, r, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(scheduled)
+}
+
+// RegisterRoutes mounts the queue under the email routes.
+func (h *ScheduleHandler) RegisterRoutes(router chi.Router) {
+	router.Post("/send", h.SendEmail)
+	router.Get("/scheduled", h.ListScheduled)
+	router.Get("/scheduled/{id}", h.GetScheduled)
+	router.Put("/scheduled/{id}", h.UpdateScheduled)
+	router.Post("/scheduled/{id}/cancel", h.CancelScheduled)
+}
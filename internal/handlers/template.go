@@ -0,0 +1,188 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+	"ai-assistant/internal/models"
+	"ai-assistant/internal/services/auth"
+)
+
+// TemplateUsecaseInterface defines the interface for email template usecase
+type TemplateUsecaseInterface interface {
+	ListTemplates(ctx context.Context, userID string) ([]*models.EmailTemplate, error)
+	GetTemplate(ctx context.Context, userID, templateID string) (*models.EmailTemplate, error)
+	CreateTemplate(ctx context.Context, userID string, req *models.TemplateRequest) (*models.EmailTemplate, error)
+	UpdateTemplate(ctx context.Context, userID, templateID string, req *models.TemplateRequest) (*models.EmailTemplate, error)
+	DeleteTemplate(ctx context.Context, userID, templateID string) error
+	RenderTemplate(ctx context.Context, userID, templateID string, data map[string]interface{}) (*models.RenderedEmail, error)
+	PreviewTemplate(ctx context.Context, req *models.TemplatePreviewRequest) (*models.RenderedEmail, error)
+}
+
+type TemplateHandler struct {
+	templateUsecase TemplateUsecaseInterface
+}
+
+func NewTemplateHandler(templateUsecase TemplateUsecaseInterface) *TemplateHandler {
+	return &TemplateHandler{
+		templateUsecase: templateUsecase,
+	}
+}
+
+func (h *TemplateHandler) List(w http.ResponseWriter, r *http.Request) {
+	user, err := auth.GetCurrentUser(r)
+	if err != nil {
+		writeJSONError(w, r, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+
+	templates, err := h.templateUsecase.ListTemplates(r.Context(), user.ID)
+	if err != nil {
+		writeUsecaseError(w, r, err)
+		return
+	}
+	if templates == nil {
+		templates = []*models.EmailTemplate{}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{"templates": templates})
+}
+
+func (h *TemplateHandler) Get(w http.ResponseWriter, r *http.Request) {
+	user, err := auth.GetCurrentUser(r)
+	if err != nil {
+		writeJSONError(w, r, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+
+	template, err := h.templateUsecase.GetTemplate(r.Context(), user.ID, chi.URLParam(r, "id"))
+	if err != nil {
+		writeUsecaseError(w, r, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(template)
+}
+
+func (h *TemplateHandler) Create(w http.ResponseWriter, r *http.Request) {
+	user, err := auth.GetCurrentUser(r)
+	if err != nil {
+		writeJSONError(w, r, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+
+	var req models.TemplateRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeJSONError(w, r, http.StatusBadRequest, "Invalid JSON")
+		return
+	}
+
+	template, err := h.templateUsecase.CreateTemplate(r.Context(), user.ID, &req)
+	if err != nil {
+		writeUsecaseError(w, r, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(template)
+}
+
+func (h *TemplateHandler) Update(w http.ResponseWriter, r *http.Request) {
+	user, err := auth.GetCurrentUser(r)
+	if err != nil {
+		writeJSONError(w, r, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+
+	var req models.TemplateRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeJSONError(w, r, http.StatusBadRequest, "Invalid JSON")
+		return
+	}
+
+	template, err := h.templateUsecase.UpdateTemplate(r.Context(), user.ID, chi.URLParam(r, "id"), &req)
+	if err != nil {
+		writeUsecaseError(w, r, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(template)
+}
+
+func (h *TemplateHandler) Delete(w http.ResponseWriter, r *http.Request) {
+	user, err := auth.GetCurrentUser(r)
+	if err != nil {
+		writeJSONError(w, r, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+
+	if err := h.templateUsecase.DeleteTemplate(r.Context(), user.ID, chi.URLParam(r, "id")); err != nil {
+		writeUsecaseError(w, r, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// Preview renders a stored template with the request's data.
+func (h *TemplateHandler) Preview(w http.ResponseWriter, r *http.Request) {
+	user, err := auth.GetCurrentUser(r)
+	if err != nil {
+		writeJSONError(w, r, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+
+	var req models.TemplatePreviewRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeJSONError(w, r, http.StatusBadRequest, "Invalid JSON")
+		return
+	}
+
+	rendered, err := h.templateUsecase.RenderTemplate(r.Context(), user.ID, chi.URLParam(r, "id"), req.Data)
+	if err != nil {
+		writeUsecaseError(w, r, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(rendered)
+}
+
+// PreviewDraft renders a subject and HTML body that are not saved yet.
+func (h *TemplateHandler) PreviewDraft(w http.ResponseWriter, r *http.Request) {
+	if _, err := auth.GetCurrentUser(r); err != nil {
+		writeJSONError(w, r, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+
+	var req models.TemplatePreviewRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeJSONError(w, r, http.StatusBadRequest, "Invalid JSON")
+		return
+	}
+
+	rendered, err := h.templateUsecase.PreviewTemplate(r.Context(), &req)
+	if err != nil {
+		writeUsecaseError(w, r, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(rendered)
+}
+
+func (h *TemplateHandler) RegisterRoutes(router chi.Router) {
+	router.Get("/", h.List)
+	router.Post("/", h.Create)
+	router.Post("/preview", h.PreviewDraft)
+	router.Get("/{id}", h.Get)
+	router.Put("/{id}", h.Update)
+	router.Delete("/{id}", h.Delete)
+	router.Post("/{id}/preview", h.Preview)
+}
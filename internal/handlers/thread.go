@@ -0,0 +1,134 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"strconv"
+
+	"github.com/go-chi/chi/v5"
+	"ai-assistant/internal/models"
+	"ai-assistant/internal/services/auth"
+)
+
+// ThreadUsecaseInterface defines the interface for thread usecase
+type ThreadUsecaseInterface interface {
+	ListThreads(ctx context.Context, userID, label string, limit, offset int) ([]*models.Thread, error)
+	GetThread(ctx context.Context, userID, threadID string) (*models.ThreadDetail, error)
+	MarkThreadRead(ctx context.Context, userID, threadID string, read bool) (*models.ThreadDetail, error)
+	ArchiveThread(ctx context.Context, userID, threadID string) (*models.ThreadDetail, error)
+	ModifyThreadLabels(ctx context.Context, userID, threadID string, add, remove []string) (*models.ThreadDetail, error)
+}
+
+type ThreadHandler struct {
+	threadUsecase ThreadUsecaseInterface
+}
+
+func NewThreadHandler(threadUsecase ThreadUsecaseInterface) *ThreadHandler {
+	return &ThreadHandler{
+		threadUsecase: threadUsecase,
+	}
+}
+
+func (h *ThreadHandler) List(w http.ResponseWriter, r *http.Request) {
+	user, err := auth.GetCurrentUser(r)
+	if err != nil {
+		writeJSONError(w, r, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+
+	label := r.URL.Query().Get("label")
+	limit, _ := strconv.Atoi(r.URL.Query().Get("limit"))
+	offset, _ := strconv.Atoi(r.URL.Query().Get("offset"))
+
+	threads, err := h.threadUsecase.ListThreads(r.Context(), user.ID, label, limit, offset)
+	if err != nil {
+		writeUsecaseError(w, r, err)
+		return
+	}
+
+	response := map[string]interface{}{
+		"threads": threads,
+		"limit":   limit,
+		"offset":  offset,
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
+
+func (h *ThreadHandler) Get(w http.ResponseWriter, r *http.Request) {
+	user, err := auth.GetCurrentUser(r)
+	if err != nil {
+		writeJSONError(w, r, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+
+	thread, err := h.threadUsecase.GetThread(r.Context(), user.ID, chi.URLParam(r, "id"))
+	writeThread(w, r, thread, err)
+}
+
+func (h *ThreadHandler) MarkRead(w http.ResponseWriter, r *http.Request) {
+	h.markRead(w, r, true)
+}
+
+func (h *ThreadHandler) MarkUnread(w http.ResponseWriter, r *http.Request) {
+	h.markRead(w, r, false)
+}
+
+func (h *ThreadHandler) markRead(w http.ResponseWriter, r *http.Request, read bool) {
+	user, err := auth.GetCurrentUser(r)
+	if err != nil {
+		writeJSONError(w, r, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+
+	thread, err := h.threadUsecase.MarkThreadRead(r.Context(), user.ID, chi.URLParam(r, "id"), read)
+	writeThread(w, r, thread, err)
+}
+
+func (h *ThreadHandler) Archive(w http.ResponseWriter, r *http.Request) {
+	user, err := auth.GetCurrentUser(r)
+	if err != nil {
+		writeJSONError(w, r, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+
+	thread, err := h.threadUsecase.ArchiveThread(r.Context(), user.ID, chi.URLParam(r, "id"))
+	writeThread(w, r, thread, err)
+}
+
+func (h *ThreadHandler) ModifyLabels(w http.ResponseWriter, r *http.Request) {
+	user, err := auth.GetCurrentUser(r)
+	if err != nil {
+		writeJSONError(w, r, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+
+	var req models.ModifyLabelsRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeJSONError(w, r, http.StatusBadRequest, "Invalid JSON")
+		return
+	}
+
+	thread, err := h.threadUsecase.ModifyThreadLabels(r.Context(), user.ID, chi.URLParam(r, "id"), req.Add, req.Remove)
+	writeThread(w, r, thread, err)
+}
+
+func writeThread(w http.ResponseWriter, r *http.Request, thread *models.ThreadDetail, err error) {
+	if err != nil {
+		writeUsecaseError(w, r, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(thread)
+}
+
+func (h *ThreadHandler) RegisterRoutes(router chi.Router) {
+	router.Get("/", h.List)
+	router.Get("/{id}", h.Get)
+	router.Post("/{id}/read", h.MarkRead)
+	router.Post("/{id}/unread", h.MarkUnread)
+	router.Post("/{id}/archive", h.Archive)
+	router.Post("/{id}/labels", h.ModifyLabels)
+}
@@ -0,0 +1,73 @@
+package handlers
+
+import (
+	"context"
+	"io"
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+	"ai-assistant/internal/models"
+	"ai-assistant/internal/services/email/resend"
+)
+
+// maxWebhookBody bounds provider webhook bodies.
+const maxWebhookBody = 1 << 20
+
+// DeliveryEventRecorder applies delivery reports from mail providers.
+type DeliveryEventRecorder interface {
+	RecordDeliveryEvent(ctx context.Context, event *models.DeliveryEvent) error
+}
+
+type WebhookHandler struct {
+	recorder       DeliveryEventRecorder
+	resendVerifier *resend.WebhookVerifier
+}
+
+// NewWebhookHandler creates the provider webhook handler. resendVerifier
+// may be nil when no signing secret is configured, which disables the
+// Resend endpoint.
+func NewWebhookHandler(recorder DeliveryEventRecorder, resendVerifier *resend.WebhookVerifier) *WebhookHandler {
+	return &WebhookHandler{
+		recorder:       recorder,
+		resendVerifier: resendVerifier,
+	}
+}
+
+// Resend receives delivery events. Resend retries anything but a 2xx, so
+// untracked event types are acknowledged too.
+func (h *WebhookHandler) Resend(w http.ResponseWriter, r *http.Request) {
+	if h.resendVerifier == nil {
+		writeJSONError(w, r, http.StatusServiceUnavailable, "Resend webhook not configured")
+		return
+	}
+
+	body, err := io.ReadAll(io.LimitReader(r.Body, maxWebhookBody))
+	if err != nil {
+		writeJSONError(w, r, http.StatusBadRequest, "Failed to read body")
+		return
+	}
+
+	if err := h.resendVerifier.Verify(r.Header, body); err != nil {
+		writeJSONError(w, r, http.StatusUnauthorized, "Invalid webhook signature")
+		return
+	}
+
+	event, err := resend.ParseEvent(body)
+	if err != nil {
+		writeJSONError(w, r, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	if delivery, ok := event.DeliveryEvent(r.Header.Get("svix-id")); ok {
+		if err := h.recorder.RecordDeliveryEvent(r.Context(), delivery); err != nil {
+			writeUsecaseError(w, r, err)
+			return
+		}
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (h *WebhookHandler) RegisterRoutes(router chi.Router) {
+	router.Post("/resend", h.Resend)
+}
@@ -0,0 +1,106 @@
+// Package httperr writes every error response of the API in one JSON
+// envelope:
+//
+//	{"error": {"code": 404, "message": "Email not found", "details": "...", "requestId": "..."}}
+//
+// AppErrors keep their status and message. Known sentinel errors, also when
+// wrapped, map to the status they stand for; anything else is an internal
+// server error whose details are only shown outside production.
+package httperr
+
+import (
+	"context"
+	"encoding/json"
+	stderrors "errors"
+	"net/http"
+	"sync/atomic"
+
+	"github.com/go-chi/chi/v5/middleware"
+	"ai-assistant/internal/repository"
+	"ai-assistant/internal/services/blob"
+	"ai-assistant/pkg/errors"
+	"ai-assistant/pkg/logger"
+)
+
+// StatusClientClosedRequest is reported when the client went away before the
+// response was ready. Nobody reads it, but it keeps such requests apart from
+// server errors in access logs.
+const StatusClientClosedRequest = 499
+
+// sentinels maps errors from below the usecases to responses. The first
+// match wins.
+var sentinels = []struct {
+	err    error
+	appErr *errors.AppError
+}{
+	{repository.ErrDuplicate, errors.ErrConflict("Resource already exists")},
+	{repository.ErrInvalidReference, errors.ErrBadRequest("Referenced resource does not exist")},
+	{blob.ErrNotFound, errors.ErrNotFound("Content not found")},
+	{context.DeadlineExceeded, errors.NewAppError(http.StatusGatewayTimeout, "Request timed out", "")},
+	{context.Canceled, errors.NewAppError(StatusClientClosedRequest, "Request cancelled", "")},
+}
+
+var production atomic.Bool
+
+// SetProduction hides the details of internal errors, which can name hosts,
+// tables or queries, from clients. They are still logged.
+func SetProduction(enabled bool) {
+	production.Store(enabled)
+}
+
+type envelope struct {
+	Error body `json:"error"`
+}
+
+type body struct {
+	Code      int    `json:"code"`
+	Message   string `json:"message"`
+	Details   string `json:"details,omitempty"`
+	RequestID string `json:"requestId,omitempty"`
+}
+
+// From returns the AppError err is or wraps, or the one its sentinel error
+// maps to. Any other error becomes an internal server error carrying err's
+// text as details.
+func From(err error) *errors.AppError {
+	var appErr *errors.AppError
+	if stderrors.As(err, &appErr) {
+		return appErr
+	}
+	for _, s := range sentinels {
+		if stderrors.Is(err, s.err) {
+			return s.appErr
+		}
+	}
+	return errors.NewAppError(http.StatusInternalServerError, "Internal server error", err.Error())
+}
+
+// Status returns the HTTP status Write would respond to err with.
+func Status(err error) int {
+	return From(err).Code
+}
+
+// Write responds to r with err. Server errors are logged with the request's
+// context, so that a response can be matched to its log line by request ID.
+func Write(w http.ResponseWriter, r *http.Request, err error) {
+	appErr := From(err)
+	requestID := middleware.GetReqID(r.Context())
+
+	details := appErr.Details
+	if appErr.Code >= http.StatusInternalServerError {
+		logger.New().ErrorContext(r.Context(), "Request failed",
+			"method", r.Method, "path", r.URL.Path, "status", appErr.Code, "error", err)
+		if production.Load() {
+			details = ""
+		}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(appErr.Code)
+	json.NewEncoder(w).Encode(envelope{Error: body{
+		Code:      appErr.Code,
+		Message:   appErr.Message,
+		Details:   details,
+		RequestID: requestID,
+	}})
+}
@@ -1,13 +1,134 @@
 package middleware
 
 import (
-	"encoding/json"
+	"fmt"
+	"log/slog"
 	"net/http"
+	"slices"
+	"strconv"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/chi/v5/middleware"
+	"go.opentelemetry.io/contrib/instrumentation/net/http/otelhttp"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	"go.opentelemetry.io/otel/trace"
+	"ai-assistant/internal/httperr"
+	"ai-assistant/pkg/errors"
+	"ai-assistant/pkg/logger"
+	"ai-assistant/pkg/metrics"
 )
 
+// Tracing starts a server span for every request, continuing the trace of
+// the caller's traceparent header if there is one. Once routing has matched
+// a route, the span is named after its pattern, such as
+// "GET /api/emails/{id}".
+func Tracing() func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		routed := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			next.ServeHTTP(w, r)
+
+			if rctx := chi.RouteContext(r.Context()); rctx != nil && rctx.RoutePattern() != "" {
+				trace.SpanFromContext(r.Context()).SetAttributes(semconv.HTTPRoute(rctx.RoutePattern()))
+			}
+		})
+		// chi records the matched pattern in r.Pattern, which otelhttp
+		// renames the span with once the request has been served.
+		return otelhttp.NewHandler(routed, "",
+			otelhttp.WithSpanNameFormatter(func(_ string, r *http.Request) string {
+				if r.Pattern != "" {
+					return r.Method + " " + r.Pattern
+				}
+				return r.Method
+			}),
+		)
+	}
+}
+
+// RequestLogger logs one record per request once it has been served. The
+// request ID, and attributes later handlers add to the context such as the
+// user ID, appear on it and on every record logged with the request's
+// context, as does the trace ID. It must come after middleware.RequestID
+// and Tracing.
+func RequestLogger() func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			ctx := logger.NewContext(r.Context())
+			if requestID := middleware.GetReqID(ctx); requestID != "" {
+				logger.AddAttrs(ctx, slog.String("request_id", requestID))
+			}
+			if spanContext := trace.SpanContextFromContext(ctx); spanContext.HasTraceID() {
+				logger.AddAttrs(ctx, slog.String("trace_id", spanContext.TraceID().String()))
+			}
+
+			ww := middleware.NewWrapResponseWriter(w, r.ProtoMajor)
+			start := time.Now()
+			defer func() {
+				status := ww.Status()
+				if status == 0 {
+					status = http.StatusOK
+				}
+				logger.New().InfoContext(ctx, "Request served",
+					"method", r.Method,
+					"path", r.URL.Path,
+					"status", status,
+					"bytes", ww.BytesWritten(),
+					"duration", time.Since(start),
+				)
+			}()
+
+			next.ServeHTTP(ww, r.WithContext(ctx))
+		})
+	}
+}
+
+// Metrics records the duration of every request by its route pattern, such
+// as /api/emails/{id}, once routing has matched one. Requests no route
+// matched share the pattern "unmatched".
+func Metrics() func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			ww := middleware.NewWrapResponseWriter(w, r.ProtoMajor)
+			start := time.Now()
+			next.ServeHTTP(ww, r)
+
+			route := "unmatched"
+			if rctx := chi.RouteContext(r.Context()); rctx != nil && rctx.RoutePattern() != "" {
+				route = rctx.RoutePattern()
+			}
+			status := ww.Status()
+			if status == 0 {
+				status = http.StatusOK
+			}
+			metrics.HTTPRequestDuration.
+				WithLabelValues(r.Method, route, strconv.Itoa(status)).
+				Observe(time.Since(start).Seconds())
+		})
+	}
+}
+
+// ErrorHandler echoes the request ID in the X-Request-ID header, so that
+// clients can quote it, and turns panics into internal server errors
+// written like any other error.
 func ErrorHandler() func(http.Handler) http.Handler {
 	return func(next http.Handler) http.Handler {
 		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if requestID := middleware.GetReqID(r.Context()); requestID != "" {
+				w.Header().Set("X-Request-ID", requestID)
+			}
+
+			defer func() {
+				p := recover()
+				if p == nil {
+					return
+				}
+				if p == http.ErrAbortHandler {
+					panic(p)
+				}
+				middleware.PrintPrettyStack(p)
+				httperr.Write(w, r, fmt.Errorf("panic: %v", p))
+			}()
+
 			next.ServeHTTP(w, r)
 		})
 	}
@@ -15,23 +136,31 @@ func ErrorHandler() func(http.Handler) http.Handler {
 
 func NotFoundHandler() http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
-		w.Header().Set("Content-Type", "application/json")
-		w.WriteHeader(http.StatusNotFound)
-		response := map[string]interface{}{
-			"error": map[string]interface{}{
-				"code":    http.StatusNotFound,
-				"message": "Route not found",
-				"path":    r.URL.Path,
-			},
-		}
-		json.NewEncoder(w).Encode(response)
+		httperr.Write(w, r, errors.NewAppError(http.StatusNotFound, "Route not found", r.URL.Path))
 	}
 }
 
-func CORS() func(http.Handler) http.Handler {
+func MethodNotAllowedHandler() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		httperr.Write(w, r, errors.NewAppError(http.StatusMethodNotAllowed, "Method not allowed", r.Method+" "+r.URL.Path))
+	}
+}
+
+// CORS lets browsers call the API from the origins allowedOrigins returns,
+// which is read on every request so that the list can change while the
+// server runs. "*" allows any origin.
+func CORS(allowedOrigins func() []string) func(http.Handler) http.Handler {
 	return func(next http.Handler) http.Handler {
 		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-			w.Header().Set("Access-Control-Allow-Origin", "*")
+			origins := allowedOrigins()
+			origin := r.Header.Get("Origin")
+			switch {
+			case slices.Contains(origins, "*"):
+				w.Header().Set("Access-Control-Allow-Origin", "*")
+			case origin != "" && slices.Contains(origins, origin):
+				w.Header().Set("Access-Control-Allow-Origin", origin)
+			}
+			w.Header().Add("Vary", "Origin")
 			w.Header().Set("Access-Control-Allow-Methods", "GET, POST, PUT, DELETE, OPTIONS")
 			w.Header().Set("Access-Control-Allow-Headers", "Content-Type, Authorization")
 			w.Header().Set("Access-Control-Allow-Credentials", "true")
@@ -0,0 +1,101 @@
+package middleware
+
+import (
+	"math"
+	"net/http"
+	"strconv"
+	"sync"
+
+	"golang.org/x/time/rate"
+	"ai-assistant/internal/httperr"
+	"ai-assistant/internal/services/auth"
+	"ai-assistant/pkg/errors"
+)
+
+// maxIdleLimiters bounds how many users' limiters are kept before those
+// with a full bucket, which a new limiter would equal, are dropped.
+const maxIdleLimiters = 10000
+
+// RateLimiter limits how many requests each user makes, with a token bucket
+// per user. The limit can change while the server runs.
+type RateLimiter struct {
+	mu        sync.Mutex
+	perMinute int
+	burst     int
+	users     map[string]*rate.Limiter
+}
+
+// NewRateLimiter allows each user perMinute requests a minute on average,
+// and burst at once. A perMinute of zero turns the limit off.
+func NewRateLimiter(perMinute, burst int) *RateLimiter {
+	l := &RateLimiter{users: make(map[string]*rate.Limiter)}
+	l.SetLimit(perMinute, burst)
+	return l
+}
+
+// SetLimit changes the limit of every user, including those already
+// limited.
+func (l *RateLimiter) SetLimit(perMinute, burst int) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.perMinute, l.burst = perMinute, burst
+	for _, limiter := range l.users {
+		limiter.SetLimit(l.rate())
+		limiter.SetBurst(burst)
+	}
+}
+
+func (l *RateLimiter) rate() rate.Limit {
+	return rate.Limit(float64(l.perMinute) / 60)
+}
+
+// allow reports whether the user may make a request now and, if not, how
+// many seconds to wait before retrying.
+func (l *RateLimiter) allow(userID string) (bool, int) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.perMinute <= 0 {
+		return true, 0
+	}
+
+	limiter, ok := l.users[userID]
+	if !ok {
+		if len(l.users) >= maxIdleLimiters {
+			l.dropIdle()
+		}
+		limiter = rate.NewLimiter(l.rate(), l.burst)
+		l.users[userID] = limiter
+	}
+	if limiter.Allow() {
+		return true, 0
+	}
+	return false, int(math.Ceil(60 / float64(l.perMinute)))
+}
+
+func (l *RateLimiter) dropIdle() {
+	for userID, limiter := range l.users {
+		if limiter.Tokens() >= float64(l.burst) {
+			delete(l.users, userID)
+		}
+	}
+}
+
+// Middleware rejects the requests of users over the limit with 429 Too Many
+// Requests. It must run after RequireAuth.
+func (l *RateLimiter) Middleware() func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			user, err := auth.GetCurrentUser(r)
+			if err != nil {
+				httperr.Write(w, r, err)
+				return
+			}
+			if ok, retryAfter := l.allow(user.ID); !ok {
+				w.Header().Set("Retry-After", strconv.Itoa(retryAfter))
+				httperr.Write(w, r, errors.ErrTooManyRequests("Rate limit exceeded, try again later"))
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
@@ -37,18 +37,447 @@ type Session struct {
 }
 
 type Email struct {
+	ID          string             `json:"id" db:"id"`
+	MessageID   string             `json:"messageId" db:"message_id"`
+	ThreadID    *string            `json:"threadId" db:"thread_id"`
+	Subject     *string            `json:"subject" db:"subject"`
+	From        string             `json:"from" db:"from"`
+	To          []string           `json:"to" db:"to"`
+	Cc          []string           `json:"cc" db:"cc"`
+	ReplyTo     *string            `json:"replyTo,omitempty" db:"reply_to"`
+	HeaderID    *string            `json:"headerMessageId,omitempty" db:"header_message_id"`
+	InReplyTo   *string            `json:"inReplyTo,omitempty" db:"in_reply_to"`
+	References  []string           `json:"references,omitempty" db:"references"`
+	Body        *string            `json:"body" db:"body"`
+	HTMLBody    *string            `json:"htmlBody" db:"html_body"`
+	IsRead      bool               `json:"isRead" db:"is_read"`
+	Labels      []string           `json:"labels" db:"labels"`
+	CreatedAt   time.Time          `json:"createdAt" db:"created_at"`
+	UserID      string             `json:"userId" db:"user_id"`
+	Attachments []*EmailAttachment `json:"attachments,omitempty" db:"-"`
+}
+
+// EmailAttachment is an attachment's metadata. StorageKey locates its
+// content in the blob store once it has been stored there.
+type EmailAttachment struct {
+	ID           string    `json:"id" db:"id"`
+	EmailID      string    `json:"emailId" db:"email_id"`
+	Filename     string    `json:"filename" db:"filename"`
+	MimeType     string    `json:"mimeType" db:"mime_type"`
+	Size         int64     `json:"size" db:"size"`
+	AttachmentID *string   `json:"attachmentId" db:"attachment_id"`
+	ContentID    *string   `json:"contentId" db:"content_id"`
+	Inline       bool      `json:"inline" db:"inline"`
+	StorageKey   *string   `json:"-" db:"storage_key"`
+	CreatedAt    time.Time `json:"createdAt" db:"created_at"`
+}
+
+// SanitizedEmail is an email's HTML made safe to show in a browser, with a
+// plain-text fallback. RemoteImages is "proxy" when remote images load
+// through the image proxy and "block" when they were removed.
+type SanitizedEmail struct {
+	EmailID       string    `json:"emailId" db:"email_id"`
+	RemoteImages  string    `json:"remoteImages" db:"remote_images"`
+	Version       string    `json:"-" db:"version"`
+	HTML          string    `json:"html" db:"html"`
+	Text          string    `json:"text" db:"text"`
+	BlockedImages int       `json:"blockedImages" db:"blocked_images"`
+	CreatedAt     time.Time `json:"createdAt" db:"created_at"`
+}
+
+// ReplyRequest is the body of POST /api/emails/{id}/reply. To and Subject
+// override the computed recipients and "Re:" subject when set.
+type ReplyRequest struct {
+	Body     string   `json:"body"`
+	To       []string `json:"to,omitempty"`
+	Subject  string   `json:"subject,omitempty"`
+	HTML     string   `json:"html,omitempty"`
+	ReplyAll bool     `json:"replyAll,omitempty"`
+	Cc       []string `json:"cc,omitempty"`
+	Bcc      []string `json:"bcc,omitempty"`
+}
+
+// ForwardRequest is the body of POST /api/emails/{id}/forward.
+type ForwardRequest struct {
+	To   []string `json:"to"`
+	Cc   []string `json:"cc,omitempty"`
+	Bcc  []string `json:"bcc,omitempty"`
+	Body string   `json:"body,omitempty"`
+}
+
+// SendEmailRequest is the body of POST /api/emails/send.
+// Without SendAt the message goes out once the undo delay has passed.
+type SendEmailRequest struct {
+	To      []string `json:"to"`
+	Cc      []string `json:"cc,omitempty"`
+	Bcc     []string `json:"bcc,omitempty"`
+	Subject string   `json:"subject"`
+	Body    string   `json:"body"`
+	HTML    string   `json:"html,omitempty"`
+	// SendAt is RFC 3339, or a local time like "2026-10-20T09:00" that is
+	// read in TimeZone.
+	SendAt   string `json:"sendAt,omitempty"`
+	TimeZone string `json:"timeZone,omitempty"`
+	// TemplateID renders a stored template with Data. A Subject given as
+	// well overrides the template's.
+	TemplateID string                 `json:"templateId,omitempty"`
+	Data       map[string]interface{} `json:"data,omitempty"`
+	// Attachments replace the queued email's attachments on update when
+	// present, even if empty.
+	Attachments []AttachmentUpload `json:"attachments,omitempty"`
+}
+
+// AttachmentUpload is a file attached to an outgoing email. An attachment
+// with a ContentID is sent inline, for the HTML to show as cid:ContentID.
+type AttachmentUpload struct {
+	Filename  string `json:"filename"`
+	MimeType  string `json:"mimeType,omitempty"`
+	ContentID string `json:"contentId,omitempty"`
+	// Content is base64-encoded.
+	Content string `json:"content"`
+}
+
+// OutgoingAttachment is an attachment of a queued email. Its content is in
+// the blob store until the email has been dispatched.
+type OutgoingAttachment struct {
+	Filename   string `json:"filename"`
+	MimeType   string `json:"mimeType"`
+	Size       int64  `json:"size"`
+	ContentID  string `json:"contentId,omitempty"`
+	StorageKey string `json:"-"`
+}
+
+type ScheduledStatus string
+
+const (
+	ScheduledStatusScheduled ScheduledStatus = "scheduled"
+	ScheduledStatusSending   ScheduledStatus = "sending"
+	ScheduledStatusSent      ScheduledStatus = "sent"
+	ScheduledStatusCancelled ScheduledStatus = "cancelled"
+	ScheduledStatusFailed    ScheduledStatus = "failed"
+)
+
+// ScheduledEmail is a message waiting in the send queue. It can be edited
+// or cancelled while its status is scheduled.
+type ScheduledEmail struct {
+	ID              string               `json:"id" db:"id"`
+	UserID          string               `json:"userId" db:"user_id"`
+	From            string               `json:"from" db:"from"`
+	To              []string             `json:"to" db:"to"`
+	Cc              []string             `json:"cc,omitempty" db:"cc"`
+	Bcc             []string             `json:"bcc,omitempty" db:"bcc"`
+	Subject         string               `json:"subject" db:"subject"`
+	Body            string               `json:"body" db:"body"`
+	HTML            *string              `json:"html,omitempty" db:"html_body"`
+	SendAt          time.Time            `json:"sendAt" db:"send_at"`
+	TimeZone        *string              `json:"timeZone,omitempty" db:"time_zone"`
+	Attachments     []OutgoingAttachment `json:"attachments,omitempty" db:"attachments"`
+	Status          ScheduledStatus      `json:"status" db:"status"`
+	OutboundEmailID *string              `json:"outboundEmailId,omitempty" db:"outbound_email_id"`
+	Error           *string              `json:"error,omitempty" db:"error"`
+	ClaimedAt       *time.Time           `json:"-" db:"claimed_at"`
+	CreatedAt       time.Time            `json:"createdAt" db:"created_at"`
+	UpdatedAt       time.Time            `json:"updatedAt" db:"updated_at"`
+}
+
+// EmailSearchResult is an email matched by a search query. Snippet is an
+// HTML-escaped excerpt of the body with matching words wrapped in <mark>
+// tags, and no other markup.
+type EmailSearchResult struct {
+	*Email
+	Rank    float64 `json:"rank"`
+	Snippet string  `json:"snippet"`
+}
+
+// Thread summarizes a conversation. Emails without a thread ID form a
+// thread of their own, identified by the email ID.
+type Thread struct {
+	ID            string    `json:"id"`
+	Subject       string    `json:"subject"`
+	Participants  []string  `json:"participants"`
+	MessageCount  int       `json:"messageCount"`
+	UnreadCount   int       `json:"unreadCount"`
+	Snippet       string    `json:"snippet"`
+	Labels        []string  `json:"labels"`
+	LastMessageAt time.Time `json:"lastMessageAt"`
+}
+
+// ThreadMessage is an email in a thread view. Content is the body with the
+// quoted history of earlier messages removed.
+type ThreadMessage struct {
+	*Email
+	Content string `json:"content"`
+}
+
+type ThreadDetail struct {
+	Thread
+	Messages []*ThreadMessage `json:"messages"`
+}
+
+// ModifyLabelsRequest is the body of POST /api/threads/{id}/labels and
+// POST /api/emails/{id}/labels.
+type ModifyLabelsRequest struct {
+	Add    []string `json:"add,omitempty"`
+	Remove []string `json:"remove,omitempty"`
+}
+
+type SentEmail struct {
+	ID         string   `json:"id"`
+	OutboundID string   `json:"outboundId,omitempty"`
+	ThreadID   string   `json:"threadId,omitempty"`
+	MessageID  string   `json:"messageId"`
+	To         []string `json:"to"`
+	Cc         []string `json:"cc,omitempty"`
+	Subject    string   `json:"subject"`
+}
+
+type OutboundStatus string
+
+const (
+	OutboundStatusQueued     OutboundStatus = "queued"
+	OutboundStatusSent       OutboundStatus = "sent"
+	OutboundStatusFailed     OutboundStatus = "failed"
+	OutboundStatusDelivered  OutboundStatus = "delivered"
+	OutboundStatusOpened     OutboundStatus = "opened"
+	OutboundStatusBounced    OutboundStatus = "bounced"
+	OutboundStatusComplained OutboundStatus = "complained"
+)
+
+// OutboundEmail is a message sent through the configured sender. Status is
+// the furthest delivery state reached; Events is its history.
+type OutboundEmail struct {
+	ID                string           `json:"id" db:"id"`
+	UserID            string           `json:"userId" db:"user_id"`
+	Provider          string           `json:"provider" db:"provider"`
+	ProviderMessageID *string          `json:"providerMessageId,omitempty" db:"provider_message_id"`
+	MessageID         string           `json:"messageId" db:"message_id"`
+	ThreadID          *string          `json:"threadId,omitempty" db:"thread_id"`
+	From              string           `json:"from" db:"from"`
+	To                []string         `json:"to" db:"to"`
+	Cc                []string         `json:"cc,omitempty" db:"cc"`
+	Bcc               []string         `json:"bcc,omitempty" db:"bcc"`
+	Subject           string           `json:"subject" db:"subject"`
+	Status            OutboundStatus   `json:"status" db:"status"`
+	Error             *string          `json:"error,omitempty" db:"error"`
+	CreatedAt         time.Time        `json:"createdAt" db:"created_at"`
+	UpdatedAt         time.Time        `json:"updatedAt" db:"updated_at"`
+	Events            []*OutboundEvent `json:"events,omitempty"`
+}
+
+type OutboundEvent struct {
+	ID              string         `json:"id" db:"id"`
+	OutboundEmailID string         `json:"-" db:"outbound_email_id"`
+	Status          OutboundStatus `json:"status" db:"status"`
+	ProviderEventID *string        `json:"-" db:"provider_event_id"`
+	Detail          *string        `json:"detail,omitempty" db:"detail"`
+	OccurredAt      time.Time      `json:"occurredAt" db:"occurred_at"`
+}
+
+// DeliveryEvent is a provider's report about a message it sent, e.g. from a
+// Resend webhook.
+type DeliveryEvent struct {
+	Provider          string
+	ProviderEventID   string
+	ProviderMessageID string
+	Status            OutboundStatus
+	Detail            string
+	OccurredAt        time.Time
+}
+
+type DraftStatus string
+
+const (
+	DraftStatusGenerated DraftStatus = "generated"
+	DraftStatusEdited    DraftStatus = "edited"
+	DraftStatusApproved  DraftStatus = "approved"
+	DraftStatusSent      DraftStatus = "sent"
+	DraftStatusDiscarded DraftStatus = "discarded"
+)
+
+type Draft struct {
+	ID            string      `json:"id" db:"id"`
+	UserID        string      `json:"userId" db:"user_id"`
+	EmailID       string      `json:"emailId" db:"email_id"`
+	ThreadID      *string     `json:"threadId" db:"thread_id"`
+	To            []string    `json:"to" db:"to"`
+	Cc            []string    `json:"cc" db:"cc"`
+	Bcc           []string    `json:"bcc" db:"bcc"`
+	Subject       string      `json:"subject" db:"subject"`
+	Body          string      `json:"body" db:"body"`
+	Status        DraftStatus `json:"status" db:"status"`
+	Provider      string      `json:"provider" db:"provider"`
+	Instructions  *string     `json:"instructions,omitempty" db:"instructions"`
+	GmailDraftID  *string     `json:"gmailDraftId,omitempty" db:"gmail_draft_id"`
+	SentMessageID *string     `json:"sentMessageId,omitempty" db:"sent_message_id"`
+	CreatedAt     time.Time   `json:"createdAt" db:"created_at"`
+	UpdatedAt     time.Time   `json:"updatedAt" db:"updated_at"`
+}
+
+// GenerateDraftRequest is the body of POST /api/drafts.
+type GenerateDraftRequest struct {
+	EmailID      string `json:"emailId"`
+	Provider     string `json:"provider,omitempty"`
+	Instructions string `json:"instructions,omitempty"`
+	ReplyAll     bool   `json:"replyAll,omitempty"`
+	SyncToGmail  bool   `json:"syncToGmail,omitempty"`
+}
+
+// UpdateDraftRequest is the body of PUT /api/drafts/{id}. Nil fields are
+// left unchanged.
+type UpdateDraftRequest struct {
+	To      []string `json:"to,omitempty"`
+	Cc      []string `json:"cc,omitempty"`
+	Bcc     []string `json:"bcc,omitempty"`
+	Subject *string  `json:"subject,omitempty"`
+	Body    *string  `json:"body,omitempty"`
+}
+
+// Label is a user-defined label. ProviderID is the mailbox provider's ID for
+// it when the provider has labels (Gmail); emails carry ProviderID, or the
+// name when there is none.
+type Label struct {
+	ID         string    `json:"id" db:"id"`
+	UserID     string    `json:"userId" db:"user_id"`
+	Name       string    `json:"name" db:"name"`
+	Color      *string   `json:"color,omitempty" db:"color"`
+	ProviderID *string   `json:"providerId,omitempty" db:"provider_label_id"`
+	CreatedAt  time.Time `json:"createdAt" db:"created_at"`
+	UpdatedAt  time.Time `json:"updatedAt" db:"updated_at"`
+}
+
+// Key is the value emails carry in their labels for this label.
+func (l *Label) Key() string {
+	if l.ProviderID != nil {
+		return *l.ProviderID
+	}
+	return l.Name
+}
+
+// LabelRequest is the body of POST /api/labels and PUT /api/labels/{id}.
+type LabelRequest struct {
+	Name  string  `json:"name"`
+	Color *string `json:"color,omitempty"`
+}
+
+// EmailTemplate is a stored outgoing email. Subject is a Go text/template
+// and HTML an html/template, both executed against the send request's data.
+type EmailTemplate struct {
 	ID        string    `json:"id" db:"id"`
-	MessageID string    `json:"messageId" db:"message_id"`
-	ThreadID  *string   `json:"threadId" db:"thread_id"`
-	Subject   *string   `json:"subject" db:"subject"`
-	From      string    `json:"from" db:"from"`
-	To        []string  `json:"to" db:"to"`
-	Body      *string   `json:"body" db:"body"`
-	HTMLBody  *string   `json:"htmlBody" db:"html_body"`
-	IsRead    bool      `json:"isRead" db:"is_read"`
-	Labels    []string  `json:"labels" db:"labels"`
-	CreatedAt time.Time `json:"createdAt" db:"created_at"`
 	UserID    string    `json:"userId" db:"user_id"`
+	Name      string    `json:"name" db:"name"`
+	Subject   string    `json:"subject" db:"subject"`
+	HTML      string    `json:"html" db:"html"`
+	CreatedAt time.Time `json:"createdAt" db:"created_at"`
+	UpdatedAt time.Time `json:"updatedAt" db:"updated_at"`
+}
+
+// TemplateRequest is the body of POST /api/templates and PUT
+// /api/templates/{id}.
+type TemplateRequest struct {
+	Name    string `json:"name"`
+	Subject string `json:"subject"`
+	HTML    string `json:"html"`
+}
+
+// TemplatePreviewRequest is the body of the preview endpoints. Subject and
+// HTML are only read when previewing a template that is not saved yet.
+type TemplatePreviewRequest struct {
+	Subject string                 `json:"subject,omitempty"`
+	HTML    string                 `json:"html,omitempty"`
+	Data    map[string]interface{} `json:"data,omitempty"`
+}
+
+// RenderedEmail is a template executed against data, with its CSS inlined
+// and a generated plain-text part.
+type RenderedEmail struct {
+	Subject string `json:"subject"`
+	HTML    string `json:"html"`
+	Text    string `json:"text"`
+}
+
+type RuleActionType string
+
+const (
+	RuleActionLabel    RuleActionType = "label"
+	RuleActionArchive  RuleActionType = "archive"
+	RuleActionMarkRead RuleActionType = "mark_read"
+	RuleActionForward  RuleActionType = "forward"
+	RuleActionNotify   RuleActionType = "notify"
+)
+
+// RuleConditions are combined with AND; unset fields are ignored. At least
+// one must be set.
+type RuleConditions struct {
+	// From matches a substring of the sender, case-insensitively.
+	From string `json:"from,omitempty"`
+	// SubjectRegex is an RE2 expression matched against the subject.
+	SubjectRegex string `json:"subjectRegex,omitempty"`
+	// BodyKeywords match whole words in the body, case-insensitively.
+	BodyKeywords []string `json:"bodyKeywords,omitempty"`
+	// AllKeywords requires every keyword instead of any of them.
+	AllKeywords bool `json:"allKeywords,omitempty"`
+	// Category asks the AI classifier whether the email belongs to it, e.g.
+	// "newsletter" or "invoice".
+	Category string `json:"category,omitempty"`
+}
+
+type RuleAction struct {
+	Type RuleActionType `json:"type"`
+	// Label names the label for "label" actions; it is created if needed.
+	Label string `json:"label,omitempty"`
+	// To is the recipient for "forward", and for "notify" when the
+	// notification should not go to the user's own address.
+	To string `json:"to,omitempty"`
+}
+
+type Rule struct {
+	ID             string         `json:"id" db:"id"`
+	UserID         string         `json:"userId" db:"user_id"`
+	Name           string         `json:"name" db:"name"`
+	Enabled        bool           `json:"enabled" db:"enabled"`
+	Priority       int            `json:"priority" db:"priority"`
+	Conditions     RuleConditions `json:"conditions" db:"conditions"`
+	Actions        []RuleAction   `json:"actions" db:"actions"`
+	StopProcessing bool           `json:"stopProcessing" db:"stop_processing"`
+	CreatedAt      time.Time      `json:"createdAt" db:"created_at"`
+	UpdatedAt      time.Time      `json:"updatedAt" db:"updated_at"`
+}
+
+// RuleRequest is the body of POST /api/rules, PUT /api/rules/{id} and
+// POST /api/rules/dry-run. Enabled defaults to true.
+type RuleRequest struct {
+	Name           string         `json:"name"`
+	Enabled        *bool          `json:"enabled,omitempty"`
+	Priority       int            `json:"priority,omitempty"`
+	Conditions     RuleConditions `json:"conditions"`
+	Actions        []RuleAction   `json:"actions"`
+	StopProcessing bool           `json:"stopProcessing,omitempty"`
+}
+
+// RuleMatch is an email a dry run found, with the actions that would run.
+type RuleMatch struct {
+	EmailID   string       `json:"emailId"`
+	Subject   string       `json:"subject"`
+	From      string       `json:"from"`
+	CreatedAt time.Time    `json:"createdAt"`
+	Actions   []RuleAction `json:"actions"`
+}
+
+type DryRunResult struct {
+	Scanned int          `json:"scanned"`
+	Matches []*RuleMatch `json:"matches"`
+}
+
+// GmailWatch is a user's Gmail push registration. HistoryID is how far the
+// mailbox has been synced.
+type GmailWatch struct {
+	UserID       string    `json:"userId" db:"user_id"`
+	EmailAddress string    `json:"emailAddress" db:"email_address"`
+	Topic        string    `json:"topic" db:"topic"`
+	HistoryID    uint64    `json:"historyId" db:"history_id"`
+	Expiration   time.Time `json:"expiration" db:"expiration"`
+	CreatedAt    time.Time `json:"createdAt" db:"created_at"`
+	UpdatedAt    time.Time `json:"updatedAt" db:"updated_at"`
 }
 
 type AIConversation struct {
@@ -60,14 +489,32 @@ type AIConversation struct {
 	CreatedAt time.Time `json:"createdAt" db:"created_at"`
 }
 
+// GroundingMailbox answers a prompt from the user's own emails.
+const GroundingMailbox = "mailbox"
+
 type AIRequest struct {
 	Prompt   string `json:"prompt" binding:"required"`
 	Provider string `json:"provider,omitempty"`
+	// Grounding selects a knowledge source for the answer; "" or "mailbox".
+	Grounding string `json:"grounding,omitempty"`
+	// TopK is how many email chunks to retrieve when grounded.
+	TopK int `json:"topK,omitempty"`
 }
 
 type AIResponse struct {
-	Response string `json:"response"`
-	Provider string `json:"provider"`
+	Response  string     `json:"response"`
+	Provider  string     `json:"provider"`
+	Citations []Citation `json:"citations,omitempty"`
+}
+
+// Citation is an email passage that was given to the model as a source.
+type Citation struct {
+	EmailID string    `json:"emailId"`
+	Subject string    `json:"subject,omitempty"`
+	From    string    `json:"from,omitempty"`
+	Date    time.Time `json:"date"`
+	Snippet string    `json:"snippet"`
+	Score   float64   `json:"score"`
 }
 
 type AuthUser struct {
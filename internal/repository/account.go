@@ -0,0 +1,39 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+
+	"ai-assistant/internal/models"
+	"ai-assistant/pkg/database"
+)
+
+type AccountRepository struct {
+	db *database.DB
+}
+
+func NewAccountRepository(db *database.DB) *AccountRepository {
+	return &AccountRepository{db: db}
+}
+
+// GetByUserAndProvider returns the user's linked account with a provider such
+// as "google", holding its OAuth tokens.
+func (r *AccountRepository) GetByUserAndProvider(ctx context.Context, userID, provider string) (*models.Account, error) {
+	account := &models.Account{}
+	query := `
+		SELECT id, user_id, type, provider, provider_account_id, refresh_token, access_token,
+			expires_at, token_type, scope, id_token, session_state
+		FROM accounts WHERE user_id = $1 AND provider = $2
+		LIMIT 1
+	`
+	err := r.db.QueryRowContext(ctx, query, userID, provider).Scan(
+		&account.ID, &account.UserID, &account.Type, &account.Provider,
+		&account.ProviderAccountID, &account.RefreshToken, &account.AccessToken,
+		&account.ExpiresAt, &account.TokenType, &account.Scope, &account.IDToken,
+		&account.SessionState)
+
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	return account, err
+}
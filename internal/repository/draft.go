@@ -0,0 +1,115 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/lib/pq"
+	"ai-assistant/internal/models"
+	"ai-assistant/pkg/database"
+)
+
+type DraftRepository struct {
+	db *database.DB
+}
+
+func NewDraftRepository(db *database.DB) *DraftRepository {
+	return &DraftRepository{db: db}
+}
+
+const draftColumns = `id, user_id, email_id, thread_id, "to", cc, bcc, subject, body, status, provider, instructions, gmail_draft_id, sent_message_id, created_at, updated_at`
+
+func scanDraft(row rowScanner) (*models.Draft, error) {
+	draft := &models.Draft{}
+	err := row.Scan(
+		&draft.ID, &draft.UserID, &draft.EmailID, &draft.ThreadID,
+		pq.Array(&draft.To), pq.Array(&draft.Cc), pq.Array(&draft.Bcc),
+		&draft.Subject, &draft.Body, &draft.Status, &draft.Provider,
+		&draft.Instructions, &draft.GmailDraftID, &draft.SentMessageID,
+		&draft.CreatedAt, &draft.UpdatedAt)
+	if err != nil {
+		return nil, err
+	}
+	return draft, nil
+}
+
+func (r *DraftRepository) Create(ctx context.Context, draft *models.Draft) error {
+	query := `
+		INSERT INTO email_drafts (` + draftColumns + `)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16)
+	`
+	_, err := r.db.ExecContext(ctx, query,
+		draft.ID, draft.UserID, draft.EmailID, draft.ThreadID,
+		pq.Array(draft.To), pq.Array(draft.Cc), pq.Array(draft.Bcc),
+		draft.Subject, draft.Body, draft.Status, draft.Provider,
+		draft.Instructions, draft.GmailDraftID, draft.SentMessageID,
+		draft.CreatedAt, draft.UpdatedAt)
+	return err
+}
+
+func (r *DraftRepository) GetByID(ctx context.Context, id string) (*models.Draft, error) {
+	query := `SELECT ` + draftColumns + ` FROM email_drafts WHERE id = $1`
+
+	draft, err := scanDraft(r.db.QueryRowContext(ctx, query, id))
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	return draft, err
+}
+
+// GetByUserID lists a user's drafts, newest first. An empty status returns
+// drafts in every state.
+func (r *DraftRepository) GetByUserID(ctx context.Context, userID string, status models.DraftStatus, limit, offset int) ([]*models.Draft, error) {
+	query := `
+		SELECT ` + draftColumns + `
+		FROM email_drafts
+		WHERE user_id = $1 AND ($2 = '' OR status = $2)
+		ORDER BY updated_at DESC
+		LIMIT $3 OFFSET $4
+	`
+
+	rows, err := r.db.QueryContext(ctx, query, userID, string(status), limit, offset)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var drafts []*models.Draft
+	for rows.Next() {
+		draft, err := scanDraft(rows)
+		if err != nil {
+			return nil, err
+		}
+		drafts = append(drafts, draft)
+	}
+
+	return drafts, rows.Err()
+}
+
+// Update saves the editable fields and status of a draft, but only if it is
+// still in expectedStatus. It reports whether the row was updated, so two
+// concurrent transitions (for example a double send) cannot both win.
+func (r *DraftRepository) Update(ctx context.Context, draft *models.Draft, expectedStatus models.DraftStatus) (bool, error) {
+	draft.UpdatedAt = time.Now()
+	query := `
+		UPDATE email_drafts
+		SET "to" = $3, cc = $4, bcc = $5, subject = $6, body = $7, status = $8,
+			gmail_draft_id = $9, sent_message_id = $10, updated_at = $11
+		WHERE id = $1 AND status = $2
+	`
+	result, err := r.db.ExecContext(ctx, query,
+		draft.ID, expectedStatus,
+		pq.Array(draft.To), pq.Array(draft.Cc), pq.Array(draft.Bcc),
+		draft.Subject, draft.Body, draft.Status,
+		draft.GmailDraftID, draft.SentMessageID, draft.UpdatedAt)
+	if err != nil {
+		return false, err
+	}
+
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	return affected == 1, nil
+}
@@ -4,6 +4,7 @@ import (
 	"context"
 	"database/sql"
 
+	"github.com/lib/pq"
 	"ai-assistant/internal/models"
 	"ai-assistant/pkg/database"
 )
@@ -24,7 +25,7 @@ func (r *AIConversationRepository) Create(ctx context.Context, conversation *mod
 	_, err := r.db.ExecContext(ctx, query,
 		conversation.ID, conversation.EmailID, conversation.Prompt,
 		conversation.Response, conversation.Provider, conversation.CreatedAt)
-	return err
+	return constraintError(err)
 }
 
 func (r *AIConversationRepository) GetByID(ctx context.Context, id string) (*models.AIConversation, error) {
@@ -87,21 +88,69 @@ func NewEmailRepository(db *database.DB) *EmailRepository {
 	return &EmailRepository{db: db}
 }
 
+const emailColumns = `id, message_id, thread_id, subject, "from", "to", cc, reply_to, header_message_id, in_reply_to, "references", body, html_body, is_read, labels, created_at, user_id`
+
+type rowScanner interface {
+	Scan(dest ...interface{}) error
+}
+
+func scanEmail(row rowScanner) (*models.Email, error) {
+	email := &models.Email{}
+	err := row.Scan(
+		&email.ID, &email.MessageID, &email.ThreadID, &email.Subject,
+		&email.From, pq.Array(&email.To), pq.Array(&email.Cc), &email.ReplyTo,
+		&email.HeaderID, &email.InReplyTo, pq.Array(&email.References),
+		&email.Body, &email.HTMLBody, &email.IsRead, pq.Array(&email.Labels),
+		&email.CreatedAt, &email.UserID)
+	if err != nil {
+		return nil, err
+	}
+	return email, nil
+}
+
 func (r *EmailRepository) Create(ctx context.Context, email *models.Email) error {
 	query := `
-		INSERT INTO emails (id, message_id, thread_id, subject, "from", "to", body, html_body, is_read, labels, created_at, user_id)
-		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12)
+		INSERT INTO emails (` + emailColumns + `)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16, $17)
 	`
 	_, err := r.db.ExecContext(ctx, query,
 		email.ID, email.MessageID, email.ThreadID, email.Subject,
-		email.From, email.To, email.Body, email.HTMLBody,
-		email.IsRead, email.Labels, email.CreatedAt, email.UserID)
-	return err
+		email.From, pq.Array(email.To), pq.Array(email.Cc), email.ReplyTo,
+		email.HeaderID, email.InReplyTo, pq.Array(email.References),
+		email.Body, email.HTMLBody, email.IsRead, pq.Array(email.Labels),
+		email.CreatedAt, email.UserID)
+	return constraintError(err)
+}
+
+// CreateWithAttachments stores an email and its attachments' metadata
+// together, so an email is never seen with part of its attachments.
+func (r *EmailRepository) CreateWithAttachments(ctx context.Context, email *models.Email, attachments []*models.EmailAttachment) error {
+	return r.db.WithTx(ctx, func(ctx context.Context) error {
+		if err := r.Create(ctx, email); err != nil {
+			return err
+		}
+		for _, attachment := range attachments {
+			if err := r.CreateAttachment(ctx, attachment); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+func (r *EmailRepository) GetByID(ctx context.Context, id string) (*models.Email, error) {
+	query := `SELECT ` + emailColumns + ` FROM emails WHERE id = $1`
+
+	email, err := scanEmail(r.db.QueryRowContext(ctx, query, id))
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	return email, err
 }
 
 func (r *EmailRepository) GetByUserID(ctx context.Context, userID string, limit int, offset int) ([]*models.Email, error) {
 	query := `
-		SELECT id, message_id, thread_id, subject, "from", "to", body, html_body, is_read, labels, created_at, user_id
+		SELECT ` + emailColumns + `
 		FROM emails 
 		WHERE user_id = $1 
 		ORDER BY created_at DESC 
@@ -116,11 +165,7 @@ func (r *EmailRepository) GetByUserID(ctx context.Context, userID string, limit
 
 	var emails []*models.Email
 	for rows.Next() {
-		email := &models.Email{}
-		err := rows.Scan(
-			&email.ID, &email.MessageID, &email.ThreadID, &email.Subject,
-			&email.From, &email.To, &email.Body, &email.HTMLBody,
-			&email.IsRead, &email.Labels, &email.CreatedAt, &email.UserID)
+		email, err := scanEmail(rows)
 		if err != nil {
 			return nil, err
 		}
@@ -134,4 +179,141 @@ func (r *EmailRepository) UpdateReadStatus(ctx context.Context, id string, isRea
 	query := `UPDATE emails SET is_read = $2 WHERE id = $1`
 	_, err := r.db.ExecContext(ctx, query, id, isRead)
 	return err
-}
\ No newline at end of file
+}
+
+const attachmentColumns = `id, email_id, filename, mime_type, size, attachment_id, content_id, inline, storage_key, created_at`
+
+func scanAttachment(row rowScanner) (*models.EmailAttachment, error) {
+	attachment := &models.EmailAttachment{}
+	err := row.Scan(
+		&attachment.ID, &attachment.EmailID, &attachment.Filename, &attachment.MimeType,
+		&attachment.Size, &attachment.AttachmentID, &attachment.ContentID,
+		&attachment.Inline, &attachment.StorageKey, &attachment.CreatedAt)
+	if err != nil {
+		return nil, err
+	}
+	return attachment, nil
+}
+
+func (r *EmailRepository) CreateAttachment(ctx context.Context, attachment *models.EmailAttachment) error {
+	query := `
+		INSERT INTO email_attachments (` + attachmentColumns + `)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)
+	`
+	_, err := r.db.ExecContext(ctx, query,
+		attachment.ID, attachment.EmailID, attachment.Filename, attachment.MimeType,
+		attachment.Size, attachment.AttachmentID, attachment.ContentID,
+		attachment.Inline, attachment.StorageKey, attachment.CreatedAt)
+	return constraintError(err)
+}
+
+func (r *EmailRepository) GetAttachments(ctx context.Context, emailID string) ([]*models.EmailAttachment, error) {
+	query := `
+		SELECT ` + attachmentColumns + `
+		FROM email_attachments
+		WHERE email_id = $1
+		ORDER BY created_at ASC, id ASC
+	`
+
+	rows, err := r.db.QueryContext(ctx, query, emailID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var attachments []*models.EmailAttachment
+	for rows.Next() {
+		attachment, err := scanAttachment(rows)
+		if err != nil {
+			return nil, err
+		}
+		attachments = append(attachments, attachment)
+	}
+
+	return attachments, rows.Err()
+}
+
+func (r *EmailRepository) GetAttachment(ctx context.Context, emailID, id string) (*models.EmailAttachment, error) {
+	query := `SELECT ` + attachmentColumns + ` FROM email_attachments WHERE email_id = $1 AND id = $2`
+
+	attachment, err := scanAttachment(r.db.QueryRowContext(ctx, query, emailID, id))
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	return attachment, err
+}
+
+// SetAttachmentStorageKey records where an attachment's content was stored.
+func (r *EmailRepository) SetAttachmentStorageKey(ctx context.Context, id, key string) error {
+	query := `UPDATE email_attachments SET storage_key = $2 WHERE id = $1`
+	_, err := r.db.ExecContext(ctx, query, id, key)
+	return err
+}
+
+// GetRendering returns the cached sanitized HTML of an email for a remote
+// image mode.
+func (r *EmailRepository) GetRendering(ctx context.Context, emailID, remoteImages string) (*models.SanitizedEmail, error) {
+	query := `
+		SELECT email_id, remote_images, version, html, text, blocked_images, created_at
+		FROM email_renderings
+		WHERE email_id = $1 AND remote_images = $2
+	`
+
+	rendering := &models.SanitizedEmail{}
+	err := r.db.QueryRowContext(ctx, query, emailID, remoteImages).Scan(
+		&rendering.EmailID, &rendering.RemoteImages, &rendering.Version, &rendering.HTML,
+		&rendering.Text, &rendering.BlockedImages, &rendering.CreatedAt)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return rendering, nil
+}
+
+// SaveRendering stores sanitized HTML, replacing an older version.
+func (r *EmailRepository) SaveRendering(ctx context.Context, rendering *models.SanitizedEmail) error {
+	query := `
+		INSERT INTO email_renderings (email_id, remote_images, version, html, text, blocked_images, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)
+		ON CONFLICT (email_id, remote_images) DO UPDATE SET
+			version = EXCLUDED.version,
+			html = EXCLUDED.html,
+			text = EXCLUDED.text,
+			blocked_images = EXCLUDED.blocked_images,
+			created_at = EXCLUDED.created_at
+	`
+	_, err := r.db.ExecContext(ctx, query,
+		rendering.EmailID, rendering.RemoteImages, rendering.Version, rendering.HTML,
+		rendering.Text, rendering.BlockedImages, rendering.CreatedAt)
+	return constraintError(err)
+}
+
+// GetByThreadID returns a user's messages in a thread, oldest first. An
+// email without a thread ID is found by its own ID.
+func (r *EmailRepository) GetByThreadID(ctx context.Context, userID, threadID string) ([]*models.Email, error) {
+	query := `
+		SELECT ` + emailColumns + `
+		FROM emails
+		WHERE user_id = $1 AND ` + threadMatch + `
+		ORDER BY created_at ASC, id ASC
+	`
+
+	rows, err := r.db.QueryContext(ctx, query, userID, threadID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var emails []*models.Email
+	for rows.Next() {
+		email, err := scanEmail(rows)
+		if err != nil {
+			return nil, err
+		}
+		emails = append(emails, email)
+	}
+
+	return emails, rows.Err()
+}
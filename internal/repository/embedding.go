@@ -0,0 +1,97 @@
+package repository
+
+import (
+	"context"
+	"strconv"
+	"strings"
+
+	"ai-assistant/internal/services/ai/embedding"
+	"ai-assistant/pkg/database"
+)
+
+// EmbeddingRepository stores chunk vectors in a pgvector column and
+// implements embedding.Store.
+type EmbeddingRepository struct {
+	db *database.DB
+}
+
+func NewEmbeddingRepository(db *database.DB) *EmbeddingRepository {
+	return &EmbeddingRepository{db: db}
+}
+
+func (r *EmbeddingRepository) ReplaceEmail(ctx context.Context, emailID, model string, chunks []embedding.Chunk) error {
+	return r.db.WithTx(ctx, func(ctx context.Context) error {
+		_, err := r.db.ExecContext(ctx, `DELETE FROM email_embeddings WHERE email_id = $1 AND model = $2`, emailID, model)
+		if err != nil {
+			return err
+		}
+
+		query := `
+			INSERT INTO email_embeddings (id, email_id, user_id, chunk_index, content, model, embedding, created_at)
+			VALUES ($1, $2, $3, $4, $5, $6, $7::vector, $8)
+		`
+		for _, chunk := range chunks {
+			_, err := r.db.ExecContext(ctx, query,
+				chunk.ID, chunk.EmailID, chunk.UserID, chunk.Index, chunk.Content,
+				chunk.Model, vectorLiteral(chunk.Vector), chunk.CreatedAt)
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// Search orders by cosine distance (<=>) so the HNSW index can be used, and
+// reports similarity as 1 - distance.
+func (r *EmbeddingRepository) Search(ctx context.Context, userID, model string, vector []float32, k int) ([]embedding.Match, error) {
+	query := `
+		SELECT id, email_id, user_id, chunk_index, content, model, created_at,
+			1 - (embedding <=> $3::vector) AS score
+		FROM email_embeddings
+		WHERE user_id = $1 AND model = $2
+		ORDER BY embedding <=> $3::vector
+		LIMIT $4
+	`
+
+	rows, err := r.db.QueryContext(ctx, query, userID, model, vectorLiteral(vector), k)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var matches []embedding.Match
+	for rows.Next() {
+		var match embedding.Match
+		err := rows.Scan(
+			&match.ID, &match.EmailID, &match.UserID, &match.Index,
+			&match.Content, &match.Model, &match.CreatedAt, &match.Score)
+		if err != nil {
+			return nil, err
+		}
+		matches = append(matches, match)
+	}
+
+	return matches, rows.Err()
+}
+
+func (r *EmbeddingRepository) Count(ctx context.Context, userID, model string) (int, error) {
+	var count int
+	query := `SELECT COUNT(*) FROM email_embeddings WHERE user_id = $1 AND model = $2`
+	err := r.db.QueryRowContext(ctx, query, userID, model).Scan(&count)
+	return count, err
+}
+
+// vectorLiteral formats v in pgvector's text input form, e.g. [0.1,0.2].
+func vectorLiteral(v []float32) string {
+	var b strings.Builder
+	b.WriteByte('[')
+	for i, x := range v {
+		if i > 0 {
+			b.WriteByte(',')
+		}
+		b.WriteString(strconv.FormatFloat(float64(x), 'g', -1, 32))
+	}
+	b.WriteByte(']')
+	return b.String()
+}
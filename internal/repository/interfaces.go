@@ -0,0 +1,101 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/lib/pq"
+	"ai-assistant/internal/models"
+	"ai-assistant/internal/services/email/search"
+)
+
+var (
+	// ErrDuplicate is returned when a record would repeat the ID or another
+	// unique value of an existing one, such as a user's email address.
+	ErrDuplicate = errors.New("repository: duplicate record")
+	// ErrInvalidReference is returned when a record refers to one that does
+	// not exist, such as an email of an unknown user.
+	ErrInvalidReference = errors.New("repository: referenced record does not exist")
+)
+
+// UserRepositoryInterface is implemented by UserRepository and by the
+// in-memory repository in package memory. Lookups return nil, nil when
+// nothing matches; updates and deletes of missing records are no-ops.
+type UserRepositoryInterface interface {
+	Create(ctx context.Context, user *models.User) error
+	GetByID(ctx context.Context, id string) (*models.User, error)
+	GetByEmail(ctx context.Context, email string) (*models.User, error)
+	Update(ctx context.Context, user *models.User) error
+	Delete(ctx context.Context, id string) error
+}
+
+type EmailRepositoryInterface interface {
+	Create(ctx context.Context, email *models.Email) error
+	CreateWithAttachments(ctx context.Context, email *models.Email, attachments []*models.EmailAttachment) error
+	GetByID(ctx context.Context, id string) (*models.Email, error)
+	GetByUserID(ctx context.Context, userID string, limit int, offset int) ([]*models.Email, error)
+	UpdateReadStatus(ctx context.Context, id string, isRead bool) error
+
+	CreateAttachment(ctx context.Context, attachment *models.EmailAttachment) error
+	GetAttachments(ctx context.Context, emailID string) ([]*models.EmailAttachment, error)
+	GetAttachment(ctx context.Context, emailID, id string) (*models.EmailAttachment, error)
+	SetAttachmentStorageKey(ctx context.Context, id, key string) error
+
+	GetRendering(ctx context.Context, emailID, remoteImages string) (*models.SanitizedEmail, error)
+	SaveRendering(ctx context.Context, rendering *models.SanitizedEmail) error
+
+	ModifyLabels(ctx context.Context, userID, emailID string, add, remove []string) (bool, error)
+	ReplaceLabel(ctx context.Context, userID, from, to string) error
+	RemoveLabel(ctx context.Context, userID, label string) error
+
+	GetByThreadID(ctx context.Context, userID, threadID string) ([]*models.Email, error)
+	ListThreads(ctx context.Context, userID, label string, limit, offset int) ([]*models.Thread, error)
+	ModifyThreadLabels(ctx context.Context, userID, threadID string, add, remove []string) (int64, error)
+	FindThreadID(ctx context.Context, userID string, headerIDs []string) (string, error)
+
+	Search(ctx context.Context, userID string, q *search.Query, limit, offset int) ([]*models.EmailSearchResult, error)
+}
+
+// OutboundRepositoryInterface records what users send and how delivery
+// went.
+type OutboundRepositoryInterface interface {
+	Create(ctx context.Context, o *models.OutboundEmail) error
+	SetSendResult(ctx context.Context, id string, status models.OutboundStatus, providerMessageID, threadID, sendError *string) error
+	AdvanceStatus(ctx context.Context, id string, status models.OutboundStatus, from []models.OutboundStatus) error
+	GetByProviderMessageID(ctx context.Context, provider, providerMessageID string) (*models.OutboundEmail, error)
+	GetByUserID(ctx context.Context, userID string, status models.OutboundStatus, limit, offset int) ([]*models.OutboundEmail, error)
+	AddEvent(ctx context.Context, event *models.OutboundEvent) (bool, error)
+	GetEvents(ctx context.Context, outboundIDs []string) ([]*models.OutboundEvent, error)
+}
+
+type AIConversationRepositoryInterface interface {
+	Create(ctx context.Context, conversation *models.AIConversation) error
+	GetByID(ctx context.Context, id string) (*models.AIConversation, error)
+	GetByProvider(ctx context.Context, provider string, limit int) ([]*models.AIConversation, error)
+	Delete(ctx context.Context, id string) error
+}
+
+var (
+	_ UserRepositoryInterface           = (*UserRepository)(nil)
+	_ EmailRepositoryInterface          = (*EmailRepository)(nil)
+	_ OutboundRepositoryInterface       = (*OutboundRepository)(nil)
+	_ AIConversationRepositoryInterface = (*AIConversationRepository)(nil)
+)
+
+// constraintError turns the constraint violations callers can act on into
+// ErrDuplicate and ErrInvalidReference, keeping the driver error in the
+// chain.
+func constraintError(err error) error {
+	var pqErr *pq.Error
+	if !errors.As(err, &pqErr) {
+		return err
+	}
+	switch pqErr.Code {
+	case "23505": // unique_violation
+		return fmt.Errorf("%w: %w", ErrDuplicate, err)
+	case "23503": // foreign_key_violation
+		return fmt.Errorf("%w: %w", ErrInvalidReference, err)
+	}
+	return err
+}
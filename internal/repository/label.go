@@ -0,0 +1,157 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/lib/pq"
+	"ai-assistant/internal/models"
+	"ai-assistant/pkg/database"
+)
+
+// modifyLabelsQuery adds labels $3 and removes labels $4, keeping is_read in
+// step with the UNREAD label. Callers append the WHERE clause.
+const modifyLabelsQuery = `
+	UPDATE emails SET
+		labels = ARRAY(
+			SELECT DISTINCT l FROM unnest(array_cat(labels, $3::text[])) AS l
+			WHERE NOT (l = ANY($4::text[]))
+		),
+		is_read = CASE
+			WHEN 'UNREAD' = ANY($4::text[]) THEN TRUE
+			WHEN 'UNREAD' = ANY($3::text[]) THEN FALSE
+			ELSE is_read
+		END`
+
+// labelArray passes labels to modifyLabelsQuery. A nil slice would be sent
+// as NULL, which ANY treats as unknown rather than empty, dropping every
+// label.
+func labelArray(labels []string) interface{} {
+	if labels == nil {
+		labels = []string{}
+	}
+	return pq.Array(labels)
+}
+
+// ModifyLabels adds and removes labels on one of a user's emails. It returns
+// false if the email does not exist.
+func (r *EmailRepository) ModifyLabels(ctx context.Context, userID, emailID string, add, remove []string) (bool, error) {
+	query := modifyLabelsQuery + ` WHERE user_id = $1 AND id = $2`
+
+	result, err := r.db.ExecContext(ctx, query, userID, emailID, labelArray(add), labelArray(remove))
+	if err != nil {
+		return false, err
+	}
+	n, err := result.RowsAffected()
+	return n > 0, err
+}
+
+// ReplaceLabel renames a label on all of a user's emails.
+func (r *EmailRepository) ReplaceLabel(ctx context.Context, userID, from, to string) error {
+	query := `
+		UPDATE emails SET labels = array_replace(labels, $2, $3)
+		WHERE user_id = $1 AND $2 = ANY(labels)
+	`
+	_, err := r.db.ExecContext(ctx, query, userID, from, to)
+	return err
+}
+
+// RemoveLabel takes a label off all of a user's emails.
+func (r *EmailRepository) RemoveLabel(ctx context.Context, userID, label string) error {
+	query := `
+		UPDATE emails SET labels = array_remove(labels, $2)
+		WHERE user_id = $1 AND $2 = ANY(labels)
+	`
+	_, err := r.db.ExecContext(ctx, query, userID, label)
+	return err
+}
+
+type LabelRepository struct {
+	db *database.DB
+}
+
+func NewLabelRepository(db *database.DB) *LabelRepository {
+	return &LabelRepository{db: db}
+}
+
+const labelColumns = `id, user_id, name, color, provider_label_id, created_at, updated_at`
+
+func scanLabel(row rowScanner) (*models.Label, error) {
+	label := &models.Label{}
+	err := row.Scan(
+		&label.ID, &label.UserID, &label.Name, &label.Color,
+		&label.ProviderID, &label.CreatedAt, &label.UpdatedAt)
+	if err != nil {
+		return nil, err
+	}
+	return label, nil
+}
+
+func (r *LabelRepository) Create(ctx context.Context, label *models.Label) error {
+	query := `
+		INSERT INTO labels (` + labelColumns + `)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)
+	`
+	_, err := r.db.ExecContext(ctx, query,
+		label.ID, label.UserID, label.Name, label.Color,
+		label.ProviderID, label.CreatedAt, label.UpdatedAt)
+	return err
+}
+
+func (r *LabelRepository) GetByID(ctx context.Context, userID, id string) (*models.Label, error) {
+	query := `SELECT ` + labelColumns + ` FROM labels WHERE user_id = $1 AND id = $2`
+
+	label, err := scanLabel(r.db.QueryRowContext(ctx, query, userID, id))
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	return label, err
+}
+
+// GetByName looks a label up case-insensitively, as Gmail compares names.
+func (r *LabelRepository) GetByName(ctx context.Context, userID, name string) (*models.Label, error) {
+	query := `SELECT ` + labelColumns + ` FROM labels WHERE user_id = $1 AND lower(name) = lower($2)`
+
+	label, err := scanLabel(r.db.QueryRowContext(ctx, query, userID, name))
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	return label, err
+}
+
+func (r *LabelRepository) GetByUserID(ctx context.Context, userID string) ([]*models.Label, error) {
+	query := `SELECT ` + labelColumns + ` FROM labels WHERE user_id = $1 ORDER BY lower(name) ASC`
+
+	rows, err := r.db.QueryContext(ctx, query, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var labels []*models.Label
+	for rows.Next() {
+		label, err := scanLabel(rows)
+		if err != nil {
+			return nil, err
+		}
+		labels = append(labels, label)
+	}
+
+	return labels, rows.Err()
+}
+
+func (r *LabelRepository) Update(ctx context.Context, label *models.Label) error {
+	query := `
+		UPDATE labels SET name = $3, color = $4, provider_label_id = $5, updated_at = $6
+		WHERE user_id = $1 AND id = $2
+	`
+	_, err := r.db.ExecContext(ctx, query,
+		label.UserID, label.ID, label.Name, label.Color, label.ProviderID, label.UpdatedAt)
+	return err
+}
+
+func (r *LabelRepository) Delete(ctx context.Context, userID, id string) error {
+	query := `DELETE FROM labels WHERE user_id = $1 AND id = $2`
+	_, err := r.db.ExecContext(ctx, query, userID, id)
+	return err
+}
@@ -0,0 +1,313 @@
+package memory
+
+import (
+	"context"
+	"sort"
+
+	"ai-assistant/internal/models"
+	"ai-assistant/internal/repository"
+)
+
+type AIConversationRepository struct {
+	s *Store
+}
+
+var _ repository.AIConversationRepositoryInterface = (*AIConversationRepository)(nil)
+
+func (r *AIConversationRepository) Create(ctx context.Context, conversation *models.AIConversation) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	if _, ok := r.s.conversations[conversation.ID]; ok {
+		return repository.ErrDuplicate
+	}
+	r.s.conversations[conversation.ID] = copyConversation(conversation)
+	return nil
+}
+
+func (r *AIConversationRepository) GetByID(ctx context.Context, id string) (*models.AIConversation, error) {
+	r.s.mu.RLock()
+	defer r.s.mu.RUnlock()
+
+	if conversation, ok := r.s.conversations[id]; ok {
+		return copyConversation(conversation), nil
+	}
+	return nil, nil
+}
+
+func (r *AIConversationRepository) GetByProvider(ctx context.Context, provider string, limit int) ([]*models.AIConversation, error) {
+	r.s.mu.RLock()
+	defer r.s.mu.RUnlock()
+
+	var conversations []*models.AIConversation
+	for _, conversation := range r.s.conversations {
+		if conversation.Provider == provider {
+			conversations = append(conversations, copyConversation(conversation))
+		}
+	}
+	sort.Slice(conversations, func(i, j int) bool {
+		a, b := conversations[i], conversations[j]
+		if !a.CreatedAt.Equal(b.CreatedAt) {
+			return a.CreatedAt.After(b.CreatedAt)
+		}
+		return a.ID < b.ID
+	})
+	return page(conversations, limit, 0), nil
+}
+
+func (r *AIConversationRepository) Delete(ctx context.Context, id string) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	delete(r.s.conversations, id)
+	return nil
+}
+
+func copyConversation(conversation *models.AIConversation) *models.AIConversation {
+	c := *conversation
+	c.EmailID = cloneString(conversation.EmailID)
+	c.CreatedAt = dbTime(conversation.CreatedAt)
+	return &c
+}
+
+type EmailRepository struct {
+	s *Store
+}
+
+var _ repository.EmailRepositoryInterface = (*EmailRepository)(nil)
+
+func (r *EmailRepository) Create(ctx context.Context, email *models.Email) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	if err := r.checkEmail(email); err != nil {
+		return err
+	}
+	r.s.emails[email.ID] = copyEmail(email)
+	return nil
+}
+
+// CreateWithAttachments stores an email and its attachments' metadata
+// together, so an email is never seen with part of its attachments.
+func (r *EmailRepository) CreateWithAttachments(ctx context.Context, email *models.Email, attachments []*models.EmailAttachment) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	if err := r.checkEmail(email); err != nil {
+		return err
+	}
+	ids := map[string]bool{}
+	for _, attachment := range attachments {
+		if attachment.EmailID != email.ID {
+			if err := r.checkAttachment(attachment); err != nil {
+				return err
+			}
+		} else if _, ok := r.s.attachments[attachment.ID]; ok {
+			return repository.ErrDuplicate
+		}
+		if ids[attachment.ID] {
+			return repository.ErrDuplicate
+		}
+		ids[attachment.ID] = true
+	}
+
+	r.s.emails[email.ID] = copyEmail(email)
+	for _, attachment := range attachments {
+		r.s.attachments[attachment.ID] = copyAttachment(attachment)
+	}
+	return nil
+}
+
+func (r *EmailRepository) GetByID(ctx context.Context, id string) (*models.Email, error) {
+	r.s.mu.RLock()
+	defer r.s.mu.RUnlock()
+
+	if email, ok := r.s.emails[id]; ok {
+		return copyEmail(email), nil
+	}
+	return nil, nil
+}
+
+func (r *EmailRepository) GetByUserID(ctx context.Context, userID string, limit int, offset int) ([]*models.Email, error) {
+	r.s.mu.RLock()
+	defer r.s.mu.RUnlock()
+
+	emails := r.find(func(email *models.Email) bool { return email.UserID == userID })
+	sortNewestFirst(emails)
+	return page(emails, limit, offset), nil
+}
+
+func (r *EmailRepository) UpdateReadStatus(ctx context.Context, id string, isRead bool) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	if email, ok := r.s.emails[id]; ok {
+		email.IsRead = isRead
+	}
+	return nil
+}
+
+func (r *EmailRepository) CreateAttachment(ctx context.Context, attachment *models.EmailAttachment) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	if err := r.checkAttachment(attachment); err != nil {
+		return err
+	}
+	r.s.attachments[attachment.ID] = copyAttachment(attachment)
+	return nil
+}
+
+func (r *EmailRepository) GetAttachments(ctx context.Context, emailID string) ([]*models.EmailAttachment, error) {
+	r.s.mu.RLock()
+	defer r.s.mu.RUnlock()
+
+	var attachments []*models.EmailAttachment
+	for _, attachment := range r.s.attachments {
+		if attachment.EmailID == emailID {
+			attachments = append(attachments, copyAttachment(attachment))
+		}
+	}
+	sort.Slice(attachments, func(i, j int) bool {
+		a, b := attachments[i], attachments[j]
+		if !a.CreatedAt.Equal(b.CreatedAt) {
+			return a.CreatedAt.Before(b.CreatedAt)
+		}
+		return a.ID < b.ID
+	})
+	return attachments, nil
+}
+
+func (r *EmailRepository) GetAttachment(ctx context.Context, emailID, id string) (*models.EmailAttachment, error) {
+	r.s.mu.RLock()
+	defer r.s.mu.RUnlock()
+
+	if attachment, ok := r.s.attachments[id]; ok && attachment.EmailID == emailID {
+		return copyAttachment(attachment), nil
+	}
+	return nil, nil
+}
+
+// SetAttachmentStorageKey records where an attachment's content was stored.
+func (r *EmailRepository) SetAttachmentStorageKey(ctx context.Context, id, key string) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	if attachment, ok := r.s.attachments[id]; ok {
+		attachment.StorageKey = &key
+	}
+	return nil
+}
+
+// GetRendering returns the cached sanitized HTML of an email for a remote
+// image mode.
+func (r *EmailRepository) GetRendering(ctx context.Context, emailID, remoteImages string) (*models.SanitizedEmail, error) {
+	r.s.mu.RLock()
+	defer r.s.mu.RUnlock()
+
+	if rendering, ok := r.s.renderings[renderingKey{emailID, remoteImages}]; ok {
+		c := *rendering
+		return &c, nil
+	}
+	return nil, nil
+}
+
+// SaveRendering stores sanitized HTML, replacing an older version.
+func (r *EmailRepository) SaveRendering(ctx context.Context, rendering *models.SanitizedEmail) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	if _, ok := r.s.emails[rendering.EmailID]; !ok {
+		return repository.ErrInvalidReference
+	}
+	c := *rendering
+	c.CreatedAt = dbTime(rendering.CreatedAt)
+	r.s.renderings[renderingKey{rendering.EmailID, rendering.RemoteImages}] = &c
+	return nil
+}
+
+// checkEmail enforces the unique ID and message ID of emails and that their
+// user exists. The caller holds the write lock.
+func (r *EmailRepository) checkEmail(email *models.Email) error {
+	if _, ok := r.s.emails[email.ID]; ok {
+		return repository.ErrDuplicate
+	}
+	for _, other := range r.s.emails {
+		if other.MessageID == email.MessageID {
+			return repository.ErrDuplicate
+		}
+	}
+	if _, ok := r.s.users[email.UserID]; !ok {
+		return repository.ErrInvalidReference
+	}
+	return nil
+}
+
+func (r *EmailRepository) checkAttachment(attachment *models.EmailAttachment) error {
+	if _, ok := r.s.attachments[attachment.ID]; ok {
+		return repository.ErrDuplicate
+	}
+	if _, ok := r.s.emails[attachment.EmailID]; !ok {
+		return repository.ErrInvalidReference
+	}
+	return nil
+}
+
+// find returns copies of the emails match accepts. The caller holds the
+// lock.
+func (r *EmailRepository) find(match func(*models.Email) bool) []*models.Email {
+	var emails []*models.Email
+	for _, email := range r.s.emails {
+		if match(email) {
+			emails = append(emails, copyEmail(email))
+		}
+	}
+	return emails
+}
+
+func sortNewestFirst(emails []*models.Email) {
+	sort.Slice(emails, func(i, j int) bool {
+		a, b := emails[i], emails[j]
+		if !a.CreatedAt.Equal(b.CreatedAt) {
+			return a.CreatedAt.After(b.CreatedAt)
+		}
+		return a.ID < b.ID
+	})
+}
+
+func sortOldestFirst(emails []*models.Email) {
+	sort.Slice(emails, func(i, j int) bool {
+		a, b := emails[i], emails[j]
+		if !a.CreatedAt.Equal(b.CreatedAt) {
+			return a.CreatedAt.Before(b.CreatedAt)
+		}
+		return a.ID < b.ID
+	})
+}
+
+func copyEmail(email *models.Email) *models.Email {
+	c := *email
+	c.ThreadID = cloneString(email.ThreadID)
+	c.Subject = cloneString(email.Subject)
+	c.To = cloneStrings(email.To)
+	c.Cc = cloneStrings(email.Cc)
+	c.ReplyTo = cloneString(email.ReplyTo)
+	c.HeaderID = cloneString(email.HeaderID)
+	c.InReplyTo = cloneString(email.InReplyTo)
+	c.References = cloneStrings(email.References)
+	c.Body = cloneString(email.Body)
+	c.HTMLBody = cloneString(email.HTMLBody)
+	c.Labels = cloneStrings(email.Labels)
+	c.CreatedAt = dbTime(email.CreatedAt)
+	c.Attachments = nil
+	return &c
+}
+
+func copyAttachment(attachment *models.EmailAttachment) *models.EmailAttachment {
+	c := *attachment
+	c.AttachmentID = cloneString(attachment.AttachmentID)
+	c.ContentID = cloneString(attachment.ContentID)
+	c.StorageKey = cloneString(attachment.StorageKey)
+	c.CreatedAt = dbTime(attachment.CreatedAt)
+	return &c
+}
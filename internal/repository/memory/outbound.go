@@ -0,0 +1,164 @@
+package memory
+
+import (
+	"context"
+	"slices"
+	"sort"
+	"time"
+
+	"ai-assistant/internal/models"
+	"ai-assistant/internal/repository"
+)
+
+type OutboundRepository struct {
+	s *Store
+}
+
+var _ repository.OutboundRepositoryInterface = (*OutboundRepository)(nil)
+
+func (r *OutboundRepository) Create(ctx context.Context, o *models.OutboundEmail) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	if _, ok := r.s.users[o.UserID]; !ok {
+		return repository.ErrInvalidReference
+	}
+	if _, ok := r.s.outbound[o.ID]; ok {
+		return repository.ErrDuplicate
+	}
+	if o.ProviderMessageID != nil && r.findByProviderMessageID(o.Provider, *o.ProviderMessageID) != nil {
+		return repository.ErrDuplicate
+	}
+	r.s.outbound[o.ID] = copyOutbound(o)
+	return nil
+}
+
+func (r *OutboundRepository) SetSendResult(ctx context.Context, id string, status models.OutboundStatus, providerMessageID, threadID, sendError *string) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	o, ok := r.s.outbound[id]
+	if !ok {
+		return nil
+	}
+	o.Status = status
+	if providerMessageID != nil {
+		o.ProviderMessageID = cloneString(providerMessageID)
+	}
+	if threadID != nil {
+		o.ThreadID = cloneString(threadID)
+	}
+	o.Error = cloneString(sendError)
+	o.UpdatedAt = dbTime(time.Now())
+	return nil
+}
+
+func (r *OutboundRepository) AdvanceStatus(ctx context.Context, id string, status models.OutboundStatus, from []models.OutboundStatus) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	if o, ok := r.s.outbound[id]; ok && slices.Contains(from, o.Status) {
+		o.Status = status
+		o.UpdatedAt = dbTime(time.Now())
+	}
+	return nil
+}
+
+func (r *OutboundRepository) GetByProviderMessageID(ctx context.Context, provider, providerMessageID string) (*models.OutboundEmail, error) {
+	r.s.mu.RLock()
+	defer r.s.mu.RUnlock()
+
+	if o := r.findByProviderMessageID(provider, providerMessageID); o != nil {
+		return copyOutbound(o), nil
+	}
+	return nil, nil
+}
+
+func (r *OutboundRepository) GetByUserID(ctx context.Context, userID string, status models.OutboundStatus, limit, offset int) ([]*models.OutboundEmail, error) {
+	r.s.mu.RLock()
+	defer r.s.mu.RUnlock()
+
+	var sent []*models.OutboundEmail
+	for _, o := range r.s.outbound {
+		if o.UserID == userID && (status == "" || o.Status == status) {
+			sent = append(sent, copyOutbound(o))
+		}
+	}
+	sort.Slice(sent, func(i, j int) bool {
+		if !sent[i].CreatedAt.Equal(sent[j].CreatedAt) {
+			return sent[i].CreatedAt.After(sent[j].CreatedAt)
+		}
+		return sent[i].ID > sent[j].ID
+	})
+	return page(sent, limit, offset), nil
+}
+
+func (r *OutboundRepository) AddEvent(ctx context.Context, event *models.OutboundEvent) (bool, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	if _, ok := r.s.outbound[event.OutboundEmailID]; !ok {
+		return false, repository.ErrInvalidReference
+	}
+	if _, ok := r.s.events[event.ID]; ok {
+		return false, repository.ErrDuplicate
+	}
+	if event.ProviderEventID != nil {
+		for _, existing := range r.s.events {
+			if existing.ProviderEventID != nil && *existing.ProviderEventID == *event.ProviderEventID {
+				return false, nil
+			}
+		}
+	}
+	stored := *event
+	stored.ProviderEventID = cloneString(event.ProviderEventID)
+	stored.Detail = cloneString(event.Detail)
+	stored.OccurredAt = dbTime(event.OccurredAt)
+	r.s.events[event.ID] = &stored
+	return true, nil
+}
+
+func (r *OutboundRepository) GetEvents(ctx context.Context, outboundIDs []string) ([]*models.OutboundEvent, error) {
+	r.s.mu.RLock()
+	defer r.s.mu.RUnlock()
+
+	var events []*models.OutboundEvent
+	for _, event := range r.s.events {
+		if slices.Contains(outboundIDs, event.OutboundEmailID) {
+			copied := *event
+			events = append(events, &copied)
+		}
+	}
+	sort.Slice(events, func(i, j int) bool {
+		if !events[i].OccurredAt.Equal(events[j].OccurredAt) {
+			return events[i].OccurredAt.Before(events[j].OccurredAt)
+		}
+		return events[i].ID < events[j].ID
+	})
+	return events, nil
+}
+
+// findByProviderMessageID returns the stored record itself. The caller
+// holds the lock.
+func (r *OutboundRepository) findByProviderMessageID(provider, providerMessageID string) *models.OutboundEmail {
+	for _, o := range r.s.outbound {
+		if o.Provider == provider && o.ProviderMessageID != nil && *o.ProviderMessageID == providerMessageID {
+			return o
+		}
+	}
+	return nil
+}
+
+func copyOutbound(o *models.OutboundEmail) *models.OutboundEmail {
+	copied := *o
+	copied.ProviderMessageID = cloneString(o.ProviderMessageID)
+	copied.ThreadID = cloneString(o.ThreadID)
+	copied.Error = cloneString(o.Error)
+	copied.To = cloneStrings(o.To)
+	copied.Cc = cloneStrings(o.Cc)
+	copied.Bcc = cloneStrings(o.Bcc)
+	copied.CreatedAt = dbTime(o.CreatedAt)
+	copied.UpdatedAt = dbTime(o.UpdatedAt)
+	copied.Events = nil
+	return &copied
+}
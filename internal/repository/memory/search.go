@@ -0,0 +1,256 @@
+package memory
+
+import (
+	"context"
+	"sort"
+	"strings"
+	"unicode"
+
+	"ai-assistant/internal/models"
+	"ai-assistant/internal/services/email/search"
+)
+
+// Search runs a parsed query over a user's emails. The operators filter as
+// in PostgreSQL. Free text is an approximation of full-text search: words
+// match whole words of the subject, sender and body case-insensitively, with
+// common stop words ignored but no stemming, and rank by where they occur.
+func (r *EmailRepository) Search(ctx context.Context, userID string, q *search.Query, limit, offset int) ([]*models.EmailSearchResult, error) {
+	r.s.mu.RLock()
+	defer r.s.mu.RUnlock()
+
+	var results []*models.EmailSearchResult
+	for _, email := range r.s.emails {
+		if email.UserID != userID || !r.matchesFilters(email, q) {
+			continue
+		}
+		result := &models.EmailSearchResult{Email: copyEmail(email)}
+		if q.HasText() {
+			rank, ok := matchText(email, q)
+			if !ok {
+				continue
+			}
+			result.Rank = rank
+			result.Snippet = headline(search.StripMarks(derefString(email.Body)), textWords(q))
+		} else {
+			result.Snippet = left(search.StripMarks(derefString(email.Body)), 200)
+		}
+		result.Snippet = search.HighlightHTML(result.Snippet)
+		results = append(results, result)
+	}
+
+	sort.Slice(results, func(i, j int) bool {
+		a, b := results[i], results[j]
+		if a.Rank != b.Rank {
+			return a.Rank > b.Rank
+		}
+		if !a.CreatedAt.Equal(b.CreatedAt) {
+			return a.CreatedAt.After(b.CreatedAt)
+		}
+		return a.ID < b.ID
+	})
+	return page(results, limit, offset), nil
+}
+
+// matchesFilters applies the terms other than free text. A condition on a
+// NULL subject is unknown, and like a failed one excludes the email even
+// when negated.
+func (r *EmailRepository) matchesFilters(email *models.Email, q *search.Query) bool {
+	for _, term := range q.Terms {
+		if term.Field == search.FieldText {
+			continue
+		}
+		match, known := r.filter(email, term)
+		if !known || match == term.Negate {
+			return false
+		}
+	}
+	return true
+}
+
+func (r *EmailRepository) filter(email *models.Email, term search.Term) (match, known bool) {
+	switch term.Field {
+	case search.FieldFrom:
+		return containsFold(email.From, term.Value), true
+	case search.FieldTo:
+		return anyContainsFold(email.To, term.Value), true
+	case search.FieldCc:
+		return anyContainsFold(email.Cc, term.Value), true
+	case search.FieldSubject:
+		if email.Subject == nil {
+			return false, false
+		}
+		return containsFold(*email.Subject, term.Value), true
+	case search.FieldLabel:
+		for _, label := range email.Labels {
+			if strings.EqualFold(label, term.Value) {
+				return true, true
+			}
+		}
+		return false, true
+	case search.FieldHas:
+		for _, attachment := range r.s.attachments {
+			if attachment.EmailID == email.ID && !attachment.Inline {
+				return true, true
+			}
+		}
+		return false, true
+	case search.FieldIs:
+		switch term.Value {
+		case search.IsUnread:
+			return !email.IsRead, true
+		case search.IsRead:
+			return email.IsRead, true
+		default:
+			return contains(email.Labels, "STARRED"), true
+		}
+	case search.FieldBefore:
+		return email.CreatedAt.Before(term.Date), true
+	case search.FieldAfter:
+		return !email.CreatedAt.Before(term.Date), true
+	}
+	return true, true
+}
+
+// Field weights of the search_vector column.
+var fieldWeights = []float64{1.0, 0.4, 0.2}
+
+// matchText reports whether the free-text terms match the email, and its
+// rank if they do.
+func matchText(email *models.Email, q *search.Query) (float64, bool) {
+	fields := [][]string{
+		words(derefString(email.Subject)),
+		words(email.From),
+		words(derefString(email.Body)),
+	}
+	var all []string
+	for _, field := range fields {
+		all = append(all, field...)
+	}
+
+	rank := 0.0
+	for _, term := range q.Terms {
+		if term.Field != search.FieldText {
+			continue
+		}
+		phrase := words(term.Value)
+		if len(phrase) == 0 {
+			continue
+		}
+		var found bool
+		if term.Phrase {
+			found = indexPhrase(all, phrase)
+		} else {
+			found = true
+			for _, w := range phrase {
+				found = found && contains(all, w)
+			}
+		}
+		if found == term.Negate {
+			return 0, false
+		}
+		if term.Negate {
+			continue
+		}
+		for i, field := range fields {
+			for _, w := range field {
+				if contains(phrase, w) {
+					rank += fieldWeights[i]
+				}
+			}
+		}
+	}
+	return rank, true
+}
+
+func indexPhrase(doc, phrase []string) bool {
+	for i := 0; i+len(phrase) <= len(doc); i++ {
+		match := true
+		for j, w := range phrase {
+			if doc[i+j] != w {
+				match = false
+				break
+			}
+		}
+		if match {
+			return true
+		}
+	}
+	return false
+}
+
+// textWords returns the words of the free-text terms that are looked for,
+// for highlighting.
+func textWords(q *search.Query) []string {
+	var result []string
+	for _, term := range q.Terms {
+		if term.Field == search.FieldText && !term.Negate {
+			result = append(result, words(term.Value)...)
+		}
+	}
+	return result
+}
+
+// headline returns up to 30 words of body around the first match, with
+// matched words marked as ts_headline marks them.
+func headline(body string, matched []string) string {
+	fields := strings.Fields(body)
+	start := 0
+	for i, f := range fields {
+		if w := words(f); len(w) > 0 && contains(matched, w[0]) {
+			start = max(0, i-5)
+			break
+		}
+	}
+	end := min(len(fields), start+30)
+
+	out := make([]string, 0, end-start)
+	for _, f := range fields[start:end] {
+		if w := words(f); len(w) > 0 && contains(matched, w[0]) {
+			f = search.MarkStart + f + search.MarkStop
+		}
+		out = append(out, f)
+	}
+	return strings.Join(out, " ")
+}
+
+var stopWords = map[string]bool{
+	"a": true, "an": true, "and": true, "are": true, "as": true, "at": true,
+	"be": true, "by": true, "for": true, "from": true, "in": true, "is": true,
+	"it": true, "of": true, "on": true, "or": true, "that": true, "the": true,
+	"this": true, "to": true, "was": true, "with": true,
+}
+
+// words splits text into lower-case words, leaving out stop words.
+func words(text string) []string {
+	var result []string
+	for _, w := range strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	}) {
+		if !stopWords[w] {
+			result = append(result, w)
+		}
+	}
+	return result
+}
+
+func containsFold(s, substr string) bool {
+	return strings.Contains(strings.ToLower(s), strings.ToLower(substr))
+}
+
+func anyContainsFold(values []string, substr string) bool {
+	for _, v := range values {
+		if containsFold(v, substr) {
+			return true
+		}
+	}
+	return false
+}
+
+// left returns the first n characters of s.
+func left(s string, n int) string {
+	runes := []rune(s)
+	if len(runes) > n {
+		return string(runes[:n])
+	}
+	return s
+}
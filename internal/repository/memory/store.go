@@ -0,0 +1,140 @@
+// Package memory implements the repository interfaces in memory, for tests
+// that should not need PostgreSQL. It keeps the database's behaviour that
+// callers can observe: result ordering, nil results for missing records,
+// repository.ErrDuplicate and repository.ErrInvalidReference for constraint
+// violations, cascading deletes, and timestamps stored to the millisecond
+// without a time zone. Records are copied on the way in and out, so callers
+// cannot change stored data by holding on to them.
+package memory
+
+import (
+	"sort"
+	"sync"
+	"time"
+
+	"ai-assistant/internal/models"
+)
+
+// Store holds the tables shared by the repositories it returns, so that
+// references between them are checked as the database would.
+type Store struct {
+	mu            sync.RWMutex
+	users         map[string]*models.User
+	emails        map[string]*models.Email
+	attachments   map[string]*models.EmailAttachment
+	renderings    map[renderingKey]*models.SanitizedEmail
+	conversations map[string]*models.AIConversation
+	outbound      map[string]*models.OutboundEmail
+	events        map[string]*models.OutboundEvent
+}
+
+type renderingKey struct {
+	emailID      string
+	remoteImages string
+}
+
+func New() *Store {
+	return &Store{
+		users:         map[string]*models.User{},
+		emails:        map[string]*models.Email{},
+		attachments:   map[string]*models.EmailAttachment{},
+		renderings:    map[renderingKey]*models.SanitizedEmail{},
+		conversations: map[string]*models.AIConversation{},
+		outbound:      map[string]*models.OutboundEmail{},
+		events:        map[string]*models.OutboundEvent{},
+	}
+}
+
+func (s *Store) Users() *UserRepository {
+	return &UserRepository{s: s}
+}
+
+func (s *Store) Emails() *EmailRepository {
+	return &EmailRepository{s: s}
+}
+
+func (s *Store) Outbound() *OutboundRepository {
+	return &OutboundRepository{s: s}
+}
+
+func (s *Store) AIConversations() *AIConversationRepository {
+	return &AIConversationRepository{s: s}
+}
+
+// deleteEmail removes an email and the rows referring to it. The caller
+// holds the write lock.
+func (s *Store) deleteEmail(id string) {
+	delete(s.emails, id)
+	for attachmentID, attachment := range s.attachments {
+		if attachment.EmailID == id {
+			delete(s.attachments, attachmentID)
+		}
+	}
+	for key := range s.renderings {
+		if key.emailID == id {
+			delete(s.renderings, key)
+		}
+	}
+}
+
+// dbTime is t as it reads back from a TIMESTAMP(3) column: the wall clock
+// time, rounded to milliseconds, in UTC.
+func dbTime(t time.Time) time.Time {
+	return time.Date(t.Year(), t.Month(), t.Day(), t.Hour(), t.Minute(), t.Second(), t.Nanosecond(), time.UTC).
+		Round(time.Millisecond)
+}
+
+func dbTimePtr(t *time.Time) *time.Time {
+	if t == nil {
+		return nil
+	}
+	v := dbTime(*t)
+	return &v
+}
+
+// cloneStrings copies a text array column, keeping NULL and empty apart.
+func cloneStrings(s []string) []string {
+	if s == nil {
+		return nil
+	}
+	return append([]string{}, s...)
+}
+
+func cloneString(s *string) *string {
+	if s == nil {
+		return nil
+	}
+	v := *s
+	return &v
+}
+
+// sortedSet returns the distinct values in order, as array_agg(DISTINCT)
+// does.
+func sortedSet(values []string) []string {
+	seen := map[string]bool{}
+	set := []string{}
+	for _, v := range values {
+		if !seen[v] {
+			seen[v] = true
+			set = append(set, v)
+		}
+	}
+	sort.Strings(set)
+	return set
+}
+
+// page applies LIMIT and OFFSET. Like a query without rows, an empty page
+// is nil.
+func page[T any](items []T, limit, offset int) []T {
+	if offset >= len(items) {
+		return nil
+	}
+	items = items[offset:]
+	if limit >= 0 && limit < len(items) {
+		items = items[:limit]
+	}
+	if len(items) == 0 {
+		return nil
+	}
+	return items
+}
@@ -0,0 +1,202 @@
+package memory
+
+import (
+	"context"
+	"sort"
+
+	"ai-assistant/internal/models"
+)
+
+// ModifyLabels adds and removes labels on one of a user's emails. It returns
+// false if the email does not exist.
+func (r *EmailRepository) ModifyLabels(ctx context.Context, userID, emailID string, add, remove []string) (bool, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	email, ok := r.s.emails[emailID]
+	if !ok || email.UserID != userID {
+		return false, nil
+	}
+	modifyLabels(email, add, remove)
+	return true, nil
+}
+
+// ReplaceLabel renames a label on all of a user's emails.
+func (r *EmailRepository) ReplaceLabel(ctx context.Context, userID, from, to string) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	for _, email := range r.s.emails {
+		if email.UserID != userID {
+			continue
+		}
+		for i, label := range email.Labels {
+			if label == from {
+				email.Labels[i] = to
+			}
+		}
+	}
+	return nil
+}
+
+// RemoveLabel takes a label off all of a user's emails.
+func (r *EmailRepository) RemoveLabel(ctx context.Context, userID, label string) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	for _, email := range r.s.emails {
+		if email.UserID != userID || !contains(email.Labels, label) {
+			continue
+		}
+		labels := []string{}
+		for _, l := range email.Labels {
+			if l != label {
+				labels = append(labels, l)
+			}
+		}
+		email.Labels = labels
+	}
+	return nil
+}
+
+// GetByThreadID returns a user's messages in a thread, oldest first. An
+// email without a thread ID is found by its own ID.
+func (r *EmailRepository) GetByThreadID(ctx context.Context, userID, threadID string) ([]*models.Email, error) {
+	r.s.mu.RLock()
+	defer r.s.mu.RUnlock()
+
+	emails := r.find(func(email *models.Email) bool {
+		return email.UserID == userID && threadKey(email) == threadID
+	})
+	sortOldestFirst(emails)
+	return emails, nil
+}
+
+// ListThreads returns a user's threads, most recently active first. If label
+// is set, only threads with at least one message carrying it are returned.
+// Snippet holds the full body of the latest message; callers shorten it.
+func (r *EmailRepository) ListThreads(ctx context.Context, userID, label string, limit, offset int) ([]*models.Thread, error) {
+	r.s.mu.RLock()
+	defer r.s.mu.RUnlock()
+
+	emails := r.find(func(email *models.Email) bool { return email.UserID == userID })
+	sortOldestFirst(emails)
+
+	byKey := map[string][]*models.Email{}
+	var keys []string
+	for _, email := range emails {
+		key := threadKey(email)
+		if byKey[key] == nil {
+			keys = append(keys, key)
+		}
+		byKey[key] = append(byKey[key], email)
+	}
+
+	var threads []*models.Thread
+	for _, key := range keys {
+		messages := byKey[key]
+		var participants, labels []string
+		thread := &models.Thread{ID: key, Subject: derefString(messages[0].Subject)}
+		for _, email := range messages {
+			participants = append(participants, email.From)
+			labels = append(labels, email.Labels...)
+			thread.MessageCount++
+			if !email.IsRead {
+				thread.UnreadCount++
+			}
+		}
+		if label != "" && !contains(labels, label) {
+			continue
+		}
+		latest := messages[len(messages)-1]
+		thread.Participants = sortedSet(participants)
+		thread.Labels = sortedSet(labels)
+		thread.Snippet = derefString(latest.Body)
+		thread.LastMessageAt = latest.CreatedAt
+		threads = append(threads, thread)
+	}
+
+	// Stable, so that threads active at the same time keep the order of
+	// their first messages.
+	sort.SliceStable(threads, func(i, j int) bool {
+		return threads[i].LastMessageAt.After(threads[j].LastMessageAt)
+	})
+	return page(threads, limit, offset), nil
+}
+
+// ModifyThreadLabels adds and removes labels on every email of a thread and
+// keeps is_read in step with the UNREAD label. It returns the number of
+// emails updated.
+func (r *EmailRepository) ModifyThreadLabels(ctx context.Context, userID, threadID string, add, remove []string) (int64, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	var n int64
+	for _, email := range r.s.emails {
+		if email.UserID == userID && threadKey(email) == threadID {
+			modifyLabels(email, add, remove)
+			n++
+		}
+	}
+	return n, nil
+}
+
+// FindThreadID returns the thread of the first email whose Message-ID header
+// is among headerIDs, or "" if none is known.
+func (r *EmailRepository) FindThreadID(ctx context.Context, userID string, headerIDs []string) (string, error) {
+	r.s.mu.RLock()
+	defer r.s.mu.RUnlock()
+
+	for _, headerID := range headerIDs {
+		for _, email := range r.s.emails {
+			if email.UserID == userID && email.HeaderID != nil && *email.HeaderID == headerID && email.ThreadID != nil {
+				return *email.ThreadID, nil
+			}
+		}
+	}
+	return "", nil
+}
+
+// threadKey groups emails into threads. Emails without a thread ID are
+// threads of their own.
+func threadKey(email *models.Email) string {
+	if email.ThreadID != nil {
+		return *email.ThreadID
+	}
+	return email.ID
+}
+
+// modifyLabels applies a label change the way the database does: labels end
+// up distinct, and is_read follows the UNREAD label when it changes.
+func modifyLabels(email *models.Email, add, remove []string) {
+	var labels []string
+	for _, label := range append(cloneStrings(email.Labels), add...) {
+		if !contains(remove, label) {
+			labels = append(labels, label)
+		}
+	}
+	email.Labels = sortedSet(labels)
+
+	switch {
+	case contains(remove, "UNREAD"):
+		email.IsRead = true
+	case contains(add, "UNREAD"):
+		email.IsRead = false
+	}
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
+func derefString(s *string) string {
+	if s == nil {
+		return ""
+	}
+	return *s
+}
@@ -0,0 +1,115 @@
+package memory
+
+import (
+	"context"
+	"time"
+
+	"ai-assistant/internal/models"
+	"ai-assistant/internal/repository"
+)
+
+type UserRepository struct {
+	s *Store
+}
+
+var _ repository.UserRepositoryInterface = (*UserRepository)(nil)
+
+func (r *UserRepository) Create(ctx context.Context, user *models.User) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	if err := r.checkUser(user, ""); err != nil {
+		return err
+	}
+	r.s.users[user.ID] = copyUser(user)
+	return nil
+}
+
+func (r *UserRepository) GetByID(ctx context.Context, id string) (*models.User, error) {
+	r.s.mu.RLock()
+	defer r.s.mu.RUnlock()
+
+	if user, ok := r.s.users[id]; ok {
+		return copyUser(user), nil
+	}
+	return nil, nil
+}
+
+func (r *UserRepository) GetByEmail(ctx context.Context, email string) (*models.User, error) {
+	r.s.mu.RLock()
+	defer r.s.mu.RUnlock()
+
+	for _, user := range r.s.users {
+		if user.Email == email {
+			return copyUser(user), nil
+		}
+	}
+	return nil, nil
+}
+
+func (r *UserRepository) Update(ctx context.Context, user *models.User) error {
+	user.UpdatedAt = time.Now()
+
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	existing, ok := r.s.users[user.ID]
+	if !ok {
+		return nil
+	}
+	if err := r.checkUser(user, user.ID); err != nil {
+		return err
+	}
+	updated := copyUser(user)
+	updated.CreatedAt = existing.CreatedAt
+	r.s.users[user.ID] = updated
+	return nil
+}
+
+// Delete removes a user with their emails and sent mail.
+func (r *UserRepository) Delete(ctx context.Context, id string) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	delete(r.s.users, id)
+	for emailID, email := range r.s.emails {
+		if email.UserID == id {
+			r.s.deleteEmail(emailID)
+		}
+	}
+	for outboundID, o := range r.s.outbound {
+		if o.UserID == id {
+			delete(r.s.outbound, outboundID)
+			for eventID, event := range r.s.events {
+				if event.OutboundEmailID == outboundID {
+					delete(r.s.events, eventID)
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// checkUser enforces the unique ID and email of users. The caller holds the
+// write lock.
+func (r *UserRepository) checkUser(user *models.User, exceptID string) error {
+	if _, ok := r.s.users[user.ID]; ok && user.ID != exceptID {
+		return repository.ErrDuplicate
+	}
+	for id, other := range r.s.users {
+		if id != exceptID && other.Email == user.Email {
+			return repository.ErrDuplicate
+		}
+	}
+	return nil
+}
+
+func copyUser(user *models.User) *models.User {
+	c := *user
+	c.Name = cloneString(user.Name)
+	c.Image = cloneString(user.Image)
+	c.EmailVerified = dbTimePtr(user.EmailVerified)
+	c.CreatedAt = dbTime(user.CreatedAt)
+	c.UpdatedAt = dbTime(user.UpdatedAt)
+	return &c
+}
@@ -0,0 +1,163 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/lib/pq"
+	"ai-assistant/internal/models"
+	"ai-assistant/pkg/database"
+)
+
+type OutboundRepository struct {
+	db *database.DB
+}
+
+func NewOutboundRepository(db *database.DB) *OutboundRepository {
+	return &OutboundRepository{db: db}
+}
+
+const outboundColumns = `id, user_id, provider, provider_message_id, message_id, thread_id, "from", "to", cc, bcc, subject, status, error, created_at, updated_at`
+
+func scanOutbound(row rowScanner) (*models.OutboundEmail, error) {
+	o := &models.OutboundEmail{}
+	err := row.Scan(
+		&o.ID, &o.UserID, &o.Provider, &o.ProviderMessageID, &o.MessageID,
+		&o.ThreadID, &o.From, pq.Array(&o.To), pq.Array(&o.Cc), pq.Array(&o.Bcc),
+		&o.Subject, &o.Status, &o.Error, &o.CreatedAt, &o.UpdatedAt)
+	if err != nil {
+		return nil, err
+	}
+	return o, nil
+}
+
+func (r *OutboundRepository) Create(ctx context.Context, o *models.OutboundEmail) error {
+	query := `
+		INSERT INTO outbound_emails (` + outboundColumns + `)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15)
+	`
+	_, err := r.db.ExecContext(ctx, query,
+		o.ID, o.UserID, o.Provider, o.ProviderMessageID, o.MessageID,
+		o.ThreadID, o.From, pq.Array(o.To), pq.Array(o.Cc), pq.Array(o.Bcc),
+		o.Subject, o.Status, o.Error, o.CreatedAt, o.UpdatedAt)
+	return err
+}
+
+// SetSendResult records the outcome of handing a message to the provider.
+func (r *OutboundRepository) SetSendResult(ctx context.Context, id string, status models.OutboundStatus, providerMessageID, threadID, sendError *string) error {
+	query := `
+		UPDATE outbound_emails SET
+			status = $2,
+			provider_message_id = coalesce($3, provider_message_id),
+			thread_id = coalesce($4, thread_id),
+			error = $5,
+			updated_at = $6
+		WHERE id = $1
+	`
+	_, err := r.db.ExecContext(ctx, query, id, status, providerMessageID, threadID, sendError, time.Now())
+	return err
+}
+
+// AdvanceStatus moves a message to status if its current status is one of
+// from, so that late events cannot move it backwards.
+func (r *OutboundRepository) AdvanceStatus(ctx context.Context, id string, status models.OutboundStatus, from []models.OutboundStatus) error {
+	statuses := make([]string, len(from))
+	for i, s := range from {
+		statuses[i] = string(s)
+	}
+
+	query := `
+		UPDATE outbound_emails SET status = $2, updated_at = $3
+		WHERE id = $1 AND status = ANY($4)
+	`
+	_, err := r.db.ExecContext(ctx, query, id, status, time.Now(), pq.Array(statuses))
+	return err
+}
+
+func (r *OutboundRepository) GetByProviderMessageID(ctx context.Context, provider, providerMessageID string) (*models.OutboundEmail, error) {
+	query := `SELECT ` + outboundColumns + ` FROM outbound_emails WHERE provider = $1 AND provider_message_id = $2`
+
+	o, err := scanOutbound(r.db.QueryRowContext(ctx, query, provider, providerMessageID))
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	return o, err
+}
+
+// GetByUserID returns a user's sent messages, newest first, optionally only
+// those in status.
+func (r *OutboundRepository) GetByUserID(ctx context.Context, userID string, status models.OutboundStatus, limit, offset int) ([]*models.OutboundEmail, error) {
+	query := `
+		SELECT ` + outboundColumns + `
+		FROM outbound_emails
+		WHERE user_id = $1 AND ($2 = '' OR status = $2)
+		ORDER BY created_at DESC, id DESC
+		LIMIT $3 OFFSET $4
+	`
+
+	rows, err := r.db.QueryContext(ctx, query, userID, status, limit, offset)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var emails []*models.OutboundEmail
+	for rows.Next() {
+		o, err := scanOutbound(rows)
+		if err != nil {
+			return nil, err
+		}
+		emails = append(emails, o)
+	}
+
+	return emails, rows.Err()
+}
+
+// AddEvent appends to a message's status history. Provider events are
+// recorded once; it returns false for a redelivered event.
+func (r *OutboundRepository) AddEvent(ctx context.Context, event *models.OutboundEvent) (bool, error) {
+	query := `
+		INSERT INTO outbound_email_events (id, outbound_email_id, status, provider_event_id, detail, occurred_at)
+		VALUES ($1, $2, $3, $4, $5, $6)
+		ON CONFLICT (provider_event_id) DO NOTHING
+	`
+	result, err := r.db.ExecContext(ctx, query,
+		event.ID, event.OutboundEmailID, event.Status, event.ProviderEventID,
+		event.Detail, event.OccurredAt)
+	if err != nil {
+		return false, err
+	}
+	n, err := result.RowsAffected()
+	return n > 0, err
+}
+
+// GetEvents returns the status history of the given messages, oldest first.
+func (r *OutboundRepository) GetEvents(ctx context.Context, outboundIDs []string) ([]*models.OutboundEvent, error) {
+	query := `
+		SELECT id, outbound_email_id, status, provider_event_id, detail, occurred_at
+		FROM outbound_email_events
+		WHERE outbound_email_id = ANY($1)
+		ORDER BY occurred_at ASC, id ASC
+	`
+
+	rows, err := r.db.QueryContext(ctx, query, pq.Array(outboundIDs))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var events []*models.OutboundEvent
+	for rows.Next() {
+		event := &models.OutboundEvent{}
+		err := rows.Scan(
+			&event.ID, &event.OutboundEmailID, &event.Status, &event.ProviderEventID,
+			&event.Detail, &event.OccurredAt)
+		if err != nil {
+			return nil, err
+		}
+		events = append(events, event)
+	}
+
+	return events, rows.Err()
+}
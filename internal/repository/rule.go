@@ -0,0 +1,128 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+
+	"ai-assistant/internal/models"
+	"ai-assistant/pkg/database"
+)
+
+type RuleRepository struct {
+	db *database.DB
+}
+
+func NewRuleRepository(db *database.DB) *RuleRepository {
+	return &RuleRepository{db: db}
+}
+
+const ruleColumns = `id, user_id, name, enabled, priority, conditions, actions, stop_processing, created_at, updated_at`
+
+func scanRule(row rowScanner) (*models.Rule, error) {
+	rule := &models.Rule{}
+	var conditions, actions []byte
+	err := row.Scan(
+		&rule.ID, &rule.UserID, &rule.Name, &rule.Enabled, &rule.Priority,
+		&conditions, &actions, &rule.StopProcessing, &rule.CreatedAt, &rule.UpdatedAt)
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(conditions, &rule.Conditions); err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(actions, &rule.Actions); err != nil {
+		return nil, err
+	}
+	return rule, nil
+}
+
+func marshalRule(rule *models.Rule) ([]byte, []byte, error) {
+	conditions, err := json.Marshal(rule.Conditions)
+	if err != nil {
+		return nil, nil, err
+	}
+	actions, err := json.Marshal(rule.Actions)
+	if err != nil {
+		return nil, nil, err
+	}
+	return conditions, actions, nil
+}
+
+func (r *RuleRepository) Create(ctx context.Context, rule *models.Rule) error {
+	conditions, actions, err := marshalRule(rule)
+	if err != nil {
+		return err
+	}
+
+	query := `
+		INSERT INTO email_rules (` + ruleColumns + `)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)
+	`
+	_, err = r.db.ExecContext(ctx, query,
+		rule.ID, rule.UserID, rule.Name, rule.Enabled, rule.Priority,
+		conditions, actions, rule.StopProcessing, rule.CreatedAt, rule.UpdatedAt)
+	return err
+}
+
+func (r *RuleRepository) GetByID(ctx context.Context, userID, id string) (*models.Rule, error) {
+	query := `SELECT ` + ruleColumns + ` FROM email_rules WHERE user_id = $1 AND id = $2`
+
+	rule, err := scanRule(r.db.QueryRowContext(ctx, query, userID, id))
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	return rule, err
+}
+
+// GetByUserID returns a user's rules in the order they run: lowest priority
+// first, then oldest first.
+func (r *RuleRepository) GetByUserID(ctx context.Context, userID string, enabledOnly bool) ([]*models.Rule, error) {
+	query := `
+		SELECT ` + ruleColumns + `
+		FROM email_rules
+		WHERE user_id = $1 AND (enabled OR NOT $2)
+		ORDER BY priority ASC, created_at ASC, id ASC
+	`
+
+	rows, err := r.db.QueryContext(ctx, query, userID, enabledOnly)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var rules []*models.Rule
+	for rows.Next() {
+		rule, err := scanRule(rows)
+		if err != nil {
+			return nil, err
+		}
+		rules = append(rules, rule)
+	}
+
+	return rules, rows.Err()
+}
+
+func (r *RuleRepository) Update(ctx context.Context, rule *models.Rule) error {
+	conditions, actions, err := marshalRule(rule)
+	if err != nil {
+		return err
+	}
+
+	query := `
+		UPDATE email_rules SET
+			name = $3, enabled = $4, priority = $5, conditions = $6, actions = $7,
+			stop_processing = $8, updated_at = $9
+		WHERE user_id = $1 AND id = $2
+	`
+	_, err = r.db.ExecContext(ctx, query,
+		rule.UserID, rule.ID, rule.Name, rule.Enabled, rule.Priority,
+		conditions, actions, rule.StopProcessing, rule.UpdatedAt)
+	return err
+}
+
+func (r *RuleRepository) Delete(ctx context.Context, userID, id string) error {
+	query := `DELETE FROM email_rules WHERE user_id = $1 AND id = $2`
+	_, err := r.db.ExecContext(ctx, query, userID, id)
+	return err
+}
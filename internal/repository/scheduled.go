@@ -0,0 +1,211 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"time"
+
+	"github.com/lib/pq"
+	"ai-assistant/internal/models"
+	"ai-assistant/pkg/database"
+)
+
+type ScheduledEmailRepository struct {
+	db *database.DB
+}
+
+func NewScheduledEmailRepository(db *database.DB) *ScheduledEmailRepository {
+	return &ScheduledEmailRepository{db: db}
+}
+
+const scheduledColumns = `id, user_id, "from", "to", cc, bcc, subject, body, html_body, send_at, time_zone, attachments, status, outbound_email_id, error, claimed_at, created_at, updated_at`
+
+// storedAttachment is the jsonb form of an outgoing attachment, which
+// unlike the API form includes the storage key.
+type storedAttachment struct {
+	Filename   string `json:"filename"`
+	MimeType   string `json:"mimeType"`
+	Size       int64  `json:"size"`
+	ContentID  string `json:"contentId,omitempty"`
+	StorageKey string `json:"storageKey"`
+}
+
+func marshalAttachments(attachments []models.OutgoingAttachment) ([]byte, error) {
+	stored := make([]storedAttachment, len(attachments))
+	for i, a := range attachments {
+		stored[i] = storedAttachment(a)
+	}
+	return json.Marshal(stored)
+}
+
+func scanScheduled(row rowScanner) (*models.ScheduledEmail, error) {
+	s := &models.ScheduledEmail{}
+	var attachments []byte
+	err := row.Scan(
+		&s.ID, &s.UserID, &s.From, pq.Array(&s.To), pq.Array(&s.Cc), pq.Array(&s.Bcc),
+		&s.Subject, &s.Body, &s.HTML, &s.SendAt, &s.TimeZone, &attachments, &s.Status,
+		&s.OutboundEmailID, &s.Error, &s.ClaimedAt, &s.CreatedAt, &s.UpdatedAt)
+	if err != nil {
+		return nil, err
+	}
+
+	var stored []storedAttachment
+	if err := json.Unmarshal(attachments, &stored); err != nil {
+		return nil, err
+	}
+	for _, a := range stored {
+		s.Attachments = append(s.Attachments, models.OutgoingAttachment(a))
+	}
+	return s, nil
+}
+
+func (r *ScheduledEmailRepository) Create(ctx context.Context, s *models.ScheduledEmail) error {
+	attachments, err := marshalAttachments(s.Attachments)
+	if err != nil {
+		return err
+	}
+
+	query := `
+		INSERT INTO scheduled_emails (` + scheduledColumns + `)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16, $17, $18)
+	`
+	_, err = r.db.ExecContext(ctx, query,
+		s.ID, s.UserID, s.From, pq.Array(s.To), pq.Array(s.Cc), pq.Array(s.Bcc),
+		s.Subject, s.Body, s.HTML, s.SendAt, s.TimeZone, attachments, s.Status,
+		s.OutboundEmailID, s.Error, s.ClaimedAt, s.CreatedAt, s.UpdatedAt)
+	return err
+}
+
+func (r *ScheduledEmailRepository) GetByID(ctx context.Context, userID, id string) (*models.ScheduledEmail, error) {
+	query := `SELECT ` + scheduledColumns + ` FROM scheduled_emails WHERE user_id = $1 AND id = $2`
+
+	s, err := scanScheduled(r.db.QueryRowContext(ctx, query, userID, id))
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	return s, err
+}
+
+// GetByUserID returns a user's queued messages, soonest first, optionally
+// only those in status.
+func (r *ScheduledEmailRepository) GetByUserID(ctx context.Context, userID string, status models.ScheduledStatus, limit, offset int) ([]*models.ScheduledEmail, error) {
+	query := `
+		SELECT ` + scheduledColumns + `
+		FROM scheduled_emails
+		WHERE user_id = $1 AND ($2 = '' OR status = $2)
+		ORDER BY send_at ASC, id ASC
+		LIMIT $3 OFFSET $4
+	`
+
+	rows, err := r.db.QueryContext(ctx, query, userID, status, limit, offset)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var emails []*models.ScheduledEmail
+	for rows.Next() {
+		s, err := scanScheduled(rows)
+		if err != nil {
+			return nil, err
+		}
+		emails = append(emails, s)
+	}
+
+	return emails, rows.Err()
+}
+
+// UpdatePending saves edits to a message that has not been claimed for
+// dispatch yet. It returns false if the message is no longer scheduled.
+func (r *ScheduledEmailRepository) UpdatePending(ctx context.Context, s *models.ScheduledEmail) (bool, error) {
+	attachments, err := marshalAttachments(s.Attachments)
+	if err != nil {
+		return false, err
+	}
+
+	query := `
+		UPDATE scheduled_emails SET
+			"to" = $3, cc = $4, bcc = $5, subject = $6, body = $7, html_body = $8,
+			send_at = $9, time_zone = $10, attachments = $11, updated_at = $12
+		WHERE user_id = $1 AND id = $2 AND status = 'scheduled'
+	`
+	result, err := r.db.ExecContext(ctx, query,
+		s.UserID, s.ID, pq.Array(s.To), pq.Array(s.Cc), pq.Array(s.Bcc),
+		s.Subject, s.Body, s.HTML, s.SendAt, s.TimeZone, attachments, s.UpdatedAt)
+	if err != nil {
+		return false, err
+	}
+	n, err := result.RowsAffected()
+	return n > 0, err
+}
+
+// Cancel withdraws a message that has not been claimed for dispatch yet.
+// It returns false if the message is no longer scheduled.
+func (r *ScheduledEmailRepository) Cancel(ctx context.Context, userID, id string) (bool, error) {
+	query := `
+		UPDATE scheduled_emails SET status = 'cancelled', updated_at = $3
+		WHERE user_id = $1 AND id = $2 AND status = 'scheduled'
+	`
+	result, err := r.db.ExecContext(ctx, query, userID, id, time.Now().UTC())
+	if err != nil {
+		return false, err
+	}
+	n, err := result.RowsAffected()
+	return n > 0, err
+}
+
+// ClaimDue moves the earliest due message to sending and returns it, or nil
+// if none is due. The row lock with SKIP LOCKED hands each message to
+// exactly one caller, however many replicas poll at once, and edits and
+// cancellations stop applying the moment it is claimed.
+func (r *ScheduledEmailRepository) ClaimDue(ctx context.Context, now time.Time) (*models.ScheduledEmail, error) {
+	query := `
+		UPDATE scheduled_emails SET status = 'sending', claimed_at = $1, updated_at = $1
+		WHERE id = (
+			SELECT id FROM scheduled_emails
+			WHERE status = 'scheduled' AND send_at <= $1
+			ORDER BY send_at ASC, id ASC
+			LIMIT 1
+			FOR UPDATE SKIP LOCKED
+		)
+		RETURNING ` + scheduledColumns
+
+	s, err := scanScheduled(r.db.QueryRowContext(ctx, query, now.UTC()))
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	return s, err
+}
+
+// CountScheduled returns how many messages wait to be sent, due or not.
+func (r *ScheduledEmailRepository) CountScheduled(ctx context.Context) (int64, error) {
+	var n int64
+	err := r.db.QueryRowContext(ctx, `SELECT COUNT(*) FROM scheduled_emails WHERE status = 'scheduled'`).Scan(&n)
+	return n, err
+}
+
+// Complete records the outcome of dispatching a claimed message.
+func (r *ScheduledEmailRepository) Complete(ctx context.Context, id string, status models.ScheduledStatus, outboundEmailID, sendError *string) error {
+	query := `
+		UPDATE scheduled_emails SET status = $2, outbound_email_id = $3, error = $4, updated_at = $5
+		WHERE id = $1 AND status = 'sending'
+	`
+	_, err := r.db.ExecContext(ctx, query, id, status, outboundEmailID, sendError, time.Now().UTC())
+	return err
+}
+
+// FailStale marks messages claimed before cutoff and never completed as
+// failed. Their dispatcher died mid-send, so whether they went out is
+// unknown; sending again could deliver them twice.
+func (r *ScheduledEmailRepository) FailStale(ctx context.Context, cutoff time.Time, reason string) (int64, error) {
+	query := `
+		UPDATE scheduled_emails SET status = 'failed', error = $2, updated_at = $3
+		WHERE status = 'sending' AND claimed_at < $1
+	`
+	result, err := r.db.ExecContext(ctx, query, cutoff.UTC(), reason, time.Now().UTC())
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
@@ -0,0 +1,171 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/lib/pq"
+	"ai-assistant/internal/models"
+	"ai-assistant/internal/services/email/search"
+)
+
+// searchConfig is the text search configuration used for both the generated
+// search_vector column and the queries run against it; they must match for
+// the GIN index to be used.
+const searchConfig = "english"
+
+// Matches are highlighted with search.MarkStart and search.MarkStop, and the
+// snippet escaped before they become <mark> tags, so that markup in the body
+// is never returned as HTML.
+const headlineOptions = "StartSel=" + search.MarkStart + ", StopSel=" + search.MarkStop + `, MaxFragments=2, MaxWords=30, MinWords=10, FragmentDelimiter=" … "`
+
+// snippetBody is the body with the highlight delimiters removed.
+const snippetBody = "translate(coalesce(body, ''), chr(2) || chr(3), '')"
+
+// argList collects positional query arguments.
+type argList []interface{}
+
+func (a *argList) add(v interface{}) string {
+	*a = append(*a, v)
+	return fmt.Sprintf("$%d", len(*a))
+}
+
+// Search runs a parsed query over a user's emails. Free-text terms are
+// matched against search_vector and rank the results; the other operators
+// only filter. Without free text, results are ordered newest first.
+func (r *EmailRepository) Search(ctx context.Context, userID string, q *search.Query, limit, offset int) ([]*models.EmailSearchResult, error) {
+	args := argList{}
+	where := []string{"user_id = " + args.add(userID)}
+
+	tsquery := textQuery(q, &args)
+	if tsquery != "" {
+		where = append(where, "search_vector @@ search.q")
+	}
+	for _, term := range q.Terms {
+		if term.Field == search.FieldText {
+			continue
+		}
+		cond := filterCondition(term, &args)
+		if term.Negate {
+			cond = "NOT (" + cond + ")"
+		}
+		where = append(where, cond)
+	}
+
+	var query string
+	if tsquery != "" {
+		query = `
+			SELECT ` + emailColumns + `, rank,
+				ts_headline('` + searchConfig + `', ` + snippetBody + `, q, ` + args.add(headlineOptions) + `)
+			FROM (
+				SELECT ` + emailColumns + `, ts_rank_cd(search_vector, search.q) AS rank, search.q
+				FROM emails CROSS JOIN (SELECT ` + tsquery + `) AS search(q)
+				WHERE ` + strings.Join(where, " AND ") + `
+				ORDER BY rank DESC, created_at DESC
+				LIMIT ` + args.add(limit) + ` OFFSET ` + args.add(offset) + `
+			) AS hits
+			ORDER BY rank DESC, created_at DESC
+		`
+	} else {
+		query = `
+			SELECT ` + emailColumns + `, 0::real, left(` + snippetBody + `, 200)
+			FROM emails
+			WHERE ` + strings.Join(where, " AND ") + `
+			ORDER BY created_at DESC
+			LIMIT ` + args.add(limit) + ` OFFSET ` + args.add(offset) + `
+		`
+	}
+
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var results []*models.EmailSearchResult
+	for rows.Next() {
+		result := &models.EmailSearchResult{Email: &models.Email{}}
+		email := result.Email
+		err := rows.Scan(
+			&email.ID, &email.MessageID, &email.ThreadID, &email.Subject,
+			&email.From, pq.Array(&email.To), pq.Array(&email.Cc), &email.ReplyTo,
+			&email.HeaderID, &email.InReplyTo, pq.Array(&email.References),
+			&email.Body, &email.HTMLBody, &email.IsRead, pq.Array(&email.Labels),
+			&email.CreatedAt, &email.UserID, &result.Rank, &result.Snippet)
+		if err != nil {
+			return nil, err
+		}
+		result.Snippet = search.HighlightHTML(result.Snippet)
+		results = append(results, result)
+	}
+
+	return results, rows.Err()
+}
+
+// textQuery combines the free-text terms into one tsquery expression, or
+// returns "" when there are none. Loose words share a single plainto_tsquery
+// so that stop words are dropped rather than producing empty queries.
+func textQuery(q *search.Query, args *argList) string {
+	var words, negated []string
+	var parts []string
+	for _, term := range q.Terms {
+		if term.Field != search.FieldText {
+			continue
+		}
+		fn := "plainto_tsquery"
+		if term.Phrase {
+			fn = "phraseto_tsquery"
+		}
+		switch {
+		case term.Negate:
+			negated = append(negated, "!!"+fn+"('"+searchConfig+"', "+args.add(term.Value)+")")
+		case term.Phrase:
+			parts = append(parts, fn+"('"+searchConfig+"', "+args.add(term.Value)+")")
+		default:
+			words = append(words, term.Value)
+		}
+	}
+	if len(words) > 0 {
+		parts = append([]string{"plainto_tsquery('" + searchConfig + "', " + args.add(strings.Join(words, " ")) + ")"}, parts...)
+	}
+	parts = append(parts, negated...)
+	return strings.Join(parts, " && ")
+}
+
+func filterCondition(term search.Term, args *argList) string {
+	switch term.Field {
+	case search.FieldFrom:
+		return `"from" ILIKE ` + args.add(likePattern(term.Value))
+	case search.FieldTo:
+		return `EXISTS (SELECT 1 FROM unnest("to") AS addr WHERE addr ILIKE ` + args.add(likePattern(term.Value)) + `)`
+	case search.FieldCc:
+		return `EXISTS (SELECT 1 FROM unnest(cc) AS addr WHERE addr ILIKE ` + args.add(likePattern(term.Value)) + `)`
+	case search.FieldSubject:
+		return `subject ILIKE ` + args.add(likePattern(term.Value))
+	case search.FieldLabel:
+		return `EXISTS (SELECT 1 FROM unnest(labels) AS label WHERE lower(label) = lower(` + args.add(term.Value) + `))`
+	case search.FieldHas:
+		return `EXISTS (SELECT 1 FROM email_attachments a WHERE a.email_id = emails.id AND NOT a.inline)`
+	case search.FieldIs:
+		switch term.Value {
+		case search.IsUnread:
+			return `NOT is_read`
+		case search.IsRead:
+			return `is_read`
+		default:
+			return `'STARRED' = ANY(labels)`
+		}
+	case search.FieldBefore:
+		return `created_at < ` + args.add(term.Date)
+	case search.FieldAfter:
+		return `created_at >= ` + args.add(term.Date)
+	}
+	return "TRUE"
+}
+
+// likePattern matches value anywhere, with LIKE wildcards in it escaped.
+func likePattern(value string) string {
+	escaped := strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`).Replace(value)
+	return "%" + escaped + "%"
+}
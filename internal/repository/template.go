@@ -0,0 +1,95 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+
+	"ai-assistant/internal/models"
+	"ai-assistant/pkg/database"
+)
+
+type TemplateRepository struct {
+	db *database.DB
+}
+
+func NewTemplateRepository(db *database.DB) *TemplateRepository {
+	return &TemplateRepository{db: db}
+}
+
+const templateColumns = `id, user_id, name, subject, html, created_at, updated_at`
+
+func scanTemplate(row rowScanner) (*models.EmailTemplate, error) {
+	t := &models.EmailTemplate{}
+	err := row.Scan(&t.ID, &t.UserID, &t.Name, &t.Subject, &t.HTML, &t.CreatedAt, &t.UpdatedAt)
+	if err != nil {
+		return nil, err
+	}
+	return t, nil
+}
+
+func (r *TemplateRepository) Create(ctx context.Context, t *models.EmailTemplate) error {
+	query := `
+		INSERT INTO email_templates (` + templateColumns + `)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)
+	`
+	_, err := r.db.ExecContext(ctx, query,
+		t.ID, t.UserID, t.Name, t.Subject, t.HTML, t.CreatedAt, t.UpdatedAt)
+	return err
+}
+
+func (r *TemplateRepository) GetByID(ctx context.Context, userID, id string) (*models.EmailTemplate, error) {
+	query := `SELECT ` + templateColumns + ` FROM email_templates WHERE user_id = $1 AND id = $2`
+
+	t, err := scanTemplate(r.db.QueryRowContext(ctx, query, userID, id))
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	return t, err
+}
+
+// GetByName looks a template up case-insensitively.
+func (r *TemplateRepository) GetByName(ctx context.Context, userID, name string) (*models.EmailTemplate, error) {
+	query := `SELECT ` + templateColumns + ` FROM email_templates WHERE user_id = $1 AND lower(name) = lower($2)`
+
+	t, err := scanTemplate(r.db.QueryRowContext(ctx, query, userID, name))
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	return t, err
+}
+
+func (r *TemplateRepository) GetByUserID(ctx context.Context, userID string) ([]*models.EmailTemplate, error) {
+	query := `SELECT ` + templateColumns + ` FROM email_templates WHERE user_id = $1 ORDER BY lower(name) ASC`
+
+	rows, err := r.db.QueryContext(ctx, query, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var templates []*models.EmailTemplate
+	for rows.Next() {
+		t, err := scanTemplate(rows)
+		if err != nil {
+			return nil, err
+		}
+		templates = append(templates, t)
+	}
+
+	return templates, rows.Err()
+}
+
+func (r *TemplateRepository) Update(ctx context.Context, t *models.EmailTemplate) error {
+	query := `
+		UPDATE email_templates SET name = $3, subject = $4, html = $5, updated_at = $6
+		WHERE user_id = $1 AND id = $2
+	`
+	_, err := r.db.ExecContext(ctx, query, t.UserID, t.ID, t.Name, t.Subject, t.HTML, t.UpdatedAt)
+	return err
+}
+
+func (r *TemplateRepository) Delete(ctx context.Context, userID, id string) error {
+	query := `DELETE FROM email_templates WHERE user_id = $1 AND id = $2`
+	_, err := r.db.ExecContext(ctx, query, userID, id)
+	return err
+}
@@ -0,0 +1,107 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/lib/pq"
+	"ai-assistant/internal/models"
+)
+
+// threadKey groups emails into threads. Emails without a thread ID are
+// threads of their own.
+const threadKey = `coalesce(thread_id, id)`
+
+// threadMatch selects the emails of thread $2, written so that the
+// thread_id index can be used.
+const threadMatch = `(thread_id = $2 OR (thread_id IS NULL AND id = $2))`
+
+// ListThreads returns a user's threads, most recently active first. If label
+// is set, only threads with at least one message carrying it are returned.
+// Snippet holds the full body of the latest message; callers shorten it.
+func (r *EmailRepository) ListThreads(ctx context.Context, userID, label string, limit, offset int) ([]*models.Thread, error) {
+	query := `
+		SELECT g.thread_key, g.subject, g.participants, g.message_count, g.unread_count,
+			coalesce(latest.body, ''), coalesce(lab.labels, '{}'), g.last_message_at
+		FROM (
+			SELECT ` + threadKey + ` AS thread_key,
+				coalesce((array_agg(subject ORDER BY created_at ASC))[1], '') AS subject,
+				array_agg(DISTINCT "from") AS participants,
+				count(*) AS message_count,
+				count(*) FILTER (WHERE NOT is_read) AS unread_count,
+				max(created_at) AS last_message_at
+			FROM emails
+			WHERE user_id = $1
+			GROUP BY thread_key
+			HAVING $2 = '' OR bool_or($2 = ANY(labels))
+			ORDER BY last_message_at DESC
+			LIMIT $3 OFFSET $4
+		) AS g
+		LEFT JOIN LATERAL (
+			SELECT body FROM emails e
+			WHERE e.user_id = $1 AND ` + threadKey + ` = g.thread_key
+			ORDER BY e.created_at DESC LIMIT 1
+		) AS latest ON TRUE
+		LEFT JOIN LATERAL (
+			SELECT array_agg(DISTINCT l) AS labels
+			FROM emails e, unnest(e.labels) AS l
+			WHERE e.user_id = $1 AND ` + threadKey + ` = g.thread_key
+		) AS lab ON TRUE
+		ORDER BY g.last_message_at DESC
+	`
+
+	rows, err := r.db.QueryContext(ctx, query, userID, label, limit, offset)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var threads []*models.Thread
+	for rows.Next() {
+		thread := &models.Thread{}
+		err := rows.Scan(
+			&thread.ID, &thread.Subject, pq.Array(&thread.Participants),
+			&thread.MessageCount, &thread.UnreadCount, &thread.Snippet,
+			pq.Array(&thread.Labels), &thread.LastMessageAt)
+		if err != nil {
+			return nil, err
+		}
+		threads = append(threads, thread)
+	}
+
+	return threads, rows.Err()
+}
+
+// ModifyThreadLabels adds and removes labels on every email of a thread and
+// keeps is_read in step with the UNREAD label. It returns the number of
+// emails updated.
+func (r *EmailRepository) ModifyThreadLabels(ctx context.Context, userID, threadID string, add, remove []string) (int64, error) {
+	query := modifyLabelsQuery + ` WHERE user_id = $1 AND ` + threadMatch
+
+	result, err := r.db.ExecContext(ctx, query, userID, threadID, labelArray(add), labelArray(remove))
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+// FindThreadID returns the thread of the first email whose Message-ID header
+// is among headerIDs, or "" if none is known.
+func (r *EmailRepository) FindThreadID(ctx context.Context, userID string, headerIDs []string) (string, error) {
+	if len(headerIDs) == 0 {
+		return "", nil
+	}
+
+	var threadID string
+	query := `
+		SELECT thread_id FROM emails
+		WHERE user_id = $1 AND header_message_id = ANY($2) AND thread_id IS NOT NULL
+		ORDER BY array_position($2, header_message_id)
+		LIMIT 1
+	`
+	err := r.db.QueryRowContext(ctx, query, userID, pq.Array(headerIDs)).Scan(&threadID)
+	if err == sql.ErrNoRows {
+		return "", nil
+	}
+	return threadID, err
+}
@@ -25,7 +25,7 @@ func (r *UserRepository) Create(ctx context.Context, user *models.User) error {
 	_, err := r.db.ExecContext(ctx, query,
 		user.ID, user.Email, user.Name, user.Image,
 		user.EmailVerified, user.CreatedAt, user.UpdatedAt)
-	return err
+	return constraintError(err)
 }
 
 func (r *UserRepository) GetByID(ctx context.Context, id string) (*models.User, error) {
@@ -70,7 +70,7 @@ func (r *UserRepository) Update(ctx context.Context, user *models.User) error {
 	_, err := r.db.ExecContext(ctx, query,
 		user.ID, user.Email, user.Name, user.Image,
 		user.EmailVerified, user.UpdatedAt)
-	return err
+	return constraintError(err)
 }
 
 func (r *UserRepository) Delete(ctx context.Context, id string) error {
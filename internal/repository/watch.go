@@ -0,0 +1,115 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"ai-assistant/internal/models"
+	"ai-assistant/pkg/database"
+)
+
+type GmailWatchRepository struct {
+	db *database.DB
+}
+
+func NewGmailWatchRepository(db *database.DB) *GmailWatchRepository {
+	return &GmailWatchRepository{db: db}
+}
+
+const watchColumns = `user_id, email_address, topic, history_id, expiration, created_at, updated_at`
+
+func scanWatch(row rowScanner) (*models.GmailWatch, error) {
+	watch := &models.GmailWatch{}
+	err := row.Scan(
+		&watch.UserID, &watch.EmailAddress, &watch.Topic, &watch.HistoryID,
+		&watch.Expiration, &watch.CreatedAt, &watch.UpdatedAt)
+	if err != nil {
+		return nil, err
+	}
+	return watch, nil
+}
+
+// Upsert stores a new or renewed watch. A renewal keeps the stored history
+// ID, which marks how far the mailbox has been synced.
+func (r *GmailWatchRepository) Upsert(ctx context.Context, watch *models.GmailWatch) error {
+	query := `
+		INSERT INTO gmail_watches (` + watchColumns + `)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)
+		ON CONFLICT (user_id) DO UPDATE SET
+			email_address = EXCLUDED.email_address,
+			topic = EXCLUDED.topic,
+			expiration = EXCLUDED.expiration,
+			updated_at = EXCLUDED.updated_at
+	`
+	_, err := r.db.ExecContext(ctx, query,
+		watch.UserID, watch.EmailAddress, watch.Topic, watch.HistoryID,
+		watch.Expiration, watch.CreatedAt, watch.UpdatedAt)
+	return err
+}
+
+func (r *GmailWatchRepository) GetByUserID(ctx context.Context, userID string) (*models.GmailWatch, error) {
+	query := `SELECT ` + watchColumns + ` FROM gmail_watches WHERE user_id = $1`
+
+	watch, err := scanWatch(r.db.QueryRowContext(ctx, query, userID))
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	return watch, err
+}
+
+func (r *GmailWatchRepository) GetByEmailAddress(ctx context.Context, emailAddress string) (*models.GmailWatch, error) {
+	query := `SELECT ` + watchColumns + ` FROM gmail_watches WHERE lower(email_address) = lower($1)`
+
+	watch, err := scanWatch(r.db.QueryRowContext(ctx, query, emailAddress))
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	return watch, err
+}
+
+// GetExpiringBefore returns watches that expire before t, soonest first.
+func (r *GmailWatchRepository) GetExpiringBefore(ctx context.Context, t time.Time, limit int) ([]*models.GmailWatch, error) {
+	query := `
+		SELECT ` + watchColumns + `
+		FROM gmail_watches
+		WHERE expiration < $1
+		ORDER BY expiration ASC
+		LIMIT $2
+	`
+
+	rows, err := r.db.QueryContext(ctx, query, t, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var watches []*models.GmailWatch
+	for rows.Next() {
+		watch, err := scanWatch(rows)
+		if err != nil {
+			return nil, err
+		}
+		watches = append(watches, watch)
+	}
+
+	return watches, rows.Err()
+}
+
+// AdvanceHistoryID records that the mailbox has been synced up to
+// historyID. It never moves the ID backwards, so a sync that finishes late
+// cannot make the next one re-read history that was already imported.
+func (r *GmailWatchRepository) AdvanceHistoryID(ctx context.Context, userID string, historyID uint64) error {
+	query := `
+		UPDATE gmail_watches SET history_id = GREATEST(history_id, $2), updated_at = $3
+		WHERE user_id = $1
+	`
+	_, err := r.db.ExecContext(ctx, query, userID, historyID, time.Now())
+	return err
+}
+
+func (r *GmailWatchRepository) Delete(ctx context.Context, userID string) error {
+	query := `DELETE FROM gmail_watches WHERE user_id = $1`
+	_, err := r.db.ExecContext(ctx, query, userID)
+	return err
+}
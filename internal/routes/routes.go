@@ -6,10 +6,12 @@ import (
 
 	"github.com/go-chi/chi/v5"
 	"github.com/go-chi/chi/v5/middleware"
+	"ai-assistant/internal/app/config"
 	"ai-assistant/internal/handlers"
 	internalMiddleware "ai-assistant/internal/middleware"
 	"ai-assistant/internal/services/auth"
-	"ai-assistant/pkg/cache"
+	"ai-assistant/pkg/health"
+	"ai-assistant/pkg/metrics"
 )
 
 // SetupRoutes configures and returns the router with all application routes
@@ -17,16 +19,33 @@ func SetupRoutes(
 	authHandler *handlers.AuthHandler,
 	aiHandler *handlers.AIHandler,
 	emailHandler *handlers.EmailHandler,
+	scheduleHandler *handlers.ScheduleHandler,
+	sanitizeHandler *handlers.SanitizeHandler,
+	draftHandler *handlers.DraftHandler,
+	threadHandler *handlers.ThreadHandler,
+	labelHandler *handlers.LabelHandler,
+	ruleHandler *handlers.RuleHandler,
+	templateHandler *handlers.TemplateHandler,
+	imageProxyHandler *handlers.ImageProxyHandler,
+	inlineImageHandler *handlers.InlineImageHandler,
+	pushHandler *handlers.PushHandler,
+	webhookHandler *handlers.WebhookHandler,
 	authService *auth.AuthService,
-	redisService *cache.RedisService,
+	healthChecker *health.Checker,
+	settings *config.Reloader,
+	aiRateLimiter *internalMiddleware.RateLimiter,
 ) chi.Router {
 	router := chi.NewRouter()
 
 	// Global middleware
-	router.Use(middleware.Logger)
-	router.Use(middleware.Recoverer)
-	router.Use(internalMiddleware.CORS())
+	router.Use(middleware.RequestID)
+	router.Use(internalMiddleware.Tracing())
+	router.Use(internalMiddleware.RequestLogger())
+	router.Use(internalMiddleware.Metrics())
 	router.Use(internalMiddleware.ErrorHandler())
+	router.Use(internalMiddleware.CORS(func() []string {
+		return settings.Current().CORS.AllowedOrigins
+	}))
 
 	// Root endpoint
 	router.Get("/", func(w http.ResponseWriter, r *http.Request) {
@@ -39,32 +58,12 @@ func SetupRoutes(
 		json.NewEncoder(w).Encode(response)
 	})
 
-	// Health check endpoint
-	router.Get("/health", func(w http.ResponseWriter, r *http.Request) {
-		healthStatus := map[string]interface{}{
-			"status":    "healthy",
-			"gemini":    "available",
-			"claude":    "not configured",
-			"redis":     "disconnected",
-			"database":  "connected",
-		}
-
-		// Check Redis status
-		if redisService != nil {
-			if err := redisService.Connect(); err == nil {
-				healthStatus["redis"] = "connected"
-			}
-		}
+	router.Handle("/metrics", metrics.Handler())
 
-		statusCode := http.StatusOK
-		if healthStatus["status"] == "unhealthy" {
-			statusCode = http.StatusServiceUnavailable
-		}
-
-		w.Header().Set("Content-Type", "application/json")
-		w.WriteHeader(statusCode)
-		json.NewEncoder(w).Encode(healthStatus)
-	})
+	// Health checks. /health predates /readyz and serves the same report.
+	router.Get("/livez", healthChecker.LiveHandler())
+	router.Get("/readyz", healthChecker.ReadyHandler())
+	router.Get("/health", healthChecker.ReadyHandler())
 
 	// API routes
 	router.Route("/api", func(r chi.Router) {
@@ -74,6 +73,7 @@ func SetupRoutes(
 		// AI routes (protected)
 		r.Route("/ai", func(r chi.Router) {
 			r.Use(authService.RequireAuth())
+			r.Use(aiRateLimiter.Middleware())
 			aiHandler.RegisterRoutes(r)
 		})
 
@@ -81,11 +81,65 @@ func SetupRoutes(
 		r.Route("/emails", func(r chi.Router) {
 			r.Use(authService.RequireAuth())
 			emailHandler.RegisterRoutes(r)
+			scheduleHandler.RegisterRoutes(r)
+			sanitizeHandler.RegisterRoutes(r)
+		})
+
+		// Draft routes (protected)
+		r.Route("/drafts", func(r chi.Router) {
+			r.Use(authService.RequireAuth())
+			draftHandler.RegisterRoutes(r)
+		})
+
+		// Thread routes (protected)
+		r.Route("/threads", func(r chi.Router) {
+			r.Use(authService.RequireAuth())
+			threadHandler.RegisterRoutes(r)
+		})
+
+		// Label routes (protected)
+		r.Route("/labels", func(r chi.Router) {
+			r.Use(authService.RequireAuth())
+			labelHandler.RegisterRoutes(r)
+		})
+
+		// Rule routes (protected)
+		r.Route("/rules", func(r chi.Router) {
+			r.Use(authService.RequireAuth())
+			ruleHandler.RegisterRoutes(r)
+		})
+
+		// Email template routes (protected)
+		r.Route("/templates", func(r chi.Router) {
+			r.Use(authService.RequireAuth())
+			templateHandler.RegisterRoutes(r)
+		})
+
+		// Remote and inline images of sanitized emails; their URLs carry a
+		// signature instead of the API token
+		r.Route("/images", func(r chi.Router) {
+			imageProxyHandler.RegisterRoutes(r)
+			inlineImageHandler.RegisterRoutes(r)
+		})
+
+		// Provider webhook routes (signed by the provider)
+		r.Route("/webhooks", func(r chi.Router) {
+			webhookHandler.RegisterRoutes(r)
+		})
+
+		// Gmail push routes; the Pub/Sub webhook authenticates with its own token
+		r.Route("/gmail", func(r chi.Router) {
+			r.Post("/push", pushHandler.Push)
+			r.Group(func(r chi.Router) {
+				r.Use(authService.RequireAuth())
+				pushHandler.RegisterRoutes(r)
+			})
 		})
 	})
 
 	// 404 handler
 	router.NotFound(internalMiddleware.NotFoundHandler())
+	router.MethodNotAllowed(internalMiddleware.MethodNotAllowedHandler())
 
 	return router
 }
\ No newline at end of file
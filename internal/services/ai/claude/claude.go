@@ -2,15 +2,22 @@ package claude
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"fmt"
 	"io"
 	"net/http"
+	"time"
 
+	"go.opentelemetry.io/otel/attribute"
 	"ai-assistant/internal/app/config"
 	"ai-assistant/pkg/logger"
+	"ai-assistant/pkg/metrics"
+	"ai-assistant/pkg/tracing"
 )
 
+const model = "claude-3-haiku-20240307"
+
 type ClaudeService struct {
 	apiKey     string
 	baseURL    string
@@ -31,9 +38,15 @@ type Message struct {
 
 type ClaudeResponse struct {
 	Content []Content `json:"content"`
+	Usage   *Usage    `json:"usage,omitempty"`
 	Error   *APIError `json:"error,omitempty"`
 }
 
+type Usage struct {
+	InputTokens  int `json:"input_tokens"`
+	OutputTokens int `json:"output_tokens"`
+}
+
 type Content struct {
 	Type string `json:"type"`
 	Text string `json:"text"`
@@ -52,16 +65,22 @@ func NewClaudeService(cfg *config.Config) *ClaudeService {
 	return &ClaudeService{
 		apiKey:     cfg.AI.ClaudeAPIKey,
 		baseURL:    "https://api.anthropic.com/v1",
-		httpClient: &http.Client{},
+		httpClient: &http.Client{Transport: tracing.Transport(nil)},
 		logger:     logger.New(),
 	}
 }
 
-func (c *ClaudeService) GenerateResponse(prompt string) (string, error) {
-	c.logger.Infof("Generating Claude response for prompt: %s", prompt[:min(50, len(prompt))]+"...")
+func (c *ClaudeService) GenerateResponse(ctx context.Context, prompt string) (response string, err error) {
+	c.logger.InfoContext(ctx, "Generating Claude response", "prompt_length", len(prompt))
+	ctx, span := tracing.Start(ctx, "claude.generate", attribute.String("gen_ai.request.model", model))
+	start := time.Now()
+	defer func() {
+		metrics.ObserveAIRequest("claude", model, "generate", start, err)
+		tracing.End(span, err)
+	}()
 
 	reqBody := ClaudeRequest{
-		Model:     "claude-3-haiku-20240307",
+		Model:     model,
 		MaxTokens: 2048,
 		Messages: []Message{
 			{
@@ -76,7 +95,7 @@ func (c *ClaudeService) GenerateResponse(prompt string) (string, error) {
 		return "", fmt.Errorf("failed to marshal request: %w", err)
 	}
 
-	req, err := http.NewRequest("POST", c.baseURL+"/messages", bytes.NewBuffer(jsonData))
+	req, err := http.NewRequestWithContext(ctx, "POST", c.baseURL+"/messages", bytes.NewBuffer(jsonData))
 	if err != nil {
 		return "", fmt.Errorf("failed to create request: %w", err)
 	}
@@ -97,7 +116,7 @@ func (c *ClaudeService) GenerateResponse(prompt string) (string, error) {
 	}
 
 	if resp.StatusCode != http.StatusOK {
-		c.logger.Errorf("Claude API error: %s", string(body))
+		c.logger.ErrorContext(ctx, "Claude API error", "status", resp.StatusCode, "error", string(body))
 		return "", fmt.Errorf("claude API error: %s", resp.Status)
 	}
 
@@ -105,6 +124,13 @@ func (c *ClaudeService) GenerateResponse(prompt string) (string, error) {
 	if err := json.Unmarshal(body, &claudeResp); err != nil {
 		return "", fmt.Errorf("failed to unmarshal response: %w", err)
 	}
+	if claudeResp.Usage != nil {
+		metrics.AddAITokens("claude", model, claudeResp.Usage.InputTokens, claudeResp.Usage.OutputTokens)
+		span.SetAttributes(
+			attribute.Int("gen_ai.usage.input_tokens", claudeResp.Usage.InputTokens),
+			attribute.Int("gen_ai.usage.output_tokens", claudeResp.Usage.OutputTokens),
+		)
+	}
 
 	if claudeResp.Error != nil {
 		return "", fmt.Errorf("claude API error: %s", claudeResp.Error.Message)
@@ -117,6 +143,29 @@ func (c *ClaudeService) GenerateResponse(prompt string) (string, error) {
 	return claudeResp.Content[0].Text, nil
 }
 
+// Ping fetches the model's metadata, which checks the API key and that the
+// API answers without generating anything.
+func (c *ClaudeService) Ping(ctx context.Context) error {
+	req, err := http.NewRequestWithContext(ctx, "GET", c.baseURL+"/models/"+model, nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("x-api-key", c.apiKey)
+	req.Header.Set("anthropic-version", "2023-06-01")
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, resp.Body)
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("claude API error: %s", resp.Status)
+	}
+	return nil
+}
+
 func (c *ClaudeService) Close() error {
 	// No resources to close for HTTP client
 	return nil
@@ -0,0 +1,112 @@
+// Package embedding turns emails into vectors for semantic retrieval. It
+// defines the Embedder and Store contracts, a text chunker, a deterministic
+// local embedder and an in-process index used when pgvector is unavailable.
+package embedding
+
+import (
+	"context"
+	"math"
+	"strings"
+	"time"
+)
+
+// Embedder produces vectors for text. Documents and queries are embedded
+// separately because some models use asymmetric encoders for retrieval.
+type Embedder interface {
+	Model() string
+	Dimensions() int
+	EmbedDocuments(ctx context.Context, texts []string) ([][]float32, error)
+	EmbedQuery(ctx context.Context, text string) ([]float32, error)
+}
+
+// Chunk is one embedded slice of an email.
+type Chunk struct {
+	ID        string
+	EmailID   string
+	UserID    string
+	Index     int
+	Content   string
+	Model     string
+	Vector    []float32
+	CreatedAt time.Time
+}
+
+// Match is a chunk returned by a similarity search. Score is the cosine
+// similarity to the query, higher being closer.
+type Match struct {
+	Chunk
+	Score float64
+}
+
+// Store keeps chunk vectors. Vectors from different models live in
+// different spaces, so every lookup is scoped to one model.
+type Store interface {
+	// ReplaceEmail stores chunks as the complete set for their email and
+	// model, dropping any earlier ones.
+	ReplaceEmail(ctx context.Context, emailID, model string, chunks []Chunk) error
+	Search(ctx context.Context, userID, model string, vector []float32, k int) ([]Match, error)
+	Count(ctx context.Context, userID, model string) (int, error)
+}
+
+// Split breaks an email into overlapping chunks of about size characters,
+// cutting at word boundaries. Every chunk is prefixed with the subject so
+// that it can be understood, and matched, on its own.
+func Split(subject, body string, size, overlap int) []string {
+	subject = strings.Join(strings.Fields(subject), " ")
+	words := strings.Fields(body)
+
+	prefix := ""
+	if subject != "" {
+		prefix = "Subject: " + subject + "\n"
+	}
+	if len(words) == 0 {
+		if prefix == "" {
+			return nil
+		}
+		return []string{strings.TrimSpace(prefix)}
+	}
+	if overlap >= size {
+		overlap = size / 4
+	}
+
+	var chunks []string
+	start := 0
+	for start < len(words) {
+		end, length := start, 0
+		for end < len(words) && (end == start || length+1+len(words[end]) <= size) {
+			length += len(words[end]) + 1
+			end++
+		}
+		chunks = append(chunks, prefix+strings.Join(words[start:end], " "))
+		if end == len(words) {
+			break
+		}
+
+		// Step back over roughly overlap characters, always making progress.
+		next, back := end, 0
+		for next > start+1 && back+len(words[next-1])+1 <= overlap {
+			next--
+			back += len(words[next]) + 1
+		}
+		start = next
+	}
+	return chunks
+}
+
+// Cosine returns the cosine similarity of a and b, or 0 if either is zero or
+// their lengths differ.
+func Cosine(a, b []float32) float64 {
+	if len(a) != len(b) {
+		return 0
+	}
+	var dot, na, nb float64
+	for i := range a {
+		dot += float64(a[i]) * float64(b[i])
+		na += float64(a[i]) * float64(a[i])
+		nb += float64(b[i]) * float64(b[i])
+	}
+	if na == 0 || nb == 0 {
+		return 0
+	}
+	return dot / (math.Sqrt(na) * math.Sqrt(nb))
+}
@@ -0,0 +1,80 @@
+package embedding
+
+import (
+	"context"
+	"hash/fnv"
+	"math"
+	"strings"
+	"unicode"
+)
+
+// LocalEmbedder is a deterministic feature-hashing embedder. It needs no
+// network access, which makes it suitable for tests and offline
+// development; it captures shared vocabulary rather than meaning.
+type LocalEmbedder struct {
+	dims int
+}
+
+func NewLocalEmbedder(dims int) *LocalEmbedder {
+	if dims <= 0 {
+		dims = 768
+	}
+	return &LocalEmbedder{dims: dims}
+}
+
+func (e *LocalEmbedder) Model() string {
+	return "local-hash"
+}
+
+func (e *LocalEmbedder) Dimensions() int {
+	return e.dims
+}
+
+func (e *LocalEmbedder) EmbedDocuments(ctx context.Context, texts []string) ([][]float32, error) {
+	vectors := make([][]float32, len(texts))
+	for i, text := range texts {
+		vectors[i] = e.embed(text)
+	}
+	return vectors, nil
+}
+
+func (e *LocalEmbedder) EmbedQuery(ctx context.Context, text string) ([]float32, error) {
+	return e.embed(text), nil
+}
+
+// embed hashes each word and each pair of adjacent words into a signed
+// bucket, then normalizes the result to unit length.
+func (e *LocalEmbedder) embed(text string) []float32 {
+	vector := make([]float32, e.dims)
+	words := strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+
+	add := func(feature string, weight float32) {
+		h := fnv.New64a()
+		h.Write([]byte(feature))
+		sum := h.Sum64()
+		if sum&(1<<63) != 0 {
+			weight = -weight
+		}
+		vector[sum%uint64(e.dims)] += weight
+	}
+	for i, word := range words {
+		add(word, 1)
+		if i > 0 {
+			add(words[i-1]+" "+word, 0.5)
+		}
+	}
+
+	var norm float64
+	for _, v := range vector {
+		norm += float64(v) * float64(v)
+	}
+	if norm > 0 {
+		scale := float32(1 / math.Sqrt(norm))
+		for i := range vector {
+			vector[i] *= scale
+		}
+	}
+	return vector
+}
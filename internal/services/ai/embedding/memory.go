@@ -0,0 +1,69 @@
+package embedding
+
+import (
+	"context"
+	"sort"
+	"sync"
+)
+
+// MemoryIndex is a brute-force in-process Store for deployments without
+// pgvector. It is lost on restart; callers rebuild it from the emails table.
+type MemoryIndex struct {
+	mu     sync.RWMutex
+	chunks map[string][]Chunk // by user ID
+}
+
+func NewMemoryIndex() *MemoryIndex {
+	return &MemoryIndex{chunks: make(map[string][]Chunk)}
+}
+
+func (m *MemoryIndex) ReplaceEmail(ctx context.Context, emailID, model string, chunks []Chunk) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for userID, existing := range m.chunks {
+		kept := existing[:0]
+		for _, chunk := range existing {
+			if chunk.EmailID != emailID || chunk.Model != model {
+				kept = append(kept, chunk)
+			}
+		}
+		m.chunks[userID] = kept
+	}
+	for _, chunk := range chunks {
+		m.chunks[chunk.UserID] = append(m.chunks[chunk.UserID], chunk)
+	}
+	return nil
+}
+
+func (m *MemoryIndex) Search(ctx context.Context, userID, model string, vector []float32, k int) ([]Match, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	var matches []Match
+	for _, chunk := range m.chunks[userID] {
+		if chunk.Model != model {
+			continue
+		}
+		matches = append(matches, Match{Chunk: chunk, Score: Cosine(vector, chunk.Vector)})
+	}
+
+	sort.SliceStable(matches, func(i, j int) bool { return matches[i].Score > matches[j].Score })
+	if len(matches) > k {
+		matches = matches[:k]
+	}
+	return matches, nil
+}
+
+func (m *MemoryIndex) Count(ctx context.Context, userID, model string) (int, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	count := 0
+	for _, chunk := range m.chunks[userID] {
+		if chunk.Model == model {
+			count++
+		}
+	}
+	return count, nil
+}
@@ -0,0 +1,79 @@
+package gemini
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/google/generative-ai-go/genai"
+	"ai-assistant/pkg/metrics"
+)
+
+const (
+	embeddingModel      = "text-embedding-004"
+	embeddingDimensions = 768
+	// embeddingBatchSize is the most texts the API accepts in one batch.
+	embeddingBatchSize = 100
+)
+
+// Embedder implements embedding.Embedder with Gemini's text embedding model,
+// sharing the client of the GeminiService it was created from.
+type Embedder struct {
+	documents *genai.EmbeddingModel
+	queries   *genai.EmbeddingModel
+}
+
+func (g *GeminiService) Embedder() *Embedder {
+	documents := g.client.EmbeddingModel(embeddingModel)
+	documents.TaskType = genai.TaskTypeRetrievalDocument
+	queries := g.client.EmbeddingModel(embeddingModel)
+	queries.TaskType = genai.TaskTypeRetrievalQuery
+
+	return &Embedder{documents: documents, queries: queries}
+}
+
+func (e *Embedder) Model() string {
+	return embeddingModel
+}
+
+func (e *Embedder) Dimensions() int {
+	return embeddingDimensions
+}
+
+func (e *Embedder) EmbedDocuments(ctx context.Context, texts []string) ([][]float32, error) {
+	vectors := make([][]float32, 0, len(texts))
+	for start := 0; start < len(texts); start += embeddingBatchSize {
+		end := min(start+embeddingBatchSize, len(texts))
+
+		batch := e.documents.NewBatch()
+		for _, text := range texts[start:end] {
+			batch.AddContent(genai.Text(text))
+		}
+		began := time.Now()
+		resp, err := e.documents.BatchEmbedContents(ctx, batch)
+		metrics.ObserveAIRequest("gemini", embeddingModel, "embed", began, err)
+		if err != nil {
+			return nil, fmt.Errorf("failed to embed documents: %w", err)
+		}
+		if len(resp.Embeddings) != end-start {
+			return nil, fmt.Errorf("expected %d embeddings, got %d", end-start, len(resp.Embeddings))
+		}
+		for _, embedding := range resp.Embeddings {
+			vectors = append(vectors, embedding.Values)
+		}
+	}
+	return vectors, nil
+}
+
+func (e *Embedder) EmbedQuery(ctx context.Context, text string) ([]float32, error) {
+	start := time.Now()
+	resp, err := e.queries.EmbedContent(ctx, genai.Text(text))
+	metrics.ObserveAIRequest("gemini", embeddingModel, "embed", start, err)
+	if err != nil {
+		return nil, fmt.Errorf("failed to embed query: %w", err)
+	}
+	if resp.Embedding == nil {
+		return nil, fmt.Errorf("no embedding returned from Gemini")
+	}
+	return resp.Embedding.Values, nil
+}
@@ -3,17 +3,22 @@ package gemini
 import (
 	"context"
 	"fmt"
+	"time"
 
 	"github.com/google/generative-ai-go/genai"
+	"go.opentelemetry.io/otel/attribute"
 	"google.golang.org/api/option"
 	"ai-assistant/internal/app/config"
 	"ai-assistant/pkg/logger"
+	"ai-assistant/pkg/metrics"
+	"ai-assistant/pkg/tracing"
 )
 
+const generativeModel = "gemini-1.5-flash"
+
 type GeminiService struct {
 	client *genai.Client
 	model  *genai.GenerativeModel
-	ctx    context.Context
 	logger *logger.Logger
 }
 
@@ -24,7 +29,7 @@ func NewGeminiService(cfg *config.Config) (*GeminiService, error) {
 		return nil, fmt.Errorf("failed to create Gemini client: %w", err)
 	}
 
-	model := client.GenerativeModel("gemini-1.5-flash")
+	model := client.GenerativeModel(generativeModel)
 	model.SetTemperature(0.7)
 	model.SetTopK(32)
 	model.SetTopP(0.9)
@@ -33,19 +38,31 @@ func NewGeminiService(cfg *config.Config) (*GeminiService, error) {
 	return &GeminiService{
 		client: client,
 		model:  model,
-		ctx:    ctx,
 		logger: logger.New(),
 	}, nil
 }
 
-func (g *GeminiService) GenerateResponse(prompt string) (string, error) {
-	g.logger.Infof("Generating response for prompt: %s", prompt[:min(50, len(prompt))]+"...")
+func (g *GeminiService) GenerateResponse(ctx context.Context, prompt string) (response string, err error) {
+	g.logger.InfoContext(ctx, "Generating Gemini response", "prompt_length", len(prompt))
+	ctx, span := tracing.Start(ctx, "gemini.generate", attribute.String("gen_ai.request.model", generativeModel))
+	start := time.Now()
+	defer func() {
+		metrics.ObserveAIRequest("gemini", generativeModel, "generate", start, err)
+		tracing.End(span, err)
+	}()
 
-	resp, err := g.model.GenerateContent(g.ctx, genai.Text(prompt))
+	resp, err := g.model.GenerateContent(ctx, genai.Text(prompt))
 	if err != nil {
-		g.logger.Errorf("Failed to generate content: %v", err)
+		g.logger.ErrorContext(ctx, "Failed to generate content", "error", err)
 		return "", fmt.Errorf("failed to generate content: %w", err)
 	}
+	if usage := resp.UsageMetadata; usage != nil {
+		metrics.AddAITokens("gemini", generativeModel, int(usage.PromptTokenCount), int(usage.CandidatesTokenCount))
+		span.SetAttributes(
+			attribute.Int("gen_ai.usage.input_tokens", int(usage.PromptTokenCount)),
+			attribute.Int("gen_ai.usage.output_tokens", int(usage.CandidatesTokenCount)),
+		)
+	}
 
 	if len(resp.Candidates) == 0 {
 		return "", fmt.Errorf("no candidates returned from Gemini")
@@ -63,6 +80,13 @@ func (g *GeminiService) GenerateResponse(prompt string) (string, error) {
 	return "", fmt.Errorf("unexpected content type from Gemini")
 }
 
+// Ping fetches the model's metadata, which checks the API key and that the
+// API answers without generating anything.
+func (g *GeminiService) Ping(ctx context.Context) error {
+	_, err := g.model.Info(ctx)
+	return err
+}
+
 func (g *GeminiService) Close() error {
 	return g.client.Close()
 }
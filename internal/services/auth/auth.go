@@ -3,14 +3,17 @@ package auth
 import (
 	"context"
 	"fmt"
+	"log/slog"
 	"net/http"
 	"strings"
 	"time"
 
 	"github.com/golang-jwt/jwt/v5"
 	"ai-assistant/internal/app/config"
+	"ai-assistant/internal/httperr"
 	"ai-assistant/internal/models"
 	"ai-assistant/pkg/errors"
+	"ai-assistant/pkg/logger"
 )
 
 type ContextKey string
@@ -72,19 +75,19 @@ func (a *AuthService) RequireAuth() func(http.Handler) http.Handler {
 		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 			authHeader := r.Header.Get("Authorization")
 			if authHeader == "" {
-				http.Error(w, "Authorization header required", http.StatusUnauthorized)
+				httperr.Write(w, r, errors.ErrUnauthorized("Authorization header required"))
 				return
 			}
 
 			parts := strings.Split(authHeader, " ")
 			if len(parts) != 2 || parts[0] != "Bearer" {
-				http.Error(w, "Invalid authorization header format", http.StatusUnauthorized)
+				httperr.Write(w, r, errors.ErrUnauthorized("Invalid authorization header format"))
 				return
 			}
 
 			claims, err := a.ValidateToken(parts[1])
 			if err != nil {
-				http.Error(w, "Invalid token", http.StatusUnauthorized)
+				httperr.Write(w, r, errors.ErrTokenInvalid)
 				return
 			}
 
@@ -93,6 +96,7 @@ func (a *AuthService) RequireAuth() func(http.Handler) http.Handler {
 				Email: claims.Email,
 			}
 
+			logger.AddAttrs(r.Context(), slog.String("user_id", user.ID))
 			ctx := context.WithValue(r.Context(), UserContextKey, user)
 			next.ServeHTTP(w, r.WithContext(ctx))
 		})
@@ -0,0 +1,37 @@
+// Package blob defines the storage for binary content such as attachments.
+// The filesystem and s3 packages implement it.
+package blob
+
+import (
+	"context"
+	"errors"
+	"io"
+	"strings"
+)
+
+// ErrNotFound is returned by Get for a key that was never stored.
+var ErrNotFound = errors.New("blob not found")
+
+// Store keeps blobs under slash-separated keys such as
+// "attachments/<user>/<email>/<attachment>".
+type Store interface {
+	Put(ctx context.Context, key string, data []byte, contentType string) error
+	// Get returns the blob's content and size. The caller closes it.
+	Get(ctx context.Context, key string) (io.ReadCloser, int64, error)
+	// Delete removes a blob. Deleting a missing key is not an error.
+	Delete(ctx context.Context, key string) error
+}
+
+// ValidKey reports whether key is safe to use as a path on every backend:
+// relative, without empty, "." or ".." segments.
+func ValidKey(key string) bool {
+	if key == "" || strings.HasPrefix(key, "/") || strings.ContainsAny(key, "\\\x00") {
+		return false
+	}
+	for _, segment := range strings.Split(key, "/") {
+		if segment == "" || segment == "." || segment == ".." {
+			return false
+		}
+	}
+	return true
+}
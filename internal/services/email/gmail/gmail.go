@@ -2,15 +2,22 @@ package gmail
 
 import (
 	"context"
-	"encoding/base64"
 	"fmt"
+	"net/http"
+	"net/mail"
+	"net/textproto"
 	"strings"
+	"time"
 
 	"golang.org/x/oauth2"
+	"golang.org/x/oauth2/google"
 	"google.golang.org/api/gmail/v1"
 	"google.golang.org/api/option"
 	"ai-assistant/internal/app/config"
+	"ai-assistant/internal/services/email"
+	"ai-assistant/internal/services/email/mailmime"
 	"ai-assistant/pkg/logger"
+	"ai-assistant/pkg/tracing"
 )
 
 type GmailService struct {
@@ -18,32 +25,29 @@ type GmailService struct {
 	logger  *logger.Logger
 }
 
-type GmailMessage struct {
-	ID       string
-	ThreadID string
-	Subject  string
-	From     string
-	To       []string
-	Body     string
-	HTMLBody string
-	Labels   []string
-	IsRead   bool
-}
-
 func NewGmailService(cfg *config.Config, token *oauth2.Token) (*GmailService, error) {
-	ctx := context.Background()
+	// The OAuth client sends API calls, and token refreshes, through the
+	// client in the context.
+	ctx := context.WithValue(context.Background(), oauth2.HTTPClient, &http.Client{Transport: tracing.Transport(nil)})
 	
 	oauthConfig := &oauth2.Config{
 		ClientID:     cfg.Google.ClientID,
 		ClientSecret: cfg.Google.ClientSecret,
+		Endpoint:     google.Endpoint,
 		Scopes: []string{
-			"https://www.googleapis.com/auth/gmail.readonly",
+			"https://www.googleapis.com/auth/gmail.modify",
+			"https://www.googleapis.com/auth/gmail.compose",
 			"https://www.googleapis.com/auth/gmail.send",
 		},
 	}
 
-	client := oauthConfig.Client(ctx, token)
-	service, err := gmail.NewService(ctx, option.WithHTTPClient(client))
+	return NewService(ctx, option.WithHTTPClient(oauthConfig.Client(ctx, token)))
+}
+
+// NewService returns a GmailService for an already authorized API client,
+// such as one pointed at another endpoint with option.WithEndpoint.
+func NewService(ctx context.Context, opts ...option.ClientOption) (*GmailService, error) {
+	service, err := gmail.NewService(ctx, opts...)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create Gmail service: %w", err)
 	}
@@ -54,18 +58,18 @@ func NewGmailService(cfg *config.Config, token *oauth2.Token) (*GmailService, er
 	}, nil
 }
 
-func (g *GmailService) GetMessages(userID string, maxResults int64) ([]*GmailMessage, error) {
+func (g *GmailService) GetMessages(userID string, maxResults int64) ([]*email.Message, error) {
 	call := g.service.Users.Messages.List(userID).MaxResults(maxResults)
 	response, err := call.Do()
 	if err != nil {
 		return nil, fmt.Errorf("failed to list messages: %w", err)
 	}
 
-	var messages []*GmailMessage
+	var messages []*email.Message
 	for _, message := range response.Messages {
 		msg, err := g.GetMessage(userID, message.Id)
 		if err != nil {
-			g.logger.Errorf("Failed to get message %s: %v", message.Id, err)
+			g.logger.Error("Failed to get message", "message_id", message.Id, "error", err)
 			continue
 		}
 		messages = append(messages, msg)
@@ -74,52 +78,77 @@ func (g *GmailService) GetMessages(userID string, maxResults int64) ([]*GmailMes
 	return messages, nil
 }
 
-func (g *GmailService) GetMessage(userID, messageID string) (*GmailMessage, error) {
+func (g *GmailService) GetMessage(userID, messageID string) (*email.Message, error) {
 	message, err := g.service.Users.Messages.Get(userID, messageID).Do()
 	if err != nil {
 		return nil, fmt.Errorf("failed to get message: %w", err)
 	}
 
-	gmailMsg := &GmailMessage{
+	return toMessage(message), nil
+}
+
+// toMessage converts a Gmail API message fetched in the "full" format.
+func toMessage(message *gmail.Message) *email.Message {
+	gmailMsg := &email.Message{
 		ID:       message.Id,
 		ThreadID: message.ThreadId,
 		Labels:   message.LabelIds,
-		IsRead:   !contains(message.LabelIds, "UNREAD"),
+		IsRead:   !email.HasLabel(message.LabelIds, email.LabelUnread),
 	}
 
 	for _, header := range message.Payload.Headers {
-		switch header.Name {
-		case "Subject":
-			gmailMsg.Subject = header.Value
-		case "From":
-			gmailMsg.From = header.Value
-		case "To":
-			gmailMsg.To = strings.Split(header.Value, ",")
+		switch strings.ToLower(header.Name) {
+		case "subject":
+			gmailMsg.Subject = mailmime.DecodeHeader(header.Value)
+		case "from":
+			gmailMsg.From = mailmime.ParseAddress(header.Value)
+		case "to":
+			gmailMsg.To = mailmime.ParseAddressList(header.Value)
+		case "cc":
+			gmailMsg.Cc = mailmime.ParseAddressList(header.Value)
+		case "reply-to":
+			gmailMsg.ReplyTo = mailmime.ParseAddress(header.Value)
+		case "date":
+			if date, err := mail.ParseDate(header.Value); err == nil {
+				gmailMsg.Date = date
+			}
+		case "message-id":
+			gmailMsg.HeaderID = strings.TrimSpace(header.Value)
+		case "in-reply-to":
+			gmailMsg.InReplyTo = strings.TrimSpace(header.Value)
+		case "references":
+			gmailMsg.References = strings.Fields(header.Value)
 		}
 	}
+	if gmailMsg.Date.IsZero() && message.InternalDate > 0 {
+		gmailMsg.Date = time.UnixMilli(message.InternalDate)
+	}
 
-	body, htmlBody := extractBody(message.Payload)
-	gmailMsg.Body = body
-	gmailMsg.HTMLBody = htmlBody
+	parsed := mailmime.Extract(payloadToPart(message.Payload))
+	gmailMsg.Body = parsed.Text
+	gmailMsg.HTMLBody = parsed.HTML
+	gmailMsg.Attachments = parsed.Attachments
 
-	return gmailMsg, nil
+	return gmailMsg
 }
 
 func (g *GmailService) SendMessage(userID string, to, subject, body string) error {
-	message := fmt.Sprintf("To: %s\nSubject: %s\n\n%s", to, subject, body)
-	encodedMessage := base64.URLEncoding.EncodeToString([]byte(message))
-
-	gmailMessage := &gmail.Message{
-		Raw: encodedMessage,
-	}
+	_, _, err := g.Send(userID, &mailmime.Outgoing{
+		To:      []string{to},
+		Subject: subject,
+		Text:    body,
+	})
+	return err
+}
 
-	_, err := g.service.Users.Messages.Send(userID, gmailMessage).Do()
+// Send composes msg and submits it through the Gmail API, returning the
+// sent copy's Gmail message and thread IDs.
+func (g *GmailService) Send(userID string, msg *mailmime.Outgoing) (string, string, error) {
+	sent, err := NewProvider(g, userID).Send(context.Background(), msg, email.SendOptions{})
 	if err != nil {
-		return fmt.Errorf("failed to send message: %w", err)
+		return "", "", err
 	}
-
-	g.logger.Infof("Message sent successfully to %s", to)
-	return nil
+	return sent.ID, sent.ThreadID, nil
 }
 
 func (g *GmailService) MarkAsRead(userID, messageID string) error {
@@ -135,42 +164,41 @@ func (g *GmailService) MarkAsRead(userID, messageID string) error {
 	return nil
 }
 
-func extractBody(payload *gmail.MessagePart) (string, string) {
-	var body, htmlBody string
+// payloadToPart converts the Gmail API part tree into a mailmime tree. Gmail
+// has already undone the transfer encoding, so body data only needs its
+// base64url wrapper removed.
+func payloadToPart(payload *gmail.MessagePart) *mailmime.Part {
+	if payload == nil {
+		return nil
+	}
 
-	if payload.Body != nil && payload.Body.Data != "" {
-		decoded, err := base64.URLEncoding.DecodeString(payload.Body.Data)
-		if err == nil {
-			if payload.MimeType == "text/html" {
-				htmlBody = string(decoded)
-			} else {
-				body = string(decoded)
-			}
-		}
+	header := textproto.MIMEHeader{}
+	for _, h := range payload.Headers {
+		header.Add(h.Name, h.Value)
+	}
+	if header.Get("Content-Type") == "" && payload.MimeType != "" {
+		header.Set("Content-Type", payload.MimeType)
 	}
 
-	for _, part := range payload.Parts {
-		if part.MimeType == "text/plain" && part.Body != nil && part.Body.Data != "" {
-			decoded, err := base64.URLEncoding.DecodeString(part.Body.Data)
-			if err == nil {
-				body = string(decoded)
-			}
-		} else if part.MimeType == "text/html" && part.Body != nil && part.Body.Data != "" {
-			decoded, err := base64.URLEncoding.DecodeString(part.Body.Data)
+	part := mailmime.NewPart(header)
+	if payload.Filename != "" {
+		part.Filename = payload.Filename
+	}
+
+	if payload.Body != nil {
+		part.AttachmentID = payload.Body.AttachmentId
+		part.Size = payload.Body.Size
+		if payload.Body.Data != "" {
+			decoded, err := mailmime.DecodeBase64URL(payload.Body.Data)
 			if err == nil {
-				htmlBody = string(decoded)
+				part.Body = decoded
 			}
 		}
 	}
 
-	return body, htmlBody
-}
-
-func contains(slice []string, item string) bool {
-	for _, s := range slice {
-		if s == item {
-			return true
-		}
+	for _, child := range payload.Parts {
+		part.Parts = append(part.Parts, payloadToPart(child))
 	}
-	return false
-}
\ No newline at end of file
+
+	return part
+}
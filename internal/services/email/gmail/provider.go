@@ -0,0 +1,287 @@
+package gmail
+
+import (
+	"context"
+	"encoding/base64"
+	stderrors "errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"google.golang.org/api/gmail/v1"
+	"google.golang.org/api/googleapi"
+	"ai-assistant/internal/services/email"
+	"ai-assistant/internal/services/email/mailmime"
+)
+
+// Provider adapts a GmailService bound to one mailbox to the
+// email.MailboxProvider, email.Sender, email.DraftStore, email.LabelStore
+// and email.AttachmentFetcher interfaces.
+type Provider struct {
+	svc    *GmailService
+	userID string
+}
+
+// NewProvider returns a provider for the mailbox of userID, which is "me"
+// for the user the OAuth token was issued to.
+func NewProvider(svc *GmailService, userID string) *Provider {
+	return &Provider{svc: svc, userID: userID}
+}
+
+func (p *Provider) List(ctx context.Context, opts email.ListOptions) (*email.ListResult, error) {
+	call := p.svc.service.Users.Messages.List(p.userID).Context(ctx)
+	if opts.MaxResults > 0 {
+		call = call.MaxResults(opts.MaxResults)
+	}
+	if opts.PageToken != "" {
+		call = call.PageToken(opts.PageToken)
+	}
+	if len(opts.Labels) > 0 {
+		call = call.LabelIds(opts.Labels...)
+	}
+
+	response, err := call.Do()
+	if err != nil {
+		return nil, fmt.Errorf("failed to list messages: %w", err)
+	}
+
+	result := &email.ListResult{NextPageToken: response.NextPageToken}
+	for _, message := range response.Messages {
+		result.Messages = append(result.Messages, email.MessageRef{ID: message.Id, ThreadID: message.ThreadId})
+	}
+	return result, nil
+}
+
+func (p *Provider) Fetch(ctx context.Context, id string) (*email.Message, error) {
+	message, err := p.svc.service.Users.Messages.Get(p.userID, id).Context(ctx).Do()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get message: %w", err)
+	}
+	return toMessage(message), nil
+}
+
+// FetchAttachment downloads an attachment that Gmail left out of the
+// message payload.
+func (p *Provider) FetchAttachment(ctx context.Context, messageID, attachmentID string) ([]byte, error) {
+	body, err := p.svc.service.Users.Messages.Attachments.Get(p.userID, messageID, attachmentID).Context(ctx).Do()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get attachment: %w", err)
+	}
+	data, err := mailmime.DecodeBase64URL(body.Data)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode attachment: %w", err)
+	}
+	return data, nil
+}
+
+func (p *Provider) ModifyLabels(ctx context.Context, id string, add, remove []string) error {
+	req := &gmail.ModifyMessageRequest{
+		AddLabelIds:    add,
+		RemoveLabelIds: remove,
+	}
+
+	if _, err := p.svc.service.Users.Messages.Modify(p.userID, id, req).Context(ctx).Do(); err != nil {
+		return fmt.Errorf("failed to modify labels: %w", err)
+	}
+	return nil
+}
+
+func (p *Provider) Name() string {
+	return "gmail"
+}
+
+func (p *Provider) Send(ctx context.Context, msg *mailmime.Outgoing, opts email.SendOptions) (*email.SendResult, error) {
+	raw, err := msg.Build(true)
+	if err != nil {
+		return nil, fmt.Errorf("failed to build message: %w", err)
+	}
+
+	gmailMessage := &gmail.Message{
+		Raw:      base64.URLEncoding.EncodeToString(raw),
+		ThreadId: opts.ThreadID,
+	}
+
+	sent, err := p.svc.service.Users.Messages.Send(p.userID, gmailMessage).Context(ctx).Do()
+	if err != nil {
+		return nil, fmt.Errorf("failed to send message: %w", err)
+	}
+
+	p.svc.logger.Info("Message sent", "provider", "gmail", "message_id", sent.Id, "recipients", len(msg.To))
+	return &email.SendResult{ID: sent.Id, ThreadID: sent.ThreadId}, nil
+}
+
+func (p *Provider) CreateDraft(ctx context.Context, threadID string, msg *mailmime.Outgoing) (string, error) {
+	draft, err := newDraft(threadID, msg)
+	if err != nil {
+		return "", err
+	}
+
+	created, err := p.svc.service.Users.Drafts.Create(p.userID, draft).Context(ctx).Do()
+	if err != nil {
+		return "", fmt.Errorf("failed to create draft: %w", err)
+	}
+	return created.Id, nil
+}
+
+func (p *Provider) UpdateDraft(ctx context.Context, draftID, threadID string, msg *mailmime.Outgoing) error {
+	draft, err := newDraft(threadID, msg)
+	if err != nil {
+		return err
+	}
+	draft.Id = draftID
+
+	if _, err := p.svc.service.Users.Drafts.Update(p.userID, draftID, draft).Context(ctx).Do(); err != nil {
+		return fmt.Errorf("failed to update draft: %w", err)
+	}
+	return nil
+}
+
+func (p *Provider) DeleteDraft(ctx context.Context, draftID string) error {
+	if err := p.svc.service.Users.Drafts.Delete(p.userID, draftID).Context(ctx).Do(); err != nil {
+		return fmt.Errorf("failed to delete draft: %w", err)
+	}
+	return nil
+}
+
+func (p *Provider) SendDraft(ctx context.Context, draftID string) (*email.SendResult, error) {
+	sent, err := p.svc.service.Users.Drafts.Send(p.userID, &gmail.Draft{Id: draftID}).Context(ctx).Do()
+	if err != nil {
+		return nil, fmt.Errorf("failed to send draft: %w", err)
+	}
+	return &email.SendResult{ID: sent.Id, ThreadID: sent.ThreadId}, nil
+}
+
+func (p *Provider) ListLabels(ctx context.Context) ([]email.Label, error) {
+	response, err := p.svc.service.Users.Labels.List(p.userID).Context(ctx).Do()
+	if err != nil {
+		return nil, fmt.Errorf("failed to list labels: %w", err)
+	}
+
+	labels := make([]email.Label, 0, len(response.Labels))
+	for _, label := range response.Labels {
+		labels = append(labels, email.Label{ID: label.Id, Name: label.Name, System: label.Type == "system"})
+	}
+	return labels, nil
+}
+
+func (p *Provider) CreateLabel(ctx context.Context, name string) (*email.Label, error) {
+	label := &gmail.Label{
+		Name:                  name,
+		LabelListVisibility:   "labelShow",
+		MessageListVisibility: "show",
+	}
+
+	created, err := p.svc.service.Users.Labels.Create(p.userID, label).Context(ctx).Do()
+	if err != nil {
+		return nil, fmt.Errorf("failed to create label: %w", err)
+	}
+	return &email.Label{ID: created.Id, Name: created.Name}, nil
+}
+
+func (p *Provider) RenameLabel(ctx context.Context, id, name string) error {
+	if _, err := p.svc.service.Users.Labels.Patch(p.userID, id, &gmail.Label{Name: name}).Context(ctx).Do(); err != nil {
+		return fmt.Errorf("failed to rename label: %w", err)
+	}
+	return nil
+}
+
+func (p *Provider) DeleteLabel(ctx context.Context, id string) error {
+	if err := p.svc.service.Users.Labels.Delete(p.userID, id).Context(ctx).Do(); err != nil {
+		return fmt.Errorf("failed to delete label: %w", err)
+	}
+	return nil
+}
+
+// EmailAddress returns the address of the mailbox, which is what push
+// notifications identify it by.
+func (p *Provider) EmailAddress(ctx context.Context) (string, error) {
+	profile, err := p.svc.service.Users.GetProfile(p.userID).Context(ctx).Do()
+	if err != nil {
+		return "", fmt.Errorf("failed to get profile: %w", err)
+	}
+	return profile.EmailAddress, nil
+}
+
+// ErrHistoryExpired is returned by History when the start ID is older than
+// the history Gmail keeps (about a week); a full sync is needed instead.
+var ErrHistoryExpired = stderrors.New("gmail history expired")
+
+type WatchResult struct {
+	HistoryID  uint64
+	Expiration time.Time
+}
+
+// Watch asks Gmail to publish changes to the mailbox's labelIDs (all mail if
+// empty) to the Pub/Sub topic. Calling it again renews the watch.
+func (p *Provider) Watch(ctx context.Context, topic string, labelIDs []string) (*WatchResult, error) {
+	req := &gmail.WatchRequest{TopicName: topic, LabelIds: labelIDs}
+	if len(labelIDs) > 0 {
+		req.LabelFilterBehavior = "include"
+	}
+
+	resp, err := p.svc.service.Users.Watch(p.userID, req).Context(ctx).Do()
+	if err != nil {
+		return nil, fmt.Errorf("failed to watch mailbox: %w", err)
+	}
+	return &WatchResult{HistoryID: resp.HistoryId, Expiration: time.UnixMilli(resp.Expiration)}, nil
+}
+
+func (p *Provider) StopWatch(ctx context.Context) error {
+	if err := p.svc.service.Users.Stop(p.userID).Context(ctx).Do(); err != nil {
+		return fmt.Errorf("failed to stop watch: %w", err)
+	}
+	return nil
+}
+
+type HistoryResult struct {
+	// MessageIDs are the messages added since the start ID, oldest first.
+	MessageIDs []string
+	HistoryID  uint64
+}
+
+// History lists the messages added to the mailbox after startHistoryID.
+func (p *Provider) History(ctx context.Context, startHistoryID uint64) (*HistoryResult, error) {
+	result := &HistoryResult{HistoryID: startHistoryID}
+	seen := make(map[string]bool)
+
+	call := p.svc.service.Users.History.List(p.userID).
+		StartHistoryId(startHistoryID).
+		HistoryTypes("messageAdded").
+		Context(ctx)
+	err := call.Pages(ctx, func(page *gmail.ListHistoryResponse) error {
+		for _, h := range page.History {
+			for _, added := range h.MessagesAdded {
+				if added.Message != nil && !seen[added.Message.Id] {
+					seen[added.Message.Id] = true
+					result.MessageIDs = append(result.MessageIDs, added.Message.Id)
+				}
+			}
+		}
+		if page.HistoryId > result.HistoryID {
+			result.HistoryID = page.HistoryId
+		}
+		return nil
+	})
+	if err != nil {
+		var apiErr *googleapi.Error
+		if stderrors.As(err, &apiErr) && apiErr.Code == http.StatusNotFound {
+			return nil, ErrHistoryExpired
+		}
+		return nil, fmt.Errorf("failed to list history: %w", err)
+	}
+	return result, nil
+}
+
+func newDraft(threadID string, msg *mailmime.Outgoing) (*gmail.Draft, error) {
+	raw, err := msg.Build(true)
+	if err != nil {
+		return nil, fmt.Errorf("failed to build message: %w", err)
+	}
+
+	return &gmail.Draft{
+		Message: &gmail.Message{
+			Raw:      base64.URLEncoding.EncodeToString(raw),
+			ThreadId: threadID,
+		},
+	}, nil
+}
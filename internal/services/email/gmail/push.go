@@ -0,0 +1,244 @@
+package gmail
+
+import (
+	"context"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// GoogleCertsURL serves the keys Google signs Pub/Sub push tokens with.
+const GoogleCertsURL = "https://www.googleapis.com/oauth2/v3/certs"
+
+var googleIssuers = []string{"accounts.google.com", "https://accounts.google.com"}
+
+// Notification is the payload Gmail publishes when a watched mailbox
+// changes. HistoryID is the mailbox's latest history record.
+type Notification struct {
+	EmailAddress string `json:"emailAddress"`
+	HistoryID    uint64 `json:"historyId"`
+}
+
+// pushEnvelope is the body of a Pub/Sub push request.
+type pushEnvelope struct {
+	Message struct {
+		Data      string `json:"data"`
+		MessageID string `json:"messageId"`
+	} `json:"message"`
+	Subscription string `json:"subscription"`
+}
+
+// ParsePush decodes a Pub/Sub push request body into the Gmail notification
+// it carries.
+func ParsePush(body []byte) (*Notification, error) {
+	var envelope pushEnvelope
+	if err := json.Unmarshal(body, &envelope); err != nil {
+		return nil, fmt.Errorf("invalid push body: %w", err)
+	}
+	if envelope.Message.Data == "" {
+		return nil, fmt.Errorf("push message has no data")
+	}
+
+	data, err := base64.StdEncoding.DecodeString(envelope.Message.Data)
+	if err != nil {
+		// Some publishers use the URL alphabet.
+		if data, err = base64.URLEncoding.DecodeString(envelope.Message.Data); err != nil {
+			return nil, fmt.Errorf("invalid push data: %w", err)
+		}
+	}
+
+	var n Notification
+	if err := json.Unmarshal(data, &n); err != nil {
+		return nil, fmt.Errorf("invalid notification: %w", err)
+	}
+	if n.EmailAddress == "" || n.HistoryID == 0 {
+		return nil, fmt.Errorf("notification needs emailAddress and historyId")
+	}
+	return &n, nil
+}
+
+// KeySource looks up the RSA public key a token was signed with.
+type KeySource interface {
+	Key(ctx context.Context, kid string) (*rsa.PublicKey, error)
+}
+
+// pushClaims are the claims of the OIDC token Pub/Sub attaches to pushes.
+type pushClaims struct {
+	Email         string `json:"email"`
+	EmailVerified bool   `json:"email_verified"`
+	jwt.RegisteredClaims
+}
+
+// PushVerifier checks the bearer token Pub/Sub sends with authenticated
+// push subscriptions.
+type PushVerifier struct {
+	keys     KeySource
+	audience string
+	// serviceAccount, if set, must be the token's email claim: the account
+	// the subscription pushes as.
+	serviceAccount string
+}
+
+func NewPushVerifier(keys KeySource, audience, serviceAccount string) *PushVerifier {
+	return &PushVerifier{
+		keys:           keys,
+		audience:       audience,
+		serviceAccount: serviceAccount,
+	}
+}
+
+// Verify validates an Authorization header value of the form "Bearer <jwt>".
+func (v *PushVerifier) Verify(ctx context.Context, authorization string) error {
+	token, ok := strings.CutPrefix(authorization, "Bearer ")
+	if !ok || token == "" {
+		return fmt.Errorf("missing bearer token")
+	}
+
+	parser := jwt.NewParser(
+		jwt.WithValidMethods([]string{"RS256"}),
+		jwt.WithAudience(v.audience),
+		jwt.WithExpirationRequired(),
+		jwt.WithIssuedAt(),
+	)
+
+	claims := &pushClaims{}
+	_, err := parser.ParseWithClaims(token, claims, func(t *jwt.Token) (interface{}, error) {
+		kid, _ := t.Header["kid"].(string)
+		return v.keys.Key(ctx, kid)
+	})
+	if err != nil {
+		return fmt.Errorf("invalid push token: %w", err)
+	}
+
+	if !validIssuer(claims.Issuer) {
+		return fmt.Errorf("invalid push token issuer %q", claims.Issuer)
+	}
+	if v.serviceAccount != "" && (claims.Email != v.serviceAccount || !claims.EmailVerified) {
+		return fmt.Errorf("push token is not from %s", v.serviceAccount)
+	}
+	return nil
+}
+
+func validIssuer(iss string) bool {
+	for _, valid := range googleIssuers {
+		if iss == valid {
+			return true
+		}
+	}
+	return false
+}
+
+// Keys are refetched when the cache is an hour old, or when a token names an
+// unknown key, at most once a minute so that forged tokens cannot make us
+// hammer the key endpoint.
+const (
+	jwksCacheTTL        = time.Hour
+	jwksRefreshInterval = time.Minute
+)
+
+// JWKS fetches and caches a JSON Web Key Set.
+type JWKS struct {
+	url    string
+	client *http.Client
+
+	mu        sync.Mutex
+	keys      map[string]*rsa.PublicKey
+	fetchedAt time.Time
+}
+
+func NewJWKS(url string, client *http.Client) *JWKS {
+	if client == nil {
+		client = &http.Client{Timeout: 10 * time.Second}
+	}
+	return &JWKS{url: url, client: client}
+}
+
+func (j *JWKS) Key(ctx context.Context, kid string) (*rsa.PublicKey, error) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	age := time.Since(j.fetchedAt)
+	key, ok := j.keys[kid]
+	if (!ok && age > jwksRefreshInterval) || age > jwksCacheTTL {
+		if err := j.fetch(ctx); err != nil {
+			if ok {
+				// Keep using a known key while the endpoint is down.
+				return key, nil
+			}
+			return nil, err
+		}
+		key, ok = j.keys[kid]
+	}
+	if !ok {
+		return nil, fmt.Errorf("unknown signing key %q", kid)
+	}
+	return key, nil
+}
+
+type jsonWebKey struct {
+	Kid string `json:"kid"`
+	Kty string `json:"kty"`
+	N   string `json:"n"`
+	E   string `json:"e"`
+}
+
+func (j *JWKS) fetch(ctx context.Context) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, j.url, nil)
+	if err != nil {
+		return err
+	}
+	resp, err := j.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to fetch signing keys: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("failed to fetch signing keys: status %d", resp.StatusCode)
+	}
+
+	var set struct {
+		Keys []jsonWebKey `json:"keys"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&set); err != nil {
+		return fmt.Errorf("invalid signing keys: %w", err)
+	}
+
+	keys := make(map[string]*rsa.PublicKey, len(set.Keys))
+	for _, k := range set.Keys {
+		if k.Kty != "RSA" {
+			continue
+		}
+		key, err := rsaKey(k)
+		if err != nil {
+			return err
+		}
+		keys[k.Kid] = key
+	}
+
+	j.keys = keys
+	j.fetchedAt = time.Now()
+	return nil
+}
+
+func rsaKey(k jsonWebKey) (*rsa.PublicKey, error) {
+	n, err := base64.RawURLEncoding.DecodeString(k.N)
+	if err != nil {
+		return nil, fmt.Errorf("invalid modulus for key %q", k.Kid)
+	}
+	e, err := base64.RawURLEncoding.DecodeString(k.E)
+	if err != nil {
+		return nil, fmt.Errorf("invalid exponent for key %q", k.Kid)
+	}
+	return &rsa.PublicKey{
+		N: new(big.Int).SetBytes(n),
+		E: int(new(big.Int).SetBytes(e).Int64()),
+	}, nil
+}
@@ -0,0 +1,326 @@
+package imap
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"fmt"
+	"net"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	goimap "github.com/emersion/go-imap"
+	"github.com/emersion/go-imap/client"
+	"ai-assistant/internal/app/config"
+	"ai-assistant/internal/services/email"
+	"ai-assistant/internal/services/email/mailmime"
+	"ai-assistant/pkg/logger"
+)
+
+// IMAPService reads a single IMAP folder. It implements
+// email.MailboxProvider; message IDs have the form "<uidvalidity>:<uid>" so
+// they stay unique if the server renumbers the folder.
+//
+// Each call opens its own connection. That keeps the service safe for
+// concurrent use without a pool, at the cost of a login per operation.
+type IMAPService struct {
+	addr      string
+	username  string
+	password  string
+	mailbox   string
+	useTLS    bool
+	tlsConfig *tls.Config
+	logger    *logger.Logger
+}
+
+func NewIMAPService(cfg *config.Config) *IMAPService {
+	if cfg.Email.IMAP.Addr == "" {
+		return nil // IMAP is optional
+	}
+
+	host, _, _ := net.SplitHostPort(cfg.Email.IMAP.Addr)
+	return &IMAPService{
+		addr:      cfg.Email.IMAP.Addr,
+		username:  cfg.Email.IMAP.Username,
+		password:  cfg.Email.IMAP.Password,
+		mailbox:   cfg.Email.IMAP.Mailbox,
+		useTLS:    cfg.Email.IMAP.TLS,
+		tlsConfig: &tls.Config{ServerName: host},
+		logger:    logger.New(),
+	}
+}
+
+// List returns message references newest first. The page token is the UID
+// to continue below.
+func (s *IMAPService) List(ctx context.Context, opts email.ListOptions) (*email.ListResult, error) {
+	c, status, err := s.open(ctx, true)
+	if err != nil {
+		return nil, err
+	}
+	defer c.Logout()
+
+	criteria := goimap.NewSearchCriteria()
+	for _, label := range opts.Labels {
+		switch label {
+		case email.LabelUnread:
+			criteria.WithoutFlags = append(criteria.WithoutFlags, goimap.SeenFlag)
+		case email.LabelStarred:
+			criteria.WithFlags = append(criteria.WithFlags, goimap.FlaggedFlag)
+		case email.LabelInbox:
+		default:
+			criteria.WithFlags = append(criteria.WithFlags, keyword(label))
+		}
+	}
+	if opts.PageToken != "" {
+		before, err := strconv.ParseUint(opts.PageToken, 10, 32)
+		if err != nil || before <= 1 {
+			return &email.ListResult{}, nil
+		}
+		criteria.Uid = new(goimap.SeqSet)
+		criteria.Uid.AddRange(1, uint32(before-1))
+	}
+
+	uids, err := c.UidSearch(criteria)
+	if err != nil {
+		return nil, fmt.Errorf("failed to search mailbox: %w", err)
+	}
+	sort.Slice(uids, func(i, j int) bool { return uids[i] > uids[j] })
+
+	max := int(opts.MaxResults)
+	if max <= 0 {
+		max = 50
+	}
+
+	result := &email.ListResult{}
+	if len(uids) > max {
+		uids = uids[:max]
+		result.NextPageToken = strconv.FormatUint(uint64(uids[max-1]), 10)
+	}
+	for _, uid := range uids {
+		result.Messages = append(result.Messages, email.MessageRef{ID: formatID(status.UidValidity, uid)})
+	}
+	return result, nil
+}
+
+func (s *IMAPService) Fetch(ctx context.Context, id string) (*email.Message, error) {
+	validity, uid, err := parseID(id)
+	if err != nil {
+		return nil, err
+	}
+
+	c, status, err := s.open(ctx, true)
+	if err != nil {
+		return nil, err
+	}
+	defer c.Logout()
+
+	if status.UidValidity != validity {
+		return nil, fmt.Errorf("message %s no longer exists: mailbox UIDVALIDITY changed", id)
+	}
+
+	seqset := new(goimap.SeqSet)
+	seqset.AddNum(uid)
+	section := &goimap.BodySectionName{Peek: true}
+	items := []goimap.FetchItem{goimap.FetchFlags, goimap.FetchInternalDate, goimap.FetchUid, section.FetchItem()}
+
+	messages := make(chan *goimap.Message, 1)
+	if err := c.UidFetch(seqset, items, messages); err != nil {
+		return nil, fmt.Errorf("failed to fetch message: %w", err)
+	}
+
+	fetched := <-messages
+	if fetched == nil {
+		return nil, fmt.Errorf("message %s not found", id)
+	}
+
+	literal := fetched.GetBody(section)
+	if literal == nil {
+		return nil, fmt.Errorf("message %s has no body", id)
+	}
+
+	return toMessage(id, s.mailbox, fetched, literal)
+}
+
+// ModifyLabels maps UNREAD and STARRED onto the \Seen and \Flagged flags and
+// any other label onto an IMAP keyword.
+func (s *IMAPService) ModifyLabels(ctx context.Context, id string, add, remove []string) error {
+	validity, uid, err := parseID(id)
+	if err != nil {
+		return err
+	}
+
+	c, status, err := s.open(ctx, false)
+	if err != nil {
+		return err
+	}
+	defer c.Logout()
+
+	if status.UidValidity != validity {
+		return fmt.Errorf("message %s no longer exists: mailbox UIDVALIDITY changed", id)
+	}
+
+	seqset := new(goimap.SeqSet)
+	seqset.AddNum(uid)
+
+	var addFlags, removeFlags []interface{}
+	for _, label := range add {
+		if label == email.LabelUnread {
+			removeFlags = append(removeFlags, goimap.SeenFlag)
+		} else if flag := labelFlag(label); flag != "" {
+			addFlags = append(addFlags, flag)
+		}
+	}
+	for _, label := range remove {
+		if label == email.LabelUnread {
+			addFlags = append(addFlags, goimap.SeenFlag)
+		} else if flag := labelFlag(label); flag != "" {
+			removeFlags = append(removeFlags, flag)
+		}
+	}
+
+	if len(addFlags) > 0 {
+		if err := c.UidStore(seqset, goimap.FormatFlagsOp(goimap.AddFlags, true), addFlags, nil); err != nil {
+			return fmt.Errorf("failed to add flags: %w", err)
+		}
+	}
+	if len(removeFlags) > 0 {
+		if err := c.UidStore(seqset, goimap.FormatFlagsOp(goimap.RemoveFlags, true), removeFlags, nil); err != nil {
+			return fmt.Errorf("failed to remove flags: %w", err)
+		}
+	}
+	return nil
+}
+
+// open connects, logs in and selects the configured folder.
+func (s *IMAPService) open(ctx context.Context, readOnly bool) (*client.Client, *goimap.MailboxStatus, error) {
+	dialer := &net.Dialer{Timeout: 30 * time.Second}
+	conn, err := dialer.DialContext(ctx, "tcp", s.addr)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to connect to %s: %w", s.addr, err)
+	}
+	if deadline, ok := ctx.Deadline(); ok {
+		conn.SetDeadline(deadline)
+	}
+	if s.useTLS {
+		conn = tls.Client(conn, s.tlsConfig)
+	}
+
+	c, err := client.New(conn)
+	if err != nil {
+		conn.Close()
+		return nil, nil, fmt.Errorf("failed to start IMAP session: %w", err)
+	}
+
+	if err := c.Login(s.username, s.password); err != nil {
+		c.Logout()
+		return nil, nil, fmt.Errorf("failed to log in: %w", err)
+	}
+
+	status, err := c.Select(s.mailbox, readOnly)
+	if err != nil {
+		c.Logout()
+		return nil, nil, fmt.Errorf("failed to select %s: %w", s.mailbox, err)
+	}
+	return c, status, nil
+}
+
+func toMessage(id, mailbox string, fetched *goimap.Message, literal goimap.Literal) (*email.Message, error) {
+	raw := new(bytes.Buffer)
+	if _, err := raw.ReadFrom(literal); err != nil {
+		return nil, fmt.Errorf("failed to read message: %w", err)
+	}
+
+	header, root, err := mailmime.Parse(bytes.NewReader(raw.Bytes()))
+	if err != nil {
+		return nil, err
+	}
+	parsed := mailmime.Extract(root)
+
+	msg := &email.Message{
+		ID:          id,
+		Subject:     mailmime.DecodeHeader(header.Get("Subject")),
+		From:        mailmime.ParseAddress(header.Get("From")),
+		To:          mailmime.ParseAddressList(header.Get("To")),
+		Cc:          mailmime.ParseAddressList(header.Get("Cc")),
+		ReplyTo:     mailmime.ParseAddress(header.Get("Reply-To")),
+		HeaderID:    strings.TrimSpace(header.Get("Message-Id")),
+		InReplyTo:   strings.TrimSpace(header.Get("In-Reply-To")),
+		References:  strings.Fields(header.Get("References")),
+		Body:        parsed.Text,
+		HTMLBody:    parsed.HTML,
+		Attachments: parsed.Attachments,
+		IsRead:      true,
+	}
+
+	if date, err := header.Date(); err == nil {
+		msg.Date = date
+	} else {
+		msg.Date = fetched.InternalDate
+	}
+
+	if strings.EqualFold(mailbox, "INBOX") {
+		msg.Labels = append(msg.Labels, email.LabelInbox)
+	}
+	seen := false
+	for _, flag := range fetched.Flags {
+		switch flag {
+		case goimap.SeenFlag:
+			seen = true
+		case goimap.FlaggedFlag:
+			msg.Labels = append(msg.Labels, email.LabelStarred)
+		default:
+			if !strings.HasPrefix(flag, "\\") {
+				msg.Labels = append(msg.Labels, flag)
+			}
+		}
+	}
+	if !seen {
+		msg.IsRead = false
+		msg.Labels = append(msg.Labels, email.LabelUnread)
+	}
+
+	return msg, nil
+}
+
+func labelFlag(label string) string {
+	switch label {
+	case email.LabelStarred:
+		return goimap.FlaggedFlag
+	case email.LabelInbox:
+		return ""
+	default:
+		return keyword(label)
+	}
+}
+
+// keyword turns a label into a valid IMAP keyword atom.
+func keyword(label string) string {
+	return strings.Map(func(r rune) rune {
+		if r <= ' ' || r > '~' || strings.ContainsRune(`(){%*"\]`, r) {
+			return '_'
+		}
+		return r
+	}, label)
+}
+
+func formatID(validity, uid uint32) string {
+	return strconv.FormatUint(uint64(validity), 10) + ":" + strconv.FormatUint(uint64(uid), 10)
+}
+
+func parseID(id string) (uint32, uint32, error) {
+	validity, uid, ok := strings.Cut(id, ":")
+	if !ok {
+		return 0, 0, fmt.Errorf("invalid IMAP message id %q", id)
+	}
+	v, err := strconv.ParseUint(validity, 10, 32)
+	if err != nil {
+		return 0, 0, fmt.Errorf("invalid IMAP message id %q", id)
+	}
+	u, err := strconv.ParseUint(uid, 10, 32)
+	if err != nil {
+		return 0, 0, fmt.Errorf("invalid IMAP message id %q", id)
+	}
+	return uint32(v), uint32(u), nil
+}
@@ -0,0 +1,72 @@
+package mailmime
+
+import (
+	"net/mail"
+	"strings"
+)
+
+var addressParser = &mail.AddressParser{WordDecoder: wordDecoder}
+
+// ParseAddressList parses a To/Cc style header into display strings. Quoted
+// display names containing commas stay intact. If the header is malformed
+// the best-effort comma split is returned so that no recipient is lost.
+func ParseAddressList(value string) []string {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return nil
+	}
+
+	list, err := addressParser.ParseList(value)
+	if err != nil {
+		var fallback []string
+		for _, addr := range strings.Split(value, ",") {
+			if addr = strings.TrimSpace(addr); addr != "" {
+				fallback = append(fallback, DecodeHeader(addr))
+			}
+		}
+		return fallback
+	}
+
+	addresses := make([]string, 0, len(list))
+	for _, addr := range list {
+		addresses = append(addresses, FormatAddress(addr))
+	}
+	return addresses
+}
+
+// ParseAddress parses a single From style header into a display string.
+func ParseAddress(value string) string {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return ""
+	}
+
+	addr, err := addressParser.Parse(value)
+	if err != nil {
+		return DecodeHeader(value)
+	}
+	return FormatAddress(addr)
+}
+
+// FormatAddress renders an address as "Name <addr>" with the name decoded,
+// which is what the API returns and what the rest of the app stores. Names
+// containing specials are quoted so the result parses back unambiguously.
+func FormatAddress(addr *mail.Address) string {
+	if addr.Name == "" {
+		return addr.Address
+	}
+	name := addr.Name
+	if strings.ContainsAny(name, `,;:<>@"()[]\`) {
+		name = `"` + strings.NewReplacer(`\`, `\\`, `"`, `\"`).Replace(name) + `"`
+	}
+	return name + " <" + addr.Address + ">"
+}
+
+// AddressOnly extracts the bare mailbox from a display string.
+func AddressOnly(value string) string {
+	addr, err := addressParser.Parse(value)
+	if err != nil {
+		return strings.ToLower(strings.Trim(strings.TrimSpace(value), "<>"))
+	}
+	return strings.ToLower(addr.Address)
+}
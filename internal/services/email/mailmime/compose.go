@@ -0,0 +1,354 @@
+package mailmime
+
+import (
+	"bytes"
+	"crypto/rand"
+	"crypto/sha1"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"mime"
+	"mime/multipart"
+	"mime/quotedprintable"
+	"net/mail"
+	"net/textproto"
+	"strings"
+	"time"
+)
+
+// maxLineLength is the RFC 5322 recommended line length limit.
+const maxLineLength = 78
+
+// Outgoing describes a message to be sent. Addresses are accepted in any
+// form net/mail understands ("Name <addr>" or a bare address).
+type Outgoing struct {
+	From        string
+	To          []string
+	Cc          []string
+	Bcc         []string
+	ReplyTo     string
+	Subject     string
+	Text        string
+	HTML        string
+	Attachments []*Attachment
+	MessageID   string
+	InReplyTo   string
+	References  []string
+	Date        time.Time
+}
+
+// NewMessageID returns a globally unique Message-ID using the domain of the
+// sender address.
+func NewMessageID(from string) string {
+	domain := "localhost"
+	if addr := AddressOnly(from); strings.Contains(addr, "@") {
+		domain = addr[strings.LastIndex(addr, "@")+1:]
+	}
+
+	b := make([]byte, 16)
+	rand.Read(b)
+	return "<" + hex.EncodeToString(b) + "@" + domain + ">"
+}
+
+// Prepare validates the message and fills in Message-ID and Date when the
+// caller left them empty. Build calls it, but callers that need the
+// generated Message-ID before sending may call it first. From may be empty
+// for providers such as Gmail that fill it in from the authenticated user.
+func (m *Outgoing) Prepare() error {
+	if len(m.To)+len(m.Cc)+len(m.Bcc) == 0 {
+		return fmt.Errorf("at least one recipient is required")
+	}
+	if m.MessageID == "" {
+		m.MessageID = NewMessageID(m.From)
+	}
+	if m.Date.IsZero() {
+		m.Date = time.Now()
+	}
+	return nil
+}
+
+// Build renders the message as RFC 5322 bytes with CRLF line endings. The
+// Bcc header is only written when includeBcc is set, which the Gmail API
+// needs in order to deliver to (and then strip) blind recipients.
+func (m *Outgoing) Build(includeBcc bool) ([]byte, error) {
+	if err := m.Prepare(); err != nil {
+		return nil, err
+	}
+
+	var buf bytes.Buffer
+	writeHeader(&buf, "Date", m.Date.Format(time.RFC1123Z))
+	if m.From != "" {
+		from, err := formatAddressHeader([]string{m.From})
+		if err != nil {
+			return nil, fmt.Errorf("invalid From address: %w", err)
+		}
+		writeHeader(&buf, "From", from)
+	}
+
+	recipients := []struct {
+		name  string
+		list  []string
+		write bool
+	}{
+		{"To", m.To, true},
+		{"Cc", m.Cc, true},
+		{"Bcc", m.Bcc, includeBcc},
+	}
+	for _, r := range recipients {
+		if len(r.list) == 0 || !r.write {
+			continue
+		}
+		value, err := formatAddressHeader(r.list)
+		if err != nil {
+			return nil, fmt.Errorf("invalid %s address: %w", r.name, err)
+		}
+		writeHeader(&buf, r.name, value)
+	}
+
+	if m.ReplyTo != "" {
+		value, err := formatAddressHeader([]string{m.ReplyTo})
+		if err != nil {
+			return nil, fmt.Errorf("invalid Reply-To address: %w", err)
+		}
+		writeHeader(&buf, "Reply-To", value)
+	}
+
+	writeHeader(&buf, "Subject", EncodeHeader(m.Subject))
+	writeHeader(&buf, "Message-ID", m.MessageID)
+	if m.InReplyTo != "" {
+		writeHeader(&buf, "In-Reply-To", m.InReplyTo)
+	}
+	if len(m.References) > 0 {
+		writeHeader(&buf, "References", strings.Join(m.References, " "))
+	}
+	writeHeader(&buf, "MIME-Version", "1.0")
+
+	if err := m.writeBody(&buf); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// EncodeHeader returns value as-is when it is printable ASCII and as RFC 2047
+// encoded-words otherwise.
+func EncodeHeader(value string) string {
+	return mime.QEncoding.Encode("utf-8", value)
+}
+
+func (m *Outgoing) writeBody(buf *bytes.Buffer) error {
+	var inline, attached []*Attachment
+	for _, att := range m.Attachments {
+		if att.Inline && att.ContentID != "" && m.HTML != "" {
+			inline = append(inline, att)
+		} else {
+			attached = append(attached, att)
+		}
+	}
+
+	if len(attached) == 0 {
+		return m.writeAlternative(buf, nil, inline)
+	}
+
+	w := multipart.NewWriter(buf)
+	w.SetBoundary(m.boundary("mixed"))
+	writeHeader(buf, "Content-Type", "multipart/mixed; boundary=\""+w.Boundary()+"\"")
+	buf.WriteString("\r\n")
+
+	if err := m.writeAlternative(nil, w, inline); err != nil {
+		return err
+	}
+	for _, att := range attached {
+		if err := writeAttachment(w, att); err != nil {
+			return err
+		}
+	}
+	return w.Close()
+}
+
+// writeAlternative writes the text/HTML body either as the top-level entity
+// (parent == nil) or as a child of the given multipart writer.
+func (m *Outgoing) writeAlternative(top *bytes.Buffer, parent *multipart.Writer, inline []*Attachment) error {
+	hasText := m.Text != "" || m.HTML == ""
+	hasHTML := m.HTML != ""
+
+	if hasText && !hasHTML {
+		return writeTextPart(top, parent, "text/plain", m.Text)
+	}
+	if hasHTML && !hasText {
+		return m.writeRelated(top, parent, inline)
+	}
+
+	w, err := m.openMultipart(top, parent, "alternative")
+	if err != nil {
+		return err
+	}
+	if err := writeTextPart(nil, w, "text/plain", m.Text); err != nil {
+		return err
+	}
+	if err := m.writeRelated(nil, w, inline); err != nil {
+		return err
+	}
+	return w.Close()
+}
+
+func (m *Outgoing) writeRelated(top *bytes.Buffer, parent *multipart.Writer, inline []*Attachment) error {
+	if len(inline) == 0 {
+		return writeTextPart(top, parent, "text/html", m.HTML)
+	}
+
+	w, err := m.openMultipart(top, parent, "related")
+	if err != nil {
+		return err
+	}
+	if err := writeTextPart(nil, w, "text/html", m.HTML); err != nil {
+		return err
+	}
+	for _, att := range inline {
+		if err := writeAttachment(w, att); err != nil {
+			return err
+		}
+	}
+	return w.Close()
+}
+
+// openMultipart starts a nested multipart entity with a deterministic
+// boundary, writing its headers either at the top level or as a child part.
+func (m *Outgoing) openMultipart(top *bytes.Buffer, parent *multipart.Writer, subtype string) (*multipart.Writer, error) {
+	boundary := m.boundary(subtype)
+	contentType := "multipart/" + subtype + "; boundary=\"" + boundary + "\""
+
+	var body io.Writer
+	if parent == nil {
+		writeHeader(top, "Content-Type", contentType)
+		top.WriteString("\r\n")
+		body = top
+	} else {
+		part, err := parent.CreatePart(textproto.MIMEHeader{"Content-Type": {contentType}})
+		if err != nil {
+			return nil, err
+		}
+		body = part
+	}
+
+	w := multipart.NewWriter(body)
+	if err := w.SetBoundary(boundary); err != nil {
+		return nil, err
+	}
+	return w, nil
+}
+
+// boundary derives a per-message, per-level boundary from the Message-ID so
+// that output is reproducible for a given message.
+func (m *Outgoing) boundary(subtype string) string {
+	sum := sha1.Sum([]byte(m.MessageID + "/" + subtype))
+	return subtype + "_" + hex.EncodeToString(sum[:12])
+}
+
+func writeTextPart(top *bytes.Buffer, parent *multipart.Writer, mimeType, content string) error {
+	header := textproto.MIMEHeader{
+		"Content-Type":              {mimeType + "; charset=\"utf-8\""},
+		"Content-Transfer-Encoding": {"quoted-printable"},
+	}
+
+	var w io.Writer
+	if parent == nil {
+		for _, key := range []string{"Content-Type", "Content-Transfer-Encoding"} {
+			writeHeader(top, key, header.Get(key))
+		}
+		top.WriteString("\r\n")
+		w = top
+	} else {
+		part, err := parent.CreatePart(header)
+		if err != nil {
+			return err
+		}
+		w = part
+	}
+
+	qp := quotedprintable.NewWriter(w)
+	if _, err := qp.Write([]byte(normalizeNewlines(content))); err != nil {
+		return err
+	}
+	return qp.Close()
+}
+
+func writeAttachment(w *multipart.Writer, att *Attachment) error {
+	mimeType := att.MimeType
+	if mimeType == "" {
+		mimeType = "application/octet-stream"
+	}
+
+	disposition := "attachment"
+	if att.Inline {
+		disposition = "inline"
+	}
+
+	header := textproto.MIMEHeader{}
+	header.Set("Content-Type", mime.FormatMediaType(mimeType, map[string]string{"name": att.Filename}))
+	header.Set("Content-Disposition", mime.FormatMediaType(disposition, map[string]string{"filename": att.Filename}))
+	header.Set("Content-Transfer-Encoding", "base64")
+	if att.ContentID != "" {
+		header.Set("Content-ID", "<"+att.ContentID+">")
+	}
+
+	part, err := w.CreatePart(header)
+	if err != nil {
+		return err
+	}
+
+	encoded := base64.StdEncoding.EncodeToString(att.Data)
+	for len(encoded) > 76 {
+		if _, err := io.WriteString(part, encoded[:76]+"\r\n"); err != nil {
+			return err
+		}
+		encoded = encoded[76:]
+	}
+	_, err = io.WriteString(part, encoded)
+	return err
+}
+
+func formatAddressHeader(list []string) (string, error) {
+	formatted := make([]string, 0, len(list))
+	for _, raw := range list {
+		addrs, err := mail.ParseAddressList(raw)
+		if err != nil {
+			return "", fmt.Errorf("%q: %w", raw, err)
+		}
+		for _, addr := range addrs {
+			if addr.Name == "" {
+				formatted = append(formatted, addr.Address)
+				continue
+			}
+			formatted = append(formatted, addr.String())
+		}
+	}
+	return strings.Join(formatted, ", "), nil
+}
+
+// writeHeader writes a header line, folding it at spaces when it exceeds the
+// recommended line length. Encoded-words and message IDs contain no spaces
+// and are therefore never split.
+func writeHeader(buf *bytes.Buffer, name, value string) {
+	line := name + ": " + value
+	if len(line) <= maxLineLength {
+		buf.WriteString(line + "\r\n")
+		return
+	}
+
+	words := strings.Split(value, " ")
+	current := name + ":"
+	for i, word := range words {
+		if i > 0 && len(current)+1+len(word) > maxLineLength {
+			buf.WriteString(current + "\r\n")
+			current = ""
+		}
+		current += " " + word
+	}
+	buf.WriteString(current + "\r\n")
+}
+
+func normalizeNewlines(s string) string {
+	s = strings.ReplaceAll(s, "\r\n", "\n")
+	return strings.ReplaceAll(s, "\r", "\n")
+}
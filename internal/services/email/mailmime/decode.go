@@ -0,0 +1,102 @@
+package mailmime
+
+import (
+	"bytes"
+	"encoding/base64"
+	"fmt"
+	"io"
+	"mime"
+	"mime/quotedprintable"
+	"strings"
+	"unicode/utf8"
+
+	"golang.org/x/text/encoding/htmlindex"
+)
+
+var wordDecoder = &mime.WordDecoder{CharsetReader: CharsetReader}
+
+// CharsetReader returns a reader converting from the named charset to UTF-8.
+// It is shaped to plug into mime.WordDecoder and mail.AddressParser.
+func CharsetReader(charset string, input io.Reader) (io.Reader, error) {
+	charset = strings.ToLower(strings.TrimSpace(charset))
+	if charset == "" || charset == "utf-8" || charset == "us-ascii" {
+		return input, nil
+	}
+
+	enc, err := htmlindex.Get(charset)
+	if err != nil {
+		return nil, fmt.Errorf("unsupported charset %q: %w", charset, err)
+	}
+	return enc.NewDecoder().Reader(input), nil
+}
+
+// DecodeCharset converts body from charset to UTF-8. Unknown charsets fall
+// back to the raw bytes so a message is never dropped for its encoding.
+func DecodeCharset(body []byte, charset string) string {
+	reader, err := CharsetReader(charset, bytes.NewReader(body))
+	if err != nil {
+		return toValidUTF8(body)
+	}
+
+	decoded, err := io.ReadAll(reader)
+	if err != nil {
+		return toValidUTF8(body)
+	}
+	return toValidUTF8(decoded)
+}
+
+// DecodeHeader decodes RFC 2047 encoded-words in a header value.
+func DecodeHeader(value string) string {
+	if value == "" {
+		return ""
+	}
+	decoded, err := wordDecoder.DecodeHeader(value)
+	if err != nil {
+		return value
+	}
+	return decoded
+}
+
+// DecodeTransfer undoes a Content-Transfer-Encoding.
+func DecodeTransfer(body []byte, encoding string) ([]byte, error) {
+	switch strings.ToLower(strings.TrimSpace(encoding)) {
+	case "base64":
+		cleaned := bytes.Map(func(r rune) rune {
+			if r == '\r' || r == '\n' || r == ' ' || r == '\t' {
+				return -1
+			}
+			return r
+		}, body)
+		decoded := make([]byte, base64.StdEncoding.DecodedLen(len(cleaned)))
+		n, err := base64.StdEncoding.Decode(decoded, cleaned)
+		if err != nil {
+			// Some senders omit padding.
+			n, err = base64.RawStdEncoding.Decode(decoded, bytes.TrimRight(cleaned, "="))
+			if err != nil {
+				return nil, fmt.Errorf("failed to decode base64 body: %w", err)
+			}
+		}
+		return decoded[:n], nil
+	case "quoted-printable":
+		decoded, err := io.ReadAll(quotedprintable.NewReader(bytes.NewReader(body)))
+		if err != nil {
+			return nil, fmt.Errorf("failed to decode quoted-printable body: %w", err)
+		}
+		return decoded, nil
+	default:
+		return body, nil
+	}
+}
+
+// DecodeBase64URL decodes the base64url payloads returned by the Gmail API,
+// which may or may not be padded.
+func DecodeBase64URL(data string) ([]byte, error) {
+	return base64.RawURLEncoding.DecodeString(strings.TrimRight(data, "="))
+}
+
+func toValidUTF8(b []byte) string {
+	if utf8.Valid(b) {
+		return string(b)
+	}
+	return strings.ToValidUTF8(string(b), "�")
+}
@@ -0,0 +1,82 @@
+package mailmime
+
+import (
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net/mail"
+	"net/textproto"
+)
+
+// maxDepth bounds multipart nesting so a hostile message cannot recurse
+// without limit.
+const maxDepth = 16
+
+// Parse reads a raw RFC 5322 message and returns its top-level headers and
+// the decoded MIME tree.
+func Parse(r io.Reader) (mail.Header, *Part, error) {
+	msg, err := mail.ReadMessage(r)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to read message: %w", err)
+	}
+
+	root, err := parsePart(textproto.MIMEHeader(msg.Header), msg.Body, 0)
+	if err != nil {
+		return nil, nil, err
+	}
+	return msg.Header, root, nil
+}
+
+func parsePart(header textproto.MIMEHeader, body io.Reader, depth int) (*Part, error) {
+	part := NewPart(header)
+
+	if part.IsMultipart() && depth < maxDepth {
+		boundary := part.Params["boundary"]
+		if boundary == "" {
+			return nil, fmt.Errorf("multipart part without boundary")
+		}
+
+		reader := multipart.NewReader(body, boundary)
+		for {
+			child, err := reader.NextRawPart()
+			if err == io.EOF {
+				break
+			}
+			if err != nil {
+				return nil, fmt.Errorf("failed to read multipart body: %w", err)
+			}
+
+			parsed, err := parsePart(child.Header, child, depth+1)
+			if err != nil {
+				return nil, err
+			}
+			part.Parts = append(part.Parts, parsed)
+		}
+		return part, nil
+	}
+
+	if part.MimeType == "message/rfc822" {
+		raw, err := io.ReadAll(body)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read part body: %w", err)
+		}
+		part.Body = raw
+		part.Size = int64(len(raw))
+		return part, nil
+	}
+
+	raw, err := io.ReadAll(body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read part body: %w", err)
+	}
+
+	decoded, err := DecodeTransfer(raw, header.Get("Content-Transfer-Encoding"))
+	if err != nil {
+		// Keep the undecoded bytes rather than failing the whole message.
+		decoded = raw
+	}
+	part.Body = decoded
+	part.Size = int64(len(part.Body))
+
+	return part, nil
+}
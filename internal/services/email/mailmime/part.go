@@ -0,0 +1,206 @@
+package mailmime
+
+import (
+	"mime"
+	"net/textproto"
+	"strings"
+)
+
+// Part is a node of a MIME tree. Body holds the transfer-decoded bytes of a
+// leaf part; multipart containers carry their children in Parts instead.
+type Part struct {
+	Header       textproto.MIMEHeader
+	MimeType     string
+	Params       map[string]string
+	Filename     string
+	Body         []byte
+	Size         int64
+	AttachmentID string
+	Parts        []*Part
+}
+
+// Message is the flattened, display-ready view of a MIME tree.
+type Message struct {
+	Text        string
+	HTML        string
+	Attachments []*Attachment
+}
+
+// Attachment describes a non-body part. Data is only populated when the part
+// content was available at parse time (raw messages); Gmail leaves it empty
+// and exposes AttachmentID for a later fetch.
+type Attachment struct {
+	Filename     string
+	MimeType     string
+	Size         int64
+	AttachmentID string
+	ContentID    string
+	Inline       bool
+	Data         []byte
+}
+
+// NewPart builds a Part from its headers, filling in the media type,
+// parameters and filename the way every caller needs them.
+func NewPart(header textproto.MIMEHeader) *Part {
+	p := &Part{Header: header}
+
+	mediaType, params, err := mime.ParseMediaType(header.Get("Content-Type"))
+	if err != nil || mediaType == "" {
+		mediaType = "text/plain"
+		params = map[string]string{}
+	}
+	p.MimeType = strings.ToLower(mediaType)
+	p.Params = params
+
+	if _, dispParams, err := mime.ParseMediaType(header.Get("Content-Disposition")); err == nil {
+		p.Filename = DecodeHeader(dispParams["filename"])
+	}
+	if p.Filename == "" {
+		p.Filename = DecodeHeader(params["name"])
+	}
+
+	return p
+}
+
+// IsMultipart reports whether the part is a container.
+func (p *Part) IsMultipart() bool {
+	return strings.HasPrefix(p.MimeType, "multipart/")
+}
+
+// Disposition returns the lower-cased Content-Disposition type, if any.
+func (p *Part) Disposition() string {
+	disposition, _, err := mime.ParseMediaType(p.Header.Get("Content-Disposition"))
+	if err != nil {
+		return ""
+	}
+	return strings.ToLower(disposition)
+}
+
+// ContentID returns the Content-ID header without its angle brackets.
+func (p *Part) ContentID() string {
+	return strings.Trim(strings.TrimSpace(p.Header.Get("Content-Id")), "<>")
+}
+
+// Text returns the part body converted to UTF-8 using its charset parameter.
+func (p *Part) Text() string {
+	return DecodeCharset(p.Body, p.Params["charset"])
+}
+
+// Walk visits the tree depth-first, parents before children.
+func Walk(p *Part, fn func(*Part)) {
+	if p == nil {
+		return
+	}
+	fn(p)
+	for _, child := range p.Parts {
+		Walk(child, fn)
+	}
+}
+
+// Extract flattens a MIME tree into its preferred text and HTML bodies plus
+// attachment metadata. Within multipart/alternative the last (richest)
+// alternative of each type wins; in multipart/mixed successive inline text
+// parts are concatenated, mirroring how mail clients display them.
+func Extract(root *Part) *Message {
+	msg := &Message{}
+	extract(root, msg)
+	return msg
+}
+
+func extract(p *Part, msg *Message) {
+	if p == nil {
+		return
+	}
+
+	if p.IsMultipart() {
+		if p.MimeType == "multipart/alternative" {
+			extractAlternative(p, msg)
+			return
+		}
+		for _, child := range p.Parts {
+			extract(child, msg)
+		}
+		return
+	}
+
+	if isAttachment(p) {
+		msg.Attachments = append(msg.Attachments, newAttachment(p))
+		return
+	}
+
+	switch p.MimeType {
+	case "text/html":
+		msg.HTML = joinBody(msg.HTML, p.Text())
+	case "text/plain":
+		msg.Text = joinBody(msg.Text, p.Text())
+	default:
+		msg.Attachments = append(msg.Attachments, newAttachment(p))
+	}
+}
+
+func extractAlternative(p *Part, msg *Message) {
+	var text, html string
+	for _, child := range p.Parts {
+		if isAttachment(child) {
+			msg.Attachments = append(msg.Attachments, newAttachment(child))
+			continue
+		}
+		switch {
+		case child.IsMultipart():
+			// multipart/related wrapping the HTML body, or a nested
+			// alternative; let it contribute its own bodies.
+			sub := &Message{}
+			extract(child, sub)
+			if sub.HTML != "" {
+				html = sub.HTML
+			}
+			if sub.Text != "" {
+				text = sub.Text
+			}
+			msg.Attachments = append(msg.Attachments, sub.Attachments...)
+		case child.MimeType == "text/html":
+			html = child.Text()
+		case child.MimeType == "text/plain":
+			text = child.Text()
+		}
+	}
+	msg.Text = joinBody(msg.Text, text)
+	msg.HTML = joinBody(msg.HTML, html)
+}
+
+func isAttachment(p *Part) bool {
+	if p.Disposition() == "attachment" {
+		return true
+	}
+	if p.Filename != "" {
+		return true
+	}
+	// Inline images referenced from an HTML body via cid: URLs.
+	return p.ContentID() != "" && !strings.HasPrefix(p.MimeType, "text/")
+}
+
+func newAttachment(p *Part) *Attachment {
+	size := p.Size
+	if size == 0 {
+		size = int64(len(p.Body))
+	}
+	return &Attachment{
+		Filename:     p.Filename,
+		MimeType:     p.MimeType,
+		Size:         size,
+		AttachmentID: p.AttachmentID,
+		ContentID:    p.ContentID(),
+		Inline:       p.Disposition() == "inline" || (p.Disposition() == "" && p.ContentID() != ""),
+		Data:         p.Body,
+	}
+}
+
+func joinBody(existing, next string) string {
+	if existing == "" {
+		return next
+	}
+	if next == "" {
+		return existing
+	}
+	return existing + "\n" + next
+}
@@ -0,0 +1,184 @@
+package mailmime
+
+import (
+	"html"
+	"regexp"
+	"strings"
+	"time"
+)
+
+var (
+	replyPrefix   = regexp.MustCompile(`(?i)^\s*(re|aw|sv)\s*:`)
+	forwardPrefix = regexp.MustCompile(`(?i)^\s*(fwd?|wg)\s*:`)
+)
+
+// Original is the subset of a received message needed to reply to or
+// forward it.
+type Original struct {
+	From       string
+	To         []string
+	Cc         []string
+	ReplyTo    string
+	Subject    string
+	Date       time.Time
+	MessageID  string
+	References []string
+	Text       string
+	HTML       string
+}
+
+// ReplySubject prefixes subject with "Re: " unless it already has a reply
+// prefix, so Gmail keeps the reply in the same conversation.
+func ReplySubject(subject string) string {
+	if replyPrefix.MatchString(subject) {
+		return subject
+	}
+	return "Re: " + subject
+}
+
+// ForwardSubject prefixes subject with "Fwd: " unless already forwarded.
+func ForwardSubject(subject string) string {
+	if forwardPrefix.MatchString(subject) {
+		return subject
+	}
+	return "Fwd: " + subject
+}
+
+// ReplyReferences returns the References chain for a reply: the original's
+// References followed by its Message-ID (RFC 5322 section 3.6.4).
+func ReplyReferences(orig *Original) []string {
+	refs := append([]string{}, orig.References...)
+	if orig.MessageID != "" && (len(refs) == 0 || refs[len(refs)-1] != orig.MessageID) {
+		refs = append(refs, orig.MessageID)
+	}
+	return refs
+}
+
+// ReplyRecipients computes To and Cc for a reply. A plain reply goes to the
+// Reply-To (or From) address. Reply-all adds the original To and Cc. Any
+// address belonging to the user (self) is removed and duplicates collapse,
+// except that a reply to one's own sent message goes back to its recipients.
+func ReplyRecipients(orig *Original, self []string, replyAll bool) (to, cc []string) {
+	isSelf := map[string]bool{}
+	for _, addr := range self {
+		isSelf[AddressOnly(addr)] = true
+	}
+
+	seen := map[string]bool{}
+	add := func(list []string, addr string) []string {
+		key := AddressOnly(addr)
+		if key == "" || isSelf[key] || seen[key] {
+			return list
+		}
+		seen[key] = true
+		return append(list, addr)
+	}
+
+	primary := orig.ReplyTo
+	if primary == "" {
+		primary = orig.From
+	}
+
+	if isSelf[AddressOnly(orig.From)] {
+		for _, addr := range orig.To {
+			to = add(to, addr)
+		}
+	} else {
+		to = add(to, primary)
+	}
+
+	if !replyAll {
+		return to, nil
+	}
+
+	for _, addr := range orig.To {
+		to = add(to, addr)
+	}
+	for _, addr := range orig.Cc {
+		cc = add(cc, addr)
+	}
+	return to, cc
+}
+
+// QuoteText renders the original below an attribution line with each line
+// prefixed by "> ".
+func QuoteText(orig *Original) string {
+	var b strings.Builder
+	b.WriteString(attribution(orig))
+	b.WriteString("\n")
+	for _, line := range strings.Split(strings.TrimRight(normalizeNewlines(orig.Text), "\n"), "\n") {
+		if strings.HasPrefix(line, ">") {
+			b.WriteString(">" + line + "\n")
+		} else {
+			b.WriteString("> " + line + "\n")
+		}
+	}
+	return b.String()
+}
+
+// QuoteHTML renders the original inside a blockquote, falling back to the
+// escaped text body when the original had no HTML part.
+func QuoteHTML(orig *Original) string {
+	body := orig.HTML
+	if body == "" {
+		body = textToHTML(orig.Text)
+	}
+	return `<div class="quote"><div>` + html.EscapeString(attribution(orig)) + `</div>` +
+		`<blockquote style="margin:0 0 0 .8ex;border-left:1px solid #ccc;padding-left:1ex">` +
+		body + `</blockquote></div>`
+}
+
+// ForwardText renders the original as an inline forwarded message.
+func ForwardText(orig *Original) string {
+	var b strings.Builder
+	b.WriteString("---------- Forwarded message ---------\n")
+	b.WriteString("From: " + orig.From + "\n")
+	if !orig.Date.IsZero() {
+		b.WriteString("Date: " + orig.Date.Format(time.RFC1123Z) + "\n")
+	}
+	b.WriteString("Subject: " + orig.Subject + "\n")
+	if len(orig.To) > 0 {
+		b.WriteString("To: " + strings.Join(orig.To, ", ") + "\n")
+	}
+	if len(orig.Cc) > 0 {
+		b.WriteString("Cc: " + strings.Join(orig.Cc, ", ") + "\n")
+	}
+	b.WriteString("\n")
+	b.WriteString(normalizeNewlines(orig.Text))
+	return b.String()
+}
+
+// ForwardHTML is the HTML counterpart of ForwardText.
+func ForwardHTML(orig *Original) string {
+	body := orig.HTML
+	if body == "" {
+		body = textToHTML(orig.Text)
+	}
+
+	var b strings.Builder
+	b.WriteString(`<div class="forward">---------- Forwarded message ---------<br>`)
+	b.WriteString("From: " + html.EscapeString(orig.From) + "<br>")
+	if !orig.Date.IsZero() {
+		b.WriteString("Date: " + html.EscapeString(orig.Date.Format(time.RFC1123Z)) + "<br>")
+	}
+	b.WriteString("Subject: " + html.EscapeString(orig.Subject) + "<br>")
+	if len(orig.To) > 0 {
+		b.WriteString("To: " + html.EscapeString(strings.Join(orig.To, ", ")) + "<br>")
+	}
+	if len(orig.Cc) > 0 {
+		b.WriteString("Cc: " + html.EscapeString(strings.Join(orig.Cc, ", ")) + "<br>")
+	}
+	b.WriteString("<br>" + body + "</div>")
+	return b.String()
+}
+
+func attribution(orig *Original) string {
+	if orig.Date.IsZero() {
+		return orig.From + " wrote:"
+	}
+	return "On " + orig.Date.Format("Mon, Jan 2, 2006 at 3:04 PM") + ", " + orig.From + " wrote:"
+}
+
+func textToHTML(text string) string {
+	return strings.ReplaceAll(html.EscapeString(normalizeNewlines(text)), "\n", "<br>")
+}
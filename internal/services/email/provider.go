@@ -0,0 +1,150 @@
+// Package email defines the provider-neutral contracts the usecases use to
+// read mailboxes and send mail. Gmail, Resend, IMAP and SMTP each adapt to
+// these interfaces in their own packages.
+package email
+
+import (
+	"context"
+	"time"
+
+	"ai-assistant/internal/services/email/mailmime"
+)
+
+// Well-known labels. Providers without native labels map these onto their
+// own concepts (IMAP uses the \Seen and \Flagged flags).
+const (
+	LabelInbox   = "INBOX"
+	LabelUnread  = "UNREAD"
+	LabelStarred = "STARRED"
+	LabelSent    = "SENT"
+)
+
+// Message is a fetched message in provider-neutral form.
+type Message struct {
+	ID          string
+	ThreadID    string
+	Subject     string
+	From        string
+	To          []string
+	Cc          []string
+	ReplyTo     string
+	Date        time.Time
+	HeaderID    string
+	InReplyTo   string
+	References  []string
+	Body        string
+	HTMLBody    string
+	Labels      []string
+	IsRead      bool
+	Attachments []*mailmime.Attachment
+}
+
+type MessageRef struct {
+	ID       string
+	ThreadID string
+}
+
+type ListOptions struct {
+	MaxResults int64
+	PageToken  string
+	Labels     []string
+}
+
+type ListResult struct {
+	Messages      []MessageRef
+	NextPageToken string
+}
+
+// MailboxProvider reads and organizes a user's mailbox.
+type MailboxProvider interface {
+	List(ctx context.Context, opts ListOptions) (*ListResult, error)
+	Fetch(ctx context.Context, id string) (*Message, error)
+	ModifyLabels(ctx context.Context, id string, add, remove []string) error
+}
+
+type SendOptions struct {
+	// ThreadID asks providers with server-side threads to file the message
+	// into an existing conversation. Others ignore it.
+	ThreadID string
+}
+
+type SendResult struct {
+	ID       string
+	ThreadID string
+}
+
+// Sender delivers composed messages. Name identifies the service in
+// delivery tracking, e.g. "resend".
+type Sender interface {
+	Name() string
+	Send(ctx context.Context, msg *mailmime.Outgoing, opts SendOptions) (*SendResult, error)
+}
+
+// MailboxFactory returns the mailbox of a user, or nil if they have none,
+// such as a Gmail user who has not linked a Google account.
+type MailboxFactory func(ctx context.Context, userID string) (MailboxProvider, error)
+
+// SenderFactory returns the sender a user's mail goes out through, or nil if
+// they have none.
+type SenderFactory func(ctx context.Context, userID string) (Sender, error)
+
+// SharedMailbox returns a factory giving every user mailbox, as IMAP is
+// configured once for the deployment. It returns nil for a nil mailbox.
+func SharedMailbox(mailbox MailboxProvider) MailboxFactory {
+	if mailbox == nil {
+		return nil
+	}
+	return func(context.Context, string) (MailboxProvider, error) { return mailbox, nil }
+}
+
+// SharedSender returns a factory giving every user sender, as Resend and
+// SMTP are configured once for the deployment. It returns nil for a nil
+// sender.
+func SharedSender(sender Sender) SenderFactory {
+	if sender == nil {
+		return nil
+	}
+	return func(context.Context, string) (Sender, error) { return sender, nil }
+}
+
+// DraftStore is implemented by providers that keep drafts server-side.
+type DraftStore interface {
+	CreateDraft(ctx context.Context, threadID string, msg *mailmime.Outgoing) (string, error)
+	UpdateDraft(ctx context.Context, draftID, threadID string, msg *mailmime.Outgoing) error
+	DeleteDraft(ctx context.Context, draftID string) error
+	SendDraft(ctx context.Context, draftID string) (*SendResult, error)
+}
+
+// AttachmentFetcher is implemented by providers that leave attachment
+// content on the server until asked for it, identified by the message ID
+// and the attachment's AttachmentID.
+type AttachmentFetcher interface {
+	FetchAttachment(ctx context.Context, messageID, attachmentID string) ([]byte, error)
+}
+
+// Label is a mailbox label as the provider knows it. ID is what messages
+// carry in their label lists.
+type Label struct {
+	ID   string
+	Name string
+	// System labels (INBOX, UNREAD, ...) cannot be renamed or deleted.
+	System bool
+}
+
+// LabelStore is implemented by providers with user-defined labels.
+type LabelStore interface {
+	ListLabels(ctx context.Context) ([]Label, error)
+	CreateLabel(ctx context.Context, name string) (*Label, error)
+	RenameLabel(ctx context.Context, id, name string) error
+	DeleteLabel(ctx context.Context, id string) error
+}
+
+// HasLabel reports whether labels contains label.
+func HasLabel(labels []string, label string) bool {
+	for _, l := range labels {
+		if l == label {
+			return true
+		}
+	}
+	return false
+}
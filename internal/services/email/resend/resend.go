@@ -2,27 +2,49 @@ package resend
 
 import (
 	"bytes"
+	"context"
+	"encoding/base64"
 	"encoding/json"
 	"fmt"
 	"net/http"
+	"strings"
 
 	"ai-assistant/internal/app/config"
+	"ai-assistant/internal/services/email"
+	"ai-assistant/internal/services/email/mailmime"
 	"ai-assistant/pkg/logger"
+	"ai-assistant/pkg/tracing"
 )
 
+// ProviderName identifies Resend in delivery tracking.
+const ProviderName = "resend"
+
 type ResendService struct {
 	apiKey     string
+	fromEmail  string
 	baseURL    string
 	httpClient *http.Client
 	logger     *logger.Logger
 }
 
 type EmailRequest struct {
-	From    string   `json:"from"`
-	To      []string `json:"to"`
-	Subject string   `json:"subject"`
-	HTML    string   `json:"html,omitempty"`
-	Text    string   `json:"text,omitempty"`
+	From        string            `json:"from"`
+	To          []string          `json:"to"`
+	Cc          []string          `json:"cc,omitempty"`
+	Bcc         []string          `json:"bcc,omitempty"`
+	ReplyTo     string            `json:"reply_to,omitempty"`
+	Subject     string            `json:"subject"`
+	HTML        string            `json:"html,omitempty"`
+	Text        string            `json:"text,omitempty"`
+	Headers     map[string]string `json:"headers,omitempty"`
+	Attachments []Attachment      `json:"attachments,omitempty"`
+}
+
+type Attachment struct {
+	Filename    string `json:"filename"`
+	Content     string `json:"content"`
+	ContentType string `json:"content_type,omitempty"`
+	ContentID   string `json:"content_id,omitempty"`
 }
 
 type EmailResponse struct {
@@ -32,21 +54,96 @@ type EmailResponse struct {
 func NewResendService(cfg *config.Config) *ResendService {
 	return &ResendService{
 		apiKey:     cfg.Email.ResendAPIKey,
+		fromEmail:  cfg.Email.ResendFromEmail,
 		baseURL:    "https://api.resend.com",
-		httpClient: &http.Client{},
+		httpClient: &http.Client{Transport: tracing.Transport(nil)},
 		logger:     logger.New(),
 	}
 }
 
+// NewEmailRequest builds a Resend request from a composed message. Resend
+// renders the MIME structure itself, so the message is passed as fields and
+// the threading headers are forwarded verbatim.
+func NewEmailRequest(msg *mailmime.Outgoing) (EmailRequest, error) {
+	if err := msg.Prepare(); err != nil {
+		return EmailRequest{}, err
+	}
+
+	req := EmailRequest{
+		From:    msg.From,
+		To:      msg.To,
+		Cc:      msg.Cc,
+		Bcc:     msg.Bcc,
+		ReplyTo: msg.ReplyTo,
+		Subject: msg.Subject,
+		HTML:    msg.HTML,
+		Text:    msg.Text,
+		Headers: map[string]string{
+			"Message-ID": msg.MessageID,
+		},
+	}
+	if msg.InReplyTo != "" {
+		req.Headers["In-Reply-To"] = msg.InReplyTo
+	}
+	if len(msg.References) > 0 {
+		req.Headers["References"] = strings.Join(msg.References, " ")
+	}
+
+	for _, att := range msg.Attachments {
+		req.Attachments = append(req.Attachments, Attachment{
+			Filename:    att.Filename,
+			Content:     base64.StdEncoding.EncodeToString(att.Data),
+			ContentType: att.MimeType,
+			ContentID:   att.ContentID,
+		})
+	}
+
+	return req, nil
+}
+
+func (r *ResendService) Name() string {
+	return ProviderName
+}
+
+// Send implements email.Sender. Resend only sends from verified domains, so
+// the message goes out from the configured address and the composing user
+// becomes the Reply-To; answers still reach them.
+func (r *ResendService) Send(ctx context.Context, msg *mailmime.Outgoing, opts email.SendOptions) (*email.SendResult, error) {
+	req, err := NewEmailRequest(msg)
+	if err != nil {
+		return nil, err
+	}
+	if req.From != "" && req.From != r.fromEmail {
+		if req.ReplyTo == "" {
+			req.ReplyTo = req.From
+		}
+		req.From = ""
+	}
+
+	resp, err := r.sendEmail(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+	return &email.SendResult{ID: resp.ID}, nil
+}
+
 func (r *ResendService) SendEmail(req EmailRequest) (*EmailResponse, error) {
-	r.logger.Infof("Sending email to %v", req.To)
+	return r.sendEmail(context.Background(), req)
+}
+
+func (r *ResendService) sendEmail(ctx context.Context, req EmailRequest) (*EmailResponse, error) {
+	r.logger.Info("Sending email", "provider", "resend", "recipients", len(req.To))
+
+	if req.From == "" {
+		req.From = r.fromEmail
+	}
 
 	jsonData, err := json.Marshal(req)
 	if err != nil {
 		return nil, fmt.Errorf("failed to marshal request: %w", err)
 	}
 
-	httpReq, err := http.NewRequest("POST", r.baseURL+"/emails", bytes.NewBuffer(jsonData))
+	httpReq, err := http.NewRequestWithContext(ctx, "POST", r.baseURL+"/emails", bytes.NewBuffer(jsonData))
 	if err != nil {
 		return nil, fmt.Errorf("failed to create request: %w", err)
 	}
@@ -69,6 +166,6 @@ func (r *ResendService) SendEmail(req EmailRequest) (*EmailResponse, error) {
 		return nil, fmt.Errorf("failed to decode response: %w", err)
 	}
 
-	r.logger.Infof("Email sent successfully with ID: %s", emailResp.ID)
+	r.logger.Info("Message sent", "provider", "resend", "message_id", emailResp.ID)
 	return &emailResp, nil
 }
\ No newline at end of file
@@ -0,0 +1,134 @@
+package resend
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"ai-assistant/internal/models"
+)
+
+// webhookTolerance is how far a webhook's timestamp may be from now, which
+// bounds how long a captured request can be replayed.
+const webhookTolerance = 5 * time.Minute
+
+// WebhookVerifier checks the Svix signatures Resend signs webhooks with.
+type WebhookVerifier struct {
+	secret []byte
+}
+
+// NewWebhookVerifier takes the endpoint's signing secret as shown in the
+// Resend dashboard, "whsec_" followed by base64.
+func NewWebhookVerifier(secret string) (*WebhookVerifier, error) {
+	key, err := base64.StdEncoding.DecodeString(strings.TrimPrefix(secret, "whsec_"))
+	if err != nil || len(key) == 0 {
+		return nil, fmt.Errorf("invalid webhook secret")
+	}
+	return &WebhookVerifier{secret: key}, nil
+}
+
+// Verify checks the svix-id, svix-timestamp and svix-signature headers
+// against body, which must be the raw request body.
+func (v *WebhookVerifier) Verify(header http.Header, body []byte) error {
+	id := header.Get("svix-id")
+	timestamp := header.Get("svix-timestamp")
+	signatures := header.Get("svix-signature")
+	if id == "" || timestamp == "" || signatures == "" {
+		return fmt.Errorf("missing signature headers")
+	}
+
+	seconds, err := strconv.ParseInt(timestamp, 10, 64)
+	if err != nil {
+		return fmt.Errorf("invalid signature timestamp")
+	}
+	if age := time.Since(time.Unix(seconds, 0)); age > webhookTolerance || age < -webhookTolerance {
+		return fmt.Errorf("signature timestamp out of tolerance")
+	}
+
+	expected := v.sign(id, timestamp, body)
+	// The header lists space-separated "v1,<base64>" signatures, several
+	// while a secret is being rotated.
+	for _, sig := range strings.Fields(signatures) {
+		version, value, ok := strings.Cut(sig, ",")
+		if !ok || version != "v1" {
+			continue
+		}
+		decoded, err := base64.StdEncoding.DecodeString(value)
+		if err == nil && hmac.Equal(decoded, expected) {
+			return nil
+		}
+	}
+	return fmt.Errorf("no matching signature")
+}
+
+func (v *WebhookVerifier) sign(id, timestamp string, body []byte) []byte {
+	mac := hmac.New(sha256.New, v.secret)
+	mac.Write([]byte(id + "." + timestamp + "."))
+	mac.Write(body)
+	return mac.Sum(nil)
+}
+
+// Event is a Resend webhook payload.
+type Event struct {
+	Type      string    `json:"type"`
+	CreatedAt time.Time `json:"created_at"`
+	Data      struct {
+		EmailID string   `json:"email_id"`
+		To      []string `json:"to"`
+		Subject string   `json:"subject"`
+		Bounce  *struct {
+			Type    string `json:"type"`
+			SubType string `json:"subType"`
+			Message string `json:"message"`
+		} `json:"bounce,omitempty"`
+	} `json:"data"`
+}
+
+func ParseEvent(body []byte) (*Event, error) {
+	var event Event
+	if err := json.Unmarshal(body, &event); err != nil {
+		return nil, fmt.Errorf("invalid webhook body: %w", err)
+	}
+	if event.Type == "" || event.Data.EmailID == "" {
+		return nil, fmt.Errorf("webhook needs type and data.email_id")
+	}
+	return &event, nil
+}
+
+var eventStatuses = map[string]models.OutboundStatus{
+	"email.sent":       models.OutboundStatusSent,
+	"email.delivered":  models.OutboundStatusDelivered,
+	"email.opened":     models.OutboundStatusOpened,
+	"email.bounced":    models.OutboundStatusBounced,
+	"email.complained": models.OutboundStatusComplained,
+}
+
+// DeliveryEvent converts the event for delivery tracking. It returns false
+// for event types that are not tracked, such as clicks.
+func (e *Event) DeliveryEvent(eventID string) (*models.DeliveryEvent, bool) {
+	status, ok := eventStatuses[e.Type]
+	if !ok {
+		return nil, false
+	}
+
+	event := &models.DeliveryEvent{
+		Provider:          ProviderName,
+		ProviderEventID:   eventID,
+		ProviderMessageID: e.Data.EmailID,
+		Status:            status,
+		OccurredAt:        e.CreatedAt,
+	}
+	if e.Data.Bounce != nil {
+		event.Detail = strings.TrimSpace(e.Data.Bounce.Type + " " + e.Data.Bounce.Message)
+	}
+	if event.OccurredAt.IsZero() {
+		event.OccurredAt = time.Now()
+	}
+	return event, true
+}
@@ -0,0 +1,153 @@
+// Package rules validates user-defined mail rules and matches them against
+// emails. Running the actions is left to the rule usecase.
+package rules
+
+import (
+	"fmt"
+	"net/mail"
+	"regexp"
+	"strings"
+
+	"ai-assistant/internal/models"
+)
+
+const (
+	maxActions  = 10
+	maxKeywords = 50
+	// maxRegexLength bounds the subject expression; RE2 runs in linear time,
+	// but compiling a huge expression for every synced email is still waste.
+	maxRegexLength = 512
+)
+
+// Matcher is a compiled rule.
+type Matcher struct {
+	Rule     *models.Rule
+	subject  *regexp.Regexp
+	keywords []*regexp.Regexp
+}
+
+// Compile validates a rule and prepares it for matching.
+func Compile(rule *models.Rule) (*Matcher, error) {
+	if strings.TrimSpace(rule.Name) == "" {
+		return nil, fmt.Errorf("rule name is required")
+	}
+	if err := validateActions(rule.Actions); err != nil {
+		return nil, err
+	}
+
+	cond := rule.Conditions
+	if strings.TrimSpace(cond.From) == "" && cond.SubjectRegex == "" &&
+		len(cond.BodyKeywords) == 0 && strings.TrimSpace(cond.Category) == "" {
+		return nil, fmt.Errorf("rule needs at least one condition")
+	}
+
+	m := &Matcher{Rule: rule}
+	if cond.SubjectRegex != "" {
+		if len(cond.SubjectRegex) > maxRegexLength {
+			return nil, fmt.Errorf("subject regex is longer than %d characters", maxRegexLength)
+		}
+		re, err := regexp.Compile(cond.SubjectRegex)
+		if err != nil {
+			return nil, fmt.Errorf("invalid subject regex: %v", err)
+		}
+		m.subject = re
+	}
+
+	if len(cond.BodyKeywords) > maxKeywords {
+		return nil, fmt.Errorf("rule has more than %d keywords", maxKeywords)
+	}
+	for _, keyword := range cond.BodyKeywords {
+		keyword = strings.TrimSpace(keyword)
+		if keyword == "" {
+			return nil, fmt.Errorf("keywords must not be empty")
+		}
+		m.keywords = append(m.keywords, regexp.MustCompile(`(?i)(^|\W)`+regexp.QuoteMeta(keyword)+`($|\W)`))
+	}
+
+	return m, nil
+}
+
+func validateActions(actions []models.RuleAction) error {
+	if len(actions) == 0 {
+		return fmt.Errorf("rule needs at least one action")
+	}
+	if len(actions) > maxActions {
+		return fmt.Errorf("rule has more than %d actions", maxActions)
+	}
+
+	for _, action := range actions {
+		switch action.Type {
+		case models.RuleActionLabel:
+			if strings.TrimSpace(action.Label) == "" {
+				return fmt.Errorf("label action needs a label")
+			}
+		case models.RuleActionArchive, models.RuleActionMarkRead:
+		case models.RuleActionForward:
+			if _, err := mail.ParseAddress(action.To); err != nil {
+				return fmt.Errorf("forward action needs a valid recipient")
+			}
+		case models.RuleActionNotify:
+			if _, err := mail.ParseAddress(action.To); action.To != "" && err != nil {
+				return fmt.Errorf("notify action has an invalid recipient")
+			}
+		default:
+			return fmt.Errorf("unknown action type %q", action.Type)
+		}
+	}
+	return nil
+}
+
+// Category returns the AI category the rule asks for, or "" if it does not
+// need the classifier.
+func (m *Matcher) Category() string {
+	return strings.TrimSpace(m.Rule.Conditions.Category)
+}
+
+// Match reports whether email satisfies every condition of the rule.
+// category is the classifier's verdict for the email and is only consulted
+// when the rule has a category condition.
+func (m *Matcher) Match(email *models.Email, category string) bool {
+	if !m.MatchContent(email) {
+		return false
+	}
+	want := m.Category()
+	return want == "" || strings.EqualFold(want, strings.TrimSpace(category))
+}
+
+// MatchContent checks every condition except the category, so that callers
+// can skip classifying emails the rule cannot match anyway.
+func (m *Matcher) MatchContent(email *models.Email) bool {
+	cond := m.Rule.Conditions
+
+	if from := strings.TrimSpace(cond.From); from != "" {
+		if !strings.Contains(strings.ToLower(email.From), strings.ToLower(from)) {
+			return false
+		}
+	}
+
+	if m.subject != nil && !m.subject.MatchString(deref(email.Subject)) {
+		return false
+	}
+
+	if len(m.keywords) > 0 {
+		body := deref(email.Body)
+		matched := 0
+		for _, keyword := range m.keywords {
+			if keyword.MatchString(body) {
+				matched++
+			}
+		}
+		if matched == 0 || (cond.AllKeywords && matched < len(m.keywords)) {
+			return false
+		}
+	}
+
+	return true
+}
+
+func deref(s *string) string {
+	if s == nil {
+		return ""
+	}
+	return *s
+}
@@ -0,0 +1,75 @@
+package sanitize
+
+import (
+	"regexp"
+	"strings"
+)
+
+var cssComment = regexp.MustCompile(`(?s)/\*.*?\*/`)
+
+// cssProperties may appear in style attributes, along with the longhands
+// of cssPrefixes. Positioning is left out so mail cannot lay itself over
+// the page around it.
+var (
+	cssProperties = map[string]bool{
+		"background-color": true, "border": true, "border-collapse": true,
+		"border-radius": true, "border-spacing": true, "caption-side": true,
+		"clear": true, "color": true, "direction": true, "display": true,
+		"empty-cells": true, "float": true, "font": true, "height": true,
+		"letter-spacing": true, "line-height": true, "list-style": true,
+		"list-style-position": true, "list-style-type": true, "margin": true,
+		"max-height": true, "max-width": true, "min-height": true, "min-width": true,
+		"overflow-wrap": true, "padding": true, "table-layout": true,
+		"text-align": true, "text-decoration": true, "text-indent": true,
+		"text-transform": true, "vertical-align": true, "white-space": true,
+		"width": true, "word-break": true, "word-spacing": true, "word-wrap": true,
+	}
+	cssPrefixes = []string{"border-", "font-", "margin-", "padding-", "text-decoration-"}
+)
+
+// cssBlocked are value fragments that load resources or run code in some
+// browser. Escapes are refused outright since they could spell any of them.
+var cssBlocked = []string{"url(", "image(", "image-set(", "expression", "javascript:", "vbscript:", "@import", "behavior", "binding", "\\", "<", ">"}
+
+// cleanStyle keeps the declarations of a style attribute whose property is
+// allowed and whose value loads nothing.
+func cleanStyle(style string) string {
+	style = cssComment.ReplaceAllString(style, "")
+
+	var kept []string
+	for _, part := range strings.Split(style, ";") {
+		colon := strings.IndexByte(part, ':')
+		if colon < 0 {
+			continue
+		}
+		property := strings.ToLower(strings.TrimSpace(part[:colon]))
+		value := strings.TrimSpace(part[colon+1:])
+		if value == "" || !allowedProperty(property) || blockedValue(value) {
+			continue
+		}
+		kept = append(kept, property+": "+value)
+	}
+	return strings.Join(kept, "; ")
+}
+
+func allowedProperty(property string) bool {
+	if cssProperties[property] {
+		return true
+	}
+	for _, prefix := range cssPrefixes {
+		if strings.HasPrefix(property, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+func blockedValue(value string) bool {
+	lower := strings.ToLower(value)
+	for _, fragment := range cssBlocked {
+		if strings.Contains(lower, fragment) {
+			return true
+		}
+	}
+	return false
+}
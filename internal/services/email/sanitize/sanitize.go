@@ -0,0 +1,288 @@
+// Package sanitize makes the HTML of received mail safe to show in a
+// browser. Only an allowlist of presentational elements, attributes and CSS
+// properties survives; scripts, event handlers, forms, embedded content and
+// anything that loads remote resources without going through Options is
+// removed.
+package sanitize
+
+import (
+	"bytes"
+	"net/url"
+	"regexp"
+	"strings"
+
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+	"ai-assistant/internal/services/email/templates"
+)
+
+// Version changes whenever the output for the same input would, so that
+// stored results can be recognised as stale.
+const Version = 1
+
+type Options struct {
+	// RemoteImage returns the URL a remote image is loaded from instead of
+	// src, e.g. an image proxy. Returning "" blocks the image; a nil
+	// RemoteImage blocks all remote images.
+	RemoteImage func(src string) string
+	// InlineImage returns the URL of the attachment a cid: reference names,
+	// or "" if there is none.
+	InlineImage func(contentID string) string
+}
+
+type Result struct {
+	HTML string
+	// Text is a plain-text rendering of HTML.
+	Text string
+	// BlockedImages counts the remote images that were removed.
+	BlockedImages int
+}
+
+type action int
+
+const (
+	keep action = iota
+	unwrap
+	drop
+)
+
+// dropped elements are removed together with their content. Elements that
+// are neither dropped nor allowed, such as forms and unknown tags, are
+// replaced by their content.
+var (
+	dropped = map[atom.Atom]bool{
+		atom.Script: true, atom.Style: true, atom.Title: true, atom.Head: true,
+		atom.Meta: true, atom.Link: true, atom.Base: true, atom.Iframe: true,
+		atom.Frame: true, atom.Frameset: true, atom.Object: true, atom.Embed: true,
+		atom.Applet: true, atom.Param: true, atom.Noscript: true, atom.Noembed: true,
+		atom.Noframes: true, atom.Template: true, atom.Svg: true, atom.Math: true,
+		atom.Audio: true, atom.Video: true, atom.Source: true, atom.Track: true,
+		atom.Canvas: true, atom.Input: true, atom.Button: true, atom.Select: true,
+		atom.Option: true, atom.Optgroup: true, atom.Textarea: true, atom.Datalist: true,
+		atom.Output: true, atom.Dialog: true, atom.Xmp: true, atom.Plaintext: true,
+	}
+	allowed = map[atom.Atom]bool{
+		atom.A: true, atom.Abbr: true, atom.Address: true, atom.Article: true,
+		atom.B: true, atom.Bdi: true, atom.Bdo: true, atom.Big: true,
+		atom.Blockquote: true, atom.Br: true, atom.Caption: true, atom.Center: true,
+		atom.Cite: true, atom.Code: true, atom.Col: true, atom.Colgroup: true,
+		atom.Dd: true, atom.Del: true, atom.Details: true, atom.Dfn: true,
+		atom.Div: true, atom.Dl: true, atom.Dt: true, atom.Em: true,
+		atom.Figcaption: true, atom.Figure: true, atom.Font: true, atom.Footer: true,
+		atom.H1: true, atom.H2: true, atom.H3: true, atom.H4: true, atom.H5: true,
+		atom.H6: true, atom.Header: true, atom.Hr: true, atom.I: true,
+		atom.Img: true, atom.Ins: true, atom.Kbd: true, atom.Li: true,
+		atom.Main: true, atom.Mark: true, atom.Ol: true, atom.P: true,
+		atom.Pre: true, atom.Q: true, atom.S: true, atom.Samp: true,
+		atom.Section: true, atom.Small: true, atom.Span: true, atom.Strike: true,
+		atom.Strong: true, atom.Sub: true, atom.Summary: true, atom.Sup: true,
+		atom.Table: true, atom.Tbody: true, atom.Td: true, atom.Tfoot: true,
+		atom.Th: true, atom.Thead: true, atom.Time: true, atom.Tr: true,
+		atom.Tt: true, atom.U: true, atom.Ul: true, atom.Var: true, atom.Wbr: true,
+	}
+	// allowedAttrs apply to every allowed element; href and src are
+	// checked separately. id, class and name are dropped so mail cannot
+	// target the page it is shown in.
+	allowedAttrs = map[string]bool{
+		"abbr": true, "align": true, "alt": true, "bgcolor": true, "border": true,
+		"cellpadding": true, "cellspacing": true, "color": true, "colspan": true,
+		"datetime": true, "dir": true, "face": true, "height": true, "hspace": true,
+		"lang": true, "nowrap": true, "open": true, "reversed": true, "rowspan": true,
+		"scope": true, "size": true, "span": true, "start": true, "summary": true,
+		"title": true, "type": true, "valign": true, "vspace": true, "width": true,
+	}
+)
+
+// dataImage matches the inline image formats browsers cannot run script in.
+var dataImage = regexp.MustCompile(`^data:image/(png|gif|jpeg|webp|bmp);base64,[a-zA-Z0-9+/=\s]*$`)
+
+// Sanitize cleans an HTML document. Style sheets are inlined first so that
+// mail that relies on them keeps its layout once <style> is gone.
+func Sanitize(document string, opts Options) (*Result, error) {
+	if inlined, err := templates.InlineCSS(document); err == nil {
+		document = inlined
+	}
+	doc, err := html.Parse(strings.NewReader(document))
+	if err != nil {
+		return nil, err
+	}
+
+	body := findBody(doc)
+	if body == nil {
+		return &Result{}, nil
+	}
+
+	s := &sanitizer{opts: opts}
+	s.children(body)
+
+	var buf bytes.Buffer
+	for c := body.FirstChild; c != nil; c = c.NextSibling {
+		if err := html.Render(&buf, c); err != nil {
+			return nil, err
+		}
+	}
+	out := strings.TrimSpace(buf.String())
+	return &Result{
+		HTML:          out,
+		Text:          templates.HTMLToText(out),
+		BlockedImages: s.blocked,
+	}, nil
+}
+
+type sanitizer struct {
+	opts    Options
+	blocked int
+}
+
+func (s *sanitizer) children(parent *html.Node) {
+	for c := parent.FirstChild; c != nil; {
+		next := c.NextSibling
+		switch s.action(c) {
+		case drop:
+			parent.RemoveChild(c)
+		case unwrap:
+			first := c.FirstChild
+			for gc := c.FirstChild; gc != nil; {
+				after := gc.NextSibling
+				c.RemoveChild(gc)
+				parent.InsertBefore(gc, c)
+				gc = after
+			}
+			parent.RemoveChild(c)
+			// The moved children are checked in turn.
+			if first != nil {
+				next = first
+			}
+		case keep:
+			if c.Type == html.ElementNode {
+				if !s.element(c) {
+					parent.RemoveChild(c)
+					break
+				}
+				s.children(c)
+			}
+		}
+		c = next
+	}
+}
+
+func (s *sanitizer) action(n *html.Node) action {
+	switch n.Type {
+	case html.TextNode:
+		return keep
+	case html.ElementNode:
+		if n.Namespace != "" || dropped[n.DataAtom] {
+			return drop
+		}
+		if allowed[n.DataAtom] {
+			return keep
+		}
+		return unwrap
+	default:
+		return drop
+	}
+}
+
+// element filters n's attributes and reports whether n should stay.
+func (s *sanitizer) element(n *html.Node) bool {
+	var attrs []html.Attribute
+	var href, src string
+	for _, a := range n.Attr {
+		if a.Namespace != "" {
+			continue
+		}
+		key := strings.ToLower(a.Key)
+		switch {
+		case key == "href" && n.DataAtom == atom.A:
+			href = a.Val
+		case key == "src" && n.DataAtom == atom.Img:
+			src = a.Val
+		case key == "style":
+			if style := cleanStyle(a.Val); style != "" {
+				attrs = append(attrs, html.Attribute{Key: key, Val: style})
+			}
+		case allowedAttrs[key]:
+			attrs = append(attrs, html.Attribute{Key: key, Val: a.Val})
+		}
+	}
+	n.Attr = attrs
+
+	switch n.DataAtom {
+	case atom.A:
+		if link := cleanLink(href); link != "" {
+			n.Attr = append(n.Attr,
+				html.Attribute{Key: "href", Val: link},
+				html.Attribute{Key: "target", Val: "_blank"},
+				html.Attribute{Key: "rel", Val: "noopener noreferrer nofollow"})
+		}
+	case atom.Img:
+		image := s.imageURL(strings.TrimSpace(src))
+		if image == "" {
+			return false
+		}
+		n.Attr = append(n.Attr, html.Attribute{Key: "src", Val: image})
+	}
+	return true
+}
+
+// imageURL returns where the image src refers to may be loaded from, or ""
+// to drop it.
+func (s *sanitizer) imageURL(src string) string {
+	lower := strings.ToLower(src)
+	switch {
+	case strings.HasPrefix(lower, "cid:"):
+		if s.opts.InlineImage == nil {
+			return ""
+		}
+		return s.opts.InlineImage(contentID(src[len("cid:"):]))
+	case dataImage.MatchString(src):
+		return src
+	case strings.HasPrefix(lower, "//"):
+		src, lower = "https:"+src, "https:"+lower
+		fallthrough
+	case strings.HasPrefix(lower, "http://") || strings.HasPrefix(lower, "https://"):
+		proxied := ""
+		if s.opts.RemoteImage != nil {
+			proxied = s.opts.RemoteImage(src)
+		}
+		if proxied == "" {
+			s.blocked++
+		}
+		return proxied
+	default:
+		return ""
+	}
+}
+
+// cleanLink keeps absolute links of the schemes a mail reader should follow.
+func cleanLink(href string) string {
+	href = strings.TrimSpace(href)
+	lower := strings.ToLower(href)
+	for _, scheme := range []string{"http://", "https://", "mailto:", "tel:"} {
+		if strings.HasPrefix(lower, scheme) && !strings.ContainsAny(href, "\x00\r\n\t") {
+			return href
+		}
+	}
+	return ""
+}
+
+// contentID normalises the target of a cid: URL, which is URL-escaped and
+// without the angle brackets of the Content-ID header.
+func contentID(cid string) string {
+	if unescaped, err := url.PathUnescape(cid); err == nil {
+		cid = unescaped
+	}
+	return strings.Trim(strings.TrimSpace(cid), "<>")
+}
+
+func findBody(n *html.Node) *html.Node {
+	if n.Type == html.ElementNode && n.DataAtom == atom.Body {
+		return n
+	}
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		if body := findBody(c); body != nil {
+			return body
+		}
+	}
+	return nil
+}
@@ -0,0 +1,30 @@
+package search
+
+import (
+	"html"
+	"strings"
+)
+
+// Search snippets mark matches with these control characters rather than
+// tags, so that the body can be escaped before the marks become <mark>.
+const (
+	MarkStart = "\x02"
+	MarkStop  = "\x03"
+)
+
+var (
+	markStripper    = strings.NewReplacer(MarkStart, "", MarkStop, "")
+	markHighlighter = strings.NewReplacer(MarkStart, "<mark>", MarkStop, "</mark>")
+)
+
+// StripMarks removes the mark characters from text, so that an email cannot
+// open or close a highlight itself.
+func StripMarks(text string) string {
+	return markStripper.Replace(text)
+}
+
+// HighlightHTML escapes a marked snippet for HTML and turns its marks into
+// <mark> tags.
+func HighlightHTML(snippet string) string {
+	return markHighlighter.Replace(html.EscapeString(snippet))
+}
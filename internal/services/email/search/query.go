@@ -0,0 +1,202 @@
+// Package search parses Gmail-style mailbox queries such as
+//
+//	from:alice subject:"quarterly report" has:attachment -is:unread after:2024/01/01
+//
+// into a list of terms that the email repository compiles to SQL.
+package search
+
+import (
+	"fmt"
+	"strings"
+	"time"
+	"unicode"
+)
+
+type Field string
+
+const (
+	FieldText    Field = ""
+	FieldFrom    Field = "from"
+	FieldTo      Field = "to"
+	FieldCc      Field = "cc"
+	FieldSubject Field = "subject"
+	FieldLabel   Field = "label"
+	FieldHas     Field = "has"
+	FieldIs      Field = "is"
+	FieldBefore  Field = "before"
+	FieldAfter   Field = "after"
+)
+
+// Values accepted by has: and is:.
+const (
+	HasAttachment = "attachment"
+	IsUnread      = "unread"
+	IsRead        = "read"
+	IsStarred     = "starred"
+)
+
+// maxTerms bounds the size of the generated SQL.
+const maxTerms = 32
+
+type Term struct {
+	Field  Field
+	Value  string
+	Phrase bool
+	Negate bool
+	// Date is set for before: and after: terms.
+	Date time.Time
+}
+
+type Query struct {
+	Terms []Term
+}
+
+// HasText reports whether the query contains free-text terms, which are the
+// only ones that contribute to ranking and snippets.
+func (q *Query) HasText() bool {
+	for _, term := range q.Terms {
+		if term.Field == FieldText {
+			return true
+		}
+	}
+	return false
+}
+
+var dateLayouts = []string{"2006/01/02", "2006-01-02", "2006/1/2", "2006-1-2"}
+
+// Parse splits raw into terms. Unknown operators are searched for as plain
+// text, as Gmail does; malformed values of known operators are errors.
+func Parse(raw string) (*Query, error) {
+	tokens, err := tokenize(raw)
+	if err != nil {
+		return nil, err
+	}
+	if len(tokens) == 0 {
+		return nil, fmt.Errorf("search query is empty")
+	}
+	if len(tokens) > maxTerms {
+		return nil, fmt.Errorf("search query has more than %d terms", maxTerms)
+	}
+
+	q := &Query{}
+	for _, tok := range tokens {
+		term, err := parseTerm(tok)
+		if err != nil {
+			return nil, err
+		}
+		q.Terms = append(q.Terms, term)
+	}
+	return q, nil
+}
+
+type token struct {
+	negate bool
+	field  string
+	value  string
+	phrase bool
+}
+
+func tokenize(raw string) ([]token, error) {
+	var tokens []token
+	runes := []rune(raw)
+	i := 0
+	for i < len(runes) {
+		if unicode.IsSpace(runes[i]) {
+			i++
+			continue
+		}
+
+		tok := token{}
+		if runes[i] == '-' && i+1 < len(runes) && !unicode.IsSpace(runes[i+1]) {
+			tok.negate = true
+			i++
+		}
+
+		// An operator is a run of letters followed by a colon.
+		j := i
+		for j < len(runes) && unicode.IsLetter(runes[j]) {
+			j++
+		}
+		if j > i && j < len(runes) && runes[j] == ':' && isOperator(string(runes[i:j])) {
+			tok.field = strings.ToLower(string(runes[i:j]))
+			i = j + 1
+		}
+
+		if i < len(runes) && runes[i] == '"' {
+			end := i + 1
+			for end < len(runes) && runes[end] != '"' {
+				end++
+			}
+			if end == len(runes) {
+				return nil, fmt.Errorf("unterminated quote in search query")
+			}
+			tok.value = string(runes[i+1 : end])
+			tok.phrase = true
+			i = end + 1
+		} else {
+			start := i
+			for i < len(runes) && !unicode.IsSpace(runes[i]) {
+				i++
+			}
+			tok.value = string(runes[start:i])
+		}
+
+		tok.value = strings.TrimSpace(tok.value)
+		if tok.value == "" {
+			if tok.field != "" {
+				return nil, fmt.Errorf("%s: needs a value", tok.field)
+			}
+			continue
+		}
+		tokens = append(tokens, tok)
+	}
+	return tokens, nil
+}
+
+func isOperator(name string) bool {
+	switch Field(strings.ToLower(name)) {
+	case FieldFrom, FieldTo, FieldCc, FieldSubject, FieldLabel, FieldHas, FieldIs, FieldBefore, FieldAfter:
+		return true
+	}
+	return false
+}
+
+func parseTerm(tok token) (Term, error) {
+	term := Term{
+		Field:  Field(tok.field),
+		Value:  tok.value,
+		Phrase: tok.phrase,
+		Negate: tok.negate,
+	}
+
+	switch term.Field {
+	case FieldHas:
+		term.Value = strings.ToLower(term.Value)
+		if term.Value != HasAttachment {
+			return term, fmt.Errorf("unsupported has: value %q", tok.value)
+		}
+	case FieldIs:
+		term.Value = strings.ToLower(term.Value)
+		switch term.Value {
+		case IsUnread, IsRead, IsStarred:
+		default:
+			return term, fmt.Errorf("unsupported is: value %q", tok.value)
+		}
+	case FieldBefore, FieldAfter:
+		date, err := parseDate(term.Value)
+		if err != nil {
+			return term, fmt.Errorf("%s: expects a date like 2024/01/31", term.Field)
+		}
+		term.Date = date
+	}
+	return term, nil
+}
+
+func parseDate(value string) (time.Time, error) {
+	for _, layout := range dateLayouts {
+		if date, err := time.Parse(layout, value); err == nil {
+			return date, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("invalid date %q", value)
+}
@@ -0,0 +1,123 @@
+package smtp
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"fmt"
+	"net"
+	"time"
+
+	"github.com/emersion/go-sasl"
+	gosmtp "github.com/emersion/go-smtp"
+	"ai-assistant/internal/app/config"
+	"ai-assistant/internal/services/email"
+	"ai-assistant/internal/services/email/mailmime"
+	"ai-assistant/pkg/logger"
+)
+
+// SMTPService sends composed messages to a submission server. It implements
+// email.Sender.
+type SMTPService struct {
+	host      string
+	port      string
+	username  string
+	password  string
+	from      string
+	tlsMode   string
+	tlsConfig *tls.Config
+	logger    *logger.Logger
+}
+
+func NewSMTPService(cfg *config.Config) *SMTPService {
+	if cfg.Email.SMTP.Host == "" {
+		return nil // SMTP is optional
+	}
+
+	return &SMTPService{
+		host:      cfg.Email.SMTP.Host,
+		port:      cfg.Email.SMTP.Port,
+		username:  cfg.Email.SMTP.Username,
+		password:  cfg.Email.SMTP.Password,
+		from:      cfg.Email.SMTP.From,
+		tlsMode:   cfg.Email.SMTP.TLSMode,
+		tlsConfig: &tls.Config{ServerName: cfg.Email.SMTP.Host},
+		logger:    logger.New(),
+	}
+}
+
+func (s *SMTPService) Name() string {
+	return "smtp"
+}
+
+func (s *SMTPService) Send(ctx context.Context, msg *mailmime.Outgoing, opts email.SendOptions) (*email.SendResult, error) {
+	if msg.From == "" {
+		msg.From = s.from
+	}
+	if msg.From == "" {
+		return nil, fmt.Errorf("sender address is required")
+	}
+
+	raw, err := msg.Build(false)
+	if err != nil {
+		return nil, fmt.Errorf("failed to build message: %w", err)
+	}
+
+	var recipients []string
+	for _, list := range [][]string{msg.To, msg.Cc, msg.Bcc} {
+		for _, addr := range list {
+			recipients = append(recipients, mailmime.AddressOnly(addr))
+		}
+	}
+
+	client, err := s.dial(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer client.Close()
+
+	if s.username != "" {
+		if err := client.Auth(sasl.NewPlainClient("", s.username, s.password)); err != nil {
+			return nil, fmt.Errorf("failed to authenticate: %w", err)
+		}
+	}
+
+	if err := client.SendMail(mailmime.AddressOnly(msg.From), recipients, bytes.NewReader(raw)); err != nil {
+		return nil, fmt.Errorf("failed to send message: %w", err)
+	}
+	if err := client.Quit(); err != nil {
+		s.logger.Warn("SMTP QUIT failed after delivery", "error", err)
+	}
+
+	s.logger.Info("Message sent", "provider", "smtp", "message_id", msg.MessageID, "recipients", len(msg.To))
+	return &email.SendResult{ID: msg.MessageID}, nil
+}
+
+func (s *SMTPService) dial(ctx context.Context) (*gosmtp.Client, error) {
+	addr := net.JoinHostPort(s.host, s.port)
+	dialer := &net.Dialer{Timeout: 30 * time.Second}
+
+	conn, err := dialer.DialContext(ctx, "tcp", addr)
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to %s: %w", addr, err)
+	}
+	if deadline, ok := ctx.Deadline(); ok {
+		conn.SetDeadline(deadline)
+	}
+
+	var client *gosmtp.Client
+	switch s.tlsMode {
+	case "tls":
+		client = gosmtp.NewClient(tls.Client(conn, s.tlsConfig))
+	case "none":
+		client = gosmtp.NewClient(conn)
+	default:
+		client, err = gosmtp.NewClientStartTLS(conn, s.tlsConfig)
+		if err != nil {
+			conn.Close()
+			return nil, fmt.Errorf("failed to start TLS: %w", err)
+		}
+	}
+
+	return client, nil
+}
@@ -0,0 +1,394 @@
+package templates
+
+import (
+	"bytes"
+	"regexp"
+	"sort"
+	"strings"
+
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+)
+
+var cssComment = regexp.MustCompile(`(?s)/\*.*?\*/`)
+
+// compound is one simple selector sequence, e.g. p.note#intro.
+type compound struct {
+	tag     string
+	id      string
+	classes []string
+}
+
+// selector is a chain of compounds joined by descendant (' ') or child
+// ('>') combinators.
+type selector struct {
+	parts       []compound
+	combinators []byte
+	specificity [3]int
+}
+
+type declaration struct {
+	property  string
+	value     string
+	important bool
+}
+
+type cssRule struct {
+	selector selector
+	decls    []declaration
+	order    int
+}
+
+// InlineCSS moves the rules of the document's <style> elements into style
+// attributes. Rules that cannot be inlined, such as @media queries and
+// selectors with pseudo-classes, stay in their <style> element for the
+// clients that honour it; emptied <style> elements are removed.
+func InlineCSS(document string) (string, error) {
+	doc, err := html.Parse(strings.NewReader(document))
+	if err != nil {
+		return "", err
+	}
+
+	var styles []*html.Node
+	var elements []*html.Node
+	var walk func(n *html.Node)
+	walk = func(n *html.Node) {
+		if n.Type == html.ElementNode {
+			if n.DataAtom == atom.Style {
+				styles = append(styles, n)
+				return
+			}
+			elements = append(elements, n)
+		}
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			walk(c)
+		}
+	}
+	walk(doc)
+
+	var rules []cssRule
+	for _, style := range styles {
+		if media := strings.ToLower(strings.TrimSpace(attr(style, "media"))); media != "" && media != "all" && media != "screen" {
+			continue
+		}
+		parsed, leftover := parseStylesheet(textContent(style), len(rules))
+		rules = append(rules, parsed...)
+
+		for c := style.FirstChild; c != nil; {
+			next := c.NextSibling
+			style.RemoveChild(c)
+			c = next
+		}
+		if leftover == "" {
+			style.Parent.RemoveChild(style)
+		} else {
+			style.AppendChild(&html.Node{Type: html.TextNode, Data: leftover})
+		}
+	}
+
+	if len(rules) > 0 {
+		for _, n := range elements {
+			applyRules(n, rules)
+		}
+	}
+
+	var buf bytes.Buffer
+	if err := html.Render(&buf, doc); err != nil {
+		return "", err
+	}
+	return buf.String(), nil
+}
+
+func applyRules(n *html.Node, rules []cssRule) {
+	var matched []cssRule
+	for _, rule := range rules {
+		if matchFrom(n, rule.selector, len(rule.selector.parts)-1) {
+			matched = append(matched, rule)
+		}
+	}
+	if len(matched) == 0 {
+		return
+	}
+
+	sort.SliceStable(matched, func(i, j int) bool {
+		a, b := matched[i].selector.specificity, matched[j].selector.specificity
+		if a != b {
+			return a[0] < b[0] || a[0] == b[0] && (a[1] < b[1] || a[1] == b[1] && a[2] < b[2])
+		}
+		return matched[i].order < matched[j].order
+	})
+
+	// Cascade: rules by specificity, then the element's own style, then
+	// !important rules, then the element's own !important declarations.
+	inline := parseDeclarations(attr(n, "style"))
+	var cascade []declaration
+	for _, important := range []bool{false, true} {
+		for _, rule := range matched {
+			for _, d := range rule.decls {
+				if d.important == important {
+					cascade = append(cascade, d)
+				}
+			}
+		}
+		for _, d := range inline {
+			if d.important == important {
+				cascade = append(cascade, d)
+			}
+		}
+	}
+
+	var order []string
+	values := map[string]string{}
+	for _, d := range cascade {
+		if _, seen := values[d.property]; !seen {
+			order = append(order, d.property)
+		}
+		values[d.property] = d.value
+	}
+	parts := make([]string, len(order))
+	for i, property := range order {
+		parts[i] = property + ": " + values[property]
+	}
+	setAttr(n, "style", strings.Join(parts, "; "))
+}
+
+// parseStylesheet splits css into rules that can be inlined, numbered from
+// order, and the text of everything else.
+func parseStylesheet(css string, order int) ([]cssRule, string) {
+	css = cssComment.ReplaceAllString(css, "")
+
+	var rules []cssRule
+	var leftover strings.Builder
+	for {
+		css = strings.TrimSpace(css)
+		if css == "" {
+			break
+		}
+
+		if css[0] == '@' {
+			end := atRuleEnd(css)
+			leftover.WriteString(strings.TrimSpace(css[:end]) + "\n")
+			css = css[end:]
+			continue
+		}
+
+		start := strings.IndexByte(css, '{')
+		if start < 0 {
+			break
+		}
+		end := strings.IndexByte(css[start:], '}')
+		if end < 0 {
+			break
+		}
+		end += start
+		selectors, body := css[:start], css[start+1:end]
+		css = css[end+1:]
+
+		decls := parseDeclarations(body)
+		for _, raw := range strings.Split(selectors, ",") {
+			raw = strings.TrimSpace(raw)
+			if raw == "" {
+				continue
+			}
+			sel, ok := parseSelector(raw)
+			if !ok {
+				leftover.WriteString(raw + " {" + strings.TrimSpace(body) + "}\n")
+				continue
+			}
+			rules = append(rules, cssRule{selector: sel, decls: decls, order: order})
+			order++
+		}
+	}
+	return rules, strings.TrimSpace(leftover.String())
+}
+
+// atRuleEnd returns the length of the at-rule css starts with: up to its
+// semicolon, or its balanced block.
+func atRuleEnd(css string) int {
+	depth := 0
+	for i := 0; i < len(css); i++ {
+		switch css[i] {
+		case ';':
+			if depth == 0 {
+				return i + 1
+			}
+		case '{':
+			depth++
+		case '}':
+			depth--
+			if depth == 0 {
+				return i + 1
+			}
+		}
+	}
+	return len(css)
+}
+
+func parseDeclarations(body string) []declaration {
+	var decls []declaration
+	for _, part := range strings.Split(body, ";") {
+		colon := strings.IndexByte(part, ':')
+		if colon < 0 {
+			continue
+		}
+		property := strings.ToLower(strings.TrimSpace(part[:colon]))
+		value := strings.TrimSpace(part[colon+1:])
+		important := false
+		if i := strings.Index(strings.ToLower(value), "!important"); i >= 0 {
+			important = true
+			value = strings.TrimSpace(value[:i])
+		}
+		if property == "" || value == "" {
+			continue
+		}
+		decls = append(decls, declaration{property: property, value: value, important: important})
+	}
+	return decls
+}
+
+// parseSelector supports type, class, ID and universal selectors joined by
+// descendant and child combinators. Anything else cannot be evaluated
+// against a static document, or is not worth it, and reports false.
+func parseSelector(raw string) (selector, bool) {
+	var sel selector
+	combinator := byte(' ')
+	for _, token := range strings.Fields(strings.ReplaceAll(raw, ">", " > ")) {
+		if token == ">" {
+			if len(sel.parts) == 0 {
+				return sel, false
+			}
+			combinator = '>'
+			continue
+		}
+		c, ok := parseCompound(token)
+		if !ok {
+			return sel, false
+		}
+		if len(sel.parts) > 0 {
+			sel.combinators = append(sel.combinators, combinator)
+		}
+		combinator = ' '
+		sel.parts = append(sel.parts, c)
+
+		if c.id != "" {
+			sel.specificity[0]++
+		}
+		sel.specificity[1] += len(c.classes)
+		if c.tag != "" && c.tag != "*" {
+			sel.specificity[2]++
+		}
+	}
+	return sel, len(sel.parts) > 0 && combinator == ' '
+}
+
+func parseCompound(token string) (compound, bool) {
+	var c compound
+	if strings.ContainsAny(token, ":[]+~()\"'") {
+		return c, false
+	}
+
+	i := strings.IndexAny(token, ".#")
+	if i < 0 {
+		i = len(token)
+	}
+	c.tag = strings.ToLower(token[:i])
+	for rest := token[i:]; rest != ""; {
+		kind := rest[0]
+		rest = rest[1:]
+		end := strings.IndexAny(rest, ".#")
+		if end < 0 {
+			end = len(rest)
+		}
+		name := rest[:end]
+		rest = rest[end:]
+		if name == "" {
+			return c, false
+		}
+		if kind == '#' {
+			if c.id != "" {
+				return c, false
+			}
+			c.id = name
+		} else {
+			c.classes = append(c.classes, name)
+		}
+	}
+	return c, true
+}
+
+func (c compound) matches(n *html.Node) bool {
+	if n.Type != html.ElementNode {
+		return false
+	}
+	if c.tag != "" && c.tag != "*" && c.tag != n.Data {
+		return false
+	}
+	if c.id != "" && attr(n, "id") != c.id {
+		return false
+	}
+	if len(c.classes) > 0 {
+		classes := strings.Fields(attr(n, "class"))
+		for _, want := range c.classes {
+			found := false
+			for _, have := range classes {
+				if have == want {
+					found = true
+					break
+				}
+			}
+			if !found {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// matchFrom reports whether n matches sel.parts[idx] with the parts before
+// it matching its ancestors.
+func matchFrom(n *html.Node, sel selector, idx int) bool {
+	if !sel.parts[idx].matches(n) {
+		return false
+	}
+	if idx == 0 {
+		return true
+	}
+	if sel.combinators[idx-1] == '>' {
+		return n.Parent != nil && matchFrom(n.Parent, sel, idx-1)
+	}
+	for p := n.Parent; p != nil; p = p.Parent {
+		if matchFrom(p, sel, idx-1) {
+			return true
+		}
+	}
+	return false
+}
+
+func attr(n *html.Node, key string) string {
+	for _, a := range n.Attr {
+		if a.Namespace == "" && a.Key == key {
+			return a.Val
+		}
+	}
+	return ""
+}
+
+func setAttr(n *html.Node, key, value string) {
+	for i, a := range n.Attr {
+		if a.Namespace == "" && a.Key == key {
+			n.Attr[i].Val = value
+			return
+		}
+	}
+	n.Attr = append(n.Attr, html.Attribute{Key: key, Val: value})
+}
+
+func textContent(n *html.Node) string {
+	var b strings.Builder
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		if c.Type == html.TextNode {
+			b.WriteString(c.Data)
+		}
+	}
+	return b.String()
+}
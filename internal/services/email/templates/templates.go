@@ -0,0 +1,88 @@
+// Package templates renders stored email templates. The subject is a
+// text/template and the body an html/template, so data is escaped for the
+// context it lands in. Rendered HTML has its <style> rules inlined, since
+// many mail clients drop them, and gets a plain-text alternative.
+package templates
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	htmltemplate "html/template"
+	"strings"
+	texttemplate "text/template"
+)
+
+// maxOutput bounds what one render may produce, so that a template ranging
+// over large data cannot exhaust memory.
+const maxOutput = 2 << 20
+
+var errTooLarge = errors.New("rendered email is larger than 2 MB")
+
+// Template is a parsed subject and HTML body.
+type Template struct {
+	subject *texttemplate.Template
+	html    *htmltemplate.Template
+}
+
+// Rendered is a template executed against data.
+type Rendered struct {
+	Subject string `json:"subject"`
+	HTML    string `json:"html"`
+	Text    string `json:"text"`
+}
+
+// Parse compiles a template. Referencing a key missing from the data is an
+// error at render time rather than a silent "<no value>".
+func Parse(subject, html string) (*Template, error) {
+	s, err := texttemplate.New("subject").Option("missingkey=error").Parse(subject)
+	if err != nil {
+		return nil, fmt.Errorf("subject: %w", err)
+	}
+	h, err := htmltemplate.New("html").Option("missingkey=error").Parse(html)
+	if err != nil {
+		return nil, fmt.Errorf("html: %w", err)
+	}
+	return &Template{subject: s, html: h}, nil
+}
+
+// Render executes the template, inlines its CSS and derives the text part.
+func (t *Template) Render(data map[string]interface{}) (*Rendered, error) {
+	if data == nil {
+		data = map[string]interface{}{}
+	}
+
+	var subject bytes.Buffer
+	if err := t.subject.Execute(&limitedWriter{w: &subject, n: maxOutput}, data); err != nil {
+		return nil, fmt.Errorf("subject: %w", err)
+	}
+	var body bytes.Buffer
+	if err := t.html.Execute(&limitedWriter{w: &body, n: maxOutput}, data); err != nil {
+		return nil, fmt.Errorf("html: %w", err)
+	}
+
+	html, err := InlineCSS(body.String())
+	if err != nil {
+		return nil, fmt.Errorf("html: %w", err)
+	}
+
+	return &Rendered{
+		// A subject is a single header line.
+		Subject: strings.Join(strings.Fields(subject.String()), " "),
+		HTML:    html,
+		Text:    HTMLToText(html),
+	}, nil
+}
+
+type limitedWriter struct {
+	w *bytes.Buffer
+	n int
+}
+
+func (l *limitedWriter) Write(p []byte) (int, error) {
+	if len(p) > l.n {
+		return 0, errTooLarge
+	}
+	l.n -= len(p)
+	return l.w.Write(p)
+}
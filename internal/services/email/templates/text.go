@@ -0,0 +1,162 @@
+package templates
+
+import (
+	"regexp"
+	"strings"
+
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+)
+
+var blankLines = regexp.MustCompile(`\n{3,}`)
+
+// paragraphs are separated from their surroundings by a blank line, and
+// lineBlocks by a line break.
+var (
+	paragraphs = map[atom.Atom]bool{
+		atom.P: true, atom.H1: true, atom.H2: true, atom.H3: true, atom.H4: true,
+		atom.H5: true, atom.H6: true, atom.Ul: true, atom.Ol: true, atom.Table: true,
+		atom.Blockquote: true, atom.Pre: true,
+	}
+	lineBlocks = map[atom.Atom]bool{
+		atom.Div: true, atom.Tr: true, atom.Li: true, atom.Section: true,
+		atom.Article: true, atom.Header: true, atom.Footer: true, atom.Hr: true,
+		atom.Center: true, atom.Dt: true, atom.Dd: true,
+	}
+)
+
+// HTMLToText renders an HTML document as readable plain text: block
+// elements become line breaks, links keep their target in parentheses and
+// list items are bulleted.
+func HTMLToText(document string) string {
+	doc, err := html.Parse(strings.NewReader(document))
+	if err != nil {
+		return ""
+	}
+
+	w := &textWriter{}
+	w.node(doc, false)
+
+	lines := strings.Split(w.b.String(), "\n")
+	for i, line := range lines {
+		lines[i] = strings.TrimRight(line, " \t")
+	}
+	return strings.TrimSpace(blankLines.ReplaceAllString(strings.Join(lines, "\n"), "\n\n"))
+}
+
+type textWriter struct {
+	b        strings.Builder
+	newlines int
+	space    bool
+}
+
+func (w *textWriter) write(s string) {
+	if s == "" {
+		return
+	}
+	if w.b.Len() > 0 {
+		if w.newlines > 0 {
+			w.b.WriteString(strings.Repeat("\n", w.newlines))
+		} else if w.space {
+			w.b.WriteByte(' ')
+		}
+	}
+	w.newlines = 0
+	w.space = false
+	w.b.WriteString(s)
+}
+
+func (w *textWriter) breakLines(n int) {
+	if n > w.newlines {
+		w.newlines = n
+	}
+}
+
+func (w *textWriter) node(n *html.Node, pre bool) {
+	switch n.Type {
+	case html.TextNode:
+		if pre {
+			w.write(n.Data)
+			return
+		}
+		words := strings.Fields(n.Data)
+		if len(words) == 0 {
+			if n.Data != "" {
+				w.space = true
+			}
+			return
+		}
+		if startsWithSpace(n.Data) {
+			w.space = true
+		}
+		w.write(strings.Join(words, " "))
+		if endsWithSpace(n.Data) {
+			w.space = true
+		}
+		return
+	case html.ElementNode:
+	default:
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			w.node(c, pre)
+		}
+		return
+	}
+
+	switch n.DataAtom {
+	case atom.Head, atom.Style, atom.Script, atom.Title:
+		return
+	case atom.Br:
+		w.breakLines(1)
+		return
+	case atom.Img:
+		if alt := strings.TrimSpace(attr(n, "alt")); alt != "" {
+			w.write(alt)
+		}
+		return
+	}
+
+	if paragraphs[n.DataAtom] {
+		w.breakLines(2)
+	} else if lineBlocks[n.DataAtom] {
+		w.breakLines(1)
+	}
+
+	switch n.DataAtom {
+	case atom.Li:
+		w.write("- ")
+		w.space = false
+	case atom.Hr:
+		w.write("---")
+		w.breakLines(1)
+		return
+	case atom.Td, atom.Th:
+		w.space = true
+	}
+
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		w.node(c, pre || n.DataAtom == atom.Pre)
+	}
+
+	if n.DataAtom == atom.A {
+		href := strings.TrimSpace(attr(n, "href"))
+		if href != "" && !strings.HasPrefix(href, "#") && !strings.HasPrefix(href, "mailto:") &&
+			!strings.HasSuffix(w.b.String(), href) {
+			w.space = true
+			w.write("(" + href + ")")
+		}
+	}
+
+	if paragraphs[n.DataAtom] {
+		w.breakLines(2)
+	} else if lineBlocks[n.DataAtom] {
+		w.breakLines(1)
+	}
+}
+
+func startsWithSpace(s string) bool {
+	return s != "" && strings.ContainsRune(" \t\r\n\f", rune(s[0]))
+}
+
+func endsWithSpace(s string) bool {
+	return s != "" && strings.ContainsRune(" \t\r\n\f", rune(s[len(s)-1]))
+}
@@ -0,0 +1,110 @@
+// Package threading reconstructs conversations for mail that does not carry
+// a provider thread ID, and trims the quoted history replies repeat.
+package threading
+
+import (
+	"crypto/sha1"
+	"encoding/hex"
+	"regexp"
+	"strings"
+	"unicode/utf8"
+)
+
+// Ancestors returns the Message-IDs a message replies to, nearest first:
+// In-Reply-To, then References from the end.
+func Ancestors(inReplyTo string, references []string) []string {
+	var ids []string
+	seen := make(map[string]bool)
+	add := func(id string) {
+		id = strings.TrimSpace(id)
+		if id != "" && !seen[id] {
+			seen[id] = true
+			ids = append(ids, id)
+		}
+	}
+	add(inReplyTo)
+	for i := len(references) - 1; i >= 0; i-- {
+		add(references[i])
+	}
+	return ids
+}
+
+// DeriveID computes a thread ID from the conversation's root Message-ID,
+// which is the first References entry, else In-Reply-To, else the message's
+// own ID. Messages of one conversation agree on the root, so they get the
+// same ID even when synced out of order. It returns "" if the message has no
+// IDs at all.
+func DeriveID(headerID, inReplyTo string, references []string) string {
+	root := headerID
+	if len(references) > 0 {
+		root = references[0]
+	} else if inReplyTo != "" {
+		root = inReplyTo
+	}
+	root = strings.ToLower(strings.Trim(strings.TrimSpace(root), "<>"))
+	if root == "" {
+		return ""
+	}
+	sum := sha1.Sum([]byte(root))
+	return "thr_" + hex.EncodeToString(sum[:10])
+}
+
+var (
+	attributionLine = regexp.MustCompile(`^On .+ wrote:\s*$`)
+	attributionEnd  = regexp.MustCompile(`wrote:\s*$`)
+	outlookHeader   = regexp.MustCompile(`^-{2,}\s*Original Message\s*-{2,}\s*$`)
+)
+
+// StripQuoted removes the quoted history at the end of a reply: the trailing
+// "> " block and its "On ... wrote:" attribution, or everything after an
+// Outlook "Original Message" separator. Inline quotes that are followed by
+// new text are kept. If nothing would remain, text is returned unchanged.
+func StripQuoted(text string) string {
+	lines := strings.Split(strings.ReplaceAll(text, "\r\n", "\n"), "\n")
+
+	for i, line := range lines {
+		if outlookHeader.MatchString(strings.TrimSpace(line)) {
+			lines = lines[:i]
+			break
+		}
+	}
+
+	end := trimBlank(lines, len(lines))
+	quoted := end
+	for quoted > 0 && strings.HasPrefix(lines[quoted-1], ">") {
+		quoted--
+	}
+	if quoted < end {
+		end = trimBlank(lines, quoted)
+		if end > 0 && attributionLine.MatchString(lines[end-1]) {
+			end--
+		} else if end > 1 && attributionEnd.MatchString(lines[end-1]) && strings.HasPrefix(lines[end-2], "On ") {
+			// Clients wrap long attributions over two lines.
+			end -= 2
+		}
+		end = trimBlank(lines, end)
+	}
+
+	stripped := strings.Join(lines[:end], "\n")
+	if strings.TrimSpace(stripped) == "" {
+		return strings.TrimSpace(text)
+	}
+	return stripped
+}
+
+func trimBlank(lines []string, end int) int {
+	for end > 0 && strings.TrimSpace(lines[end-1]) == "" {
+		end--
+	}
+	return end
+}
+
+// Snippet collapses whitespace and cuts text to at most n runes.
+func Snippet(text string, n int) string {
+	text = strings.Join(strings.Fields(text), " ")
+	if utf8.RuneCountInString(text) <= n {
+		return text
+	}
+	runes := []rune(text)
+	return strings.TrimSpace(string(runes[:n])) + "…"
+}
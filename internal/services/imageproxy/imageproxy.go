@@ -0,0 +1,163 @@
+// Package imageproxy loads the remote images of received mail on the
+// reader's behalf, so that senders' tracking pixels see the server rather
+// than the reader. Proxied URLs are signed: the proxy only fetches URLs that
+// came out of the sanitizer, and never anything on a private network.
+package imageproxy
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"syscall"
+	"time"
+)
+
+// MaxImageBytes bounds the images the proxy passes on.
+const MaxImageBytes = 10 << 20
+
+var (
+	ErrNotAllowed = errors.New("imageproxy: address not allowed")
+	ErrNotImage   = errors.New("imageproxy: not an image")
+	ErrTooLarge   = errors.New("imageproxy: image too large")
+)
+
+type Image struct {
+	ContentType string
+	Data        []byte
+}
+
+type Proxy struct {
+	key     []byte
+	baseURL string
+	client  *http.Client
+}
+
+// New returns a proxy serving at baseURL + "/api/images/proxy" with URLs
+// signed by secret. A nil client uses one that only connects to public
+// addresses on the standard web ports.
+func New(secret, baseURL string, client *http.Client) *Proxy {
+	if client == nil {
+		client = newClient()
+	}
+	key := sha256.Sum256([]byte("imageproxy:" + secret))
+	return &Proxy{key: key[:], baseURL: strings.TrimSuffix(baseURL, "/"), client: client}
+}
+
+// URL returns the proxied address of src, or "" for URLs that are not
+// http(s).
+func (p *Proxy) URL(src string) string {
+	u, err := url.Parse(src)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return ""
+	}
+	return p.baseURL + "/api/images/proxy?" + url.Values{"url": {src}, "sig": {p.sign(src)}}.Encode()
+}
+
+// KeyID identifies the signing key and address, which proxied URLs stay
+// valid for.
+func (p *Proxy) KeyID() string {
+	sum := sha256.Sum256(append(append([]byte{}, p.key...), p.baseURL...))
+	return base64.RawURLEncoding.EncodeToString(sum[:6])
+}
+
+func (p *Proxy) Verify(src, sig string) bool {
+	return hmac.Equal([]byte(p.sign(src)), []byte(sig))
+}
+
+func (p *Proxy) sign(src string) string {
+	mac := hmac.New(sha256.New, p.key)
+	mac.Write([]byte(src))
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+// Fetch downloads src. Only raster images are returned; SVG can carry
+// script and would run in the API's origin if opened directly.
+func (p *Proxy) Fetch(ctx context.Context, src string) (*Image, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, src, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Accept", "image/*")
+	req.Header.Set("User-Agent", "ai-assistant-image-proxy")
+
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("imageproxy: %s returned %d", src, resp.StatusCode)
+	}
+
+	contentType, _, err := mime.ParseMediaType(resp.Header.Get("Content-Type"))
+	if err != nil || !strings.HasPrefix(contentType, "image/") || strings.Contains(contentType, "svg") {
+		return nil, ErrNotImage
+	}
+	if resp.ContentLength > MaxImageBytes {
+		return nil, ErrTooLarge
+	}
+
+	var buf bytes.Buffer
+	if _, err := io.Copy(&buf, io.LimitReader(resp.Body, MaxImageBytes+1)); err != nil {
+		return nil, err
+	}
+	if buf.Len() > MaxImageBytes {
+		return nil, ErrTooLarge
+	}
+	return &Image{ContentType: contentType, Data: buf.Bytes()}, nil
+}
+
+// newClient checks every address it connects to after name resolution,
+// redirects included, so DNS cannot point the proxy into the network it
+// runs in. Environment proxies are ignored for the same reason.
+func newClient() *http.Client {
+	dialer := &net.Dialer{
+		Timeout: 5 * time.Second,
+		Control: func(network, address string, _ syscall.RawConn) error {
+			host, port, err := net.SplitHostPort(address)
+			if err != nil {
+				return err
+			}
+			ip := net.ParseIP(host)
+			if ip == nil || !publicIP(ip) || (port != "80" && port != "443") {
+				return ErrNotAllowed
+			}
+			return nil
+		},
+	}
+	return &http.Client{
+		Timeout: 15 * time.Second,
+		Transport: &http.Transport{
+			DialContext:           dialer.DialContext,
+			TLSHandshakeTimeout:   5 * time.Second,
+			ResponseHeaderTimeout: 10 * time.Second,
+			MaxIdleConns:          20,
+			IdleConnTimeout:       60 * time.Second,
+		},
+		CheckRedirect: func(req *http.Request, via []*http.Request) error {
+			if len(via) >= 3 {
+				return errors.New("imageproxy: too many redirects")
+			}
+			if req.URL.Scheme != "http" && req.URL.Scheme != "https" {
+				return ErrNotAllowed
+			}
+			return nil
+		},
+	}
+}
+
+var sharedAddressSpace = &net.IPNet{IP: net.IPv4(100, 64, 0, 0), Mask: net.CIDRMask(10, 32)}
+
+func publicIP(ip net.IP) bool {
+	return ip.IsGlobalUnicast() && !ip.IsPrivate() && !sharedAddressSpace.Contains(ip)
+}
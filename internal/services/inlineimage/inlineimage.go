@@ -0,0 +1,57 @@
+// Package inlineimage signs the URLs that the inline (cid:) images of
+// sanitized emails load from. <img> tags cannot send the API token, so a
+// signature over the email, the attachment and an expiry time is what
+// authorizes them.
+package inlineimage
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+type Signer struct {
+	key     []byte
+	baseURL string
+}
+
+// New returns a signer for URLs served at baseURL + "/api/images/inline".
+func New(secret, baseURL string) *Signer {
+	key := sha256.Sum256([]byte("inlineimage:" + secret))
+	return &Signer{key: key[:], baseURL: strings.TrimSuffix(baseURL, "/")}
+}
+
+// URL returns the address of an email's inline attachment, valid until
+// expires.
+func (s *Signer) URL(emailID, attachmentID string, expires time.Time) string {
+	exp := strconv.FormatInt(expires.Unix(), 10)
+	return s.baseURL + "/api/images/inline/" + url.PathEscape(emailID) + "/" + url.PathEscape(attachmentID) +
+		"?" + url.Values{"exp": {exp}, "sig": {s.sign(emailID, attachmentID, exp)}}.Encode()
+}
+
+// KeyID identifies the signing key and address, which signed URLs stay
+// valid for.
+func (s *Signer) KeyID() string {
+	sum := sha256.Sum256(append(append([]byte{}, s.key...), s.baseURL...))
+	return base64.RawURLEncoding.EncodeToString(sum[:6])
+}
+
+// Verify reports whether sig was made by URL for the attachment and exp,
+// and exp has not passed.
+func (s *Signer) Verify(emailID, attachmentID, exp, sig string) bool {
+	expires, err := strconv.ParseInt(exp, 10, 64)
+	if err != nil || time.Now().Unix() >= expires {
+		return false
+	}
+	return hmac.Equal([]byte(s.sign(emailID, attachmentID, exp)), []byte(sig))
+}
+
+func (s *Signer) sign(emailID, attachmentID, exp string) string {
+	mac := hmac.New(sha256.New, s.key)
+	mac.Write([]byte(emailID + "\n" + attachmentID + "\n" + exp))
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
@@ -2,55 +2,236 @@ package usecase
 
 import (
 	"context"
+	"fmt"
+	"slices"
+	"strings"
+	"sync"
+	"sync/atomic"
 
 	"ai-assistant/internal/models"
 	"ai-assistant/internal/repository"
 	"ai-assistant/pkg/cache"
 	"ai-assistant/pkg/errors"
+	"ai-assistant/pkg/tracing"
 )
 
 // AIProvider interface for AI services
 type AIProvider interface {
-	GenerateResponse(prompt string) (string, error)
+	GenerateResponse(ctx context.Context, prompt string) (string, error)
 	Close() error
 }
 
+// MailboxRetriever finds the passages of a user's emails most relevant to a
+// question.
+type MailboxRetriever interface {
+	Retrieve(ctx context.Context, userID, question string, k int) ([]models.Citation, error)
+}
+
+const (
+	defaultTopK = 5
+	maxTopK     = 20
+)
+
 type AIUsecase struct {
 	geminiService AIProvider
 	claudeService AIProvider
 	redisService  *cache.RedisService
+	retriever     MailboxRetriever
+
+	// providersMu guards the provider policy, which changes when the
+	// configuration is reloaded.
+	providersMu      sync.RWMutex
+	defaultProvider  string
+	allowedProviders []string
+
+	mailboxGrounding atomic.Bool
+	classification   atomic.Bool
 }
 
-func NewAIUsecase(geminiService AIProvider, claudeService AIProvider, redisService *cache.RedisService) *AIUsecase {
-	return &AIUsecase{
-		geminiService: geminiService,
-		claudeService: claudeService,
-		redisService:  redisService,
+func NewAIUsecase(geminiService AIProvider, claudeService AIProvider, redisService *cache.RedisService, retriever MailboxRetriever) *AIUsecase {
+	u := &AIUsecase{
+		geminiService:    geminiService,
+		claudeService:    claudeService,
+		redisService:     redisService,
+		retriever:        retriever,
+		defaultProvider:  "gemini",
+		allowedProviders: []string{"gemini", "claude"},
 	}
+	u.SetFeatures(true, true)
+	return u
+}
+
+// SetProviders sets the provider answering requests that name none, and the
+// providers requests may name.
+func (u *AIUsecase) SetProviders(defaultProvider string, allowed []string) {
+	u.providersMu.Lock()
+	defer u.providersMu.Unlock()
+	u.defaultProvider = defaultProvider
+	u.allowedProviders = allowed
+}
+
+// SetFeatures switches answering from the user's emails and classifying
+// emails for mail rules on or off.
+func (u *AIUsecase) SetFeatures(mailboxGrounding, classification bool) {
+	u.mailboxGrounding.Store(mailboxGrounding)
+	u.classification.Store(classification)
+}
+
+// provider resolves the provider a request names against the policy.
+func (u *AIUsecase) provider(requested string) (string, error) {
+	u.providersMu.RLock()
+	defer u.providersMu.RUnlock()
+	if requested == "" {
+		return u.defaultProvider, nil
+	}
+	if !slices.Contains(u.allowedProviders, requested) {
+		return "", errors.ErrBadRequest("Invalid provider. Use one of: " + strings.Join(u.allowedProviders, ", "))
+	}
+	return requested, nil
 }
 
 func (u *AIUsecase) ProcessAIRequest(ctx context.Context, userID string, req *models.AIRequest) (*models.AIResponse, error) {
+	ctx, span := tracing.Start(ctx, "AIUsecase.ProcessAIRequest")
+	defer span.End()
+
 	if req.Prompt == "" {
 		return nil, errors.ErrBadRequest("Prompt is required")
 	}
 
-	if req.Provider == "" {
-		req.Provider = "gemini"
+	switch req.Grounding {
+	case "":
+	case models.GroundingMailbox:
+		return u.askMailbox(ctx, userID, req)
+	default:
+		return nil, errors.ErrBadRequest("Invalid grounding. Use 'mailbox' or omit it")
+	}
+
+	return u.generate(ctx, req)
+}
+
+// askMailbox answers req.Prompt from the user's emails. The retrieved
+// passages are numbered in the prompt so that the model can cite them, and
+// the emails they came from are returned as citations.
+func (u *AIUsecase) askMailbox(ctx context.Context, userID string, req *models.AIRequest) (*models.AIResponse, error) {
+	ctx, span := tracing.Start(ctx, "AIUsecase.askMailbox")
+	defer span.End()
+
+	if u.retriever == nil {
+		return nil, errors.ErrServiceUnavailable("Mailbox grounding not configured")
+	}
+	if !u.mailboxGrounding.Load() {
+		return nil, errors.ErrServiceUnavailable("Mailbox grounding is disabled")
+	}
+
+	k := req.TopK
+	if k <= 0 {
+		k = defaultTopK
+	}
+	if k > maxTopK {
+		k = maxTopK
+	}
+
+	passages, err := u.retriever.Retrieve(ctx, userID, req.Prompt, k)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := u.generate(ctx, &models.AIRequest{
+		Prompt:   buildGroundedPrompt(req.Prompt, passages),
+		Provider: req.Provider,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	seen := make(map[string]bool)
+	resp.Citations = []models.Citation{}
+	for _, passage := range passages {
+		if seen[passage.EmailID] {
+			continue
+		}
+		seen[passage.EmailID] = true
+		resp.Citations = append(resp.Citations, passage)
+	}
+	return resp, nil
+}
+
+func buildGroundedPrompt(question string, passages []models.Citation) string {
+	var b strings.Builder
+	b.WriteString("Answer the question using only the email excerpts below. ")
+	b.WriteString("Cite the excerpts you rely on as [1], [2] and so on. ")
+	b.WriteString("If the excerpts do not contain the answer, say so instead of guessing.\n\n")
+
+	for i, passage := range passages {
+		fmt.Fprintf(&b, "[%d] From: %s | Date: %s\n%s\n\n", i+1, passage.From, passage.Date.Format("2006-01-02"), passage.Snippet)
+	}
+	if len(passages) == 0 {
+		b.WriteString("(No matching emails were found.)\n\n")
+	}
+
+	b.WriteString("Question: " + question + "\n")
+	return b.String()
+}
+
+// classifyBodyLength bounds how much of an email is sent for classification.
+const classifyBodyLength = 2000
+
+// ClassifyEmail asks the model which of categories email belongs to. It
+// returns "" when the answer is none of them.
+func (u *AIUsecase) ClassifyEmail(ctx context.Context, email *models.Email, categories []string) (string, error) {
+	ctx, span := tracing.Start(ctx, "AIUsecase.ClassifyEmail")
+	defer span.End()
+
+	if len(categories) == 0 {
+		return "", nil
+	}
+	if !u.classification.Load() {
+		return "", errors.ErrServiceUnavailable("AI classification is disabled")
+	}
+
+	body := derefString(email.Body)
+	if runes := []rune(body); len(runes) > classifyBodyLength {
+		body = string(runes[:classifyBodyLength])
+	}
+
+	var b strings.Builder
+	b.WriteString("Classify the email below into exactly one of these categories: ")
+	b.WriteString(strings.Join(categories, ", "))
+	b.WriteString(". Reply with the category name only, or \"none\" if no category fits.\n\n")
+	fmt.Fprintf(&b, "From: %s\nSubject: %s\n\n%s\n", email.From, derefString(email.Subject), body)
+
+	resp, err := u.generate(ctx, &models.AIRequest{Prompt: b.String()})
+	if err != nil {
+		return "", err
+	}
+
+	answer := strings.Trim(strings.TrimSpace(resp.Response), `"'.`)
+	for _, category := range categories {
+		if strings.EqualFold(answer, category) {
+			return category, nil
+		}
+	}
+	return "", nil
+}
+
+func (u *AIUsecase) generate(ctx context.Context, req *models.AIRequest) (*models.AIResponse, error) {
+	requested, err := u.provider(req.Provider)
+	if err != nil {
+		return nil, err
 	}
 
 	var response string
 	var provider string
-	var err error
 
-	switch req.Provider {
+	switch requested {
 	case "claude":
 		if u.claudeService == nil {
 			return nil, errors.ErrServiceUnavailable("Claude service not available")
 		}
-		response, err = u.claudeService.GenerateResponse(req.Prompt)
+		response, err = u.claudeService.GenerateResponse(ctx, req.Prompt)
 		provider = "claude"
 	case "gemini":
-		response, err = u.geminiService.GenerateResponse(req.Prompt)
+		response, err = u.geminiService.GenerateResponse(ctx, req.Prompt)
 		provider = "gemini"
 	default:
 		return nil, errors.ErrBadRequest("Invalid provider. Use 'gemini' or 'claude'")
@@ -67,10 +248,10 @@ func (u *AIUsecase) ProcessAIRequest(ctx context.Context, userID string, req *mo
 }
 
 type AuthUsecase struct {
-	userRepo *repository.UserRepository
+	userRepo repository.UserRepositoryInterface
 }
 
-func NewAuthUsecase(userRepo *repository.UserRepository) *AuthUsecase {
+func NewAuthUsecase(userRepo repository.UserRepositoryInterface) *AuthUsecase {
 	return &AuthUsecase{
 		userRepo: userRepo,
 	}
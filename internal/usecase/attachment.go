@@ -0,0 +1,259 @@
+package usecase
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	stderrors "errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"path"
+	"strings"
+
+	"ai-assistant/internal/models"
+	"ai-assistant/internal/services/blob"
+	"ai-assistant/internal/services/email"
+	"ai-assistant/internal/services/email/mailmime"
+	"ai-assistant/pkg/errors"
+	"ai-assistant/pkg/tracing"
+)
+
+// maxOutgoingAttachmentBytes is Gmail's limit for a message's attachments,
+// which other providers are at least as generous with.
+const maxOutgoingAttachmentBytes = 25 << 20
+
+func attachmentKey(userID, emailID, attachmentID string) string {
+	return "attachments/" + userID + "/" + emailID + "/" + attachmentID
+}
+
+// storeAttachment keeps the content of a freshly imported attachment when
+// the provider delivered it with the message. Gmail leaves larger parts out;
+// those are fetched on first download.
+func (u *EmailUsecase) storeAttachment(ctx context.Context, userID string, attachment *models.EmailAttachment, data []byte) {
+	if u.blobs == nil || len(data) == 0 {
+		return
+	}
+	key := attachmentKey(userID, attachment.EmailID, attachment.ID)
+	if err := u.blobs.Put(ctx, key, data, attachment.MimeType); err != nil {
+		return
+	}
+	if err := u.emailRepo.SetAttachmentStorageKey(ctx, attachment.ID, key); err == nil {
+		attachment.StorageKey = &key
+	}
+}
+
+func (u *EmailUsecase) ListAttachments(ctx context.Context, userID, emailID string) ([]*models.EmailAttachment, error) {
+	ctx, span := tracing.Start(ctx, "EmailUsecase.ListAttachments")
+	defer span.End()
+
+	if _, err := u.getOwnedEmail(ctx, userID, emailID); err != nil {
+		return nil, err
+	}
+
+	attachments, err := u.emailRepo.GetAttachments(ctx, emailID)
+	if err != nil {
+		return nil, errors.ErrDatabaseError
+	}
+	return attachments, nil
+}
+
+// OpenAttachment returns an attachment with its content and size. Content
+// that is not stored yet is fetched from the mailbox provider and stored.
+func (u *EmailUsecase) OpenAttachment(ctx context.Context, userID, emailID, attachmentID string) (*models.EmailAttachment, io.ReadCloser, int64, error) {
+	ctx, span := tracing.Start(ctx, "EmailUsecase.OpenAttachment")
+	defer span.End()
+
+	record, err := u.getOwnedEmail(ctx, userID, emailID)
+	if err != nil {
+		return nil, nil, 0, err
+	}
+
+	attachment, err := u.emailRepo.GetAttachment(ctx, emailID, attachmentID)
+	if err != nil {
+		return nil, nil, 0, errors.ErrDatabaseError
+	}
+	if attachment == nil {
+		return nil, nil, 0, errors.ErrNotFound("Attachment not found")
+	}
+	if u.blobs == nil {
+		return nil, nil, 0, errors.ErrServiceUnavailable("Attachment storage not configured")
+	}
+
+	if attachment.StorageKey != nil {
+		content, size, err := u.blobs.Get(ctx, *attachment.StorageKey)
+		if err == nil {
+			return attachment, content, size, nil
+		}
+		if !stderrors.Is(err, blob.ErrNotFound) {
+			return nil, nil, 0, errors.ErrInternalServerError("Failed to read attachment")
+		}
+		// The blob is gone; fetch it again if the provider still has it.
+	}
+
+	mailbox, err := u.mailboxFor(ctx, userID)
+	if err != nil {
+		return nil, nil, 0, err
+	}
+	fetcher, ok := mailbox.(email.AttachmentFetcher)
+	if !ok || attachment.AttachmentID == nil {
+		return nil, nil, 0, errors.ErrNotFound("Attachment content is not available")
+	}
+	data, err := fetcher.FetchAttachment(ctx, record.MessageID, *attachment.AttachmentID)
+	if err != nil {
+		return nil, nil, 0, errors.ErrExternalService
+	}
+
+	key := attachmentKey(userID, emailID, attachment.ID)
+	// Storing is only a cache; the content can still be served.
+	if err := u.blobs.Put(ctx, key, data, attachment.MimeType); err == nil {
+		if err := u.emailRepo.SetAttachmentStorageKey(ctx, attachment.ID, key); err == nil {
+			attachment.StorageKey = &key
+		}
+	}
+
+	return attachment, io.NopCloser(bytes.NewReader(data)), int64(len(data)), nil
+}
+
+// inlineImageTypes are the image formats served to <img> tags; SVG can
+// carry script and would run in the API's origin if opened directly.
+var inlineImageTypes = map[string]bool{
+	"image/png": true, "image/gif": true, "image/jpeg": true, "image/webp": true, "image/bmp": true,
+}
+
+// OpenInlineImage returns an image attachment of an email on behalf of the
+// email's owner. It is reached through signed URLs, which are what
+// authorize it, so only raster images are served.
+func (u *EmailUsecase) OpenInlineImage(ctx context.Context, emailID, attachmentID string) (*models.EmailAttachment, io.ReadCloser, int64, error) {
+	ctx, span := tracing.Start(ctx, "EmailUsecase.OpenInlineImage")
+	defer span.End()
+
+	record, err := u.emailRepo.GetByID(ctx, emailID)
+	if err != nil {
+		return nil, nil, 0, errors.ErrDatabaseError
+	}
+	if record == nil {
+		return nil, nil, 0, errors.ErrNotFound("Email not found")
+	}
+	attachment, err := u.emailRepo.GetAttachment(ctx, emailID, attachmentID)
+	if err != nil {
+		return nil, nil, 0, errors.ErrDatabaseError
+	}
+	if attachment == nil {
+		return nil, nil, 0, errors.ErrNotFound("Attachment not found")
+	}
+	if mediaType, _, _ := mime.ParseMediaType(attachment.MimeType); !inlineImageTypes[mediaType] {
+		return nil, nil, 0, errors.ErrNotFound("Attachment is not an image")
+	}
+	return u.OpenAttachment(ctx, record.UserID, emailID, attachmentID)
+}
+
+// storeOutgoing decodes uploaded attachments and stores them until the
+// queued email is dispatched. Every call uses fresh keys, so an edit that
+// loses the race with dispatch never changes what is sent.
+func (u *EmailUsecase) storeOutgoing(ctx context.Context, userID, scheduledID string, uploads []models.AttachmentUpload) ([]models.OutgoingAttachment, error) {
+	if len(uploads) == 0 {
+		return []models.OutgoingAttachment{}, nil
+	}
+	if u.blobs == nil {
+		return nil, errors.ErrServiceUnavailable("Attachment storage not configured")
+	}
+
+	type decoded struct {
+		attachment models.OutgoingAttachment
+		data       []byte
+	}
+	var files []decoded
+	total := 0
+	for _, upload := range uploads {
+		name := path.Base(strings.ReplaceAll(strings.TrimSpace(upload.Filename), "\\", "/"))
+		if name == "" || name == "." || name == "/" || strings.ContainsAny(name, "\r\n") {
+			return nil, errors.ErrBadRequest("Attachment filename is required")
+		}
+		data, err := base64.StdEncoding.DecodeString(upload.Content)
+		if err != nil {
+			return nil, errors.ErrBadRequest(fmt.Sprintf("Attachment %q is not valid base64", name))
+		}
+		total += len(data)
+		if total > maxOutgoingAttachmentBytes {
+			return nil, errors.ErrBadRequest("Attachments are larger than 25 MB")
+		}
+		if strings.ContainsAny(upload.ContentID, "<>\r\n") {
+			return nil, errors.ErrBadRequest("Attachment contentId must not contain angle brackets")
+		}
+
+		mimeType := upload.MimeType
+		if mimeType == "" {
+			mimeType = mime.TypeByExtension(path.Ext(name))
+		}
+		if mimeType == "" {
+			mimeType = http.DetectContentType(data)
+		}
+		if _, _, err := mime.ParseMediaType(mimeType); err != nil {
+			return nil, errors.ErrBadRequest(fmt.Sprintf("Attachment %q has an invalid mimeType", name))
+		}
+
+		files = append(files, decoded{
+			attachment: models.OutgoingAttachment{
+				Filename:   name,
+				MimeType:   mimeType,
+				Size:       int64(len(data)),
+				ContentID:  upload.ContentID,
+				StorageKey: "outgoing/" + userID + "/" + scheduledID + "/" + generateID("blob"),
+			},
+			data: data,
+		})
+	}
+
+	attachments := make([]models.OutgoingAttachment, 0, len(files))
+	for _, f := range files {
+		if err := u.blobs.Put(ctx, f.attachment.StorageKey, f.data, f.attachment.MimeType); err != nil {
+			u.deleteOutgoing(ctx, attachments)
+			return nil, errors.ErrInternalServerError("Failed to store attachment")
+		}
+		attachments = append(attachments, f.attachment)
+	}
+	return attachments, nil
+}
+
+// loadOutgoing reads a queued email's attachments back for sending.
+func (u *EmailUsecase) loadOutgoing(ctx context.Context, attachments []models.OutgoingAttachment) ([]*mailmime.Attachment, error) {
+	if len(attachments) == 0 {
+		return nil, nil
+	}
+	if u.blobs == nil {
+		return nil, fmt.Errorf("attachment storage not configured")
+	}
+
+	loaded := make([]*mailmime.Attachment, 0, len(attachments))
+	for _, a := range attachments {
+		content, _, err := u.blobs.Get(ctx, a.StorageKey)
+		if err != nil {
+			return nil, fmt.Errorf("attachment %q: %w", a.Filename, err)
+		}
+		data, err := io.ReadAll(content)
+		content.Close()
+		if err != nil {
+			return nil, fmt.Errorf("attachment %q: %w", a.Filename, err)
+		}
+		loaded = append(loaded, &mailmime.Attachment{
+			Filename:  a.Filename,
+			MimeType:  a.MimeType,
+			Size:      int64(len(data)),
+			ContentID: a.ContentID,
+			Inline:    a.ContentID != "",
+			Data:      data,
+		})
+	}
+	return loaded, nil
+}
+
+func (u *EmailUsecase) deleteOutgoing(ctx context.Context, attachments []models.OutgoingAttachment) {
+	if u.blobs == nil {
+		return
+	}
+	for _, a := range attachments {
+		u.blobs.Delete(ctx, a.StorageKey)
+	}
+}
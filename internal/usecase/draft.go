@@ -0,0 +1,378 @@
+package usecase
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"ai-assistant/internal/models"
+	"ai-assistant/internal/repository"
+	"ai-assistant/internal/services/email/mailmime"
+	"ai-assistant/pkg/errors"
+)
+
+const (
+	// draftHistoryMessages bounds how much of a thread is sent to the model.
+	draftHistoryMessages = 10
+	// draftHistoryChars bounds each quoted message body in the prompt.
+	draftHistoryChars = 4000
+)
+
+// draftTransitions lists the states a draft may move to from each state.
+// Editing an approved draft revokes the approval; sent and discarded are
+// terminal.
+var draftTransitions = map[models.DraftStatus][]models.DraftStatus{
+	models.DraftStatusGenerated: {models.DraftStatusEdited, models.DraftStatusApproved, models.DraftStatusDiscarded},
+	models.DraftStatusEdited:    {models.DraftStatusEdited, models.DraftStatusApproved, models.DraftStatusDiscarded},
+	models.DraftStatusApproved:  {models.DraftStatusEdited, models.DraftStatusSent, models.DraftStatusDiscarded},
+}
+
+type DraftUsecase struct {
+	draftRepo    *repository.DraftRepository
+	emailRepo    repository.EmailRepositoryInterface
+	aiUsecase    *AIUsecase
+	emailUsecase *EmailUsecase
+}
+
+func NewDraftUsecase(draftRepo *repository.DraftRepository, emailRepo repository.EmailRepositoryInterface, aiUsecase *AIUsecase, emailUsecase *EmailUsecase) *DraftUsecase {
+	return &DraftUsecase{
+		draftRepo:    draftRepo,
+		emailRepo:    emailRepo,
+		aiUsecase:    aiUsecase,
+		emailUsecase: emailUsecase,
+	}
+}
+
+// GenerateDraft asks the AI provider for a reply to an email, using the rest
+// of its thread as context, and stores the result as a draft awaiting review.
+func (u *DraftUsecase) GenerateDraft(ctx context.Context, userID, userEmail string, req *models.GenerateDraftRequest) (*models.Draft, error) {
+	if req.EmailID == "" {
+		return nil, errors.ErrBadRequest("emailId is required")
+	}
+
+	email, err := u.emailUsecase.getOwnedEmail(ctx, userID, req.EmailID)
+	if err != nil {
+		return nil, err
+	}
+
+	history := []*models.Email{email}
+	if email.ThreadID != nil && *email.ThreadID != "" {
+		thread, err := u.emailRepo.GetByThreadID(ctx, userID, *email.ThreadID)
+		if err != nil {
+			return nil, errors.ErrDatabaseError
+		}
+		if len(thread) > 0 {
+			history = threadUpTo(thread, email.ID)
+		}
+	}
+
+	aiResp, err := u.aiUsecase.ProcessAIRequest(ctx, userID, &models.AIRequest{
+		Prompt:   buildDraftPrompt(userEmail, history, req.Instructions),
+		Provider: req.Provider,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	to, cc := mailmime.ReplyRecipients(toOriginal(email), []string{userEmail}, req.ReplyAll)
+	now := time.Now()
+	draft := &models.Draft{
+		ID:        generateID("draft"),
+		UserID:    userID,
+		EmailID:   email.ID,
+		ThreadID:  email.ThreadID,
+		To:        to,
+		Cc:        cc,
+		Subject:   mailmime.ReplySubject(derefString(email.Subject)),
+		Body:      strings.TrimSpace(aiResp.Response),
+		Status:    models.DraftStatusGenerated,
+		Provider:  aiResp.Provider,
+		CreatedAt: now,
+		UpdatedAt: now,
+	}
+	if req.Instructions != "" {
+		draft.Instructions = &req.Instructions
+	}
+
+	if req.SyncToGmail {
+		if err := u.syncToGmail(ctx, draft, email, userEmail); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := u.draftRepo.Create(ctx, draft); err != nil {
+		return nil, errors.ErrDatabaseError
+	}
+	return draft, nil
+}
+
+func (u *DraftUsecase) GetDraft(ctx context.Context, userID, draftID string) (*models.Draft, error) {
+	draft, err := u.draftRepo.GetByID(ctx, draftID)
+	if err != nil {
+		return nil, errors.ErrDatabaseError
+	}
+	if draft == nil || draft.UserID != userID {
+		return nil, errors.ErrNotFound("Draft not found")
+	}
+	return draft, nil
+}
+
+func (u *DraftUsecase) ListDrafts(ctx context.Context, userID string, status models.DraftStatus, limit, offset int) ([]*models.Draft, error) {
+	if limit <= 0 || limit > 100 {
+		limit = 20
+	}
+
+	drafts, err := u.draftRepo.GetByUserID(ctx, userID, status, limit, offset)
+	if err != nil {
+		return nil, errors.ErrDatabaseError
+	}
+	return drafts, nil
+}
+
+// UpdateDraft applies user edits. Any edit, including one to an approved
+// draft, leaves the draft in the edited state so it must be re-approved.
+func (u *DraftUsecase) UpdateDraft(ctx context.Context, userID, userEmail, draftID string, req *models.UpdateDraftRequest) (*models.Draft, error) {
+	draft, err := u.GetDraft(ctx, userID, draftID)
+	if err != nil {
+		return nil, err
+	}
+	if !canTransition(draft.Status, models.DraftStatusEdited) {
+		return nil, errors.ErrConflict(fmt.Sprintf("Cannot edit a %s draft", draft.Status))
+	}
+
+	if req.To != nil {
+		draft.To = req.To
+	}
+	if req.Cc != nil {
+		draft.Cc = req.Cc
+	}
+	if req.Bcc != nil {
+		draft.Bcc = req.Bcc
+	}
+	if req.Subject != nil {
+		draft.Subject = *req.Subject
+	}
+	if req.Body != nil {
+		draft.Body = *req.Body
+	}
+
+	if draft.GmailDraftID != nil {
+		if err := u.syncToGmail(ctx, draft, nil, userEmail); err != nil {
+			return nil, err
+		}
+	}
+
+	return u.transition(ctx, draft, models.DraftStatusEdited)
+}
+
+func (u *DraftUsecase) ApproveDraft(ctx context.Context, userID, draftID string) (*models.Draft, error) {
+	draft, err := u.GetDraft(ctx, userID, draftID)
+	if err != nil {
+		return nil, err
+	}
+	return u.transition(ctx, draft, models.DraftStatusApproved)
+}
+
+func (u *DraftUsecase) DiscardDraft(ctx context.Context, userID, draftID string) (*models.Draft, error) {
+	draft, err := u.GetDraft(ctx, userID, draftID)
+	if err != nil {
+		return nil, err
+	}
+
+	draft, err = u.transition(ctx, draft, models.DraftStatusDiscarded)
+	if err != nil {
+		return nil, err
+	}
+
+	if draft.GmailDraftID != nil {
+		// The local draft is already discarded; a stale server copy is harmless.
+		if drafts, err := u.emailUsecase.draftsFor(ctx, userID); err == nil && drafts != nil {
+			drafts.DeleteDraft(ctx, *draft.GmailDraftID)
+		}
+	}
+	return draft, nil
+}
+
+// SendDraft sends an approved draft. The draft is moved to sent before
+// delivery so that concurrent requests cannot send it twice, and moved back
+// to approved if delivery fails.
+func (u *DraftUsecase) SendDraft(ctx context.Context, userID, userEmail, draftID string) (*models.Draft, error) {
+	draft, err := u.GetDraft(ctx, userID, draftID)
+	if err != nil {
+		return nil, err
+	}
+	if draft.Status != models.DraftStatusApproved {
+		return nil, errors.ErrConflict("Only approved drafts can be sent")
+	}
+
+	email, err := u.emailUsecase.getOwnedEmail(ctx, userID, draft.EmailID)
+	if err != nil {
+		return nil, err
+	}
+
+	if _, err := u.transition(ctx, draft, models.DraftStatusSent); err != nil {
+		return nil, err
+	}
+
+	sent, sendErr := u.deliverDraft(ctx, draft, email, userEmail)
+	if sendErr != nil {
+		draft.Status = models.DraftStatusApproved
+		if _, err := u.draftRepo.Update(ctx, draft, models.DraftStatusSent); err != nil {
+			return nil, errors.ErrDatabaseError
+		}
+		return nil, sendErr
+	}
+
+	draft.SentMessageID = &sent.ID
+	if _, err := u.draftRepo.Update(ctx, draft, models.DraftStatusSent); err != nil {
+		return nil, errors.ErrDatabaseError
+	}
+	return draft, nil
+}
+
+func (u *DraftUsecase) deliverDraft(ctx context.Context, draft *models.Draft, email *models.Email, userEmail string) (*models.SentEmail, error) {
+	drafts, err := u.emailUsecase.draftsFor(ctx, draft.UserID)
+	if err != nil {
+		return nil, err
+	}
+	if draft.GmailDraftID != nil && drafts != nil {
+		result, err := drafts.SendDraft(ctx, *draft.GmailDraftID)
+		if err != nil {
+			return nil, errors.ErrInternalServerError(fmt.Sprintf("Failed to send draft: %v", err))
+		}
+
+		// The provider composed the message, so only the draft's fields are
+		// known; the send has happened either way.
+		sentMsg := &mailmime.Outgoing{From: userEmail, To: draft.To, Cc: draft.Cc, Bcc: draft.Bcc, Subject: draft.Subject}
+		outbound := newOutbound(draft.UserID, "gmail", draft.ThreadID, sentMsg, models.OutboundStatusSent)
+		outbound.ProviderMessageID = optionalString(result.ID)
+		u.emailUsecase.recordOutbound(ctx, outbound)
+
+		return &models.SentEmail{ID: result.ID, ThreadID: result.ThreadID, To: draft.To, Cc: draft.Cc, Subject: draft.Subject}, nil
+	}
+
+	msg, err := buildReply(email, userEmail, draftReplyRequest(draft))
+	if err != nil {
+		return nil, err
+	}
+	return u.emailUsecase.deliver(ctx, draft.UserID, email.ThreadID, msg)
+}
+
+// syncToGmail creates or updates the provider's copy of a draft. email may
+// be nil when the draft already exists, in which case it is loaded.
+func (u *DraftUsecase) syncToGmail(ctx context.Context, draft *models.Draft, email *models.Email, userEmail string) error {
+	drafts, err := u.emailUsecase.draftsFor(ctx, draft.UserID)
+	if err != nil {
+		return err
+	}
+	if drafts == nil {
+		return errors.ErrServiceUnavailable("Draft sync not supported by the configured provider")
+	}
+
+	if email == nil {
+		var err error
+		if email, err = u.emailUsecase.getOwnedEmail(ctx, draft.UserID, draft.EmailID); err != nil {
+			return err
+		}
+	}
+
+	msg, err := buildReply(email, userEmail, draftReplyRequest(draft))
+	if err != nil {
+		return err
+	}
+
+	threadID := derefString(draft.ThreadID)
+	if draft.GmailDraftID != nil {
+		if err := drafts.UpdateDraft(ctx, *draft.GmailDraftID, threadID, msg); err != nil {
+			return errors.ErrExternalService
+		}
+		return nil
+	}
+
+	id, err := drafts.CreateDraft(ctx, threadID, msg)
+	if err != nil {
+		return errors.ErrExternalService
+	}
+	draft.GmailDraftID = &id
+	return nil
+}
+
+// transition validates and persists a status change.
+func (u *DraftUsecase) transition(ctx context.Context, draft *models.Draft, next models.DraftStatus) (*models.Draft, error) {
+	if !canTransition(draft.Status, next) {
+		return nil, errors.ErrConflict(fmt.Sprintf("Cannot move draft from %s to %s", draft.Status, next))
+	}
+
+	previous := draft.Status
+	draft.Status = next
+	updated, err := u.draftRepo.Update(ctx, draft, previous)
+	if err != nil {
+		return nil, errors.ErrDatabaseError
+	}
+	if !updated {
+		return nil, errors.ErrConflict("Draft was modified concurrently")
+	}
+	return draft, nil
+}
+
+func canTransition(from, to models.DraftStatus) bool {
+	for _, status := range draftTransitions[from] {
+		if status == to {
+			return true
+		}
+	}
+	return false
+}
+
+func draftReplyRequest(draft *models.Draft) *models.ReplyRequest {
+	return &models.ReplyRequest{
+		Body:    draft.Body,
+		To:      draft.To,
+		Cc:      draft.Cc,
+		Bcc:     draft.Bcc,
+		Subject: draft.Subject,
+	}
+}
+
+// threadUpTo returns the last messages of thread ending with the message
+// being replied to.
+func threadUpTo(thread []*models.Email, emailID string) []*models.Email {
+	end := len(thread)
+	for i, email := range thread {
+		if email.ID == emailID {
+			end = i + 1
+			break
+		}
+	}
+	start := end - draftHistoryMessages
+	if start < 0 {
+		start = 0
+	}
+	return thread[start:end]
+}
+
+func buildDraftPrompt(userEmail string, history []*models.Email, instructions string) string {
+	var b strings.Builder
+	b.WriteString("You are an email assistant drafting a reply on behalf of " + userEmail + ".\n")
+	b.WriteString("Below is the conversation so far, oldest message first. Write a reply to the last message.\n")
+	b.WriteString("Respond with the reply body only: no subject line, no quoted text and no placeholders.\n\n")
+
+	for i, email := range history {
+		body := derefString(email.Body)
+		if len(body) > draftHistoryChars {
+			body = strings.ToValidUTF8(body[:draftHistoryChars], "") + "\n[...]"
+		}
+		fmt.Fprintf(&b, "--- Message %d ---\n", i+1)
+		fmt.Fprintf(&b, "From: %s\n", email.From)
+		fmt.Fprintf(&b, "To: %s\n", strings.Join(email.To, ", "))
+		fmt.Fprintf(&b, "Date: %s\n", email.CreatedAt.Format(time.RFC1123Z))
+		fmt.Fprintf(&b, "Subject: %s\n\n", derefString(email.Subject))
+		b.WriteString(body + "\n\n")
+	}
+
+	if instructions != "" {
+		b.WriteString("Additional instructions from the user: " + instructions + "\n")
+	}
+	return b.String()
+}
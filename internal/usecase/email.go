@@ -2,30 +2,127 @@ package usecase
 
 import (
 	"context"
+	"crypto/rand"
+	"encoding/hex"
 	"fmt"
+	"html"
+	"strings"
+	"time"
 
 	"ai-assistant/internal/models"
 	"ai-assistant/internal/repository"
-	"ai-assistant/internal/services/email/gmail"
-	"ai-assistant/internal/services/email/resend"
+	"ai-assistant/internal/services/blob"
+	"ai-assistant/internal/services/email"
+	"ai-assistant/internal/services/email/mailmime"
+	"ai-assistant/internal/services/email/search"
+	"ai-assistant/internal/services/email/threading"
 	"ai-assistant/pkg/errors"
+	"ai-assistant/pkg/tracing"
 )
 
+// syncBatchSize is how many messages one SyncMailbox call imports.
+const syncBatchSize = 50
+
+// EmailIndexer is told about newly synced emails, e.g. to embed them for
+// retrieval. RequestBackfill asks it to index a user's earlier mail in the
+// background if it has none of it.
+type EmailIndexer interface {
+	IndexEmail(ctx context.Context, email *models.Email) error
+	RequestBackfill(ctx context.Context, userID string)
+}
+
+// RuleRunner applies the user's mail rules to a newly synced email.
+type RuleRunner interface {
+	ApplyRules(ctx context.Context, userID string, email *models.Email) error
+}
+
+// EmailUsecase works against the provider-neutral mailbox and sender
+// interfaces so that Gmail, IMAP, Resend and SMTP can be mixed freely. Both
+// are resolved per user, since each Gmail user has a mailbox of their own.
 type EmailUsecase struct {
-	emailRepo    *repository.EmailRepository
-	resendSvc    *resend.ResendService
-	gmailSvc     *gmail.GmailService
+	emailRepo    repository.EmailRepositoryInterface
+	outboundRepo repository.OutboundRepositoryInterface
+	mailboxes    email.MailboxFactory
+	senders      email.SenderFactory
+	blobs        blob.Store
+	indexer      EmailIndexer
+	rules        RuleRunner
 }
 
-func NewEmailUsecase(emailRepo *repository.EmailRepository, resendSvc *resend.ResendService, gmailSvc *gmail.GmailService) *EmailUsecase {
+// NewEmailUsecase wires the usecase to its providers. Any of them may be
+// nil. If a user's sender or mailbox also keeps drafts server-side, drafts
+// are synced to it. Everything sent is recorded in outboundRepo for
+// delivery tracking. Attachment content is kept in blobs.
+func NewEmailUsecase(emailRepo repository.EmailRepositoryInterface, outboundRepo repository.OutboundRepositoryInterface, mailboxes email.MailboxFactory, senders email.SenderFactory, blobs blob.Store, indexer EmailIndexer) *EmailUsecase {
 	return &EmailUsecase{
-		emailRepo: emailRepo,
-		resendSvc: resendSvc,
-		gmailSvc:  gmailSvc,
+		emailRepo:    emailRepo,
+		outboundRepo: outboundRepo,
+		mailboxes:    mailboxes,
+		senders:      senders,
+		blobs:        blobs,
+		indexer:      indexer,
+	}
+}
+
+// mailboxFor returns the user's mailbox, or nil if they have none.
+func (u *EmailUsecase) mailboxFor(ctx context.Context, userID string) (email.MailboxProvider, error) {
+	return openMailbox(ctx, u.mailboxes, userID)
+}
+
+// senderFor returns the sender of the user's mail, or nil if they have none.
+func (u *EmailUsecase) senderFor(ctx context.Context, userID string) (email.Sender, error) {
+	if u.senders == nil {
+		return nil, nil
+	}
+	sender, err := u.senders(ctx, userID)
+	if err != nil {
+		return nil, errors.ErrExternalService
+	}
+	return sender, nil
+}
+
+// draftsFor returns where the user's drafts are kept server-side: their
+// sender if it keeps drafts, else their mailbox if it does, else nil.
+func (u *EmailUsecase) draftsFor(ctx context.Context, userID string) (email.DraftStore, error) {
+	sender, err := u.senderFor(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if drafts, ok := sender.(email.DraftStore); ok {
+		return drafts, nil
+	}
+	mailbox, err := u.mailboxFor(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if drafts, ok := mailbox.(email.DraftStore); ok {
+		return drafts, nil
+	}
+	return nil, nil
+}
+
+// openMailbox resolves a user's mailbox through mailboxes, which may be nil.
+func openMailbox(ctx context.Context, mailboxes email.MailboxFactory, userID string) (email.MailboxProvider, error) {
+	if mailboxes == nil {
+		return nil, nil
+	}
+	mailbox, err := mailboxes(ctx, userID)
+	if err != nil {
+		return nil, errors.ErrExternalService
 	}
+	return mailbox, nil
+}
+
+// SetRuleRunner installs the rules engine. It is set after construction
+// because the engine itself acts on mail through this usecase.
+func (u *EmailUsecase) SetRuleRunner(rules RuleRunner) {
+	u.rules = rules
 }
 
 func (u *EmailUsecase) GetUserEmails(ctx context.Context, userID string, limit, offset int) ([]*models.Email, error) {
+	ctx, span := tracing.Start(ctx, "EmailUsecase.GetUserEmails")
+	defer span.End()
+
 	if limit <= 0 || limit > 100 {
 		limit = 20
 	}
@@ -38,63 +135,578 @@ func (u *EmailUsecase) GetUserEmails(ctx context.Context, userID string, limit,
 	return emails, nil
 }
 
-func (u *EmailUsecase) SendEmail(ctx context.Context, from string, to []string, subject, body string) error {
-	if u.resendSvc == nil {
-		return errors.ErrServiceUnavailable("Email service not configured")
+// SearchEmails runs a Gmail-style query, e.g. `from:alice has:attachment
+// "budget review" -is:unread`, over the user's synced emails.
+func (u *EmailUsecase) SearchEmails(ctx context.Context, userID, rawQuery string, limit, offset int) ([]*models.EmailSearchResult, error) {
+	ctx, span := tracing.Start(ctx, "EmailUsecase.SearchEmails")
+	defer span.End()
+
+	if limit <= 0 || limit > 100 {
+		limit = 20
 	}
-	
-	req := resend.EmailRequest{
+
+	query, err := search.Parse(rawQuery)
+	if err != nil {
+		return nil, errors.ErrBadRequest(err.Error())
+	}
+
+	results, err := u.emailRepo.Search(ctx, userID, query, limit, offset)
+	if err != nil {
+		return nil, errors.ErrDatabaseError
+	}
+
+	return results, nil
+}
+
+func (u *EmailUsecase) SendEmail(ctx context.Context, userID, from string, to []string, subject, body string) error {
+	ctx, span := tracing.Start(ctx, "EmailUsecase.SendEmail")
+	defer span.End()
+
+	msg := &mailmime.Outgoing{
 		From:    from,
 		To:      to,
 		Subject: subject,
 		Text:    body,
 	}
-	
-	_, err := u.resendSvc.SendEmail(req)
+	_, err := u.deliver(ctx, userID, nil, msg)
+	return err
+}
+
+// SyncMailbox imports the most recent messages from the mailbox provider.
+func (u *EmailUsecase) SyncMailbox(ctx context.Context, userID string) error {
+	ctx, span := tracing.Start(ctx, "EmailUsecase.SyncMailbox")
+	defer span.End()
+
+	mailbox, err := u.mailboxFor(ctx, userID)
 	if err != nil {
-		return errors.ErrInternalServerError(fmt.Sprintf("Failed to send email: %v", err))
+		return err
 	}
-	
-	return nil
+	if mailbox == nil {
+		return errors.ErrServiceUnavailable("Mailbox provider not configured")
+	}
+	return u.SyncRecent(ctx, userID, mailbox)
 }
 
-func (u *EmailUsecase) SyncGmailEmails(ctx context.Context, userID string) error {
-	if u.gmailSvc == nil {
-		return errors.ErrServiceUnavailable("Gmail service not configured")
-	}
-	
-	messages, err := u.gmailSvc.GetMessages(userID, 50)
+// SyncRecent imports the most recent messages of mailbox, which need not be
+// the configured one, e.g. a user's own Gmail mailbox.
+func (u *EmailUsecase) SyncRecent(ctx context.Context, userID string, mailbox email.MailboxProvider) error {
+	ctx, span := tracing.Start(ctx, "EmailUsecase.SyncRecent")
+	defer span.End()
+
+	list, err := mailbox.List(ctx, email.ListOptions{MaxResults: syncBatchSize})
 	if err != nil {
 		return errors.ErrExternalService
 	}
-	
-	for _, msg := range messages {
-		email := &models.Email{
-			ID:        generateID(),
-			MessageID: msg.ID,
-			ThreadID:  &msg.ThreadID,
-			Subject:   &msg.Subject,
-			From:      msg.From,
-			To:        msg.To,
-			Body:      &msg.Body,
-			HTMLBody:  &msg.HTMLBody,
-			IsRead:    msg.IsRead,
-			Labels:    msg.Labels,
-			UserID:    userID,
+
+	ids := make([]string, 0, len(list.Messages))
+	for _, ref := range list.Messages {
+		ids = append(ids, ref.ID)
+	}
+	u.ImportMessages(ctx, userID, mailbox, ids)
+	return nil
+}
+
+// ImportMessages fetches and stores the given messages of mailbox, indexing
+// them and running the user's rules. Messages that cannot be fetched or are
+// already stored are skipped. It returns how many were imported.
+func (u *EmailUsecase) ImportMessages(ctx context.Context, userID string, mailbox email.MailboxProvider, ids []string) int {
+	ctx, span := tracing.Start(ctx, "EmailUsecase.ImportMessages")
+	defer span.End()
+
+	if u.indexer != nil {
+		// Before this batch is indexed, so that it does not hide a missing
+		// index.
+		u.indexer.RequestBackfill(ctx, userID)
+	}
+
+	imported := 0
+	for _, id := range ids {
+		msg, err := mailbox.Fetch(ctx, id)
+		if err != nil {
+			continue
+		}
+
+		createdAt := msg.Date
+		if createdAt.IsZero() {
+			createdAt = time.Now()
+		}
+
+		threadID := msg.ThreadID
+		if threadID == "" {
+			threadID = u.reconstructThreadID(ctx, userID, msg)
+		}
+
+		record := &models.Email{
+			ID:         generateID("email"),
+			MessageID:  msg.ID,
+			ThreadID:   optionalString(threadID),
+			Subject:    &msg.Subject,
+			From:       msg.From,
+			To:         msg.To,
+			Cc:         msg.Cc,
+			ReplyTo:    optionalString(msg.ReplyTo),
+			HeaderID:   optionalString(msg.HeaderID),
+			InReplyTo:  optionalString(msg.InReplyTo),
+			References: msg.References,
+			Body:       &msg.Body,
+			HTMLBody:   &msg.HTMLBody,
+			IsRead:     msg.IsRead,
+			Labels:     msg.Labels,
+			CreatedAt:  createdAt,
+			UserID:     userID,
 		}
 		
-		if err := u.emailRepo.Create(ctx, email); err != nil {
+		attachments := make([]*models.EmailAttachment, 0, len(msg.Attachments))
+		var contents [][]byte
+		for _, att := range msg.Attachments {
+			attachment := &models.EmailAttachment{
+				ID:        generateID("att"),
+				EmailID:   record.ID,
+				Filename:  att.Filename,
+				MimeType:  att.MimeType,
+				Size:      att.Size,
+				Inline:    att.Inline,
+				CreatedAt: createdAt,
+			}
+			if att.AttachmentID != "" {
+				attachment.AttachmentID = &att.AttachmentID
+			}
+			if att.ContentID != "" {
+				attachment.ContentID = &att.ContentID
+			}
+			attachments = append(attachments, attachment)
+			contents = append(contents, att.Data)
+		}
+
+		if err := u.emailRepo.CreateWithAttachments(ctx, record, attachments); err != nil {
 			continue
 		}
+		imported++
+		for i, attachment := range attachments {
+			u.storeAttachment(ctx, userID, attachment, contents[i])
+		}
+		if u.indexer != nil {
+			// An unindexed email is only missing from retrieval; keep syncing.
+			u.indexer.IndexEmail(ctx, record)
+		}
+
+		if u.rules != nil {
+			// A failing rule must not stop the rest of the mailbox syncing.
+			u.rules.ApplyRules(ctx, userID, record)
+		}
 	}
-	
+
+	return imported
+}
+
+// reconstructThreadID files mail from providers without server-side threads
+// into the thread of the nearest known message it replies to, or else into a
+// thread derived from the conversation's root Message-ID.
+func (u *EmailUsecase) reconstructThreadID(ctx context.Context, userID string, msg *email.Message) string {
+	ancestors := threading.Ancestors(msg.InReplyTo, msg.References)
+	if threadID, err := u.emailRepo.FindThreadID(ctx, userID, ancestors); err == nil && threadID != "" {
+		return threadID
+	}
+	return threading.DeriveID(msg.HeaderID, msg.InReplyTo, msg.References)
+}
+
+// MarkEmailAsRead updates the local copy and, when the owner has a mailbox,
+// the message on the server.
+func (u *EmailUsecase) MarkEmailAsRead(ctx context.Context, userID, emailID string) error {
+	ctx, span := tracing.Start(ctx, "EmailUsecase.MarkEmailAsRead")
+	defer span.End()
+
+	record, err := u.getOwnedEmail(ctx, userID, emailID)
+	if err != nil {
+		return err
+	}
+	mailbox, err := u.mailboxFor(ctx, userID)
+	if err != nil {
+		return err
+	}
+	if mailbox != nil {
+		if err := mailbox.ModifyLabels(ctx, record.MessageID, nil, []string{email.LabelUnread}); err != nil {
+			return errors.ErrExternalService
+		}
+	}
+	if err := u.emailRepo.UpdateReadStatus(ctx, emailID, true); err != nil {
+		return errors.ErrDatabaseError
+	}
+	return nil
+}
+
+// ModifyEmailLabels adds and removes labels on one email, on the mailbox
+// provider first so that a provider failure leaves the local copy untouched.
+func (u *EmailUsecase) ModifyEmailLabels(ctx context.Context, userID, emailID string, add, remove []string) (*models.Email, error) {
+	ctx, span := tracing.Start(ctx, "EmailUsecase.ModifyEmailLabels")
+	defer span.End()
+
+	add, remove = cleanLabels(add), cleanLabels(remove)
+	if len(add) == 0 && len(remove) == 0 {
+		return nil, errors.ErrBadRequest("No labels to add or remove")
+	}
+
+	record, err := u.getOwnedEmail(ctx, userID, emailID)
+	if err != nil {
+		return nil, err
+	}
+
+	mailbox, err := u.mailboxFor(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if mailbox != nil {
+		if err := mailbox.ModifyLabels(ctx, record.MessageID, add, remove); err != nil {
+			return nil, errors.ErrExternalService
+		}
+	}
+
+	if _, err := u.emailRepo.ModifyLabels(ctx, userID, emailID, add, remove); err != nil {
+		return nil, errors.ErrDatabaseError
+	}
+
+	return u.getOwnedEmail(ctx, userID, emailID)
+}
+
+func (u *EmailUsecase) ReplyToEmail(ctx context.Context, userID, userEmail, emailID string, req *models.ReplyRequest) (*models.SentEmail, error) {
+	ctx, span := tracing.Start(ctx, "EmailUsecase.ReplyToEmail")
+	defer span.End()
+
+	email, err := u.getOwnedEmail(ctx, userID, emailID)
+	if err != nil {
+		return nil, err
+	}
+
+	msg, err := buildReply(email, userEmail, req)
+	if err != nil {
+		return nil, err
+	}
+
+	return u.deliver(ctx, userID, email.ThreadID, msg)
+}
+
+// buildReply composes a reply to email that quotes the original and carries
+// the In-Reply-To and References headers of its thread.
+func buildReply(email *models.Email, userEmail string, req *models.ReplyRequest) (*mailmime.Outgoing, error) {
+	if req.Body == "" && req.HTML == "" {
+		return nil, errors.ErrBadRequest("Reply body is required")
+	}
+
+	orig := toOriginal(email)
+	to, cc := mailmime.ReplyRecipients(orig, []string{userEmail}, req.ReplyAll)
+	if len(req.To) > 0 {
+		to = req.To
+	}
+	if len(to) == 0 {
+		return nil, errors.ErrBadRequest("Reply has no recipients")
+	}
+
+	subject := req.Subject
+	if subject == "" {
+		subject = mailmime.ReplySubject(orig.Subject)
+	}
+
+	msg := &mailmime.Outgoing{
+		From:       userEmail,
+		To:         to,
+		Cc:         append(cc, req.Cc...),
+		Bcc:        req.Bcc,
+		Subject:    subject,
+		Text:       req.Body + "\n\n" + mailmime.QuoteText(orig),
+		InReplyTo:  orig.MessageID,
+		References: mailmime.ReplyReferences(orig),
+	}
+	if req.HTML != "" || orig.HTML != "" {
+		body := req.HTML
+		if body == "" {
+			body = textToHTML(req.Body)
+		}
+		msg.HTML = body + "<br><br>" + mailmime.QuoteHTML(orig)
+	}
+
+	return msg, nil
+}
+
+func (u *EmailUsecase) ForwardEmail(ctx context.Context, userID, userEmail, emailID string, req *models.ForwardRequest) (*models.SentEmail, error) {
+	ctx, span := tracing.Start(ctx, "EmailUsecase.ForwardEmail")
+	defer span.End()
+
+	if len(req.To) == 0 {
+		return nil, errors.ErrBadRequest("At least one recipient is required")
+	}
+
+	email, err := u.getOwnedEmail(ctx, userID, emailID)
+	if err != nil {
+		return nil, err
+	}
+
+	orig := toOriginal(email)
+	msg := &mailmime.Outgoing{
+		From:       userEmail,
+		To:         req.To,
+		Cc:         req.Cc,
+		Bcc:        req.Bcc,
+		Subject:    mailmime.ForwardSubject(orig.Subject),
+		Text:       joinNonEmpty(req.Body, mailmime.ForwardText(orig)),
+		References: mailmime.ReplyReferences(orig),
+	}
+	if orig.HTML != "" {
+		msg.HTML = joinNonEmpty(textToHTML(req.Body), mailmime.ForwardHTML(orig))
+	}
+
+	return u.deliver(ctx, userID, email.ThreadID, msg)
+}
+
+func (u *EmailUsecase) getOwnedEmail(ctx context.Context, userID, emailID string) (*models.Email, error) {
+	email, err := u.emailRepo.GetByID(ctx, emailID)
+	if err != nil {
+		return nil, errors.ErrDatabaseError
+	}
+	if email == nil || email.UserID != userID {
+		return nil, errors.ErrNotFound("Email not found")
+	}
+	return email, nil
+}
+
+// deliver sends msg through the user's sender. Providers with
+// server-side threads file it into the original conversation; for the rest
+// only the threading headers keep recipients' clients grouping it correctly.
+// The message is recorded as queued first, so that a send whose outcome is
+// lost still shows up in the sent list.
+func (u *EmailUsecase) deliver(ctx context.Context, userID string, threadID *string, msg *mailmime.Outgoing) (*models.SentEmail, error) {
+	ctx, span := tracing.Start(ctx, "EmailUsecase.deliver")
+	defer span.End()
+
+	sender, err := u.senderFor(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if sender == nil {
+		return nil, errors.ErrServiceUnavailable("Email service not configured")
+	}
+	if err := msg.Prepare(); err != nil {
+		return nil, errors.ErrBadRequest(err.Error())
+	}
+
+	outbound := newOutbound(userID, sender.Name(), threadID, msg, models.OutboundStatusQueued)
+	if err := u.recordOutbound(ctx, outbound); err != nil {
+		return nil, err
+	}
+
+	result, err := sender.Send(ctx, msg, email.SendOptions{ThreadID: derefString(threadID)})
+	if err != nil {
+		sendErr := err.Error()
+		u.outboundRepo.SetSendResult(ctx, outbound.ID, models.OutboundStatusFailed, nil, nil, &sendErr)
+		u.addOutboundEvent(ctx, outbound.ID, models.OutboundStatusFailed, sendErr)
+		return nil, errors.ErrInternalServerError(fmt.Sprintf("Failed to send email: %v", err))
+	}
+
+	// The message is out; failing to record that must not report the send
+	// as failed and invite a retry.
+	u.outboundRepo.SetSendResult(ctx, outbound.ID, models.OutboundStatusSent,
+		optionalString(result.ID), optionalString(result.ThreadID), nil)
+	u.addOutboundEvent(ctx, outbound.ID, models.OutboundStatusSent, "")
+
+	return &models.SentEmail{
+		ID:         result.ID,
+		OutboundID: outbound.ID,
+		ThreadID:   result.ThreadID,
+		MessageID:  msg.MessageID,
+		To:         msg.To,
+		Cc:         msg.Cc,
+		Subject:    msg.Subject,
+	}, nil
+}
+
+// outboundRank orders delivery states so that webhook events, which can
+// arrive out of order, only ever move a message forward.
+var outboundRank = map[models.OutboundStatus]int{
+	models.OutboundStatusQueued:     0,
+	models.OutboundStatusSent:       1,
+	models.OutboundStatusFailed:     1,
+	models.OutboundStatusDelivered:  2,
+	models.OutboundStatusOpened:     3,
+	models.OutboundStatusBounced:    4,
+	models.OutboundStatusComplained: 4,
+}
+
+// ListSentEmails returns what the user has sent, newest first, with each
+// message's status history. status filters by current status if set.
+func (u *EmailUsecase) ListSentEmails(ctx context.Context, userID, status string, limit, offset int) ([]*models.OutboundEmail, error) {
+	ctx, span := tracing.Start(ctx, "EmailUsecase.ListSentEmails")
+	defer span.End()
+
+	if limit <= 0 || limit > 100 {
+		limit = 20
+	}
+	if _, ok := outboundRank[models.OutboundStatus(status)]; status != "" && !ok {
+		return nil, errors.ErrBadRequest("Invalid status")
+	}
+
+	sent, err := u.outboundRepo.GetByUserID(ctx, userID, models.OutboundStatus(status), limit, offset)
+	if err != nil {
+		return nil, errors.ErrDatabaseError
+	}
+	if len(sent) == 0 {
+		return []*models.OutboundEmail{}, nil
+	}
+
+	ids := make([]string, len(sent))
+	byID := make(map[string]*models.OutboundEmail, len(sent))
+	for i, o := range sent {
+		ids[i] = o.ID
+		byID[o.ID] = o
+	}
+	events, err := u.outboundRepo.GetEvents(ctx, ids)
+	if err != nil {
+		return nil, errors.ErrDatabaseError
+	}
+	for _, event := range events {
+		o := byID[event.OutboundEmailID]
+		o.Events = append(o.Events, event)
+	}
+
+	return sent, nil
+}
+
+// RecordDeliveryEvent applies a provider's delivery report. Reports about
+// messages this app did not send, and redelivered reports, are ignored.
+func (u *EmailUsecase) RecordDeliveryEvent(ctx context.Context, event *models.DeliveryEvent) error {
+	ctx, span := tracing.Start(ctx, "EmailUsecase.RecordDeliveryEvent")
+	defer span.End()
+
+	rank, ok := outboundRank[event.Status]
+	if !ok {
+		return errors.ErrBadRequest("Invalid status")
+	}
+
+	outbound, err := u.outboundRepo.GetByProviderMessageID(ctx, event.Provider, event.ProviderMessageID)
+	if err != nil {
+		return errors.ErrDatabaseError
+	}
+	if outbound == nil {
+		return nil
+	}
+
+	added, err := u.outboundRepo.AddEvent(ctx, &models.OutboundEvent{
+		ID:              generateID("oev"),
+		OutboundEmailID: outbound.ID,
+		Status:          event.Status,
+		ProviderEventID: optionalString(event.ProviderEventID),
+		Detail:          optionalString(event.Detail),
+		OccurredAt:      event.OccurredAt,
+	})
+	if err != nil {
+		return errors.ErrDatabaseError
+	}
+	if !added {
+		return nil
+	}
+
+	var from []models.OutboundStatus
+	for status, r := range outboundRank {
+		if r < rank {
+			from = append(from, status)
+		}
+	}
+	if err := u.outboundRepo.AdvanceStatus(ctx, outbound.ID, event.Status, from); err != nil {
+		return errors.ErrDatabaseError
+	}
+	return nil
+}
+
+func newOutbound(userID, provider string, threadID *string, msg *mailmime.Outgoing, status models.OutboundStatus) *models.OutboundEmail {
+	now := time.Now()
+	return &models.OutboundEmail{
+		ID:        generateID("out"),
+		UserID:    userID,
+		Provider:  provider,
+		MessageID: msg.MessageID,
+		ThreadID:  threadID,
+		From:      msg.From,
+		To:        nonNil(msg.To),
+		Cc:        nonNil(msg.Cc),
+		Bcc:       nonNil(msg.Bcc),
+		Subject:   msg.Subject,
+		Status:    status,
+		CreatedAt: now,
+		UpdatedAt: now,
+	}
+}
+
+// recordOutbound stores a new outbound message with the first entry of its
+// status history.
+func (u *EmailUsecase) recordOutbound(ctx context.Context, outbound *models.OutboundEmail) error {
+	if err := u.outboundRepo.Create(ctx, outbound); err != nil {
+		return errors.ErrDatabaseError
+	}
+	u.addOutboundEvent(ctx, outbound.ID, outbound.Status, "")
 	return nil
 }
 
-func (u *EmailUsecase) MarkEmailAsRead(ctx context.Context, emailID string) error {
-	return u.emailRepo.UpdateReadStatus(ctx, emailID, true)
+func (u *EmailUsecase) addOutboundEvent(ctx context.Context, outboundID string, status models.OutboundStatus, detail string) {
+	u.outboundRepo.AddEvent(ctx, &models.OutboundEvent{
+		ID:              generateID("oev"),
+		OutboundEmailID: outboundID,
+		Status:          status,
+		Detail:          optionalString(detail),
+		OccurredAt:      time.Now(),
+	})
+}
+
+func toOriginal(email *models.Email) *mailmime.Original {
+	return &mailmime.Original{
+		From:       email.From,
+		To:         email.To,
+		Cc:         email.Cc,
+		ReplyTo:    derefString(email.ReplyTo),
+		Subject:    derefString(email.Subject),
+		Date:       email.CreatedAt,
+		MessageID:  derefString(email.HeaderID),
+		References: email.References,
+		Text:       derefString(email.Body),
+		HTML:       derefString(email.HTMLBody),
+	}
+}
+
+func textToHTML(text string) string {
+	return strings.ReplaceAll(html.EscapeString(text), "\n", "<br>")
+}
+
+func joinNonEmpty(parts ...string) string {
+	var nonEmpty []string
+	for _, part := range parts {
+		if part != "" {
+			nonEmpty = append(nonEmpty, part)
+		}
+	}
+	return strings.Join(nonEmpty, "\n\n")
+}
+
+func optionalString(s string) *string {
+	if s == "" {
+		return nil
+	}
+	return &s
+}
+
+// nonNil keeps empty address lists from being stored as NULL.
+func nonNil(list []string) []string {
+	if list == nil {
+		return []string{}
+	}
+	return list
+}
+
+func derefString(s *string) string {
+	if s == nil {
+		return ""
+	}
+	return *s
 }
 
-func generateID() string {
-	return fmt.Sprintf("email_%d", len("placeholder"))
+func generateID(prefix string) string {
+	b := make([]byte, 12)
+	rand.Read(b)
+	return prefix + "_" + hex.EncodeToString(b)
 }
\ No newline at end of file
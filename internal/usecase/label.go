@@ -0,0 +1,272 @@
+package usecase
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"ai-assistant/internal/models"
+	"ai-assistant/internal/repository"
+	"ai-assistant/internal/services/email"
+	"ai-assistant/pkg/errors"
+)
+
+const maxLabelNameLength = 225
+
+// systemLabels are provider labels every mailbox has. They can be applied to
+// emails but not created, renamed or deleted.
+var systemLabels = map[string]bool{
+	email.LabelInbox:   true,
+	email.LabelUnread:  true,
+	email.LabelStarred: true,
+	email.LabelSent:    true,
+	"IMPORTANT":        true,
+	"SPAM":             true,
+	"TRASH":            true,
+	"DRAFT":            true,
+}
+
+type LabelUsecase struct {
+	labelRepo *repository.LabelRepository
+	emailRepo repository.EmailRepositoryInterface
+	mailboxes email.MailboxFactory
+}
+
+// NewLabelUsecase creates the label usecase. If a user's mailbox keeps
+// labels of its own (Gmail), changes are made there as well.
+func NewLabelUsecase(labelRepo *repository.LabelRepository, emailRepo repository.EmailRepositoryInterface, mailboxes email.MailboxFactory) *LabelUsecase {
+	return &LabelUsecase{
+		labelRepo: labelRepo,
+		emailRepo: emailRepo,
+		mailboxes: mailboxes,
+	}
+}
+
+// storeFor returns the user's mailbox if it keeps labels, else nil.
+func (u *LabelUsecase) storeFor(ctx context.Context, userID string) (email.LabelStore, error) {
+	mailbox, err := openMailbox(ctx, u.mailboxes, userID)
+	if err != nil {
+		return nil, err
+	}
+	store, _ := mailbox.(email.LabelStore)
+	return store, nil
+}
+
+func (u *LabelUsecase) ListLabels(ctx context.Context, userID string) ([]*models.Label, error) {
+	labels, err := u.labelRepo.GetByUserID(ctx, userID)
+	if err != nil {
+		return nil, errors.ErrDatabaseError
+	}
+	return labels, nil
+}
+
+func (u *LabelUsecase) CreateLabel(ctx context.Context, userID string, req *models.LabelRequest) (*models.Label, error) {
+	name, err := u.checkName(ctx, userID, "", req.Name)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	label := &models.Label{
+		ID:        generateID("label"),
+		UserID:    userID,
+		Name:      name,
+		Color:     req.Color,
+		CreatedAt: now,
+		UpdatedAt: now,
+	}
+
+	store, err := u.storeFor(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if store != nil {
+		created, err := store.CreateLabel(ctx, name)
+		if err != nil {
+			return nil, errors.ErrExternalService
+		}
+		label.ProviderID = &created.ID
+	}
+
+	if err := u.labelRepo.Create(ctx, label); err != nil {
+		return nil, errors.ErrDatabaseError
+	}
+	return label, nil
+}
+
+// UpdateLabel renames or recolors a label. Emails carry provider labels by
+// ID, so only labels without one need their emails rewritten.
+func (u *LabelUsecase) UpdateLabel(ctx context.Context, userID, labelID string, req *models.LabelRequest) (*models.Label, error) {
+	label, err := u.getLabel(ctx, userID, labelID)
+	if err != nil {
+		return nil, err
+	}
+
+	name, err := u.checkName(ctx, userID, labelID, req.Name)
+	if err != nil {
+		return nil, err
+	}
+
+	if name != label.Name {
+		if label.ProviderID != nil {
+			store, err := u.storeFor(ctx, userID)
+			if err != nil {
+				return nil, err
+			}
+			if store == nil {
+				return nil, errors.ErrServiceUnavailable("Mailbox provider not configured")
+			}
+			if err := store.RenameLabel(ctx, *label.ProviderID, name); err != nil {
+				return nil, errors.ErrExternalService
+			}
+		} else if err := u.emailRepo.ReplaceLabel(ctx, userID, label.Name, name); err != nil {
+			return nil, errors.ErrDatabaseError
+		}
+	}
+
+	label.Name = name
+	label.Color = req.Color
+	label.UpdatedAt = time.Now()
+	if err := u.labelRepo.Update(ctx, label); err != nil {
+		return nil, errors.ErrDatabaseError
+	}
+	return label, nil
+}
+
+// DeleteLabel removes a label and takes it off every email.
+func (u *LabelUsecase) DeleteLabel(ctx context.Context, userID, labelID string) error {
+	label, err := u.getLabel(ctx, userID, labelID)
+	if err != nil {
+		return err
+	}
+
+	if label.ProviderID != nil {
+		store, err := u.storeFor(ctx, userID)
+		if err != nil {
+			return err
+		}
+		if store == nil {
+			return errors.ErrServiceUnavailable("Mailbox provider not configured")
+		}
+		if err := store.DeleteLabel(ctx, *label.ProviderID); err != nil {
+			return errors.ErrExternalService
+		}
+	}
+
+	if err := u.emailRepo.RemoveLabel(ctx, userID, label.Key()); err != nil {
+		return errors.ErrDatabaseError
+	}
+	if err := u.labelRepo.Delete(ctx, userID, labelID); err != nil {
+		return errors.ErrDatabaseError
+	}
+	return nil
+}
+
+// SyncLabels imports the provider's user labels, linking existing labels of
+// the same name, and returns the resulting list.
+func (u *LabelUsecase) SyncLabels(ctx context.Context, userID string) ([]*models.Label, error) {
+	store, err := u.storeFor(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if store == nil {
+		return nil, errors.ErrServiceUnavailable("Mailbox provider does not support labels")
+	}
+
+	remote, err := store.ListLabels(ctx)
+	if err != nil {
+		return nil, errors.ErrExternalService
+	}
+
+	for _, r := range remote {
+		if r.System {
+			continue
+		}
+
+		label, err := u.labelRepo.GetByName(ctx, userID, r.Name)
+		if err != nil {
+			return nil, errors.ErrDatabaseError
+		}
+
+		now := time.Now()
+		if label == nil {
+			id := r.ID
+			label = &models.Label{
+				ID:         generateID("label"),
+				UserID:     userID,
+				Name:       r.Name,
+				ProviderID: &id,
+				CreatedAt:  now,
+				UpdatedAt:  now,
+			}
+			err = u.labelRepo.Create(ctx, label)
+		} else if derefString(label.ProviderID) != r.ID {
+			id := r.ID
+			label.ProviderID = &id
+			label.UpdatedAt = now
+			err = u.labelRepo.Update(ctx, label)
+		}
+		if err != nil {
+			return nil, errors.ErrDatabaseError
+		}
+	}
+
+	return u.ListLabels(ctx, userID)
+}
+
+// EnsureLabel returns the key emails carry for the label called name,
+// creating the label if the user has none of that name. System labels are
+// returned as they are.
+func (u *LabelUsecase) EnsureLabel(ctx context.Context, userID, name string) (string, error) {
+	name = strings.TrimSpace(name)
+	if systemLabels[strings.ToUpper(name)] {
+		return strings.ToUpper(name), nil
+	}
+
+	label, err := u.labelRepo.GetByName(ctx, userID, name)
+	if err != nil {
+		return "", errors.ErrDatabaseError
+	}
+	if label == nil {
+		label, err = u.CreateLabel(ctx, userID, &models.LabelRequest{Name: name})
+		if err != nil {
+			return "", err
+		}
+	}
+	return label.Key(), nil
+}
+
+func (u *LabelUsecase) getLabel(ctx context.Context, userID, labelID string) (*models.Label, error) {
+	label, err := u.labelRepo.GetByID(ctx, userID, labelID)
+	if err != nil {
+		return nil, errors.ErrDatabaseError
+	}
+	if label == nil {
+		return nil, errors.ErrNotFound("Label not found")
+	}
+	return label, nil
+}
+
+// checkName validates a label name and makes sure no other label of the
+// user's (other than labelID) already has it.
+func (u *LabelUsecase) checkName(ctx context.Context, userID, labelID, name string) (string, error) {
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return "", errors.ErrBadRequest("Label name is required")
+	}
+	if len(name) > maxLabelNameLength {
+		return "", errors.ErrBadRequest("Label name is too long")
+	}
+	if systemLabels[strings.ToUpper(name)] {
+		return "", errors.ErrBadRequest("Label name is reserved")
+	}
+
+	existing, err := u.labelRepo.GetByName(ctx, userID, name)
+	if err != nil {
+		return "", errors.ErrDatabaseError
+	}
+	if existing != nil && existing.ID != labelID {
+		return "", errors.ErrConflict("A label with this name already exists")
+	}
+	return name, nil
+}
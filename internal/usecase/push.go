@@ -0,0 +1,299 @@
+package usecase
+
+import (
+	"context"
+	stderrors "errors"
+	"sync"
+	"time"
+
+	"ai-assistant/internal/models"
+	"ai-assistant/internal/repository"
+	"ai-assistant/internal/services/email"
+	"ai-assistant/internal/services/email/gmail"
+	"ai-assistant/pkg/errors"
+	"ai-assistant/pkg/logger"
+	"ai-assistant/pkg/metrics"
+)
+
+const (
+	pushQueueSize   = 256
+	pushSyncWorkers = 4
+	// Gmail watches expire after 7 days. They are renewed once less than a
+	// day is left, checking hourly.
+	watchRenewBefore   = 24 * time.Hour
+	watchRenewInterval = time.Hour
+	watchRenewBatch    = 100
+)
+
+// PushMailbox is a user's Gmail mailbox with the calls push sync needs.
+type PushMailbox interface {
+	email.MailboxProvider
+	EmailAddress(ctx context.Context) (string, error)
+	Watch(ctx context.Context, topic string, labelIDs []string) (*gmail.WatchResult, error)
+	StopWatch(ctx context.Context) error
+	History(ctx context.Context, startHistoryID uint64) (*gmail.HistoryResult, error)
+}
+
+// PushMailboxFactory opens a user's Gmail mailbox. It returns nil if the
+// user has not linked a Google account.
+type PushMailboxFactory func(ctx context.Context, userID string) (PushMailbox, error)
+
+// PushUsecase registers Gmail watches and turns Pub/Sub notifications into
+// incremental syncs. Notifications only enqueue work; a sync for a user that
+// is already queued is not queued twice.
+type PushUsecase struct {
+	watchRepo    *repository.GmailWatchRepository
+	emailUsecase *EmailUsecase
+	mailboxes    PushMailboxFactory
+	topic        string
+	logger       *logger.Logger
+
+	queue  chan string
+	mu     sync.Mutex
+	queued map[string]bool
+}
+
+// NewPushUsecase creates the push usecase. topic is the full Pub/Sub topic
+// name, projects/<project>/topics/<topic>; push is disabled without one.
+func NewPushUsecase(watchRepo *repository.GmailWatchRepository, emailUsecase *EmailUsecase, mailboxes PushMailboxFactory, topic string, logger *logger.Logger) *PushUsecase {
+	return &PushUsecase{
+		watchRepo:    watchRepo,
+		emailUsecase: emailUsecase,
+		mailboxes:    mailboxes,
+		topic:        topic,
+		logger:       logger,
+		queue:        make(chan string, pushQueueSize),
+		queued:       make(map[string]bool),
+	}
+}
+
+// Start runs the sync workers and the watch renewal loop until ctx is done.
+func (u *PushUsecase) Start(ctx context.Context) {
+	for i := 0; i < pushSyncWorkers; i++ {
+		go u.syncWorker(ctx)
+	}
+	if u.topic != "" {
+		go u.renewLoop(ctx)
+	}
+}
+
+// Watch registers, or renews, push notifications for the user's mailbox.
+func (u *PushUsecase) Watch(ctx context.Context, userID string) (*models.GmailWatch, error) {
+	if u.topic == "" {
+		return nil, errors.ErrServiceUnavailable("Gmail push notifications not configured")
+	}
+
+	mailbox, err := u.openMailbox(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	address, err := mailbox.EmailAddress(ctx)
+	if err != nil {
+		return nil, errors.ErrExternalService
+	}
+	result, err := mailbox.Watch(ctx, u.topic, nil)
+	if err != nil {
+		return nil, errors.ErrExternalService
+	}
+
+	now := time.Now()
+	watch := &models.GmailWatch{
+		UserID:       userID,
+		EmailAddress: address,
+		Topic:        u.topic,
+		HistoryID:    result.HistoryID,
+		Expiration:   result.Expiration,
+		CreatedAt:    now,
+		UpdatedAt:    now,
+	}
+	if err := u.watchRepo.Upsert(ctx, watch); err != nil {
+		return nil, errors.ErrDatabaseError
+	}
+
+	stored, err := u.watchRepo.GetByUserID(ctx, userID)
+	if err != nil || stored == nil {
+		return nil, errors.ErrDatabaseError
+	}
+	return stored, nil
+}
+
+func (u *PushUsecase) StopWatch(ctx context.Context, userID string) error {
+	watch, err := u.watchRepo.GetByUserID(ctx, userID)
+	if err != nil {
+		return errors.ErrDatabaseError
+	}
+	if watch == nil {
+		return errors.ErrNotFound("Gmail watch not found")
+	}
+
+	mailbox, err := u.openMailbox(ctx, userID)
+	if err != nil {
+		return err
+	}
+	if err := mailbox.StopWatch(ctx); err != nil {
+		return errors.ErrExternalService
+	}
+
+	if err := u.watchRepo.Delete(ctx, userID); err != nil {
+		return errors.ErrDatabaseError
+	}
+	return nil
+}
+
+// HandleNotification queues a sync of the mailbox a notification is about.
+// Notifications for mailboxes nobody watches are dropped, so that Pub/Sub
+// stops redelivering them.
+func (u *PushUsecase) HandleNotification(ctx context.Context, n *gmail.Notification) error {
+	watch, err := u.watchRepo.GetByEmailAddress(ctx, n.EmailAddress)
+	if err != nil {
+		return errors.ErrDatabaseError
+	}
+	if watch == nil || n.HistoryID <= watch.HistoryID {
+		return nil
+	}
+
+	if !u.enqueue(watch.UserID) {
+		// Failing the push makes Pub/Sub retry it later.
+		return errors.ErrServiceUnavailable("Sync queue is full")
+	}
+	return nil
+}
+
+func (u *PushUsecase) enqueue(userID string) bool {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+
+	if u.queued[userID] {
+		return true
+	}
+	select {
+	case u.queue <- userID:
+		u.queued[userID] = true
+		metrics.QueueDepth.WithLabelValues("gmail_sync").Set(float64(len(u.queue)))
+		return true
+	default:
+		return false
+	}
+}
+
+func (u *PushUsecase) syncWorker(ctx context.Context) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case userID := <-u.queue:
+			// Unmark first so that a notification arriving mid-sync queues
+			// another pass.
+			u.mu.Lock()
+			delete(u.queued, userID)
+			metrics.QueueDepth.WithLabelValues("gmail_sync").Set(float64(len(u.queue)))
+			u.mu.Unlock()
+
+			if err := u.SyncUser(ctx, userID); err != nil {
+				u.logger.ErrorContext(ctx, "Gmail push sync failed", "user_id", userID, "error", err)
+			}
+		}
+	}
+}
+
+// SyncUser imports the messages added to the user's mailbox since the last
+// sync. If Gmail no longer has history that old, the watch is renewed for a
+// fresh starting point and the most recent messages are imported instead.
+func (u *PushUsecase) SyncUser(ctx context.Context, userID string) error {
+	watch, err := u.watchRepo.GetByUserID(ctx, userID)
+	if err != nil {
+		return errors.ErrDatabaseError
+	}
+	if watch == nil {
+		return nil
+	}
+
+	mailbox, err := u.openMailbox(ctx, userID)
+	if err != nil {
+		return err
+	}
+
+	history, err := mailbox.History(ctx, watch.HistoryID)
+	if stderrors.Is(err, gmail.ErrHistoryExpired) {
+		result, err := mailbox.Watch(ctx, watch.Topic, nil)
+		if err != nil {
+			return errors.ErrExternalService
+		}
+		if err := u.emailUsecase.SyncRecent(ctx, userID, mailbox); err != nil {
+			return err
+		}
+		history = &gmail.HistoryResult{HistoryID: result.HistoryID}
+	} else if err != nil {
+		return errors.ErrExternalService
+	}
+
+	u.emailUsecase.ImportMessages(ctx, userID, mailbox, history.MessageIDs)
+
+	if err := u.watchRepo.AdvanceHistoryID(ctx, userID, history.HistoryID); err != nil {
+		return errors.ErrDatabaseError
+	}
+	return nil
+}
+
+func (u *PushUsecase) renewLoop(ctx context.Context) {
+	ticker := time.NewTicker(watchRenewInterval)
+	defer ticker.Stop()
+
+	for {
+		if renewed, err := u.RenewWatches(ctx); err != nil {
+			u.logger.ErrorContext(ctx, "Gmail watch renewal failed", "error", err)
+		} else if renewed > 0 {
+			u.logger.InfoContext(ctx, "Renewed Gmail watches", "count", renewed)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// RenewWatches renews the watches that expire within a day and returns how
+// many were renewed. A watch that fails to renew is retried on the next run.
+func (u *PushUsecase) RenewWatches(ctx context.Context) (int, error) {
+	watches, err := u.watchRepo.GetExpiringBefore(ctx, time.Now().Add(watchRenewBefore), watchRenewBatch)
+	if err != nil {
+		return 0, errors.ErrDatabaseError
+	}
+
+	renewed := 0
+	for _, watch := range watches {
+		mailbox, err := u.openMailbox(ctx, watch.UserID)
+		if err != nil {
+			u.logger.WarnContext(ctx, "Cannot renew Gmail watch", "user_id", watch.UserID, "error", err)
+			continue
+		}
+
+		result, err := mailbox.Watch(ctx, watch.Topic, nil)
+		if err != nil {
+			u.logger.WarnContext(ctx, "Cannot renew Gmail watch", "user_id", watch.UserID, "error", err)
+			continue
+		}
+
+		watch.Expiration = result.Expiration
+		watch.UpdatedAt = time.Now()
+		if err := u.watchRepo.Upsert(ctx, watch); err != nil {
+			return renewed, errors.ErrDatabaseError
+		}
+		renewed++
+	}
+	return renewed, nil
+}
+
+func (u *PushUsecase) openMailbox(ctx context.Context, userID string) (PushMailbox, error) {
+	mailbox, err := u.mailboxes(ctx, userID)
+	if err != nil {
+		return nil, errors.ErrDatabaseError
+	}
+	if mailbox == nil {
+		return nil, errors.ErrBadRequest("No Google account linked")
+	}
+	return mailbox, nil
+}
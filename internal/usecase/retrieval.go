@@ -0,0 +1,261 @@
+package usecase
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"ai-assistant/internal/models"
+	"ai-assistant/internal/repository"
+	"ai-assistant/internal/services/ai/embedding"
+	"ai-assistant/pkg/errors"
+	"ai-assistant/pkg/logger"
+)
+
+const (
+	// chunkSize and chunkOverlap are in characters; about 250 and 40 tokens.
+	chunkSize    = 1000
+	chunkOverlap = 150
+	// maxChunksPerEmail keeps one long newsletter from flooding the index.
+	maxChunksPerEmail = 20
+	// backfillEmails is how many recent emails are indexed in the background
+	// when a user has no vectors yet, e.g. after a restart with the memory
+	// index.
+	backfillEmails = 500
+	backfillPage   = 100
+	// backfillQueueSize bounds the users waiting for a backfill; requests
+	// beyond it are dropped and made again later.
+	backfillQueueSize = 100
+)
+
+// RetrievalUsecase embeds synced emails and finds the passages closest to a
+// question. It implements EmailIndexer and MailboxRetriever.
+type RetrievalUsecase struct {
+	emailRepo repository.EmailRepositoryInterface
+	embedder  embedding.Embedder
+	store     embedding.Store
+	logger    *logger.Logger
+	backfills chan string
+
+	// attempted records the users and models a backfill was decided on, so
+	// that users without mail are not checked over and over.
+	mu        sync.Mutex
+	attempted map[string]bool
+}
+
+func NewRetrievalUsecase(emailRepo repository.EmailRepositoryInterface, embedder embedding.Embedder, store embedding.Store, logger *logger.Logger) *RetrievalUsecase {
+	return &RetrievalUsecase{
+		emailRepo: emailRepo,
+		embedder:  embedder,
+		store:     store,
+		logger:    logger,
+		backfills: make(chan string, backfillQueueSize),
+		attempted: make(map[string]bool),
+	}
+}
+
+// Start runs queued backfills in the background until ctx is done.
+func (u *RetrievalUsecase) Start(ctx context.Context) {
+	go u.backfillWorker(ctx)
+}
+
+// IndexEmail chunks and embeds an email, replacing any earlier vectors.
+func (u *RetrievalUsecase) IndexEmail(ctx context.Context, email *models.Email) error {
+	texts := embedding.Split(derefString(email.Subject), derefString(email.Body), chunkSize, chunkOverlap)
+	if len(texts) > maxChunksPerEmail {
+		texts = texts[:maxChunksPerEmail]
+	}
+	if len(texts) == 0 {
+		return nil
+	}
+
+	vectors, err := u.embedder.EmbedDocuments(ctx, texts)
+	if err != nil {
+		return err
+	}
+
+	model := u.embedder.Model()
+	now := time.Now()
+	chunks := make([]embedding.Chunk, len(texts))
+	for i, text := range texts {
+		chunks[i] = embedding.Chunk{
+			ID:        generateID("emb"),
+			EmailID:   email.ID,
+			UserID:    email.UserID,
+			Index:     i,
+			Content:   text,
+			Model:     model,
+			Vector:    vectors[i],
+			CreatedAt: now,
+		}
+	}
+
+	return u.store.ReplaceEmail(ctx, email.ID, model, chunks)
+}
+
+// Retrieve returns the k passages most similar to question, best first.
+// Several passages may come from the same email. Until any of the user's
+// emails are indexed it returns the start of the most recent ones instead,
+// and queues a backfill.
+func (u *RetrievalUsecase) Retrieve(ctx context.Context, userID, question string, k int) ([]models.Citation, error) {
+	count, err := u.store.Count(ctx, userID, u.embedder.Model())
+	if err != nil {
+		return nil, errors.ErrDatabaseError
+	}
+	if count == 0 {
+		u.queueBackfill(userID)
+		return u.recent(ctx, userID, k)
+	}
+
+	vector, err := u.embedder.EmbedQuery(ctx, question)
+	if err != nil {
+		return nil, errors.ErrExternalService
+	}
+
+	matches, err := u.store.Search(ctx, userID, u.embedder.Model(), vector, k)
+	if err != nil {
+		return nil, errors.ErrDatabaseError
+	}
+
+	emails := make(map[string]*models.Email)
+	var citations []models.Citation
+	for _, match := range matches {
+		email, ok := emails[match.EmailID]
+		if !ok {
+			if email, err = u.emailRepo.GetByID(ctx, match.EmailID); err != nil {
+				return nil, errors.ErrDatabaseError
+			}
+			emails[match.EmailID] = email
+		}
+		if email == nil {
+			continue // deleted since it was indexed
+		}
+
+		citations = append(citations, models.Citation{
+			EmailID: email.ID,
+			Subject: derefString(email.Subject),
+			From:    email.From,
+			Date:    email.CreatedAt,
+			Snippet: match.Content,
+			Score:   match.Score,
+		})
+	}
+	return citations, nil
+}
+
+// recent cites the start of a user's latest emails.
+func (u *RetrievalUsecase) recent(ctx context.Context, userID string, k int) ([]models.Citation, error) {
+	emails, err := u.emailRepo.GetByUserID(ctx, userID, k, 0)
+	if err != nil {
+		return nil, errors.ErrDatabaseError
+	}
+
+	var citations []models.Citation
+	for _, email := range emails {
+		texts := embedding.Split(derefString(email.Subject), derefString(email.Body), chunkSize, chunkOverlap)
+		if len(texts) == 0 {
+			continue
+		}
+		citations = append(citations, models.Citation{
+			EmailID: email.ID,
+			Subject: derefString(email.Subject),
+			From:    email.From,
+			Date:    email.CreatedAt,
+			Snippet: texts[0],
+		})
+	}
+	return citations, nil
+}
+
+// RequestBackfill queues indexing of a user's recent emails if none are
+// indexed for the current model. It is checked once per user and model
+// while the server runs.
+func (u *RetrievalUsecase) RequestBackfill(ctx context.Context, userID string) {
+	model := u.embedder.Model()
+	if !u.attempt(userID, model) {
+		return
+	}
+	count, err := u.store.Count(ctx, userID, model)
+	if err != nil {
+		u.forget(userID, model)
+		return
+	}
+	if count == 0 {
+		u.enqueue(userID, model)
+	}
+}
+
+// queueBackfill queues a backfill for a user known to have no vectors.
+func (u *RetrievalUsecase) queueBackfill(userID string) {
+	model := u.embedder.Model()
+	if u.attempt(userID, model) {
+		u.enqueue(userID, model)
+	}
+}
+
+// attempt records a backfill decision for the user and model, and reports
+// whether none was recorded before.
+func (u *RetrievalUsecase) attempt(userID, model string) bool {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	key := userID + "\x00" + model
+	if u.attempted[key] {
+		return false
+	}
+	u.attempted[key] = true
+	return true
+}
+
+func (u *RetrievalUsecase) forget(userID, model string) {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	delete(u.attempted, userID+"\x00"+model)
+}
+
+func (u *RetrievalUsecase) enqueue(userID, model string) {
+	select {
+	case u.backfills <- userID:
+	default:
+		// The queue is full; the next request tries again.
+		u.forget(userID, model)
+	}
+}
+
+func (u *RetrievalUsecase) backfillWorker(ctx context.Context) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case userID := <-u.backfills:
+			indexed, err := u.backfill(ctx, userID)
+			if err != nil {
+				// Not retried: newly synced mail is still indexed as it
+				// arrives.
+				u.logger.WarnContext(ctx, "Embedding backfill failed", "user_id", userID, "indexed", indexed, "error", err)
+				continue
+			}
+			u.logger.InfoContext(ctx, "Embedding backfill finished", "user_id", userID, "indexed", indexed)
+		}
+	}
+}
+
+// backfill indexes a user's recent emails and returns how many it indexed.
+func (u *RetrievalUsecase) backfill(ctx context.Context, userID string) (int, error) {
+	indexed := 0
+	for offset := 0; offset < backfillEmails; offset += backfillPage {
+		emails, err := u.emailRepo.GetByUserID(ctx, userID, backfillPage, offset)
+		if err != nil {
+			return indexed, err
+		}
+		for _, email := range emails {
+			if err := u.IndexEmail(ctx, email); err != nil {
+				return indexed, err
+			}
+			indexed++
+		}
+		if len(emails) < backfillPage {
+			break
+		}
+	}
+	return indexed, nil
+}
@@ -0,0 +1,346 @@
+package usecase
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"ai-assistant/internal/models"
+	"ai-assistant/internal/repository"
+	"ai-assistant/internal/services/email"
+	"ai-assistant/internal/services/email/mailmime"
+	"ai-assistant/internal/services/email/rules"
+	"ai-assistant/pkg/errors"
+)
+
+const (
+	defaultDryRunLimit = 200
+	maxDryRunLimit     = 1000
+	// maxClassifiedDryRun bounds the model calls a dry run of a rule with a
+	// category condition can make.
+	maxClassifiedDryRun = 50
+)
+
+// EmailClassifier sorts an email into one of the given categories, returning
+// "" if none fits.
+type EmailClassifier interface {
+	ClassifyEmail(ctx context.Context, email *models.Email, categories []string) (string, error)
+}
+
+type RuleUsecase struct {
+	ruleRepo     *repository.RuleRepository
+	emailRepo    repository.EmailRepositoryInterface
+	userRepo     repository.UserRepositoryInterface
+	labelUsecase *LabelUsecase
+	emailUsecase *EmailUsecase
+	classifier   EmailClassifier
+}
+
+// NewRuleUsecase creates the rules engine. classifier may be nil, in which
+// case rules with a category condition never match.
+func NewRuleUsecase(ruleRepo *repository.RuleRepository, emailRepo repository.EmailRepositoryInterface, userRepo repository.UserRepositoryInterface, labelUsecase *LabelUsecase, emailUsecase *EmailUsecase, classifier EmailClassifier) *RuleUsecase {
+	return &RuleUsecase{
+		ruleRepo:     ruleRepo,
+		emailRepo:    emailRepo,
+		userRepo:     userRepo,
+		labelUsecase: labelUsecase,
+		emailUsecase: emailUsecase,
+		classifier:   classifier,
+	}
+}
+
+func (u *RuleUsecase) ListRules(ctx context.Context, userID string) ([]*models.Rule, error) {
+	list, err := u.ruleRepo.GetByUserID(ctx, userID, false)
+	if err != nil {
+		return nil, errors.ErrDatabaseError
+	}
+	return list, nil
+}
+
+func (u *RuleUsecase) GetRule(ctx context.Context, userID, ruleID string) (*models.Rule, error) {
+	rule, err := u.ruleRepo.GetByID(ctx, userID, ruleID)
+	if err != nil {
+		return nil, errors.ErrDatabaseError
+	}
+	if rule == nil {
+		return nil, errors.ErrNotFound("Rule not found")
+	}
+	return rule, nil
+}
+
+func (u *RuleUsecase) CreateRule(ctx context.Context, userID string, req *models.RuleRequest) (*models.Rule, error) {
+	now := time.Now()
+	rule := &models.Rule{
+		ID:        generateID("rule"),
+		UserID:    userID,
+		CreatedAt: now,
+		UpdatedAt: now,
+	}
+	applyRuleRequest(rule, req)
+	if _, err := rules.Compile(rule); err != nil {
+		return nil, errors.ErrBadRequest(err.Error())
+	}
+
+	if err := u.ruleRepo.Create(ctx, rule); err != nil {
+		return nil, errors.ErrDatabaseError
+	}
+	return rule, nil
+}
+
+func (u *RuleUsecase) UpdateRule(ctx context.Context, userID, ruleID string, req *models.RuleRequest) (*models.Rule, error) {
+	rule, err := u.GetRule(ctx, userID, ruleID)
+	if err != nil {
+		return nil, err
+	}
+
+	applyRuleRequest(rule, req)
+	if _, err := rules.Compile(rule); err != nil {
+		return nil, errors.ErrBadRequest(err.Error())
+	}
+
+	rule.UpdatedAt = time.Now()
+	if err := u.ruleRepo.Update(ctx, rule); err != nil {
+		return nil, errors.ErrDatabaseError
+	}
+	return rule, nil
+}
+
+func (u *RuleUsecase) DeleteRule(ctx context.Context, userID, ruleID string) error {
+	if _, err := u.GetRule(ctx, userID, ruleID); err != nil {
+		return err
+	}
+	if err := u.ruleRepo.Delete(ctx, userID, ruleID); err != nil {
+		return errors.ErrDatabaseError
+	}
+	return nil
+}
+
+func applyRuleRequest(rule *models.Rule, req *models.RuleRequest) {
+	rule.Name = strings.TrimSpace(req.Name)
+	rule.Enabled = req.Enabled == nil || *req.Enabled
+	rule.Priority = req.Priority
+	rule.Conditions = req.Conditions
+	rule.Actions = req.Actions
+	rule.StopProcessing = req.StopProcessing
+}
+
+// ApplyRules runs the user's enabled rules, in priority order, against a
+// newly synced email. The label, archive and mark-read actions of all
+// matching rules are combined into one label change; forwards and
+// notifications follow. Mail the user sent is left alone so that forwards
+// and notifications cannot trigger rules again.
+func (u *RuleUsecase) ApplyRules(ctx context.Context, userID string, record *models.Email) error {
+	if email.HasLabel(record.Labels, email.LabelSent) {
+		return nil
+	}
+
+	list, err := u.ruleRepo.GetByUserID(ctx, userID, true)
+	if err != nil {
+		return errors.ErrDatabaseError
+	}
+	if len(list) == 0 {
+		return nil
+	}
+
+	user, err := u.userRepo.GetByID(ctx, userID)
+	if err != nil {
+		return errors.ErrDatabaseError
+	}
+	if user == nil {
+		return errors.ErrNotFound("User not found")
+	}
+	if mailmime.AddressOnly(record.From) == strings.ToLower(user.Email) {
+		return nil
+	}
+
+	matchers := compileRules(list)
+	classify := u.lazyClassifier(ctx, record, matchers)
+
+	var actions []models.RuleAction
+	var matched []*models.Rule
+	for _, m := range matchers {
+		if !m.MatchContent(record) {
+			continue
+		}
+		if m.Category() != "" && !m.Match(record, classify()) {
+			continue
+		}
+		matched = append(matched, m.Rule)
+		actions = append(actions, m.Rule.Actions...)
+		if m.Rule.StopProcessing {
+			break
+		}
+	}
+	if len(actions) == 0 {
+		return nil
+	}
+
+	return u.runActions(ctx, user, record, matched, actions)
+}
+
+// lazyClassifier returns a function that classifies record into the
+// categories the rules ask for, calling the model at most once.
+func (u *RuleUsecase) lazyClassifier(ctx context.Context, record *models.Email, matchers []*rules.Matcher) func() string {
+	var categories []string
+	for _, m := range matchers {
+		if category := m.Category(); category != "" {
+			categories = append(categories, category)
+		}
+	}
+
+	done := false
+	var category string
+	return func() string {
+		if !done && u.classifier != nil {
+			// A failed classification matches no category rule.
+			category, _ = u.classifier.ClassifyEmail(ctx, record, categories)
+		}
+		done = true
+		return category
+	}
+}
+
+func (u *RuleUsecase) runActions(ctx context.Context, user *models.User, record *models.Email, matched []*models.Rule, actions []models.RuleAction) error {
+	var add, remove []string
+	var firstErr error
+	for _, action := range actions {
+		switch action.Type {
+		case models.RuleActionLabel:
+			key, err := u.labelUsecase.EnsureLabel(ctx, user.ID, action.Label)
+			if err != nil {
+				if firstErr == nil {
+					firstErr = err
+				}
+				continue
+			}
+			add = append(add, key)
+		case models.RuleActionArchive:
+			remove = append(remove, email.LabelInbox)
+		case models.RuleActionMarkRead:
+			remove = append(remove, email.LabelUnread)
+		}
+	}
+
+	if len(add) > 0 || len(remove) > 0 {
+		if _, err := u.emailUsecase.ModifyEmailLabels(ctx, user.ID, record.ID, add, remove); err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+
+	for _, action := range actions {
+		var err error
+		switch action.Type {
+		case models.RuleActionForward:
+			_, err = u.emailUsecase.ForwardEmail(ctx, user.ID, user.Email, record.ID, &models.ForwardRequest{To: []string{action.To}})
+		case models.RuleActionNotify:
+			to := action.To
+			if to == "" {
+				to = user.Email
+			}
+			err = u.emailUsecase.SendEmail(ctx, user.ID, user.Email, []string{to}, notificationSubject(record), notificationBody(record, matched))
+		}
+		if err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+
+	return firstErr
+}
+
+func notificationSubject(record *models.Email) string {
+	subject := derefString(record.Subject)
+	if subject == "" {
+		subject = "(no subject)"
+	}
+	return "New email: " + subject
+}
+
+func notificationBody(record *models.Email, matched []*models.Rule) string {
+	names := make([]string, 0, len(matched))
+	for _, rule := range matched {
+		names = append(names, rule.Name)
+	}
+	return fmt.Sprintf("From: %s\nSubject: %s\nMatched rules: %s\n",
+		record.From, derefString(record.Subject), strings.Join(names, ", "))
+}
+
+func compileRules(list []*models.Rule) []*rules.Matcher {
+	matchers := make([]*rules.Matcher, 0, len(list))
+	for _, rule := range list {
+		// Rules are validated when saved; skip any that no longer compile.
+		if m, err := rules.Compile(rule); err == nil {
+			matchers = append(matchers, m)
+		}
+	}
+	return matchers
+}
+
+// DryRun reports which of the user's most recent emails an unsaved rule
+// would match, without changing anything.
+func (u *RuleUsecase) DryRun(ctx context.Context, userID string, req *models.RuleRequest, limit int) (*models.DryRunResult, error) {
+	rule := &models.Rule{UserID: userID}
+	applyRuleRequest(rule, req)
+	return u.dryRun(ctx, userID, rule, limit)
+}
+
+// DryRunRule is DryRun for a saved rule.
+func (u *RuleUsecase) DryRunRule(ctx context.Context, userID, ruleID string, limit int) (*models.DryRunResult, error) {
+	rule, err := u.GetRule(ctx, userID, ruleID)
+	if err != nil {
+		return nil, err
+	}
+	return u.dryRun(ctx, userID, rule, limit)
+}
+
+func (u *RuleUsecase) dryRun(ctx context.Context, userID string, rule *models.Rule, limit int) (*models.DryRunResult, error) {
+	m, err := rules.Compile(rule)
+	if err != nil {
+		return nil, errors.ErrBadRequest(err.Error())
+	}
+
+	if limit <= 0 {
+		limit = defaultDryRunLimit
+	}
+	if limit > maxDryRunLimit {
+		limit = maxDryRunLimit
+	}
+	if m.Category() != "" {
+		if u.classifier == nil {
+			return nil, errors.ErrServiceUnavailable("AI classifier not configured")
+		}
+		if limit > maxClassifiedDryRun {
+			limit = maxClassifiedDryRun
+		}
+	}
+
+	emails, err := u.emailRepo.GetByUserID(ctx, userID, limit, 0)
+	if err != nil {
+		return nil, errors.ErrDatabaseError
+	}
+
+	result := &models.DryRunResult{Scanned: len(emails), Matches: []*models.RuleMatch{}}
+	for _, record := range emails {
+		if !m.MatchContent(record) {
+			continue
+		}
+		if m.Category() != "" {
+			category, err := u.classifier.ClassifyEmail(ctx, record, []string{m.Category()})
+			if err != nil {
+				return nil, err
+			}
+			if !m.Match(record, category) {
+				continue
+			}
+		}
+
+		result.Matches = append(result.Matches, &models.RuleMatch{
+			EmailID:   record.ID,
+			Subject:   derefString(record.Subject),
+			From:      record.From,
+			CreatedAt: record.CreatedAt,
+			Actions:   rule.Actions,
+		})
+	}
+	return result, nil
+}
@@ -0,0 +1,143 @@
+package usecase
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"ai-assistant/internal/models"
+	"ai-assistant/internal/repository"
+	"ai-assistant/internal/services/email/sanitize"
+	"ai-assistant/pkg/errors"
+)
+
+const (
+	RemoteImagesProxy = "proxy"
+	RemoteImagesBlock = "block"
+)
+
+// inlineImageWindow is how long a cached rendering is reused. Its inline
+// image URLs stay valid for one more window, so that a page loaded at the
+// end of one can still show them.
+const inlineImageWindow = 12 * time.Hour
+
+// ImageProxy rewrites the URLs of remote images to load through the image
+// proxy. KeyID changes whenever previously rewritten URLs stop working.
+type ImageProxy interface {
+	URL(src string) string
+	KeyID() string
+}
+
+// InlineImages signs the URLs inline images load from, which are valid
+// until expires. KeyID changes whenever previously signed URLs stop working.
+type InlineImages interface {
+	URL(emailID, attachmentID string, expires time.Time) string
+	KeyID() string
+}
+
+type SanitizeUsecase struct {
+	emailRepo repository.EmailRepositoryInterface
+	inline    InlineImages
+	images    ImageProxy
+}
+
+// NewSanitizeUsecase links inline images to signed URLs made by inline. A
+// nil images blocks all remote images.
+func NewSanitizeUsecase(emailRepo repository.EmailRepositoryInterface, inline InlineImages, images ImageProxy) *SanitizeUsecase {
+	return &SanitizeUsecase{
+		emailRepo: emailRepo,
+		inline:    inline,
+		images:    images,
+	}
+}
+
+// GetSanitizedEmail returns an email's HTML made safe to display. Remote
+// images load through the image proxy unless blockImages is set or there is
+// no proxy. Results are cached per email and mode.
+func (u *SanitizeUsecase) GetSanitizedEmail(ctx context.Context, userID, emailID string, blockImages bool) (*models.SanitizedEmail, error) {
+	record, err := u.emailRepo.GetByID(ctx, emailID)
+	if err != nil {
+		return nil, errors.ErrDatabaseError
+	}
+	if record == nil || record.UserID != userID {
+		return nil, errors.ErrNotFound("Email not found")
+	}
+
+	mode := RemoteImagesBlock
+	if u.images != nil && !blockImages {
+		mode = RemoteImagesProxy
+	}
+	window := time.Now().Truncate(inlineImageWindow)
+	version := u.version(mode, window)
+
+	cached, err := u.emailRepo.GetRendering(ctx, emailID, mode)
+	if err == nil && cached != nil && cached.Version == version {
+		return cached, nil
+	}
+
+	attachments, err := u.emailRepo.GetAttachments(ctx, emailID)
+	if err != nil {
+		return nil, errors.ErrDatabaseError
+	}
+
+	rendering, err := u.sanitize(record, attachments, mode, window.Add(2*inlineImageWindow))
+	if err != nil {
+		return nil, errors.ErrInternalServerError("Failed to sanitize email")
+	}
+	rendering.Version = version
+
+	// The cache only saves work; a failed write is retried on the next read.
+	u.emailRepo.SaveRendering(ctx, rendering)
+	return rendering, nil
+}
+
+func (u *SanitizeUsecase) sanitize(record *models.Email, attachments []*models.EmailAttachment, mode string, expires time.Time) (*models.SanitizedEmail, error) {
+	rendering := &models.SanitizedEmail{
+		EmailID:      record.ID,
+		RemoteImages: mode,
+		CreatedAt:    time.Now(),
+	}
+
+	body := derefString(record.Body)
+	document := derefString(record.HTMLBody)
+	if strings.TrimSpace(document) == "" {
+		document = textToHTML(body)
+	}
+
+	opts := sanitize.Options{
+		InlineImage: func(contentID string) string {
+			for _, a := range attachments {
+				if a.ContentID != nil && strings.Trim(*a.ContentID, "<> ") == contentID {
+					return u.inline.URL(record.ID, a.ID, expires)
+				}
+			}
+			return ""
+		},
+	}
+	if mode == RemoteImagesProxy {
+		opts.RemoteImage = u.images.URL
+	}
+
+	result, err := sanitize.Sanitize(document, opts)
+	if err != nil {
+		return nil, err
+	}
+	rendering.HTML = result.HTML
+	rendering.BlockedImages = result.BlockedImages
+	rendering.Text = body
+	if strings.TrimSpace(body) == "" {
+		rendering.Text = result.Text
+	}
+	return rendering, nil
+}
+
+// version identifies everything a cached rendering depends on besides the
+// email itself, including the window its inline image URLs were signed in.
+func (u *SanitizeUsecase) version(mode string, window time.Time) string {
+	keyID := ""
+	if mode == RemoteImagesProxy {
+		keyID = u.images.KeyID()
+	}
+	return fmt.Sprintf("%d|%s|%d|%s", sanitize.Version, u.inline.KeyID(), window.Unix(), keyID)
+}
@@ -0,0 +1,384 @@
+package usecase
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"ai-assistant/internal/models"
+	"ai-assistant/internal/repository"
+	"ai-assistant/internal/services/email/mailmime"
+	"ai-assistant/pkg/errors"
+	"ai-assistant/pkg/logger"
+	"ai-assistant/pkg/metrics"
+)
+
+const (
+	// dispatchInterval is how often each replica polls for due messages.
+	dispatchInterval = time.Second
+	// dispatchLease is how long a claimed message may stay in sending
+	// before its dispatcher is presumed dead.
+	dispatchLease = 5 * time.Minute
+	// queueDepthInterval is how often the queue is counted for metrics.
+	queueDepthInterval = 15 * time.Second
+	// maxScheduleAhead bounds how far in the future a send can be scheduled.
+	maxScheduleAhead = 366 * 24 * time.Hour
+)
+
+// localSendAtLayouts are accepted for sendAt values without a UTC offset,
+// which are read in the request's time zone.
+var localSendAtLayouts = []string{"2006-01-02T15:04:05", "2006-01-02T15:04"}
+
+// TemplateRenderer renders the user's stored email templates.
+type TemplateRenderer interface {
+	RenderTemplate(ctx context.Context, userID, templateID string, data map[string]interface{}) (*models.RenderedEmail, error)
+}
+
+// ScheduleUsecase queues outgoing mail. Every send waits in the queue, at
+// least for the undo delay, and is dispatched by whichever replica claims
+// it first.
+type ScheduleUsecase struct {
+	scheduledRepo *repository.ScheduledEmailRepository
+	emailUsecase  *EmailUsecase
+	templates     TemplateRenderer
+	undoDelay     time.Duration
+	logger        *logger.Logger
+}
+
+func NewScheduleUsecase(scheduledRepo *repository.ScheduledEmailRepository, emailUsecase *EmailUsecase, templates TemplateRenderer, undoDelay time.Duration, logger *logger.Logger) *ScheduleUsecase {
+	return &ScheduleUsecase{
+		scheduledRepo: scheduledRepo,
+		emailUsecase:  emailUsecase,
+		templates:     templates,
+		undoDelay:     undoDelay,
+		logger:        logger,
+	}
+}
+
+// ParseSendAt resolves a request's sendAt. An empty sendAt means now plus
+// the undo delay, which the caller applies. A time without an offset is
+// read in timeZone, an IANA name such as "Europe/Berlin".
+func ParseSendAt(sendAt, timeZone string, now time.Time) (time.Time, error) {
+	var loc *time.Location
+	if timeZone != "" {
+		var err error
+		if loc, err = time.LoadLocation(timeZone); err != nil {
+			return time.Time{}, fmt.Errorf("unknown time zone %q", timeZone)
+		}
+	}
+
+	t, err := time.Parse(time.RFC3339, sendAt)
+	if err != nil {
+		if loc == nil {
+			return time.Time{}, fmt.Errorf("sendAt must be RFC 3339 or a local time with timeZone")
+		}
+		parsed := false
+		for _, layout := range localSendAtLayouts {
+			if t, err = time.ParseInLocation(layout, sendAt, loc); err == nil {
+				parsed = true
+				break
+			}
+		}
+		if !parsed {
+			return time.Time{}, fmt.Errorf("invalid sendAt %q", sendAt)
+		}
+	}
+
+	if t.Before(now) {
+		return time.Time{}, fmt.Errorf("sendAt is in the past")
+	}
+	if t.After(now.Add(maxScheduleAhead)) {
+		return time.Time{}, fmt.Errorf("sendAt is more than a year ahead")
+	}
+	return t.UTC(), nil
+}
+
+// QueueEmail validates req and queues it. It goes out at sendAt if given,
+// else once the undo delay has passed.
+func (u *ScheduleUsecase) QueueEmail(ctx context.Context, userID, from string, req *models.SendEmailRequest) (*models.ScheduledEmail, error) {
+	now := time.Now().UTC()
+	scheduled := &models.ScheduledEmail{
+		ID:        generateID("sched"),
+		UserID:    userID,
+		From:      from,
+		Status:    models.ScheduledStatusScheduled,
+		CreatedAt: now,
+		UpdatedAt: now,
+	}
+	if err := u.apply(ctx, scheduled, req, now); err != nil {
+		return nil, err
+	}
+
+	attachments, err := u.emailUsecase.storeOutgoing(ctx, userID, scheduled.ID, req.Attachments)
+	if err != nil {
+		return nil, err
+	}
+	scheduled.Attachments = attachments
+
+	if err := u.scheduledRepo.Create(ctx, scheduled); err != nil {
+		u.emailUsecase.deleteOutgoing(ctx, attachments)
+		return nil, errors.ErrDatabaseError
+	}
+	return scheduled, nil
+}
+
+// apply copies the editable fields of req onto scheduled after validating
+// them. A template is rendered now, so the queue holds exactly what will be
+// sent.
+func (u *ScheduleUsecase) apply(ctx context.Context, scheduled *models.ScheduledEmail, req *models.SendEmailRequest, now time.Time) error {
+	subject, body, html := req.Subject, req.Body, req.HTML
+	if req.TemplateID != "" {
+		if u.templates == nil {
+			return errors.ErrServiceUnavailable("Templates not configured")
+		}
+		rendered, err := u.templates.RenderTemplate(ctx, scheduled.UserID, req.TemplateID, req.Data)
+		if err != nil {
+			return err
+		}
+		if strings.TrimSpace(subject) == "" {
+			subject = rendered.Subject
+		}
+		body, html = rendered.Text, rendered.HTML
+	}
+
+	if strings.TrimSpace(subject) == "" {
+		return errors.ErrBadRequest("Subject is required")
+	}
+	if strings.TrimSpace(body) == "" && strings.TrimSpace(html) == "" {
+		return errors.ErrBadRequest("Body is required")
+	}
+
+	scheduled.To = nonNil(req.To)
+	scheduled.Cc = nonNil(req.Cc)
+	scheduled.Bcc = nonNil(req.Bcc)
+	scheduled.Subject = subject
+	scheduled.Body = body
+	scheduled.HTML = optionalString(html)
+	scheduled.TimeZone = optionalString(req.TimeZone)
+
+	// Building the message catches bad recipients now rather than at
+	// dispatch, when nobody is waiting on the answer.
+	if _, err := toOutgoing(scheduled).Build(true); err != nil {
+		return errors.ErrBadRequest(err.Error())
+	}
+
+	if req.SendAt == "" {
+		if req.TimeZone != "" {
+			if _, err := time.LoadLocation(req.TimeZone); err != nil {
+				return errors.ErrBadRequest(fmt.Sprintf("unknown time zone %q", req.TimeZone))
+			}
+		}
+		scheduled.SendAt = now.Add(u.undoDelay).UTC()
+		return nil
+	}
+
+	sendAt, err := ParseSendAt(req.SendAt, req.TimeZone, now)
+	if err != nil {
+		return errors.ErrBadRequest(err.Error())
+	}
+	scheduled.SendAt = sendAt
+	return nil
+}
+
+// ListScheduled returns the user's queue, soonest first. status filters by
+// status if set.
+func (u *ScheduleUsecase) ListScheduled(ctx context.Context, userID, status string, limit, offset int) ([]*models.ScheduledEmail, error) {
+	if limit <= 0 || limit > 100 {
+		limit = 20
+	}
+
+	switch models.ScheduledStatus(status) {
+	case "", models.ScheduledStatusScheduled, models.ScheduledStatusSending, models.ScheduledStatusSent,
+		models.ScheduledStatusCancelled, models.ScheduledStatusFailed:
+	default:
+		return nil, errors.ErrBadRequest(fmt.Sprintf("Unknown status %q", status))
+	}
+
+	emails, err := u.scheduledRepo.GetByUserID(ctx, userID, models.ScheduledStatus(status), limit, offset)
+	if err != nil {
+		return nil, errors.ErrDatabaseError
+	}
+	return emails, nil
+}
+
+func (u *ScheduleUsecase) GetScheduled(ctx context.Context, userID, id string) (*models.ScheduledEmail, error) {
+	scheduled, err := u.scheduledRepo.GetByID(ctx, userID, id)
+	if err != nil {
+		return nil, errors.ErrDatabaseError
+	}
+	if scheduled == nil {
+		return nil, errors.ErrNotFound("Scheduled email not found")
+	}
+	return scheduled, nil
+}
+
+// UpdateScheduled replaces the content and send time of a queued message.
+// Once dispatch has claimed it, the edit is refused with a conflict.
+func (u *ScheduleUsecase) UpdateScheduled(ctx context.Context, userID, id string, req *models.SendEmailRequest) (*models.ScheduledEmail, error) {
+	scheduled, err := u.GetScheduled(ctx, userID, id)
+	if err != nil {
+		return nil, err
+	}
+	if scheduled.Status != models.ScheduledStatusScheduled {
+		return nil, errors.ErrConflict(fmt.Sprintf("Email is already %s", scheduled.Status))
+	}
+
+	now := time.Now().UTC()
+	if err := u.apply(ctx, scheduled, req, now); err != nil {
+		return nil, err
+	}
+	scheduled.UpdatedAt = now
+
+	// Attachments are kept unless the request lists new ones.
+	previous := scheduled.Attachments
+	if req.Attachments != nil {
+		attachments, err := u.emailUsecase.storeOutgoing(ctx, userID, scheduled.ID, req.Attachments)
+		if err != nil {
+			return nil, err
+		}
+		scheduled.Attachments = attachments
+	}
+	replaced := req.Attachments != nil
+
+	updated, err := u.scheduledRepo.UpdatePending(ctx, scheduled)
+	if err != nil || !updated {
+		if replaced {
+			u.emailUsecase.deleteOutgoing(ctx, scheduled.Attachments)
+		}
+		if err != nil {
+			return nil, errors.ErrDatabaseError
+		}
+		return nil, errors.ErrConflict("Email is already being sent")
+	}
+	if replaced {
+		u.emailUsecase.deleteOutgoing(ctx, previous)
+	}
+	return scheduled, nil
+}
+
+// CancelScheduled withdraws a queued message; this is what undo-send does.
+func (u *ScheduleUsecase) CancelScheduled(ctx context.Context, userID, id string) (*models.ScheduledEmail, error) {
+	cancelled, err := u.scheduledRepo.Cancel(ctx, userID, id)
+	if err != nil {
+		return nil, errors.ErrDatabaseError
+	}
+
+	scheduled, err := u.GetScheduled(ctx, userID, id)
+	if err != nil {
+		return nil, err
+	}
+	if !cancelled {
+		return nil, errors.ErrConflict(fmt.Sprintf("Email is already %s", scheduled.Status))
+	}
+	u.emailUsecase.deleteOutgoing(ctx, scheduled.Attachments)
+	return scheduled, nil
+}
+
+// Start runs the dispatcher until ctx is cancelled. Replicas may all run
+// it: each due message is claimed by exactly one of them.
+func (u *ScheduleUsecase) Start(ctx context.Context) {
+	go u.dispatchLoop(ctx)
+}
+
+func (u *ScheduleUsecase) dispatchLoop(ctx context.Context) {
+	ticker := time.NewTicker(dispatchInterval)
+	defer ticker.Stop()
+
+	lastReap, lastCount := time.Time{}, time.Time{}
+	for {
+		if time.Since(lastReap) >= dispatchLease {
+			u.failStale(ctx)
+			lastReap = time.Now()
+		}
+		u.DispatchDue(ctx)
+		if time.Since(lastCount) >= queueDepthInterval {
+			u.recordQueueDepth(ctx)
+			lastCount = time.Now()
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// DispatchDue sends every message that is due and returns how many were
+// claimed.
+func (u *ScheduleUsecase) DispatchDue(ctx context.Context) int {
+	claimed := 0
+	for ctx.Err() == nil {
+		scheduled, err := u.scheduledRepo.ClaimDue(ctx, time.Now().UTC())
+		if err != nil {
+			u.logger.ErrorContext(ctx, "Claiming scheduled emails failed", "error", err)
+			return claimed
+		}
+		if scheduled == nil {
+			return claimed
+		}
+		claimed++
+		u.dispatch(ctx, scheduled)
+	}
+	return claimed
+}
+
+func (u *ScheduleUsecase) recordQueueDepth(ctx context.Context) {
+	n, err := u.scheduledRepo.CountScheduled(ctx)
+	if err != nil {
+		u.logger.WarnContext(ctx, "Counting scheduled emails failed", "error", err)
+		return
+	}
+	metrics.QueueDepth.WithLabelValues("scheduled_emails").Set(float64(n))
+}
+
+func (u *ScheduleUsecase) dispatch(ctx context.Context, scheduled *models.ScheduledEmail) {
+	var sent *models.SentEmail
+	msg := toOutgoing(scheduled)
+	attachments, err := u.emailUsecase.loadOutgoing(ctx, scheduled.Attachments)
+	if err == nil {
+		msg.Attachments = attachments
+		sent, err = u.emailUsecase.deliver(ctx, scheduled.UserID, nil, msg)
+	}
+
+	status := models.ScheduledStatusSent
+	var outboundID, sendErr *string
+	if err != nil {
+		status = models.ScheduledStatusFailed
+		sendErr = optionalString(err.Error())
+		u.logger.WarnContext(ctx, "Scheduled email failed", "scheduled_id", scheduled.ID, "user_id", scheduled.UserID, "error", err)
+	} else {
+		outboundID = optionalString(sent.OutboundID)
+	}
+
+	// The send has happened either way, so the outcome is recorded even if
+	// the worker is shutting down.
+	if err := u.scheduledRepo.Complete(context.WithoutCancel(ctx), scheduled.ID, status, outboundID, sendErr); err != nil {
+		u.logger.ErrorContext(ctx, "Recording scheduled email outcome failed", "scheduled_id", scheduled.ID, "status", status, "error", err)
+	}
+	u.emailUsecase.deleteOutgoing(context.WithoutCancel(ctx), scheduled.Attachments)
+}
+
+// failStale gives up on messages whose dispatcher died mid-send. They are
+// not retried, since they may already have gone out.
+func (u *ScheduleUsecase) failStale(ctx context.Context) {
+	n, err := u.scheduledRepo.FailStale(ctx, time.Now().UTC().Add(-dispatchLease), "dispatch interrupted; check Sent before resending")
+	if err != nil {
+		u.logger.ErrorContext(ctx, "Failing stale scheduled emails failed", "error", err)
+	} else if n > 0 {
+		u.logger.WarnContext(ctx, "Marked interrupted scheduled emails as failed", "count", n)
+	}
+}
+
+func toOutgoing(scheduled *models.ScheduledEmail) *mailmime.Outgoing {
+	return &mailmime.Outgoing{
+		From:    scheduled.From,
+		To:      scheduled.To,
+		Cc:      scheduled.Cc,
+		Bcc:     scheduled.Bcc,
+		Subject: scheduled.Subject,
+		Text:    scheduled.Body,
+		HTML:    derefString(scheduled.HTML),
+	}
+}
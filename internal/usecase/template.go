@@ -0,0 +1,163 @@
+package usecase
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"ai-assistant/internal/models"
+	"ai-assistant/internal/repository"
+	"ai-assistant/internal/services/email/templates"
+	"ai-assistant/pkg/errors"
+)
+
+const (
+	maxTemplateNameLength = 200
+	maxTemplateSize       = 256 << 10
+)
+
+type TemplateUsecase struct {
+	templateRepo *repository.TemplateRepository
+}
+
+func NewTemplateUsecase(templateRepo *repository.TemplateRepository) *TemplateUsecase {
+	return &TemplateUsecase{templateRepo: templateRepo}
+}
+
+func (u *TemplateUsecase) ListTemplates(ctx context.Context, userID string) ([]*models.EmailTemplate, error) {
+	list, err := u.templateRepo.GetByUserID(ctx, userID)
+	if err != nil {
+		return nil, errors.ErrDatabaseError
+	}
+	return list, nil
+}
+
+func (u *TemplateUsecase) GetTemplate(ctx context.Context, userID, templateID string) (*models.EmailTemplate, error) {
+	t, err := u.templateRepo.GetByID(ctx, userID, templateID)
+	if err != nil {
+		return nil, errors.ErrDatabaseError
+	}
+	if t == nil {
+		return nil, errors.ErrNotFound("Template not found")
+	}
+	return t, nil
+}
+
+func (u *TemplateUsecase) CreateTemplate(ctx context.Context, userID string, req *models.TemplateRequest) (*models.EmailTemplate, error) {
+	name, err := u.check(ctx, userID, "", req)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	t := &models.EmailTemplate{
+		ID:        generateID("tmpl"),
+		UserID:    userID,
+		Name:      name,
+		Subject:   req.Subject,
+		HTML:      req.HTML,
+		CreatedAt: now,
+		UpdatedAt: now,
+	}
+	if err := u.templateRepo.Create(ctx, t); err != nil {
+		return nil, errors.ErrDatabaseError
+	}
+	return t, nil
+}
+
+func (u *TemplateUsecase) UpdateTemplate(ctx context.Context, userID, templateID string, req *models.TemplateRequest) (*models.EmailTemplate, error) {
+	t, err := u.GetTemplate(ctx, userID, templateID)
+	if err != nil {
+		return nil, err
+	}
+
+	name, err := u.check(ctx, userID, templateID, req)
+	if err != nil {
+		return nil, err
+	}
+
+	t.Name = name
+	t.Subject = req.Subject
+	t.HTML = req.HTML
+	t.UpdatedAt = time.Now()
+	if err := u.templateRepo.Update(ctx, t); err != nil {
+		return nil, errors.ErrDatabaseError
+	}
+	return t, nil
+}
+
+func (u *TemplateUsecase) DeleteTemplate(ctx context.Context, userID, templateID string) error {
+	if _, err := u.GetTemplate(ctx, userID, templateID); err != nil {
+		return err
+	}
+	if err := u.templateRepo.Delete(ctx, userID, templateID); err != nil {
+		return errors.ErrDatabaseError
+	}
+	return nil
+}
+
+// RenderTemplate executes a stored template against data.
+func (u *TemplateUsecase) RenderTemplate(ctx context.Context, userID, templateID string, data map[string]interface{}) (*models.RenderedEmail, error) {
+	t, err := u.GetTemplate(ctx, userID, templateID)
+	if err != nil {
+		return nil, err
+	}
+	return render(t.Subject, t.HTML, data)
+}
+
+// PreviewTemplate renders a template that has not been saved, so it can be
+// checked while being edited.
+func (u *TemplateUsecase) PreviewTemplate(ctx context.Context, req *models.TemplatePreviewRequest) (*models.RenderedEmail, error) {
+	if len(req.Subject)+len(req.HTML) > maxTemplateSize {
+		return nil, errors.ErrBadRequest("Template is too large")
+	}
+	return render(req.Subject, req.HTML, req.Data)
+}
+
+// check validates a template request and makes sure no other template of
+// the user's (other than templateID) already has its name.
+func (u *TemplateUsecase) check(ctx context.Context, userID, templateID string, req *models.TemplateRequest) (string, error) {
+	name := strings.TrimSpace(req.Name)
+	if name == "" {
+		return "", errors.ErrBadRequest("Template name is required")
+	}
+	if len(name) > maxTemplateNameLength {
+		return "", errors.ErrBadRequest("Template name is too long")
+	}
+	if strings.TrimSpace(req.Subject) == "" || strings.TrimSpace(req.HTML) == "" {
+		return "", errors.ErrBadRequest("Template subject and html are required")
+	}
+	if len(req.Subject)+len(req.HTML) > maxTemplateSize {
+		return "", errors.ErrBadRequest("Template is too large")
+	}
+	if _, err := templates.Parse(req.Subject, req.HTML); err != nil {
+		return "", errors.ErrBadRequest(err.Error())
+	}
+
+	existing, err := u.templateRepo.GetByName(ctx, userID, name)
+	if err != nil {
+		return "", errors.ErrDatabaseError
+	}
+	if existing != nil && existing.ID != templateID {
+		return "", errors.ErrConflict("A template with this name already exists")
+	}
+	return name, nil
+}
+
+// render reports both syntax errors and missing data as bad requests: the
+// template and the data are both the caller's.
+func render(subject, html string, data map[string]interface{}) (*models.RenderedEmail, error) {
+	t, err := templates.Parse(subject, html)
+	if err != nil {
+		return nil, errors.ErrBadRequest(err.Error())
+	}
+	rendered, err := t.Render(data)
+	if err != nil {
+		return nil, errors.ErrBadRequest(err.Error())
+	}
+	return &models.RenderedEmail{
+		Subject: rendered.Subject,
+		HTML:    rendered.HTML,
+		Text:    rendered.Text,
+	}, nil
+}
@@ -0,0 +1,157 @@
+package usecase
+
+import (
+	"context"
+	"strings"
+
+	"ai-assistant/internal/models"
+	"ai-assistant/internal/repository"
+	"ai-assistant/internal/services/email"
+	"ai-assistant/internal/services/email/mailmime"
+	"ai-assistant/internal/services/email/threading"
+	"ai-assistant/pkg/errors"
+)
+
+const threadSnippetLength = 200
+
+type ThreadUsecase struct {
+	emailRepo repository.EmailRepositoryInterface
+	mailboxes email.MailboxFactory
+}
+
+// NewThreadUsecase creates the thread usecase. For users without a mailbox,
+// label changes are only applied to the synced copies.
+func NewThreadUsecase(emailRepo repository.EmailRepositoryInterface, mailboxes email.MailboxFactory) *ThreadUsecase {
+	return &ThreadUsecase{
+		emailRepo: emailRepo,
+		mailboxes: mailboxes,
+	}
+}
+
+// ListThreads returns the user's threads, most recently active first,
+// optionally only those carrying label (e.g. INBOX).
+func (u *ThreadUsecase) ListThreads(ctx context.Context, userID, label string, limit, offset int) ([]*models.Thread, error) {
+	if limit <= 0 || limit > 100 {
+		limit = 20
+	}
+
+	threads, err := u.emailRepo.ListThreads(ctx, userID, label, limit, offset)
+	if err != nil {
+		return nil, errors.ErrDatabaseError
+	}
+
+	for _, thread := range threads {
+		thread.Snippet = threading.Snippet(threading.StripQuoted(thread.Snippet), threadSnippetLength)
+	}
+	return threads, nil
+}
+
+// GetThread returns a thread's messages oldest first, each with its quoted
+// history trimmed. Copies of the same message (same Message-ID header) are
+// shown once.
+func (u *ThreadUsecase) GetThread(ctx context.Context, userID, threadID string) (*models.ThreadDetail, error) {
+	emails, err := u.emailRepo.GetByThreadID(ctx, userID, threadID)
+	if err != nil {
+		return nil, errors.ErrDatabaseError
+	}
+	if len(emails) == 0 {
+		return nil, errors.ErrNotFound("Thread not found")
+	}
+
+	detail := &models.ThreadDetail{Thread: models.Thread{ID: threadID}}
+	seenHeaders := make(map[string]bool)
+	seenParticipants := make(map[string]bool)
+	seenLabels := make(map[string]bool)
+	for _, e := range emails {
+		if header := derefString(e.HeaderID); header != "" {
+			if seenHeaders[header] {
+				continue
+			}
+			seenHeaders[header] = true
+		}
+
+		detail.Messages = append(detail.Messages, &models.ThreadMessage{
+			Email:   e,
+			Content: threading.StripQuoted(derefString(e.Body)),
+		})
+
+		for _, addr := range append(append([]string{e.From}, e.To...), e.Cc...) {
+			key := mailmime.AddressOnly(addr)
+			if key != "" && !seenParticipants[key] {
+				seenParticipants[key] = true
+				detail.Participants = append(detail.Participants, addr)
+			}
+		}
+		for _, label := range e.Labels {
+			if !seenLabels[label] {
+				seenLabels[label] = true
+				detail.Labels = append(detail.Labels, label)
+			}
+		}
+		if !e.IsRead {
+			detail.UnreadCount++
+		}
+	}
+
+	first, last := detail.Messages[0], detail.Messages[len(detail.Messages)-1]
+	detail.Subject = derefString(first.Subject)
+	detail.MessageCount = len(detail.Messages)
+	detail.LastMessageAt = last.CreatedAt
+	detail.Snippet = threading.Snippet(last.Content, threadSnippetLength)
+	return detail, nil
+}
+
+func (u *ThreadUsecase) MarkThreadRead(ctx context.Context, userID, threadID string, read bool) (*models.ThreadDetail, error) {
+	if read {
+		return u.ModifyThreadLabels(ctx, userID, threadID, nil, []string{email.LabelUnread})
+	}
+	return u.ModifyThreadLabels(ctx, userID, threadID, []string{email.LabelUnread}, nil)
+}
+
+// ArchiveThread removes the thread from the inbox, as Gmail's archive does.
+func (u *ThreadUsecase) ArchiveThread(ctx context.Context, userID, threadID string) (*models.ThreadDetail, error) {
+	return u.ModifyThreadLabels(ctx, userID, threadID, nil, []string{email.LabelInbox})
+}
+
+// ModifyThreadLabels applies a label change to every message of a thread,
+// first on the mailbox provider and then on the synced copies, so that a
+// provider failure leaves the local state untouched.
+func (u *ThreadUsecase) ModifyThreadLabels(ctx context.Context, userID, threadID string, add, remove []string) (*models.ThreadDetail, error) {
+	add, remove = cleanLabels(add), cleanLabels(remove)
+	if len(add) == 0 && len(remove) == 0 {
+		return nil, errors.ErrBadRequest("No labels to add or remove")
+	}
+
+	thread, err := u.GetThread(ctx, userID, threadID)
+	if err != nil {
+		return nil, err
+	}
+
+	mailbox, err := openMailbox(ctx, u.mailboxes, userID)
+	if err != nil {
+		return nil, err
+	}
+	if mailbox != nil {
+		for _, msg := range thread.Messages {
+			if err := mailbox.ModifyLabels(ctx, msg.MessageID, add, remove); err != nil {
+				return nil, errors.ErrExternalService
+			}
+		}
+	}
+
+	if _, err := u.emailRepo.ModifyThreadLabels(ctx, userID, threadID, add, remove); err != nil {
+		return nil, errors.ErrDatabaseError
+	}
+
+	return u.GetThread(ctx, userID, threadID)
+}
+
+func cleanLabels(labels []string) []string {
+	var cleaned []string
+	for _, label := range labels {
+		if label = strings.TrimSpace(label); label != "" {
+			cleaned = append(cleaned, label)
+		}
+	}
+	return cleaned
+}
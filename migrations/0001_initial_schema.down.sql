@@ -0,0 +1,7 @@
+DROP TABLE ai_conversations;
+DROP TABLE email_drafts;
+DROP TABLE email_attachments;
+DROP TABLE emails;
+DROP TABLE sessions;
+DROP TABLE accounts;
+DROP TABLE users;
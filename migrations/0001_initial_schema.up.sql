@@ -0,0 +1,105 @@
+-- Users, their linked OAuth accounts and sessions, synced mail, AI drafts
+-- and the AI conversation log.
+CREATE TABLE users (
+    id             TEXT PRIMARY KEY,
+    email          TEXT NOT NULL UNIQUE,
+    name           TEXT,
+    image          TEXT,
+    email_verified TIMESTAMP(3),
+    created_at     TIMESTAMP(3) NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at     TIMESTAMP(3) NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE TABLE accounts (
+    id                  TEXT PRIMARY KEY,
+    user_id             TEXT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    type                TEXT NOT NULL,
+    provider            TEXT NOT NULL,
+    provider_account_id TEXT NOT NULL,
+    refresh_token       TEXT,
+    access_token        TEXT,
+    expires_at          INTEGER,
+    token_type          TEXT,
+    scope               TEXT,
+    id_token            TEXT,
+    session_state       TEXT
+);
+
+CREATE UNIQUE INDEX accounts_provider_provider_account_id_key ON accounts (provider, provider_account_id);
+CREATE INDEX accounts_user_id_provider_idx ON accounts (user_id, provider);
+
+CREATE TABLE sessions (
+    id            TEXT PRIMARY KEY,
+    session_token TEXT NOT NULL UNIQUE,
+    user_id       TEXT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    expires       TIMESTAMP(3) NOT NULL
+);
+
+CREATE TABLE emails (
+    id                TEXT PRIMARY KEY,
+    message_id        TEXT NOT NULL UNIQUE,
+    thread_id         TEXT,
+    subject           TEXT,
+    "from"            TEXT NOT NULL,
+    "to"              TEXT[],
+    cc                TEXT[],
+    reply_to          TEXT,
+    header_message_id TEXT,
+    in_reply_to       TEXT,
+    "references"      TEXT[],
+    body              TEXT,
+    html_body         TEXT,
+    is_read           BOOLEAN NOT NULL DEFAULT FALSE,
+    labels            TEXT[],
+    created_at        TIMESTAMP(3) NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    user_id           TEXT NOT NULL REFERENCES users(id) ON DELETE CASCADE
+);
+
+CREATE INDEX emails_user_id_thread_id_idx ON emails (user_id, thread_id);
+CREATE INDEX emails_user_id_created_at_idx ON emails (user_id, created_at);
+
+-- attachment_id is the provider's handle for content it left out of the
+-- message; content_id is the part's Content-ID for cid: references.
+CREATE TABLE email_attachments (
+    id            TEXT PRIMARY KEY,
+    email_id      TEXT NOT NULL REFERENCES emails(id) ON DELETE CASCADE,
+    filename      TEXT NOT NULL,
+    mime_type     TEXT NOT NULL,
+    size          BIGINT NOT NULL,
+    attachment_id TEXT,
+    content_id    TEXT,
+    inline        BOOLEAN NOT NULL DEFAULT FALSE,
+    created_at    TIMESTAMP(3) NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX email_attachments_email_id_idx ON email_attachments (email_id);
+
+CREATE TABLE email_drafts (
+    id              TEXT PRIMARY KEY,
+    user_id         TEXT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    email_id        TEXT NOT NULL REFERENCES emails(id) ON DELETE CASCADE,
+    thread_id       TEXT,
+    "to"            TEXT[],
+    cc              TEXT[],
+    bcc             TEXT[],
+    subject         TEXT NOT NULL,
+    body            TEXT NOT NULL,
+    status          TEXT NOT NULL DEFAULT 'generated',
+    provider        TEXT NOT NULL,
+    instructions    TEXT,
+    gmail_draft_id  TEXT,
+    sent_message_id TEXT,
+    created_at      TIMESTAMP(3) NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at      TIMESTAMP(3) NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX email_drafts_user_id_status_idx ON email_drafts (user_id, status);
+
+CREATE TABLE ai_conversations (
+    id         TEXT PRIMARY KEY,
+    email_id   TEXT,
+    prompt     TEXT NOT NULL,
+    response   TEXT NOT NULL,
+    provider   TEXT NOT NULL,
+    created_at TIMESTAMP(3) NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
@@ -0,0 +1,2 @@
+DROP INDEX emails_search_vector_idx;
+ALTER TABLE emails DROP COLUMN search_vector;
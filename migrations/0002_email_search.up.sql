@@ -0,0 +1,11 @@
+-- Full-text search over synced emails. The column is generated, so the app
+-- never writes it. The text search configuration must match searchConfig in
+-- internal/repository/search.go.
+ALTER TABLE emails ADD COLUMN search_vector tsvector
+    GENERATED ALWAYS AS (
+        setweight(to_tsvector('english', coalesce(subject, '')), 'A') ||
+        setweight(to_tsvector('english', coalesce("from", '')), 'B') ||
+        setweight(to_tsvector('english', coalesce(body, '')), 'C')
+    ) STORED;
+
+CREATE INDEX emails_search_vector_idx ON emails USING GIN (search_vector);
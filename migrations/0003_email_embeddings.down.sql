@@ -0,0 +1,2 @@
+-- The vector extension stays; other database objects may use it.
+DROP TABLE email_embeddings;
@@ -0,0 +1,17 @@
+-- Chunk embeddings for mailbox retrieval (VECTOR_STORE=pgvector).
+CREATE EXTENSION IF NOT EXISTS vector;
+
+CREATE TABLE email_embeddings (
+    id          TEXT PRIMARY KEY,
+    email_id    TEXT NOT NULL REFERENCES emails(id) ON DELETE CASCADE,
+    user_id     TEXT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    chunk_index INTEGER NOT NULL,
+    content     TEXT NOT NULL,
+    model       TEXT NOT NULL,
+    embedding   vector(768) NOT NULL,
+    created_at  TIMESTAMP(3) NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX email_embeddings_email_id_model_idx ON email_embeddings (email_id, model);
+CREATE INDEX email_embeddings_user_id_model_idx ON email_embeddings (user_id, model);
+CREATE INDEX email_embeddings_embedding_idx ON email_embeddings USING hnsw (embedding vector_cosine_ops);
@@ -0,0 +1,29 @@
+-- User labels, synced to the mailbox provider's labels where it has them.
+CREATE TABLE labels (
+    id                TEXT PRIMARY KEY,
+    user_id           TEXT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    name              TEXT NOT NULL,
+    color             TEXT,
+    provider_label_id TEXT,
+    created_at        TIMESTAMP(3) NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at        TIMESTAMP(3) NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+-- Names are compared case-insensitively, as Gmail does.
+CREATE UNIQUE INDEX labels_user_id_name_key ON labels (user_id, lower(name));
+
+-- Auto-labeling rules, run on newly synced mail in priority order.
+CREATE TABLE email_rules (
+    id              TEXT PRIMARY KEY,
+    user_id         TEXT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    name            TEXT NOT NULL,
+    enabled         BOOLEAN NOT NULL DEFAULT TRUE,
+    priority        INTEGER NOT NULL DEFAULT 0,
+    conditions      JSONB NOT NULL,
+    actions         JSONB NOT NULL,
+    stop_processing BOOLEAN NOT NULL DEFAULT FALSE,
+    created_at      TIMESTAMP(3) NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at      TIMESTAMP(3) NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX email_rules_user_id_enabled_priority_idx ON email_rules (user_id, enabled, priority);
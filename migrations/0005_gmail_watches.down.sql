@@ -0,0 +1 @@
+DROP TABLE gmail_watches;
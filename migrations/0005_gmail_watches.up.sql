@@ -0,0 +1,14 @@
+-- Gmail push registrations, one per user. Notifications name the mailbox by
+-- address, and the renewal loop scans by expiration.
+CREATE TABLE gmail_watches (
+    user_id       TEXT PRIMARY KEY REFERENCES users(id) ON DELETE CASCADE,
+    email_address TEXT NOT NULL,
+    topic         TEXT NOT NULL,
+    history_id    BIGINT NOT NULL,
+    expiration    TIMESTAMP(3) NOT NULL,
+    created_at    TIMESTAMP(3) NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at    TIMESTAMP(3) NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX gmail_watches_email_address_idx ON gmail_watches (lower(email_address));
+CREATE INDEX gmail_watches_expiration_idx ON gmail_watches (expiration);
@@ -0,0 +1,2 @@
+DROP TABLE outbound_email_events;
+DROP TABLE outbound_emails;
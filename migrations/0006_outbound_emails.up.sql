@@ -0,0 +1,34 @@
+-- Everything sent through the configured sender, with its delivery status.
+CREATE TABLE outbound_emails (
+    id                  TEXT PRIMARY KEY,
+    user_id             TEXT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    provider            TEXT NOT NULL,
+    provider_message_id TEXT,
+    message_id          TEXT NOT NULL,
+    thread_id           TEXT,
+    "from"              TEXT NOT NULL,
+    "to"                TEXT[] NOT NULL DEFAULT '{}',
+    cc                  TEXT[] NOT NULL DEFAULT '{}',
+    bcc                 TEXT[] NOT NULL DEFAULT '{}',
+    subject             TEXT NOT NULL,
+    status              TEXT NOT NULL DEFAULT 'queued',
+    error               TEXT,
+    created_at          TIMESTAMP(3) NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at          TIMESTAMP(3) NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE UNIQUE INDEX outbound_emails_provider_provider_message_id_key ON outbound_emails (provider, provider_message_id);
+CREATE INDEX outbound_emails_user_id_created_at_idx ON outbound_emails (user_id, created_at);
+
+-- Status history. Webhook deliveries carry an ID, so redeliveries conflict.
+CREATE TABLE outbound_email_events (
+    id                TEXT PRIMARY KEY,
+    outbound_email_id TEXT NOT NULL REFERENCES outbound_emails(id) ON DELETE CASCADE,
+    status            TEXT NOT NULL,
+    provider_event_id TEXT UNIQUE,
+    detail            TEXT,
+    occurred_at       TIMESTAMP(3) NOT NULL,
+    created_at        TIMESTAMP(3) NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX outbound_email_events_outbound_email_id_occurred_at_idx ON outbound_email_events (outbound_email_id, occurred_at);
@@ -0,0 +1,24 @@
+-- The send queue. Every send waits here for at least the undo delay;
+-- dispatchers claim due rows with FOR UPDATE SKIP LOCKED.
+CREATE TABLE scheduled_emails (
+    id                TEXT PRIMARY KEY,
+    user_id           TEXT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    "from"            TEXT NOT NULL,
+    "to"              TEXT[] NOT NULL DEFAULT '{}',
+    cc                TEXT[] NOT NULL DEFAULT '{}',
+    bcc               TEXT[] NOT NULL DEFAULT '{}',
+    subject           TEXT NOT NULL,
+    body              TEXT NOT NULL,
+    html_body         TEXT,
+    send_at           TIMESTAMP(3) NOT NULL,
+    time_zone         TEXT,
+    status            TEXT NOT NULL DEFAULT 'scheduled',
+    outbound_email_id TEXT REFERENCES outbound_emails(id) ON DELETE SET NULL,
+    error             TEXT,
+    claimed_at        TIMESTAMP(3),
+    created_at        TIMESTAMP(3) NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at        TIMESTAMP(3) NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX scheduled_emails_status_send_at_idx ON scheduled_emails (status, send_at);
+CREATE INDEX scheduled_emails_user_id_send_at_idx ON scheduled_emails (user_id, send_at);
@@ -0,0 +1,2 @@
+ALTER TABLE scheduled_emails DROP COLUMN attachments;
+ALTER TABLE email_attachments DROP COLUMN storage_key;
@@ -0,0 +1,6 @@
+-- Where an incoming attachment's content is kept in the blob store. NULL
+-- until it is stored; Gmail attachments are fetched on first download.
+ALTER TABLE email_attachments ADD COLUMN storage_key TEXT;
+
+-- Files attached to queued emails, with the blob store keys of their content.
+ALTER TABLE scheduled_emails ADD COLUMN attachments JSONB NOT NULL DEFAULT '[]';
@@ -0,0 +1,13 @@
+-- Sanitized HTML of received emails, one row per remote image mode. Rows
+-- whose version no longer matches the sanitizer and image proxy are
+-- rebuilt on the next read.
+CREATE TABLE email_renderings (
+    email_id       TEXT NOT NULL REFERENCES emails(id) ON DELETE CASCADE,
+    remote_images  TEXT NOT NULL,
+    version        TEXT NOT NULL,
+    html           TEXT NOT NULL,
+    text           TEXT NOT NULL,
+    blocked_images INTEGER NOT NULL DEFAULT 0,
+    created_at     TIMESTAMP(3) NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (email_id, remote_images)
+);
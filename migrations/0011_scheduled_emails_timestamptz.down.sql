@@ -0,0 +1,5 @@
+ALTER TABLE scheduled_emails
+    ALTER COLUMN send_at TYPE TIMESTAMP(3) USING send_at AT TIME ZONE 'UTC',
+    ALTER COLUMN claimed_at TYPE TIMESTAMP(3) USING claimed_at AT TIME ZONE 'UTC',
+    ALTER COLUMN created_at TYPE TIMESTAMP(3) USING created_at AT TIME ZONE 'UTC',
+    ALTER COLUMN updated_at TYPE TIMESTAMP(3) USING updated_at AT TIME ZONE 'UTC';
@@ -0,0 +1,8 @@
+-- The send queue compares send_at and claimed_at with the dispatchers'
+-- clocks, so they hold instants rather than wall-clock times that depend on
+-- each writer's time zone. Existing values were written in UTC.
+ALTER TABLE scheduled_emails
+    ALTER COLUMN send_at TYPE TIMESTAMPTZ(3) USING send_at AT TIME ZONE 'UTC',
+    ALTER COLUMN claimed_at TYPE TIMESTAMPTZ(3) USING claimed_at AT TIME ZONE 'UTC',
+    ALTER COLUMN created_at TYPE TIMESTAMPTZ(3) USING created_at AT TIME ZONE 'UTC',
+    ALTER COLUMN updated_at TYPE TIMESTAMPTZ(3) USING updated_at AT TIME ZONE 'UTC';
@@ -0,0 +1,10 @@
+// Package migrations holds the database schema as ordered SQL migrations,
+// applied by pkg/migrate. Each change is a pair of files,
+// NNNN_name.up.sql and NNNN_name.down.sql; applied migrations must never be
+// edited, only followed by new ones.
+package migrations
+
+import "embed"
+
+//go:embed *.sql
+var FS embed.FS
@@ -7,6 +7,7 @@ import (
 	"github.com/go-redis/redis/v8"
 	"ai-assistant/internal/app/config"
 	"ai-assistant/pkg/logger"
+	"ai-assistant/pkg/metrics"
 )
 
 type RedisService struct {
@@ -18,10 +19,13 @@ type RedisService struct {
 func NewRedisService(cfg *config.Config) *RedisService {
 	opts, err := redis.ParseURL(cfg.Redis.URL)
 	if err != nil {
-		logger.New().Error("Failed to parse Redis URL:", err)
+		logger.New().Error("Failed to parse Redis URL", "error", err)
 		return nil
 	}
 
+	opts.MaxRetries = cfg.Redis.MaxRetries
+	opts.PoolSize = cfg.Redis.PoolSize
+	opts.MinIdleConns = cfg.Redis.MinIdleConns
 	client := redis.NewClient(opts)
 	
 	return &RedisService{
@@ -34,13 +38,18 @@ func NewRedisService(cfg *config.Config) *RedisService {
 func (r *RedisService) Connect() error {
 	_, err := r.client.Ping(r.ctx).Result()
 	if err != nil {
-		r.logger.Error("Failed to connect to Redis:", err)
+		r.logger.Error("Failed to connect to Redis", "error", err)
 		return err
 	}
 	r.logger.Info("Successfully connected to Redis")
 	return nil
 }
 
+// Ping checks that Redis answers, without logging, for health checks.
+func (r *RedisService) Ping(ctx context.Context) error {
+	return r.client.Ping(ctx).Err()
+}
+
 func (r *RedisService) Disconnect() error {
 	return r.client.Close()
 }
@@ -50,7 +59,9 @@ func (r *RedisService) Set(key string, value interface{}, expiration time.Durati
 }
 
 func (r *RedisService) Get(key string) (string, error) {
-	return r.client.Get(r.ctx, key).Result()
+	value, err := r.client.Get(r.ctx, key).Result()
+	observeRead("get", err)
+	return value, err
 }
 
 func (r *RedisService) Del(key string) error {
@@ -71,7 +82,9 @@ func (r *RedisService) HSet(key string, field string, value interface{}) error {
 }
 
 func (r *RedisService) HGet(key string, field string) (string, error) {
-	return r.client.HGet(r.ctx, key, field).Result()
+	value, err := r.client.HGet(r.ctx, key, field).Result()
+	observeRead("hget", err)
+	return value, err
 }
 
 func (r *RedisService) HGetAll(key string) (map[string]string, error) {
@@ -80,4 +93,16 @@ func (r *RedisService) HGetAll(key string) (map[string]string, error) {
 
 func (r *RedisService) HDel(key string, fields ...string) error {
 	return r.client.HDel(r.ctx, key, fields...).Err()
-}
\ No newline at end of file
+}
+
+// observeRead counts a read as a hit, a miss (redis.Nil) or an error.
+func observeRead(operation string, err error) {
+	result := metrics.CacheHit
+	switch {
+	case err == redis.Nil:
+		result = metrics.CacheMiss
+	case err != nil:
+		result = metrics.CacheError
+	}
+	metrics.CacheRequests.WithLabelValues(operation, result).Inc()
+}
@@ -22,9 +22,9 @@ func New(cfg *config.Config) (*DB, error) {
 		return nil, fmt.Errorf("failed to open database: %w", err)
 	}
 
-	db.SetMaxOpenConns(25)
-	db.SetMaxIdleConns(25)
-	db.SetConnMaxLifetime(5 * time.Minute)
+	db.SetMaxOpenConns(cfg.Database.MaxOpenConns)
+	db.SetMaxIdleConns(cfg.Database.MaxIdleConns)
+	db.SetConnMaxLifetime(cfg.Database.ConnMaxLifetime)
 
 	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
 	defer cancel()
@@ -42,6 +42,12 @@ func New(cfg *config.Config) (*DB, error) {
 	}, nil
 }
 
+// Wrap uses an already opened pool, e.g. one opened with another driver in
+// tests.
+func Wrap(db *sql.DB) *DB {
+	return &DB{DB: db, logger: logger.New()}
+}
+
 func (db *DB) Close() error {
 	db.logger.Info("Closing database connection")
 	return db.DB.Close()
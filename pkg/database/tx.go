@@ -0,0 +1,195 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"math/rand/v2"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/lib/pq"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	"go.opentelemetry.io/otel/trace"
+	"ai-assistant/pkg/tracing"
+)
+
+// maxTxAttempts is how often WithTx runs a transaction that keeps failing
+// with a serialization failure or deadlock.
+const maxTxAttempts = 3
+
+type txKey struct{}
+
+// Tx is a transaction in progress. WithTx passes it on in the context,
+// where DB's query methods pick it up, so repositories take part in it
+// without being told.
+type Tx struct {
+	*sql.Tx
+	savepoints int
+}
+
+// TxFrom returns the transaction ctx carries, or nil.
+func TxFrom(ctx context.Context) *Tx {
+	tx, _ := ctx.Value(txKey{}).(*Tx)
+	return tx
+}
+
+// WithTx runs fn in a transaction, committing if it returns nil and rolling
+// back if it fails, panics or ctx is done first. Repositories called with
+// the context fn receives run inside the transaction.
+//
+// Called inside another transaction, fn runs in a savepoint instead: its
+// failure undoes only its own work, and the outer transaction decides what
+// happens next. A transaction that fails with a serialization failure or
+// deadlock is retried from the start, so fn must not have effects outside
+// the database that cannot be repeated.
+func (db *DB) WithTx(ctx context.Context, fn func(ctx context.Context) error) error {
+	return db.WithTxOptions(ctx, nil, fn)
+}
+
+// WithTxOptions is WithTx with an isolation level or read-only
+// transaction. Nested calls share the outer transaction's options.
+func (db *DB) WithTxOptions(ctx context.Context, opts *sql.TxOptions, fn func(ctx context.Context) error) error {
+	if tx := TxFrom(ctx); tx != nil {
+		return tx.savepoint(ctx, fn)
+	}
+
+	for attempt := 1; ; attempt++ {
+		err := db.runTx(ctx, opts, fn)
+		if err == nil || !retryable(err) || attempt == maxTxAttempts {
+			return err
+		}
+
+		// Back off a little, with jitter, so the conflicting transactions
+		// do not collide again.
+		delay := time.Duration(attempt*attempt)*10*time.Millisecond + rand.N(10*time.Millisecond)
+		select {
+		case <-ctx.Done():
+			return err
+		case <-time.After(delay):
+		}
+	}
+}
+
+func (db *DB) runTx(ctx context.Context, opts *sql.TxOptions, fn func(ctx context.Context) error) error {
+	sqlTx, err := db.DB.BeginTx(ctx, opts)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if p := recover(); p != nil {
+			sqlTx.Rollback()
+			panic(p)
+		}
+	}()
+
+	if err := fn(context.WithValue(ctx, txKey{}, &Tx{Tx: sqlTx})); err != nil {
+		sqlTx.Rollback()
+		return err
+	}
+	// Work the caller has given up on is not committed.
+	if err := ctx.Err(); err != nil {
+		sqlTx.Rollback()
+		return err
+	}
+	return sqlTx.Commit()
+}
+
+func (tx *Tx) savepoint(ctx context.Context, fn func(ctx context.Context) error) error {
+	tx.savepoints++
+	name := "sp_" + strconv.Itoa(tx.savepoints)
+	if _, err := tx.ExecContext(ctx, "SAVEPOINT "+name); err != nil {
+		return err
+	}
+	defer func() {
+		if p := recover(); p != nil {
+			tx.ExecContext(ctx, "ROLLBACK TO SAVEPOINT "+name)
+			panic(p)
+		}
+	}()
+
+	if err := fn(ctx); err != nil {
+		if _, rbErr := tx.ExecContext(ctx, "ROLLBACK TO SAVEPOINT "+name); rbErr != nil {
+			return fmt.Errorf("%w (rolling back to savepoint: %v)", err, rbErr)
+		}
+		return err
+	}
+	_, err := tx.ExecContext(ctx, "RELEASE SAVEPOINT "+name)
+	return err
+}
+
+// retryable reports whether err means the transaction lost a conflict with
+// a concurrent one and would likely succeed if run again.
+func retryable(err error) bool {
+	var pqErr *pq.Error
+	if !errors.As(err, &pqErr) {
+		return false
+	}
+	switch pqErr.Code {
+	case "40001", // serialization_failure
+		"40P01": // deadlock_detected
+		return true
+	}
+	return false
+}
+
+// ExecContext runs query in the transaction ctx carries, if any.
+func (db *DB) ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error) {
+	ctx, span := startQuery(ctx, query)
+	var result sql.Result
+	var err error
+	if tx := TxFrom(ctx); tx != nil {
+		result, err = tx.ExecContext(ctx, query, args...)
+	} else {
+		result, err = db.DB.ExecContext(ctx, query, args...)
+	}
+	tracing.End(span, err)
+	return result, err
+}
+
+// QueryContext runs query in the transaction ctx carries, if any. Its span
+// ends once the first rows are in, not when they have all been read.
+func (db *DB) QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error) {
+	ctx, span := startQuery(ctx, query)
+	var rows *sql.Rows
+	var err error
+	if tx := TxFrom(ctx); tx != nil {
+		rows, err = tx.QueryContext(ctx, query, args...)
+	} else {
+		rows, err = db.DB.QueryContext(ctx, query, args...)
+	}
+	tracing.End(span, err)
+	return rows, err
+}
+
+// QueryRowContext runs query in the transaction ctx carries, if any. Its
+// errors surface in Scan, after the span has ended, so they are not on it.
+func (db *DB) QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row {
+	ctx, span := startQuery(ctx, query)
+	defer span.End()
+	if tx := TxFrom(ctx); tx != nil {
+		return tx.QueryRowContext(ctx, query, args...)
+	}
+	return db.DB.QueryRowContext(ctx, query, args...)
+}
+
+// startQuery starts a span named after the statement's first keyword, such
+// as "postgres SELECT". The statement's text goes on the span; it holds
+// placeholders, never the values. Queries outside a trace, such as the
+// scheduler's polling, get no span rather than a trace each.
+func startQuery(ctx context.Context, query string) (context.Context, trace.Span) {
+	if !trace.SpanContextFromContext(ctx).IsValid() {
+		return ctx, trace.SpanFromContext(ctx)
+	}
+	operation := "query"
+	if fields := strings.Fields(query); len(fields) > 0 {
+		operation = strings.ToUpper(fields[0])
+	}
+	return tracing.Start(ctx, "postgres "+operation,
+		semconv.DBSystemPostgreSQL,
+		semconv.DBOperationName(operation),
+		semconv.DBQueryText(strings.TrimSpace(query)),
+	)
+}
@@ -46,6 +46,10 @@ func ErrInternalServerError(message string) *AppError {
 	return NewAppError(http.StatusInternalServerError, message, "")
 }
 
+func ErrTooManyRequests(message string) *AppError {
+	return NewAppError(http.StatusTooManyRequests, message, "")
+}
+
 func ErrServiceUnavailable(message string) *AppError {
 	return NewAppError(http.StatusServiceUnavailable, message, "")
 }
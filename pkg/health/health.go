@@ -0,0 +1,163 @@
+// Package health checks the dependencies of the API for liveness and
+// readiness probes. Each dependency registers a check with a timeout and
+// says whether the API can serve without it: a failing critical check makes
+// the API unhealthy, a failing optional one only degraded.
+package health
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"sync"
+	"time"
+)
+
+type Status string
+
+const (
+	StatusHealthy   Status = "healthy"
+	StatusDegraded  Status = "degraded"
+	StatusUnhealthy Status = "unhealthy"
+)
+
+// CheckFunc returns nil if the dependency is usable. It should give up when
+// ctx is done.
+type CheckFunc func(ctx context.Context) error
+
+type check struct {
+	name     string
+	critical bool
+	timeout  time.Duration
+	fn       CheckFunc
+}
+
+// Result is the outcome of one check.
+type Result struct {
+	Status   string `json:"status"`
+	Critical bool   `json:"critical"`
+	Error    string `json:"error,omitempty"`
+	// DurationMs is how long the check took, in milliseconds.
+	DurationMs int64 `json:"durationMs"`
+}
+
+// Report is the outcome of all checks, as /readyz serves it.
+type Report struct {
+	Status    Status            `json:"status"`
+	Checks    map[string]Result `json:"checks"`
+	CheckedAt time.Time         `json:"checkedAt"`
+}
+
+// Checker runs the registered checks. Results are cached for a while, so
+// that frequent probes from several sources do not load the dependencies,
+// and concurrent probes share one run.
+type Checker struct {
+	cacheTTL time.Duration
+
+	mu     sync.Mutex
+	checks []check
+	cached *Report
+}
+
+func New(cacheTTL time.Duration) *Checker {
+	return &Checker{cacheTTL: cacheTTL}
+}
+
+// Register adds a check. A check that has not returned after timeout fails.
+func (c *Checker) Register(name string, critical bool, timeout time.Duration, fn CheckFunc) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.checks = append(c.checks, check{name: name, critical: critical, timeout: timeout, fn: fn})
+	c.cached = nil
+}
+
+// Check returns the report of the last run if it is recent enough, and runs
+// all checks in parallel otherwise. The run outlives a probe that gives up
+// on it, as its report is shared.
+func (c *Checker) Check(ctx context.Context) *Report {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.cached != nil && time.Since(c.cached.CheckedAt) < c.cacheTTL {
+		return c.cached
+	}
+	ctx = context.WithoutCancel(ctx)
+
+	report := &Report{Status: StatusHealthy, Checks: make(map[string]Result, len(c.checks)), CheckedAt: time.Now()}
+	results := make([]Result, len(c.checks))
+	var wg sync.WaitGroup
+	for i, chk := range c.checks {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			results[i] = run(ctx, chk)
+		}()
+	}
+	wg.Wait()
+
+	for i, chk := range c.checks {
+		result := results[i]
+		report.Checks[chk.name] = result
+		if result.Status == "up" {
+			continue
+		}
+		if chk.critical {
+			report.Status = StatusUnhealthy
+		} else if report.Status == StatusHealthy {
+			report.Status = StatusDegraded
+		}
+	}
+	c.cached = report
+	return report
+}
+
+func run(ctx context.Context, chk check) Result {
+	ctx, cancel := context.WithTimeout(ctx, chk.timeout)
+	defer cancel()
+
+	start := time.Now()
+	done := make(chan error, 1)
+	go func() { done <- chk.fn(ctx) }()
+
+	var err error
+	select {
+	case err = <-done:
+	case <-ctx.Done():
+		// A check that ignores ctx is left to finish on its own.
+		err = ctx.Err()
+	}
+
+	result := Result{Status: "up", Critical: chk.critical, DurationMs: time.Since(start).Milliseconds()}
+	if err != nil {
+		result.Status = "down"
+		result.Error = err.Error()
+	}
+	return result
+}
+
+// LiveHandler serves /livez. It only reports that the process is serving
+// requests: restarting it would not bring a failed dependency back.
+func (c *Checker) LiveHandler() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, http.StatusOK, map[string]string{"status": "alive"})
+	}
+}
+
+// ReadyHandler serves /readyz: 200 when healthy or degraded, 503 when a
+// critical dependency is down.
+func (c *Checker) ReadyHandler() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		report := c.Check(r.Context())
+		status := http.StatusOK
+		if report.Status == StatusUnhealthy {
+			status = http.StatusServiceUnavailable
+		}
+		writeJSON(w, status, report)
+	}
+}
+
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
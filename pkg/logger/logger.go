@@ -1,82 +1,193 @@
+// Package logger sets up structured logging on log/slog. Records carry the
+// attributes that middleware attached to the request context, such as the
+// request and user IDs, and the values of sensitive attributes (prompts,
+// tokens, email bodies) are replaced before they are written.
 package logger
 
 import (
+	"context"
 	"fmt"
-	"log"
+	"io"
+	"log/slog"
 	"os"
-	"time"
-
-	"github.com/gin-gonic/gin"
+	"strings"
+	"sync"
 )
 
+// Logger is a slog.Logger. New returns one writing to the handler Setup
+// installed.
 type Logger struct {
-	*log.Logger
+	*slog.Logger
 }
 
 func New() *Logger {
-	return &Logger{
-		Logger: log.New(os.Stdout, "", log.LstdFlags),
-	}
+	return &Logger{Logger: slog.Default()}
 }
 
-func (l *Logger) Info(v ...interface{}) {
-	l.Logger.SetPrefix("[INFO] ")
-	l.Logger.Println(v...)
+// Options configure the handler Setup installs.
+type Options struct {
+	// Level is debug, info, warn or error.
+	Level string
+	// Format is json or text.
+	Format string
+	// Output is stdout, stderr or file, which appends to FilePath.
+	Output   string
+	FilePath string
 }
 
-func (l *Logger) Error(v ...interface{}) {
-	l.Logger.SetPrefix("[ERROR] ")
-	l.Logger.Println(v...)
+// level is the minimum level of the handler Setup installs. SetLevel
+// changes it while the handler is in use.
+var level = new(slog.LevelVar)
+
+// Setup makes a handler built from opts the default, for New, slog and the
+// standard log package alike. The returned io.Closer closes the log file,
+// if any.
+func Setup(opts Options) (io.Closer, error) {
+	if err := SetLevel(opts.Level); err != nil {
+		return nil, err
+	}
+
+	var out io.Writer
+	var closer io.Closer = nopCloser{}
+	switch defaultString(opts.Output, "stdout") {
+	case "stdout":
+		out = os.Stdout
+	case "stderr":
+		out = os.Stderr
+	case "file":
+		if opts.FilePath == "" {
+			return nil, fmt.Errorf("logger: file output needs a file path")
+		}
+		f, err := os.OpenFile(opts.FilePath, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o644)
+		if err != nil {
+			return nil, fmt.Errorf("logger: %w", err)
+		}
+		out, closer = f, f
+	default:
+		return nil, fmt.Errorf("logger: invalid output %q", opts.Output)
+	}
+
+	handler, err := NewHandler(out, level, opts.Format)
+	if err != nil {
+		closer.Close()
+		return nil, err
+	}
+	slog.SetDefault(slog.New(handler))
+	return closer, nil
 }
 
-func (l *Logger) Debug(v ...interface{}) {
-	l.Logger.SetPrefix("[DEBUG] ")
-	l.Logger.Println(v...)
+// SetLevel changes the minimum level of the handler Setup installs, which
+// is info when level is empty.
+func SetLevel(name string) error {
+	var l slog.Level
+	if err := l.UnmarshalText([]byte(defaultString(name, "info"))); err != nil {
+		return fmt.Errorf("logger: invalid level %q", name)
+	}
+	level.Set(l)
+	return nil
 }
 
-func (l *Logger) Warn(v ...interface{}) {
-	l.Logger.SetPrefix("[WARN] ")
-	l.Logger.Println(v...)
+// NewHandler returns a handler writing records at level and above to w in
+// format, with context attributes added and sensitive values redacted.
+func NewHandler(w io.Writer, level slog.Leveler, format string) (slog.Handler, error) {
+	handlerOpts := &slog.HandlerOptions{Level: level, ReplaceAttr: redact}
+	switch defaultString(format, "json") {
+	case "json":
+		return contextHandler{slog.NewJSONHandler(w, handlerOpts)}, nil
+	case "text":
+		return contextHandler{slog.NewTextHandler(w, handlerOpts)}, nil
+	}
+	return nil, fmt.Errorf("logger: invalid format %q", format)
 }
 
-func (l *Logger) Infof(format string, v ...interface{}) {
-	l.Logger.SetPrefix("[INFO] ")
-	l.Logger.Printf(format, v...)
+// Redacted replaces the values of sensitive attributes.
+const Redacted = "[REDACTED]"
+
+// sensitiveKeys are the attribute keys, compared case-insensitively, whose
+// values are never logged.
+var sensitiveKeys = map[string]bool{
+	"prompt":        true,
+	"response":      true,
+	"token":         true,
+	"access_token":  true,
+	"refresh_token": true,
+	"id_token":      true,
+	"authorization": true,
+	"password":      true,
+	"secret":        true,
+	"body":          true,
+	"html_body":     true,
+	"text":          true,
+	"content":       true,
 }
 
-func (l *Logger) Errorf(format string, v ...interface{}) {
-	l.Logger.SetPrefix("[ERROR] ")
-	l.Logger.Printf(format, v...)
+func redact(groups []string, a slog.Attr) slog.Attr {
+	if sensitiveKeys[strings.ToLower(a.Key)] {
+		return slog.String(a.Key, Redacted)
+	}
+	return a
 }
 
-func (l *Logger) Debugf(format string, v ...interface{}) {
-	l.Logger.SetPrefix("[DEBUG] ")
-	l.Logger.Printf(format, v...)
+type contextKey struct{}
+
+// fields is the set of attributes a request context carries. It is shared
+// by the contexts derived from the one it was added to, so attributes found
+// deep in the handler chain, such as the user ID, also appear on records the
+// outer middleware logs.
+type fields struct {
+	mu    sync.Mutex
+	attrs []slog.Attr
 }
 
-func (l *Logger) Warnf(format string, v ...interface{}) {
-	l.Logger.SetPrefix("[WARN] ")
-	l.Logger.Printf(format, v...)
+// NewContext returns ctx with an empty set of attributes for records logged
+// with it, unless it already has one.
+func NewContext(ctx context.Context) context.Context {
+	if _, ok := ctx.Value(contextKey{}).(*fields); ok {
+		return ctx
+	}
+	return context.WithValue(ctx, contextKey{}, &fields{})
 }
 
-func GinLogger() gin.HandlerFunc {
-	return func(c *gin.Context) {
-		start := time.Now()
-		path := c.Request.URL.Path
-		raw := c.Request.URL.RawQuery
-		method := c.Request.Method
+// AddAttrs adds attributes to the set ctx carries. Without one, it does
+// nothing.
+func AddAttrs(ctx context.Context, attrs ...slog.Attr) {
+	f, ok := ctx.Value(contextKey{}).(*fields)
+	if !ok {
+		return
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.attrs = append(f.attrs, attrs...)
+}
 
-		fmt.Printf("→ %s %s", method, path)
-		if raw != "" {
-			fmt.Printf("?%s", raw)
-		}
-		fmt.Println()
+type contextHandler struct {
+	slog.Handler
+}
 
-		c.Next()
+func (h contextHandler) Handle(ctx context.Context, r slog.Record) error {
+	if f, ok := ctx.Value(contextKey{}).(*fields); ok {
+		f.mu.Lock()
+		r.AddAttrs(f.attrs...)
+		f.mu.Unlock()
+	}
+	return h.Handler.Handle(ctx, r)
+}
 
-		duration := time.Since(start)
-		status := c.Writer.Status()
+func (h contextHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return contextHandler{h.Handler.WithAttrs(attrs)}
+}
+
+func (h contextHandler) WithGroup(name string) slog.Handler {
+	return contextHandler{h.Handler.WithGroup(name)}
+}
 
-		fmt.Printf("← %s %s %d %v\n", method, path, status, duration)
+func defaultString(s, fallback string) string {
+	if s == "" {
+		return fallback
 	}
-}
\ No newline at end of file
+	return s
+}
+
+type nopCloser struct{}
+
+func (nopCloser) Close() error { return nil }
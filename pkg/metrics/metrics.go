@@ -0,0 +1,112 @@
+// Package metrics defines the Prometheus metrics of the API and serves them
+// from its own registry, so that tests and tools importing the packages
+// that record them never clash with the global one.
+package metrics
+
+import (
+	"database/sql"
+	"net/http"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+const namespace = "ai_assistant"
+
+// Cache results, the values of CacheRequests' result label.
+const (
+	CacheHit   = "hit"
+	CacheMiss  = "miss"
+	CacheError = "error"
+)
+
+var registry = prometheus.NewRegistry()
+
+var (
+	// HTTPRequestDuration is labelled with the chi route pattern rather than
+	// the path, so that IDs in paths do not each make a series.
+	HTTPRequestDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "http_request_duration_seconds",
+		Help:      "Duration of HTTP requests by method, route pattern and status.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"method", "route", "status"})
+
+	// AIRequestDuration covers failed requests too. Model calls take seconds,
+	// so its buckets reach further than the HTTP ones.
+	AIRequestDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "ai_request_duration_seconds",
+		Help:      "Duration of AI provider requests by provider, model and operation.",
+		Buckets:   []float64{0.1, 0.25, 0.5, 1, 2, 4, 8, 15, 30, 60},
+	}, []string{"provider", "model", "operation"})
+
+	AIRequestErrors = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "ai_request_errors_total",
+		Help:      "AI provider requests that failed, by provider, model and operation.",
+	}, []string{"provider", "model", "operation"})
+
+	// AITokens counts tokens as the provider reports them; direction is
+	// input or output.
+	AITokens = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "ai_tokens_total",
+		Help:      "Tokens used by AI provider requests, by provider, model and direction.",
+	}, []string{"provider", "model", "direction"})
+
+	CacheRequests = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "cache_requests_total",
+		Help:      "Cache reads by operation and result: hit, miss or error.",
+	}, []string{"operation", "result"})
+
+	// QueueDepth is set by each queue's owner whenever it changes or, for
+	// queues kept in the database, whenever the owner polls it.
+	QueueDepth = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "queue_depth",
+		Help:      "Jobs waiting in each background queue.",
+	}, []string{"queue"})
+)
+
+func init() {
+	registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+		HTTPRequestDuration,
+		AIRequestDuration,
+		AIRequestErrors,
+		AITokens,
+		CacheRequests,
+		QueueDepth,
+	)
+}
+
+// ObserveAIRequest records a provider request that started at start and
+// ended with err.
+func ObserveAIRequest(provider, model, operation string, start time.Time, err error) {
+	AIRequestDuration.WithLabelValues(provider, model, operation).Observe(time.Since(start).Seconds())
+	if err != nil {
+		AIRequestErrors.WithLabelValues(provider, model, operation).Inc()
+	}
+}
+
+// AddAITokens records the tokens a provider reported for one request.
+func AddAITokens(provider, model string, input, output int) {
+	AITokens.WithLabelValues(provider, model, "input").Add(float64(input))
+	AITokens.WithLabelValues(provider, model, "output").Add(float64(output))
+}
+
+// RegisterDB exports the connection pool statistics of db, as go_sql_*
+// metrics labelled with name.
+func RegisterDB(db *sql.DB, name string) error {
+	return registry.Register(collectors.NewDBStatsCollector(db, name))
+}
+
+// Handler serves the metrics in the Prometheus exposition format.
+func Handler() http.Handler {
+	return promhttp.HandlerFor(registry, promhttp.HandlerOpts{Registry: registry})
+}
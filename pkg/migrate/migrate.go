@@ -0,0 +1,331 @@
+// Package migrate applies versioned SQL migrations to PostgreSQL. Applied
+// versions are recorded in schema_migrations, and every command holds a
+// session advisory lock, so replicas migrating on startup run one at a time
+// and the later ones find nothing left to do.
+package migrate
+
+import (
+	"context"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"fmt"
+	"io/fs"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// lockKey identifies the advisory lock; it is arbitrary but must never
+// change.
+const lockKey int64 = 7_253_141_990_104_233
+
+// noTransaction at the start of an up or down file runs it outside a
+// transaction, for statements such as CREATE INDEX CONCURRENTLY.
+const noTransaction = "-- migrate:no-transaction"
+
+var fileName = regexp.MustCompile(`^(\d+)_([a-z0-9_]+)\.(up|down)\.sql$`)
+
+type Migration struct {
+	Version int64
+	Name    string
+	Up      string
+	Down    string
+}
+
+// Checksum identifies the content of the up migration, so that an applied
+// migration that was edited afterwards is noticed.
+func (m *Migration) Checksum() string {
+	sum := sha256.Sum256([]byte(m.Up))
+	return hex.EncodeToString(sum[:])
+}
+
+// Status is a migration and whether it has been applied. Migrations that
+// are recorded in the database but unknown to this binary, because a newer
+// one applied them, have no Up or Down.
+type Status struct {
+	Migration
+	AppliedAt *time.Time
+	Modified  bool
+}
+
+type Migrator struct {
+	db         *sql.DB
+	migrations []*Migration
+}
+
+// Load reads the migrations in the root of fsys in version order. Every
+// version needs an up file; the down file is optional.
+func Load(fsys fs.FS) ([]*Migration, error) {
+	entries, err := fs.ReadDir(fsys, ".")
+	if err != nil {
+		return nil, err
+	}
+
+	byVersion := map[int64]*Migration{}
+	for _, entry := range entries {
+		if entry.IsDir() || !strings.HasSuffix(entry.Name(), ".sql") {
+			continue
+		}
+		match := fileName.FindStringSubmatch(entry.Name())
+		if match == nil {
+			return nil, fmt.Errorf("migrate: %s is not named NNNN_name.up.sql or NNNN_name.down.sql", entry.Name())
+		}
+		version, err := strconv.ParseInt(match[1], 10, 64)
+		if err != nil || version <= 0 {
+			return nil, fmt.Errorf("migrate: %s has an invalid version", entry.Name())
+		}
+		content, err := fs.ReadFile(fsys, entry.Name())
+		if err != nil {
+			return nil, err
+		}
+
+		m := byVersion[version]
+		if m == nil {
+			m = &Migration{Version: version, Name: match[2]}
+			byVersion[version] = m
+		} else if m.Name != match[2] {
+			return nil, fmt.Errorf("migrate: version %d is used by both %s and %s", version, m.Name, match[2])
+		}
+		if match[3] == "up" {
+			m.Up = string(content)
+		} else {
+			m.Down = string(content)
+		}
+	}
+
+	migrations := make([]*Migration, 0, len(byVersion))
+	for _, m := range byVersion {
+		if strings.TrimSpace(m.Up) == "" {
+			return nil, fmt.Errorf("migrate: version %d (%s) has no up migration", m.Version, m.Name)
+		}
+		migrations = append(migrations, m)
+	}
+	sort.Slice(migrations, func(i, j int) bool { return migrations[i].Version < migrations[j].Version })
+	return migrations, nil
+}
+
+func New(db *sql.DB, fsys fs.FS) (*Migrator, error) {
+	migrations, err := Load(fsys)
+	if err != nil {
+		return nil, err
+	}
+	return &Migrator{db: db, migrations: migrations}, nil
+}
+
+// Up applies the pending migrations up to and including target, or all of
+// them when target is 0, each in its own transaction. It stops at the first
+// failure, leaving the earlier ones applied.
+func (m *Migrator) Up(ctx context.Context, target int64) ([]*Migration, error) {
+	var applied []*Migration
+	err := m.locked(ctx, func(conn *sql.Conn, done map[int64]record) error {
+		latest := int64(0)
+		for version := range done {
+			if version > latest {
+				latest = version
+			}
+		}
+
+		for _, mig := range m.migrations {
+			if target > 0 && mig.Version > target {
+				break
+			}
+			if rec, ok := done[mig.Version]; ok {
+				if rec.checksum != mig.Checksum() {
+					return fmt.Errorf("migrate: applied migration %d (%s) has been modified", mig.Version, mig.Name)
+				}
+				continue
+			}
+			if mig.Version < latest {
+				return fmt.Errorf("migrate: migration %d (%s) is older than the applied migration %d", mig.Version, mig.Name, latest)
+			}
+
+			if err := run(ctx, conn, mig.Up, func(tx execer) error {
+				_, err := tx.ExecContext(ctx,
+					`INSERT INTO schema_migrations (version, name, checksum) VALUES ($1, $2, $3)`,
+					mig.Version, mig.Name, mig.Checksum())
+				return err
+			}); err != nil {
+				return fmt.Errorf("migrate: %d (%s): %w", mig.Version, mig.Name, err)
+			}
+			applied = append(applied, mig)
+		}
+		return nil
+	})
+	return applied, err
+}
+
+// Down reverts the latest steps applied migrations, newest first.
+func (m *Migrator) Down(ctx context.Context, steps int) ([]*Migration, error) {
+	var reverted []*Migration
+	err := m.locked(ctx, func(conn *sql.Conn, done map[int64]record) error {
+		known := map[int64]bool{}
+		for _, mig := range m.migrations {
+			known[mig.Version] = true
+		}
+		for version := range done {
+			if !known[version] {
+				return fmt.Errorf("migrate: migration %d was applied by a newer version and must be reverted by it", version)
+			}
+		}
+
+		for i := len(m.migrations) - 1; i >= 0 && len(reverted) < steps; i-- {
+			mig := m.migrations[i]
+			if _, ok := done[mig.Version]; !ok {
+				continue
+			}
+			if strings.TrimSpace(mig.Down) == "" {
+				return fmt.Errorf("migrate: %d (%s) has no down migration", mig.Version, mig.Name)
+			}
+
+			if err := run(ctx, conn, mig.Down, func(tx execer) error {
+				_, err := tx.ExecContext(ctx, `DELETE FROM schema_migrations WHERE version = $1`, mig.Version)
+				return err
+			}); err != nil {
+				return fmt.Errorf("migrate: reverting %d (%s): %w", mig.Version, mig.Name, err)
+			}
+			reverted = append(reverted, mig)
+		}
+		return nil
+	})
+	return reverted, err
+}
+
+// Baseline records the migrations up to and including version as applied
+// without running them, for a database whose schema was created before
+// migrations were tracked.
+func (m *Migrator) Baseline(ctx context.Context, version int64) ([]*Migration, error) {
+	var recorded []*Migration
+	err := m.locked(ctx, func(conn *sql.Conn, done map[int64]record) error {
+		for _, mig := range m.migrations {
+			if mig.Version > version {
+				break
+			}
+			if _, ok := done[mig.Version]; ok {
+				continue
+			}
+			if _, err := conn.ExecContext(ctx,
+				`INSERT INTO schema_migrations (version, name, checksum) VALUES ($1, $2, $3)`,
+				mig.Version, mig.Name, mig.Checksum()); err != nil {
+				return err
+			}
+			recorded = append(recorded, mig)
+		}
+		return nil
+	})
+	return recorded, err
+}
+
+// Status lists every known migration, and every applied one this binary
+// does not know, in version order.
+func (m *Migrator) Status(ctx context.Context) ([]*Status, error) {
+	var statuses []*Status
+	err := m.locked(ctx, func(conn *sql.Conn, done map[int64]record) error {
+		for _, mig := range m.migrations {
+			s := &Status{Migration: *mig}
+			if rec, ok := done[mig.Version]; ok {
+				appliedAt := rec.appliedAt
+				s.AppliedAt = &appliedAt
+				s.Modified = rec.checksum != mig.Checksum()
+				delete(done, mig.Version)
+			}
+			statuses = append(statuses, s)
+		}
+		for version, rec := range done {
+			appliedAt := rec.appliedAt
+			statuses = append(statuses, &Status{
+				Migration: Migration{Version: version, Name: rec.name},
+				AppliedAt: &appliedAt,
+			})
+		}
+		return nil
+	})
+	sort.Slice(statuses, func(i, j int) bool { return statuses[i].Version < statuses[j].Version })
+	return statuses, err
+}
+
+type record struct {
+	name      string
+	checksum  string
+	appliedAt time.Time
+}
+
+// locked runs fn on a connection holding the migration lock, with the
+// applied migrations as of taking it.
+func (m *Migrator) locked(ctx context.Context, fn func(conn *sql.Conn, done map[int64]record) error) error {
+	conn, err := m.db.Conn(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	if _, err := conn.ExecContext(ctx, `SELECT pg_advisory_lock($1)`, lockKey); err != nil {
+		return fmt.Errorf("migrate: taking lock: %w", err)
+	}
+	// Unlock even if ctx is done: the connection goes back to the pool, and
+	// a lock left on its session would block every later run.
+	defer conn.ExecContext(context.WithoutCancel(ctx), `SELECT pg_advisory_unlock($1)`, lockKey)
+
+	if _, err := conn.ExecContext(ctx, `
+		CREATE TABLE IF NOT EXISTS schema_migrations (
+			version    BIGINT PRIMARY KEY,
+			name       TEXT NOT NULL,
+			checksum   TEXT NOT NULL,
+			applied_at TIMESTAMP(3) NOT NULL DEFAULT CURRENT_TIMESTAMP
+		)
+	`); err != nil {
+		return fmt.Errorf("migrate: creating schema_migrations: %w", err)
+	}
+
+	rows, err := conn.QueryContext(ctx, `SELECT version, name, checksum, applied_at FROM schema_migrations`)
+	if err != nil {
+		return err
+	}
+	done := map[int64]record{}
+	for rows.Next() {
+		var version int64
+		var rec record
+		if err := rows.Scan(&version, &rec.name, &rec.checksum, &rec.appliedAt); err != nil {
+			rows.Close()
+			return err
+		}
+		done[version] = rec
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	return fn(conn, done)
+}
+
+type execer interface {
+	ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error)
+}
+
+// run executes a migration script and then record, in one transaction
+// unless the script opts out.
+func run(ctx context.Context, conn *sql.Conn, script string, record func(execer) error) error {
+	if strings.HasPrefix(strings.TrimSpace(script), noTransaction) {
+		if _, err := conn.ExecContext(ctx, script); err != nil {
+			return err
+		}
+		return record(conn)
+	}
+
+	tx, err := conn.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	if _, err := tx.ExecContext(ctx, script); err != nil {
+		tx.Rollback()
+		return err
+	}
+	if err := record(tx); err != nil {
+		tx.Rollback()
+		return err
+	}
+	return tx.Commit()
+}
@@ -125,7 +125,7 @@ run_migrations() {
     
     if [ -d "migrations" ] && [ "$(ls -A migrations)" ]; then
         print_status "Running database migrations..."
-        print_warning "Migration logic not implemented yet. Please run migrations manually if needed."
+        go run ./cmd/migrate up
     else
         print_status "No migrations found"
     fi
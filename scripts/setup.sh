@@ -79,7 +79,7 @@ if [ $? -eq 0 ]; then
     echo -e "${BLUE}🎯 Next steps:${NC}"
     echo "1. Update your .env file with actual API keys and database URLs"
     echo "2. Start PostgreSQL and Redis (docker-compose up -d)"
-    echo "3. Run database migrations (make db-push)"
+    echo "3. Run database migrations (make db-migrate)"
     echo "4. Start the development server (make dev)"
     echo ""
     echo -e "${BLUE}📚 Available commands:${NC}"
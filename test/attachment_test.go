@@ -0,0 +1,137 @@
+package handlers_test
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"ai-assistant/internal/handlers"
+	"ai-assistant/internal/models"
+	"ai-assistant/internal/services/blob"
+	"ai-assistant/internal/services/blob/filesystem"
+	"ai-assistant/internal/services/blob/s3"
+)
+
+// fakeS3 is a path-style S3 stand-in that checks each request is signed
+// over the body it carries.
+type fakeS3 struct {
+	mu      sync.Mutex
+	objects map[string][]byte
+}
+
+func (f *fakeS3) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	body, _ := io.ReadAll(r.Body)
+	sum := sha256.Sum256(body)
+	auth := r.Header.Get("Authorization")
+	if r.Header.Get("X-Amz-Content-Sha256") != hex.EncodeToString(sum[:]) ||
+		!strings.HasPrefix(auth, "AWS4-HMAC-SHA256 Credential=test-key/") ||
+		!strings.Contains(auth, "/eu-central-1/s3/aws4_request") ||
+		!strings.Contains(auth, "Signature=") {
+		w.WriteHeader(http.StatusForbidden)
+		return
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	switch r.Method {
+	case http.MethodPut:
+		f.objects[r.URL.Path] = body
+	case http.MethodGet:
+		data, ok := f.objects[r.URL.Path]
+		if !ok {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		w.Write(data)
+	case http.MethodDelete:
+		delete(f.objects, r.URL.Path)
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+func testBlobStore(t *testing.T, store blob.Store) {
+	ctx := context.Background()
+	key := "attachments/user1/email1/report q3.pdf"
+
+	require.NoError(t, store.Put(ctx, key, []byte("%PDF-1.4"), "application/pdf"))
+
+	content, size, err := store.Get(ctx, key)
+	require.NoError(t, err)
+	data, _ := io.ReadAll(content)
+	content.Close()
+	assert.Equal(t, "%PDF-1.4", string(data))
+	assert.Equal(t, int64(8), size)
+
+	require.NoError(t, store.Delete(ctx, key))
+	_, _, err = store.Get(ctx, key)
+	assert.ErrorIs(t, err, blob.ErrNotFound)
+	assert.NoError(t, store.Delete(ctx, key))
+
+	assert.Error(t, store.Put(ctx, "attachments/../../etc/passwd", []byte("x"), ""))
+}
+
+func TestBlobStore_Filesystem(t *testing.T) {
+	store, err := filesystem.NewStore(t.TempDir())
+	require.NoError(t, err)
+	testBlobStore(t, store)
+}
+
+func TestBlobStore_S3(t *testing.T) {
+	fake := &fakeS3{objects: map[string][]byte{}}
+	server := httptest.NewServer(fake)
+	defer server.Close()
+
+	store, err := s3.NewStore(s3.Config{
+		Endpoint:        server.URL,
+		Region:          "eu-central-1",
+		Bucket:          "mail",
+		AccessKeyID:     "test-key",
+		SecretAccessKey: "test-secret",
+		PathStyle:       true,
+	}, server.Client())
+	require.NoError(t, err)
+	testBlobStore(t, store)
+}
+
+// attachmentUsecase serves one attachment; the rest of the interface is
+// not used by these tests.
+type attachmentUsecase struct {
+	handlers.EmailUsecaseInterface
+	attachment *models.EmailAttachment
+	content    string
+}
+
+func (u *attachmentUsecase) OpenAttachment(ctx context.Context, userID, emailID, attachmentID string) (*models.EmailAttachment, io.ReadCloser, int64, error) {
+	return u.attachment, io.NopCloser(strings.NewReader(u.content)), int64(len(u.content)), nil
+}
+
+func TestEmailHandler_DownloadAttachment(t *testing.T) {
+	usecase := &attachmentUsecase{
+		attachment: &models.EmailAttachment{ID: "att_1", Filename: "Überweisung.html", MimeType: "text/html"},
+		content:    "<script>alert(1)</script>",
+	}
+
+	router := chi.NewRouter()
+	router.Use(mockAuthMiddleware)
+	handlers.NewEmailHandler(usecase).RegisterRoutes(router)
+
+	rr := httptest.NewRecorder()
+	router.ServeHTTP(rr, httptest.NewRequest("GET", "/email_1/attachments/att_1", nil))
+
+	assert.Equal(t, http.StatusOK, rr.Code)
+	assert.Equal(t, "text/html", rr.Header().Get("Content-Type"))
+	assert.Equal(t, "25", rr.Header().Get("Content-Length"))
+	assert.Equal(t, "nosniff", rr.Header().Get("X-Content-Type-Options"))
+	assert.Equal(t, "attachment; filename*=utf-8''%C3%9Cberweisung.html", rr.Header().Get("Content-Disposition"))
+	assert.True(t, bytes.Equal([]byte(usecase.content), rr.Body.Bytes()))
+}
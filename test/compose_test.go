@@ -0,0 +1,103 @@
+package handlers_test
+
+import (
+	"bytes"
+	"flag"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"ai-assistant/internal/services/email/mailmime"
+)
+
+var updateGolden = flag.Bool("update", false, "rewrite golden files in testdata")
+
+func assertGolden(t *testing.T, name string, got []byte) {
+	t.Helper()
+	path := filepath.Join("testdata", name)
+
+	if *updateGolden {
+		require.NoError(t, os.MkdirAll(filepath.Dir(path), 0755))
+		require.NoError(t, os.WriteFile(path, got, 0644))
+	}
+
+	want, err := os.ReadFile(path)
+	require.NoError(t, err, "run go test ./test -update to create %s", path)
+	assert.Equal(t, string(want), string(got))
+}
+
+func TestMailMime_ComposeGolden(t *testing.T) {
+	date := time.Date(2024, 3, 1, 9, 30, 0, 0, time.UTC)
+
+	tests := []struct {
+		name   string
+		golden string
+		msg    *mailmime.Outgoing
+	}{
+		{
+			name:   "plain text",
+			golden: "compose/plain.eml",
+			msg: &mailmime.Outgoing{
+				From:      "Assistant <assistant@example.com>",
+				To:        []string{"bob@example.com"},
+				Subject:   "Hello",
+				Text:      "Hi Bob,\nSee you tomorrow.\n",
+				MessageID: "<plain@example.com>",
+				Date:      date,
+			},
+		},
+		{
+			name:   "alternative with attachment and encoded subject",
+			golden: "compose/alternative_attachment.eml",
+			msg: &mailmime.Outgoing{
+				From:    "José Pérez <jose@example.com>",
+				To:      []string{`"Doe, Jane" <jane@example.com>`},
+				Cc:      []string{"carol@example.com"},
+				Bcc:     []string{"audit@example.com"},
+				Subject: "Résumé attached",
+				Text:    "Please find my résumé attached.",
+				HTML:    "<p>Please find my <b>résumé</b> attached.</p>",
+				Attachments: []*mailmime.Attachment{
+					{Filename: "resume.pdf", MimeType: "application/pdf", Data: []byte("%PDF-1.4\n")},
+				},
+				MessageID: "<alt@example.com>",
+				Date:      date,
+			},
+		},
+		{
+			name:   "reply headers",
+			golden: "compose/reply.eml",
+			msg: &mailmime.Outgoing{
+				From:       "assistant@example.com",
+				To:         []string{"bob@example.com"},
+				Subject:    "Re: Lunch",
+				Text:       "Sounds good.",
+				MessageID:  "<reply@example.com>",
+				InReplyTo:  "<original@example.com>",
+				References: []string{"<root@example.com>", "<original@example.com>"},
+				Date:       date,
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			raw, err := tt.msg.Build(true)
+			require.NoError(t, err)
+			assertGolden(t, tt.golden, raw)
+
+			// The composed message must round-trip through the parser.
+			header, root, err := mailmime.Parse(bytes.NewReader(raw))
+			require.NoError(t, err)
+			assert.Equal(t, tt.msg.Subject, mailmime.DecodeHeader(header.Get("Subject")))
+			assert.Equal(t, tt.msg.MessageID, header.Get("Message-Id"))
+
+			parsed := mailmime.Extract(root)
+			assert.Equal(t, tt.msg.HTML, parsed.HTML)
+			assert.Len(t, parsed.Attachments, len(tt.msg.Attachments))
+		})
+	}
+}
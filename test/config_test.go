@@ -0,0 +1,193 @@
+package handlers_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"ai-assistant/internal/app/config"
+)
+
+// configEnv are the variables the tests below set or clear, so that the
+// environment the tests run in does not leak into them.
+var configEnv = []string{
+	"CONFIG_FILE", "APP_ENV", "PORT", "DATABASE_URL", "JWT_SECRET", "GEMINI_API_KEY",
+	"GOOGLE_CLIENT_ID", "GOOGLE_CLIENT_SECRET", "GMAIL_PUBSUB_TOPIC", "RESEND_API_KEY", "RESEND_FROM_EMAIL",
+	"MAIL_PROVIDER", "MAIL_SENDER", "SMTP_HOST", "SMTP_FROM", "IMAP_ADDR", "IMAP_USERNAME",
+	"DATABASE_MAX_OPEN_CONNS", "DATABASE_MAX_IDLE_CONNS", "REDIS_POOL_SIZE", "SERVER_READ_TIMEOUT",
+	"LOG_LEVEL", "LOG_OUTPUT", "LOG_FILE", "STORAGE_BACKEND", "TRACING_SAMPLE_RATIO",
+	"AI_DEFAULT_PROVIDER", "AI_ALLOWED_PROVIDERS", "CORS_ALLOWED_ORIGINS", "RATE_LIMIT_AI_PER_MINUTE", "RATE_LIMIT_AI_BURST",
+	"FEATURE_MAILBOX_GROUNDING", "FEATURE_AI_CLASSIFICATION",
+}
+
+// minimalEnv configures the API to read mail over IMAP and send it over
+// SMTP, which needs neither Google nor Resend.
+func minimalEnv(t *testing.T) {
+	t.Helper()
+	for _, key := range configEnv {
+		t.Setenv(key, "")
+	}
+	t.Setenv("DATABASE_URL", "postgres://localhost/ai_assistant")
+	t.Setenv("JWT_SECRET", "secret")
+	t.Setenv("GEMINI_API_KEY", "key")
+	t.Setenv("MAIL_PROVIDER", "imap")
+	t.Setenv("IMAP_ADDR", "imap.example.com:993")
+	t.Setenv("IMAP_USERNAME", "me")
+	t.Setenv("MAIL_SENDER", "smtp")
+	t.Setenv("SMTP_HOST", "smtp.example.com")
+	t.Setenv("SMTP_FROM", "me@example.com")
+}
+
+func writeConfigFile(t *testing.T, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	require.NoError(t, os.WriteFile(path, []byte(content), 0o600))
+	return path
+}
+
+func TestLoadLayersFileEnvAndFlags(t *testing.T) {
+	minimalEnv(t)
+	path := writeConfigFile(t, `
+development:
+  server:
+    port: 9000
+    read_timeout: "1s"
+  database:
+    max_open_conns: 5
+    max_idle_conns: 2
+production:
+  server:
+    port: 9100
+    read_timeout: "30s"
+    write_timeout: "30s"
+  database:
+    max_open_conns: 100
+    max_idle_conns: 10
+    conn_max_lifetime: "1h"
+  redis:
+    pool_size: 50
+`)
+	t.Setenv("CONFIG_FILE", path)
+	t.Setenv("APP_ENV", "production")
+	t.Setenv("DATABASE_MAX_OPEN_CONNS", "80")
+	t.Setenv("PORT", "9200")
+
+	cfg, err := config.Load([]string{"-port", "9300"})
+	require.NoError(t, err)
+
+	// The flag beats the environment, which beats the production section.
+	assert.Equal(t, "9300", cfg.Server.Port)
+	assert.Equal(t, 80, cfg.Database.MaxOpenConns)
+	assert.Equal(t, 10, cfg.Database.MaxIdleConns)
+	assert.Equal(t, time.Hour, cfg.Database.ConnMaxLifetime)
+	assert.Equal(t, 30*time.Second, cfg.Server.ReadTimeout)
+	assert.Equal(t, 50, cfg.Redis.PoolSize)
+	// What no layer sets keeps its default.
+	assert.Equal(t, 15*time.Second, cfg.Server.IdleTimeout)
+	assert.Equal(t, 3, cfg.Redis.MaxRetries)
+
+	cfg, err = config.Load([]string{"-env", "development"})
+	require.NoError(t, err)
+	assert.Equal(t, "9200", cfg.Server.Port)
+	assert.Equal(t, time.Second, cfg.Server.ReadTimeout)
+	assert.Equal(t, 80, cfg.Database.MaxOpenConns)
+	assert.Equal(t, 2, cfg.Database.MaxIdleConns)
+}
+
+func TestLoadRejectsBadConfigFiles(t *testing.T) {
+	minimalEnv(t)
+
+	t.Setenv("CONFIG_FILE", writeConfigFile(t, "development:\n  server:\n    prot: 9000\n"))
+	_, err := config.Load(nil)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "prot")
+
+	t.Setenv("CONFIG_FILE", writeConfigFile(t, "development:\n  server:\n    port: 9000\n"))
+	_, err = config.Load([]string{"-env", "staging"})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), `no "staging" section`)
+}
+
+func TestLoadReportsEveryProblem(t *testing.T) {
+	minimalEnv(t)
+	t.Setenv("DATABASE_URL", "")
+	t.Setenv("JWT_SECRET", "")
+	t.Setenv("REDIS_POOL_SIZE", "many")
+	t.Setenv("SERVER_READ_TIMEOUT", "10")
+	t.Setenv("LOG_LEVEL", "verbose")
+	t.Setenv("DATABASE_MAX_IDLE_CONNS", "50")
+	t.Setenv("TRACING_SAMPLE_RATIO", "2")
+
+	_, err := config.Load(nil)
+	require.Error(t, err)
+	for _, problem := range []string{
+		"DATABASE_URL (database.url) is required",
+		"JWT_SECRET (auth.jwt_secret) is required",
+		`REDIS_POOL_SIZE: "many" is not a whole number`,
+		`SERVER_READ_TIMEOUT: "10" is not a duration`,
+		`LOG_LEVEL (logging.level) must be debug, info, warn, error, not "verbose"`,
+		"DATABASE_MAX_IDLE_CONNS (database.max_idle_conns) must not exceed",
+		"TRACING_SAMPLE_RATIO (tracing.sample_ratio) must be between 0 and 1",
+	} {
+		assert.Contains(t, err.Error(), problem)
+	}
+}
+
+func TestValidateRequiresOnlyEnabledFeatures(t *testing.T) {
+	minimalEnv(t)
+
+	_, err := config.Load(nil)
+	require.NoError(t, err, "IMAP and SMTP need neither Google nor Resend")
+
+	t.Setenv("MAIL_SENDER", "resend")
+	t.Setenv("LOG_OUTPUT", "file")
+	_, err = config.Load([]string{"-mail-provider", "gmail"})
+	require.Error(t, err)
+	for _, problem := range []string{
+		"GOOGLE_CLIENT_ID (google.client_id) is required with Gmail",
+		"GOOGLE_CLIENT_SECRET (google.client_secret) is required with Gmail",
+		"RESEND_API_KEY (email.resend_api_key) is required with MAIL_SENDER=resend",
+		"RESEND_FROM_EMAIL (email.resend_from_email) is required with MAIL_SENDER=resend",
+		"LOG_FILE (logging.file_path) is required with LOG_OUTPUT=file",
+	} {
+		assert.Contains(t, err.Error(), problem)
+	}
+	assert.NotContains(t, err.Error(), "SMTP_HOST")
+}
+
+func TestLoadDatabaseValidatesOnlyTheDatabase(t *testing.T) {
+	minimalEnv(t)
+	t.Setenv("JWT_SECRET", "")
+	t.Setenv("GEMINI_API_KEY", "")
+	t.Setenv("DATABASE_URL", "")
+
+	_, err := config.LoadDatabase(nil)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "DATABASE_URL (database.url) is required")
+	assert.NotContains(t, err.Error(), "JWT_SECRET")
+
+	t.Setenv("CONFIG_FILE", writeConfigFile(t, "production:\n  database:\n    url: postgres://db/prod\n"))
+	cfg, err := config.LoadDatabase([]string{"-env=production"})
+	require.NoError(t, err)
+	assert.Equal(t, "postgres://db/prod", cfg.Database.URL)
+
+	cfg, err = config.LoadDatabase([]string{"-env=production", "-database-url=postgres://db/other"})
+	require.NoError(t, err)
+	assert.Equal(t, "postgres://db/other", cfg.Database.URL)
+}
+
+func TestShippedConfigFileLoads(t *testing.T) {
+	for _, environment := range []string{"development", "production", "test"} {
+		t.Run(environment, func(t *testing.T) {
+			minimalEnv(t)
+			t.Setenv("CONFIG_FILE", "../scripts/configs/config.yaml")
+			t.Setenv("APP_ENV", environment)
+
+			_, err := config.Load(nil)
+			assert.NoError(t, err)
+		})
+	}
+}
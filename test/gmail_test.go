@@ -0,0 +1,116 @@
+package handlers_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/api/option"
+	"ai-assistant/internal/models"
+	"ai-assistant/internal/repository/memory"
+	"ai-assistant/internal/services/email/gmail"
+	"ai-assistant/internal/usecase"
+)
+
+// gmailMessage is a users.messages.get response in the "full" format. The
+// text body is ISO-8859-1, and its base64url data uses '_' where standard
+// base64 has '/'. The PDF is only referenced by attachmentId.
+const gmailMessage = `{
+  "id": "msg-1",
+  "threadId": "thread-1",
+  "labelIds": ["INBOX", "UNREAD"],
+  "internalDate": "1136214245000",
+  "payload": {
+    "mimeType": "multipart/mixed",
+    "headers": [
+      {"name": "From", "value": "Bob <bob@example.com>"},
+      {"name": "To", "value": "me@example.com"},
+      {"name": "Subject", "value": "=?ISO-8859-1?Q?D=E9jeuner?="},
+      {"name": "Message-ID", "value": "<lunch@example.com>"},
+      {"name": "Content-Type", "value": "multipart/mixed; boundary=outer"}
+    ],
+    "body": {"size": 0},
+    "parts": [
+      {
+        "partId": "0",
+        "mimeType": "multipart/alternative",
+        "headers": [{"name": "Content-Type", "value": "multipart/alternative; boundary=inner"}],
+        "body": {"size": 0},
+        "parts": [
+          {
+            "partId": "0.0",
+            "mimeType": "text/plain",
+            "headers": [
+              {"name": "Content-Type", "value": "text/plain; charset=ISO-8859-1"},
+              {"name": "Content-Transfer-Encoding", "value": "quoted-printable"}
+            ],
+            "body": {"size": 18, "data": "Q2Fm6SDgIG1pZGk_IL9PdWm7"}
+          },
+          {
+            "partId": "0.1",
+            "mimeType": "text/html",
+            "headers": [{"name": "Content-Type", "value": "text/html; charset=UTF-8"}],
+            "body": {"size": 12, "data": "PHA-Q2Fmw6k8L3A-"}
+          }
+        ]
+      },
+      {
+        "partId": "1",
+        "mimeType": "application/pdf",
+        "filename": "menu.pdf",
+        "headers": [
+          {"name": "Content-Type", "value": "application/pdf"},
+          {"name": "Content-Disposition", "value": "attachment"},
+          {"name": "Content-Transfer-Encoding", "value": "base64"}
+        ],
+        "body": {"attachmentId": "ANGjdJ8-att", "size": 48213}
+      }
+    ]
+  }
+}`
+
+func TestGmailImportConvertsAPIPayload(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/gmail/v1/users/me/messages/msg-1" {
+			http.NotFound(w, r)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(gmailMessage))
+	}))
+	defer server.Close()
+
+	ctx := context.Background()
+	svc, err := gmail.NewService(ctx, option.WithHTTPClient(server.Client()), option.WithEndpoint(server.URL+"/"))
+	require.NoError(t, err)
+
+	store := memory.New()
+	require.NoError(t, store.Users().Create(ctx, &models.User{ID: "alice", Email: "alice@example.com"}))
+	emails := usecase.NewEmailUsecase(store.Emails(), nil, nil, nil, nil, nil)
+	require.Equal(t, 1, emails.ImportMessages(ctx, "alice", gmail.NewProvider(svc, "me"), []string{"msg-1"}))
+
+	stored, err := store.Emails().GetByUserID(ctx, "alice", 10, 0)
+	require.NoError(t, err)
+	require.Len(t, stored, 1)
+	record := stored[0]
+	assert.Equal(t, "msg-1", record.MessageID)
+	assert.Equal(t, "thread-1", *record.ThreadID)
+	assert.Equal(t, "Déjeuner", *record.Subject)
+	assert.Equal(t, "Café à midi? ¿Oui»", *record.Body, "body decoded from base64url and ISO-8859-1")
+	assert.Equal(t, "<p>Café</p>", *record.HTMLBody)
+	assert.False(t, record.IsRead)
+
+	attachments, err := store.Emails().GetAttachments(ctx, record.ID)
+	require.NoError(t, err)
+	require.Len(t, attachments, 1)
+	att := attachments[0]
+	assert.Equal(t, "menu.pdf", att.Filename)
+	assert.Equal(t, "application/pdf", att.MimeType)
+	assert.Equal(t, int64(48213), att.Size)
+	require.NotNil(t, att.AttachmentID)
+	assert.Equal(t, "ANGjdJ8-att", *att.AttachmentID)
+	assert.False(t, att.Inline)
+}
@@ -6,11 +6,13 @@ import (
 	"encoding/json"
 	"net/http"
 	"net/http/httptest"
+	"net/url"
 	"testing"
 
 	"github.com/go-chi/chi/v5"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
+	"ai-assistant/internal/app/config"
 	"ai-assistant/internal/handlers"
 	"ai-assistant/internal/models"
 	"ai-assistant/internal/services/auth"
@@ -120,4 +122,34 @@ func TestAIHandler_Ask(t *testing.T) {
 			mockUsecase.AssertExpectations(t)
 		})
 	}
-}
\ No newline at end of file
+}
+
+func TestAuthHandler_GoogleOAuthUsesConfig(t *testing.T) {
+	t.Setenv("GOOGLE_CLIENT_ID", "from-env")
+	cfg := config.Default()
+	cfg.Auth.JWTSecret = "secret"
+	cfg.Google.ClientID = "client-123"
+	cfg.Server.BaseURL = "https://api.example.com/"
+
+	router := chi.NewRouter()
+	handlers.NewAuthHandler(auth.NewAuthService(cfg), cfg).RegisterRoutes(router)
+	rr := httptest.NewRecorder()
+	router.ServeHTTP(rr, httptest.NewRequest("GET", "/auth/google", nil))
+	assert.Equal(t, http.StatusOK, rr.Code)
+
+	var response struct {
+		AuthorizationURL string `json:"authorization_url"`
+	}
+	json.NewDecoder(rr.Body).Decode(&response)
+	u, err := url.Parse(response.AuthorizationURL)
+	assert.NoError(t, err)
+	assert.Equal(t, "client-123", u.Query().Get("client_id"))
+	assert.Equal(t, "https://api.example.com/api/auth/google/callback", u.Query().Get("redirect_uri"))
+
+	cfg.Google.ClientID = ""
+	router = chi.NewRouter()
+	handlers.NewAuthHandler(auth.NewAuthService(cfg), cfg).RegisterRoutes(router)
+	rr = httptest.NewRecorder()
+	router.ServeHTTP(rr, httptest.NewRequest("GET", "/auth/google", nil))
+	assert.Equal(t, http.StatusInternalServerError, rr.Code, "the environment is not read")
+}
@@ -0,0 +1,112 @@
+package handlers_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"ai-assistant/pkg/health"
+)
+
+func probeReady(t *testing.T, checker *health.Checker) (int, health.Report) {
+	t.Helper()
+	rec := httptest.NewRecorder()
+	checker.ReadyHandler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/readyz", nil))
+	var report health.Report
+	require.NoError(t, json.NewDecoder(rec.Body).Decode(&report))
+	return rec.Code, report
+}
+
+func up(context.Context) error { return nil }
+
+func TestReadinessStatus(t *testing.T) {
+	down := func(context.Context) error { return errors.New("connection refused") }
+
+	tests := []struct {
+		name         string
+		dbCheck      health.CheckFunc
+		cacheCheck   health.CheckFunc
+		wantCode     int
+		wantStatus   health.Status
+		wantDatabase string
+	}{
+		{"all up", up, up, http.StatusOK, health.StatusHealthy, "up"},
+		{"optional down", up, down, http.StatusOK, health.StatusDegraded, "up"},
+		{"critical down", down, up, http.StatusServiceUnavailable, health.StatusUnhealthy, "down"},
+		{"both down", down, down, http.StatusServiceUnavailable, health.StatusUnhealthy, "down"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			checker := health.New(time.Minute)
+			checker.Register("database", true, time.Second, tt.dbCheck)
+			checker.Register("redis", false, time.Second, tt.cacheCheck)
+
+			code, report := probeReady(t, checker)
+			assert.Equal(t, tt.wantCode, code)
+			assert.Equal(t, tt.wantStatus, report.Status)
+			assert.Equal(t, tt.wantDatabase, report.Checks["database"].Status)
+			assert.True(t, report.Checks["database"].Critical)
+			assert.False(t, report.Checks["redis"].Critical)
+		})
+	}
+}
+
+func TestReadinessCheckTimesOut(t *testing.T) {
+	checker := health.New(time.Minute)
+	checker.Register("database", true, 20*time.Millisecond, func(ctx context.Context) error {
+		<-ctx.Done()
+		return ctx.Err()
+	})
+	checker.Register("stuck", false, 20*time.Millisecond, func(context.Context) error {
+		time.Sleep(time.Second)
+		return nil
+	})
+
+	start := time.Now()
+	code, report := probeReady(t, checker)
+	assert.Less(t, time.Since(start), 500*time.Millisecond)
+	assert.Equal(t, http.StatusServiceUnavailable, code)
+	assert.Equal(t, context.DeadlineExceeded.Error(), report.Checks["database"].Error)
+	assert.Equal(t, context.DeadlineExceeded.Error(), report.Checks["stuck"].Error)
+}
+
+func TestReadinessResultsAreCached(t *testing.T) {
+	var calls atomic.Int32
+	checker := health.New(time.Minute)
+	checker.Register("database", true, time.Second, func(context.Context) error {
+		calls.Add(1)
+		return nil
+	})
+
+	for i := 0; i < 3; i++ {
+		code, _ := probeReady(t, checker)
+		assert.Equal(t, http.StatusOK, code)
+	}
+	assert.Equal(t, int32(1), calls.Load())
+
+	expired := health.New(0)
+	expired.Register("database", true, time.Second, func(context.Context) error {
+		calls.Add(1)
+		return nil
+	})
+	probeReady(t, expired)
+	probeReady(t, expired)
+	assert.Equal(t, int32(3), calls.Load())
+}
+
+func TestLivenessIgnoresDependencies(t *testing.T) {
+	checker := health.New(time.Minute)
+	checker.Register("database", true, time.Second, func(context.Context) error { return errors.New("down") })
+
+	rec := httptest.NewRecorder()
+	checker.LiveHandler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/livez", nil))
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.JSONEq(t, `{"status":"alive"}`, rec.Body.String())
+}
@@ -0,0 +1,128 @@
+package handlers_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+	chimiddleware "github.com/go-chi/chi/v5/middleware"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"ai-assistant/internal/app/config"
+	"ai-assistant/internal/handlers"
+	"ai-assistant/internal/httperr"
+	"ai-assistant/internal/middleware"
+	"ai-assistant/internal/models"
+	"ai-assistant/internal/repository"
+	"ai-assistant/internal/services/auth"
+	"ai-assistant/pkg/errors"
+)
+
+type errorEnvelope struct {
+	Error struct {
+		Code      int    `json:"code"`
+		Message   string `json:"message"`
+		Details   string `json:"details"`
+		RequestID string `json:"requestId"`
+	} `json:"error"`
+}
+
+func decodeError(t *testing.T, rec *httptest.ResponseRecorder) errorEnvelope {
+	t.Helper()
+	assert.Equal(t, "application/json", rec.Header().Get("Content-Type"))
+	var body errorEnvelope
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &body))
+	assert.Equal(t, rec.Code, body.Error.Code)
+	return body
+}
+
+func TestErrorResponses(t *testing.T) {
+	tests := []struct {
+		name    string
+		err     error
+		status  int
+		message string
+	}{
+		{"app error", errors.ErrBadRequest("Prompt is required"), http.StatusBadRequest, "Prompt is required"},
+		{"wrapped app error", fmt.Errorf("asking: %w", errors.ErrNotFound("Email not found")), http.StatusNotFound, "Email not found"},
+		{"wrapped sentinel", fmt.Errorf("creating label: %w", repository.ErrDuplicate), http.StatusConflict, "Resource already exists"},
+		{"unknown error", fmt.Errorf("dial tcp 10.0.0.5:5432: connection refused"), http.StatusInternalServerError, "Internal server error"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := new(MockAIUsecase)
+			m.On("ProcessAIRequest", mock.Anything, "user123", mock.Anything).Return((*models.AIResponse)(nil), tt.err)
+
+			router := chi.NewRouter()
+			router.Use(chimiddleware.RequestID)
+			router.Use(mockAuthMiddleware)
+			router.Post("/ask", handlers.NewAIHandler(m).Ask)
+
+			rec := httptest.NewRecorder()
+			router.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/ask", bytes.NewBufferString(`{"prompt":"hi"}`)))
+
+			assert.Equal(t, tt.status, rec.Code)
+			body := decodeError(t, rec)
+			assert.Equal(t, tt.message, body.Error.Message)
+			assert.NotEmpty(t, body.Error.RequestID)
+		})
+	}
+}
+
+func TestErrorResponsesRedactInProduction(t *testing.T) {
+	serve := func() errorEnvelope {
+		rec := httptest.NewRecorder()
+		httperr.Write(rec, httptest.NewRequest(http.MethodGet, "/", nil), fmt.Errorf("pq: relation \"users\" does not exist"))
+		return decodeError(t, rec)
+	}
+
+	assert.Contains(t, serve().Error.Details, "relation")
+
+	httperr.SetProduction(true)
+	defer httperr.SetProduction(false)
+	body := serve()
+	assert.Equal(t, "Internal server error", body.Error.Message)
+	assert.Empty(t, body.Error.Details)
+}
+
+func TestMiddlewareErrors(t *testing.T) {
+	authService := auth.NewAuthService(&config.Config{Auth: config.AuthConfig{JWTSecret: "secret"}})
+
+	router := chi.NewRouter()
+	router.Use(chimiddleware.RequestID)
+	router.Use(middleware.ErrorHandler())
+	router.With(authService.RequireAuth()).Get("/private", func(w http.ResponseWriter, r *http.Request) {})
+	router.Get("/panic", func(w http.ResponseWriter, r *http.Request) { panic("boom") })
+	router.NotFound(middleware.NotFoundHandler())
+	router.MethodNotAllowed(middleware.MethodNotAllowedHandler())
+
+	serve := func(method, path string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, httptest.NewRequest(method, path, nil))
+		return rec
+	}
+
+	rec := serve(http.MethodGet, "/private")
+	assert.Equal(t, http.StatusUnauthorized, rec.Code)
+	body := decodeError(t, rec)
+	assert.Equal(t, "Authorization header required", body.Error.Message)
+	assert.Equal(t, rec.Header().Get("X-Request-ID"), body.Error.RequestID)
+
+	rec = serve(http.MethodGet, "/panic")
+	assert.Equal(t, http.StatusInternalServerError, rec.Code)
+	decodeError(t, rec)
+
+	rec = serve(http.MethodGet, "/missing")
+	assert.Equal(t, http.StatusNotFound, rec.Code)
+	decodeError(t, rec)
+
+	rec = serve(http.MethodPost, "/private")
+	assert.Equal(t, http.StatusMethodNotAllowed, rec.Code)
+	decodeError(t, rec)
+}
@@ -0,0 +1,121 @@
+package handlers_test
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+	chimiddleware "github.com/go-chi/chi/v5/middleware"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"ai-assistant/internal/app/config"
+	"ai-assistant/internal/middleware"
+	"ai-assistant/internal/models"
+	"ai-assistant/internal/services/auth"
+	"ai-assistant/pkg/logger"
+)
+
+// captureLogs makes a JSON handler writing to a buffer the default until
+// the test ends.
+func captureLogs(t *testing.T, level slog.Level) *bytes.Buffer {
+	t.Helper()
+	var buf bytes.Buffer
+	handler, err := logger.NewHandler(&buf, level, "json")
+	require.NoError(t, err)
+	previous := slog.Default()
+	slog.SetDefault(slog.New(handler))
+	t.Cleanup(func() { slog.SetDefault(previous) })
+	return &buf
+}
+
+func logRecords(t *testing.T, buf *bytes.Buffer) []map[string]any {
+	t.Helper()
+	var records []map[string]any
+	scanner := bufio.NewScanner(buf)
+	for scanner.Scan() {
+		var record map[string]any
+		require.NoError(t, json.Unmarshal(scanner.Bytes(), &record))
+		records = append(records, record)
+	}
+	return records
+}
+
+func TestLoggerRedactsSensitiveValues(t *testing.T) {
+	buf := captureLogs(t, slog.LevelInfo)
+
+	logger.New().Info("Generated reply",
+		"prompt", "summarise my bank statement",
+		"Authorization", "Bearer abc",
+		"html_body", "<p>hello</p>",
+		"prompt_length", 27,
+	)
+
+	records := logRecords(t, buf)
+	require.Len(t, records, 1)
+	assert.Equal(t, logger.Redacted, records[0]["prompt"])
+	assert.Equal(t, logger.Redacted, records[0]["Authorization"])
+	assert.Equal(t, logger.Redacted, records[0]["html_body"])
+	assert.Equal(t, float64(27), records[0]["prompt_length"])
+}
+
+func TestLoggerFiltersByLevel(t *testing.T) {
+	buf := captureLogs(t, slog.LevelWarn)
+
+	appLogger := logger.New()
+	appLogger.Debug("debug")
+	appLogger.Info("info")
+	appLogger.Warn("warn")
+	appLogger.Error("error")
+
+	records := logRecords(t, buf)
+	require.Len(t, records, 2)
+	assert.Equal(t, "warn", records[0]["msg"])
+	assert.Equal(t, "error", records[1]["msg"])
+}
+
+func TestNewHandlerRejectsUnknownFormat(t *testing.T) {
+	_, err := logger.NewHandler(&bytes.Buffer{}, slog.LevelInfo, "xml")
+	assert.Error(t, err)
+}
+
+func TestRequestLoggerCorrelatesRecords(t *testing.T) {
+	buf := captureLogs(t, slog.LevelInfo)
+
+	authService := auth.NewAuthService(&config.Config{Auth: config.AuthConfig{JWTSecret: "secret"}})
+	token, err := authService.GenerateToken(&models.User{ID: "user-1", Email: "user@example.com"})
+	require.NoError(t, err)
+
+	router := chi.NewRouter()
+	router.Use(chimiddleware.RequestID)
+	router.Use(middleware.RequestLogger())
+	router.With(authService.RequireAuth()).Get("/emails", func(w http.ResponseWriter, r *http.Request) {
+		logger.New().InfoContext(r.Context(), "Listing emails")
+		w.WriteHeader(http.StatusTeapot)
+		w.Write([]byte("ok"))
+	})
+
+	req := httptest.NewRequest(http.MethodGet, "/emails", nil)
+	req.Header.Set("Authorization", "Bearer "+token)
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, req)
+
+	records := logRecords(t, buf)
+	require.Len(t, records, 2)
+	for _, record := range records {
+		assert.NotEmpty(t, record["request_id"])
+		assert.Equal(t, "user-1", record["user_id"])
+	}
+	assert.Equal(t, records[0]["request_id"], records[1]["request_id"])
+
+	served := records[1]
+	assert.Equal(t, "Request served", served["msg"])
+	assert.Equal(t, "GET", served["method"])
+	assert.Equal(t, "/emails", served["path"])
+	assert.Equal(t, float64(http.StatusTeapot), served["status"])
+	assert.Equal(t, float64(2), served["bytes"])
+}
@@ -0,0 +1,87 @@
+package handlers_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"ai-assistant/internal/services/email/mailmime"
+)
+
+const nestedMessage = "From: =?UTF-8?Q?Jos=C3=A9_P=C3=A9rez?= <jose@example.com>\r\n" +
+	"To: \"Doe, Jane\" <jane@example.com>, bob@example.com\r\n" +
+	"Subject: =?ISO-8859-1?Q?R=E9sum=E9?=\r\n" +
+	"MIME-Version: 1.0\r\n" +
+	"Content-Type: multipart/mixed; boundary=\"outer\"\r\n" +
+	"\r\n" +
+	"--outer\r\n" +
+	"Content-Type: multipart/alternative; boundary=\"inner\"\r\n" +
+	"\r\n" +
+	"--inner\r\n" +
+	"Content-Type: text/plain; charset=iso-8859-1\r\n" +
+	"Content-Transfer-Encoding: quoted-printable\r\n" +
+	"\r\n" +
+	"Caf=E9 at noon?\r\n" +
+	"--inner\r\n" +
+	"Content-Type: text/html; charset=utf-8\r\n" +
+	"Content-Transfer-Encoding: base64\r\n" +
+	"\r\n" +
+	"PHA+Q2Fmw6kgYXQgbm9vbj88L3A+\r\n" +
+	"--inner--\r\n" +
+	"--outer\r\n" +
+	"Content-Type: application/pdf; name=\"menu.pdf\"\r\n" +
+	"Content-Disposition: attachment; filename=\"menu.pdf\"\r\n" +
+	"Content-Transfer-Encoding: base64\r\n" +
+	"\r\n" +
+	"JVBERi0xLjQK\r\n" +
+	"--outer--\r\n"
+
+func TestMailMime_ParseNestedMultipart(t *testing.T) {
+	header, root, err := mailmime.Parse(strings.NewReader(nestedMessage))
+	require.NoError(t, err)
+
+	assert.Equal(t, "Résumé", mailmime.DecodeHeader(header.Get("Subject")))
+	assert.Equal(t, "José Pérez <jose@example.com>", mailmime.ParseAddress(header.Get("From")))
+	assert.Equal(t, []string{`"Doe, Jane" <jane@example.com>`, "bob@example.com"},
+		mailmime.ParseAddressList(header.Get("To")))
+
+	msg := mailmime.Extract(root)
+	assert.Equal(t, "Café at noon?", msg.Text)
+	assert.Equal(t, "<p>Café at noon?</p>", msg.HTML)
+
+	require.Len(t, msg.Attachments, 1)
+	assert.Equal(t, "menu.pdf", msg.Attachments[0].Filename)
+	assert.Equal(t, "application/pdf", msg.Attachments[0].MimeType)
+	assert.Equal(t, int64(9), msg.Attachments[0].Size)
+	assert.False(t, msg.Attachments[0].Inline)
+}
+
+func TestMailMime_ReplyRecipients(t *testing.T) {
+	orig := &mailmime.Original{
+		From: "Bob <bob@example.com>",
+		To:   []string{"me@example.com", "Carol <carol@example.com>"},
+		Cc:   []string{"ME@example.com", "dave@example.com", "bob@example.com"},
+	}
+	self := []string{"Me <me@example.com>"}
+
+	to, cc := mailmime.ReplyRecipients(orig, self, false)
+	assert.Equal(t, []string{"Bob <bob@example.com>"}, to)
+	assert.Empty(t, cc)
+
+	to, cc = mailmime.ReplyRecipients(orig, self, true)
+	assert.Equal(t, []string{"Bob <bob@example.com>", "Carol <carol@example.com>"}, to)
+	assert.Equal(t, []string{"dave@example.com"}, cc)
+
+	orig.ReplyTo = "list@example.com"
+	to, _ = mailmime.ReplyRecipients(orig, self, false)
+	assert.Equal(t, []string{"list@example.com"}, to)
+
+	sent := &mailmime.Original{From: "me@example.com", To: []string{"bob@example.com"}}
+	to, _ = mailmime.ReplyRecipients(sent, self, false)
+	assert.Equal(t, []string{"bob@example.com"}, to)
+
+	assert.Equal(t, "Re: Lunch", mailmime.ReplySubject("Lunch"))
+	assert.Equal(t, "RE: Lunch", mailmime.ReplySubject("RE: Lunch"))
+	assert.Equal(t, "Fwd: Lunch", mailmime.ForwardSubject("Lunch"))
+}
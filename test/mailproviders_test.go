@@ -0,0 +1,194 @@
+package handlers_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"ai-assistant/internal/app/config"
+	"ai-assistant/internal/handlers"
+	"ai-assistant/internal/models"
+	"ai-assistant/internal/repository/memory"
+	"ai-assistant/internal/services/auth"
+	"ai-assistant/internal/services/email"
+	"ai-assistant/internal/services/email/mailmime"
+	"ai-assistant/internal/usecase"
+	"ai-assistant/pkg/errors"
+)
+
+// fakeMailbox is one user's server-side mailbox with threads, like Gmail's.
+// It records what the usecases ask of it.
+type fakeMailbox struct {
+	mu       sync.Mutex
+	modified []string
+	sent     []email.SendOptions
+	messages []*mailmime.Outgoing
+}
+
+func (f *fakeMailbox) List(ctx context.Context, opts email.ListOptions) (*email.ListResult, error) {
+	return &email.ListResult{}, nil
+}
+
+func (f *fakeMailbox) Fetch(ctx context.Context, id string) (*email.Message, error) {
+	return nil, errors.ErrNotFound("Message not found")
+}
+
+func (f *fakeMailbox) ModifyLabels(ctx context.Context, id string, add, remove []string) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.modified = append(f.modified, id)
+	return nil
+}
+
+func (f *fakeMailbox) Name() string { return "gmail" }
+
+func (f *fakeMailbox) Send(ctx context.Context, msg *mailmime.Outgoing, opts email.SendOptions) (*email.SendResult, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.sent = append(f.sent, opts)
+	f.messages = append(f.messages, msg)
+	threadID := opts.ThreadID
+	if threadID == "" {
+		threadID = "new-thread"
+	}
+	return &email.SendResult{ID: "sent-1", ThreadID: threadID}, nil
+}
+
+// perUserMailboxes resolves users to their fake mailboxes, like the Gmail
+// factory does with linked Google accounts. Users without one get nil.
+func perUserMailboxes(mailboxes map[string]*fakeMailbox) (email.MailboxFactory, email.SenderFactory) {
+	mailboxFactory := func(ctx context.Context, userID string) (email.MailboxProvider, error) {
+		if mailbox, ok := mailboxes[userID]; ok {
+			return mailbox, nil
+		}
+		return nil, nil
+	}
+	senderFactory := func(ctx context.Context, userID string) (email.Sender, error) {
+		if mailbox, ok := mailboxes[userID]; ok {
+			return mailbox, nil
+		}
+		return nil, nil
+	}
+	return mailboxFactory, senderFactory
+}
+
+func seedThread(t *testing.T, store *memory.Store, userID, threadID string) *models.Email {
+	t.Helper()
+	require.NoError(t, store.Users().Create(context.Background(), &models.User{ID: userID, Email: userID + "@example.com"}))
+	record := &models.Email{
+		ID:        "email-" + userID,
+		MessageID: "gmail-msg-" + userID,
+		ThreadID:  &threadID,
+		Subject:   stringPtr("Quarterly numbers"),
+		From:      "Carol <carol@example.com>",
+		To:        []string{userID + "@example.com"},
+		HeaderID:  stringPtr("<orig-" + userID + "@example.com>"),
+		Body:      stringPtr("Can you send the Q3 numbers?"),
+		Labels:    []string{email.LabelInbox},
+		CreatedAt: time.Now(),
+		UserID:    userID,
+	}
+	require.NoError(t, store.Emails().Create(context.Background(), record))
+	return record
+}
+
+func TestMailboxesAreResolvedPerUser(t *testing.T) {
+	ctx := context.Background()
+	store := memory.New()
+	seedThread(t, store, "alice", "thread-a")
+	seedThread(t, store, "bob", "thread-b")
+
+	aliceMailbox := &fakeMailbox{}
+	mailboxes, _ := perUserMailboxes(map[string]*fakeMailbox{"alice": aliceMailbox})
+	threads := usecase.NewThreadUsecase(store.Emails(), mailboxes)
+
+	_, err := threads.ArchiveThread(ctx, "alice", "thread-a")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"gmail-msg-alice"}, aliceMailbox.modified)
+
+	// Bob has no linked mailbox: only the synced copy changes.
+	detail, err := threads.ArchiveThread(ctx, "bob", "thread-b")
+	require.NoError(t, err)
+	assert.NotContains(t, detail.Labels, email.LabelInbox)
+	assert.Len(t, aliceMailbox.modified, 1)
+
+	emails := usecase.NewEmailUsecase(store.Emails(), nil, mailboxes, nil, nil, nil)
+	assert.NoError(t, emails.SyncMailbox(ctx, "alice"))
+	err = emails.SyncMailbox(ctx, "bob")
+	var appErr *errors.AppError
+	require.ErrorAs(t, err, &appErr)
+	assert.Equal(t, 503, appErr.Code)
+}
+
+func TestMarkEmailAsReadOnlyTouchesOwnEmail(t *testing.T) {
+	ctx := context.Background()
+	store := memory.New()
+	aliceEmail := seedThread(t, store, "alice", "thread-a")
+	seedThread(t, store, "bob", "thread-b")
+
+	aliceMailbox := &fakeMailbox{}
+	mailboxes, _ := perUserMailboxes(map[string]*fakeMailbox{"alice": aliceMailbox})
+	emails := usecase.NewEmailUsecase(store.Emails(), nil, mailboxes, nil, nil, nil)
+
+	err := emails.MarkEmailAsRead(ctx, "bob", aliceEmail.ID)
+	var appErr *errors.AppError
+	require.ErrorAs(t, err, &appErr)
+	assert.Equal(t, 404, appErr.Code)
+	assert.Empty(t, aliceMailbox.modified)
+	record, err := store.Emails().GetByID(ctx, aliceEmail.ID)
+	require.NoError(t, err)
+	assert.False(t, record.IsRead)
+
+	require.NoError(t, emails.MarkEmailAsRead(ctx, "alice", aliceEmail.ID))
+	assert.Equal(t, []string{"gmail-msg-alice"}, aliceMailbox.modified)
+	record, err = store.Emails().GetByID(ctx, aliceEmail.ID)
+	require.NoError(t, err)
+	assert.True(t, record.IsRead)
+}
+
+func stringPtr(s string) *string { return &s }
+
+func TestReplyFromGmailAccountStaysInThread(t *testing.T) {
+	store := memory.New()
+	original := seedThread(t, store, "alice", "gmail-thread-42")
+
+	gmailAccount := &fakeMailbox{}
+	mailboxes, senders := perUserMailboxes(map[string]*fakeMailbox{"alice": gmailAccount})
+	emails := usecase.NewEmailUsecase(store.Emails(), store.Outbound(), mailboxes, senders, nil, nil)
+
+	authService := auth.NewAuthService(&config.Config{Auth: config.AuthConfig{JWTSecret: "secret"}})
+	token, err := authService.GenerateToken(&models.User{ID: "alice", Email: "alice@example.com"})
+	require.NoError(t, err)
+	router := chi.NewRouter()
+	router.Route("/api/emails", func(r chi.Router) {
+		r.Use(authService.RequireAuth())
+		handlers.NewEmailHandler(emails).RegisterRoutes(r)
+	})
+
+	req := httptest.NewRequest(http.MethodPost, "/api/emails/"+original.ID+"/reply", strings.NewReader(`{"body":"Attached."}`))
+	req.Header.Set("Authorization", "Bearer "+token)
+	req.Header.Set("Content-Type", "application/json")
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, req)
+	require.Equal(t, http.StatusCreated, rec.Code, rec.Body.String())
+
+	require.Len(t, gmailAccount.sent, 1)
+	assert.Equal(t, "gmail-thread-42", gmailAccount.sent[0].ThreadID)
+	msg := gmailAccount.messages[0]
+	assert.Equal(t, "<orig-alice@example.com>", msg.InReplyTo)
+	assert.Equal(t, "Re: Quarterly numbers", msg.Subject)
+
+	sent, err := emails.ListSentEmails(context.Background(), "alice", "", 10, 0)
+	require.NoError(t, err)
+	require.Len(t, sent, 1)
+	assert.Equal(t, "gmail", sent[0].Provider)
+	assert.Equal(t, "gmail-thread-42", *sent[0].ThreadID)
+	assert.Equal(t, models.OutboundStatusSent, sent[0].Status)
+}
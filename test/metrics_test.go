@@ -0,0 +1,57 @@
+package handlers_test
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"ai-assistant/internal/middleware"
+	"ai-assistant/pkg/metrics"
+)
+
+func scrapeMetrics(t *testing.T) string {
+	t.Helper()
+	rec := httptest.NewRecorder()
+	metrics.Handler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	require.Equal(t, http.StatusOK, rec.Code)
+	body, err := io.ReadAll(rec.Body)
+	require.NoError(t, err)
+	return string(body)
+}
+
+func TestMetricsLabelRequestsByRoutePattern(t *testing.T) {
+	router := chi.NewRouter()
+	router.Use(middleware.Metrics())
+	router.Route("/api/metrics-test", func(r chi.Router) {
+		r.Get("/{id}", func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusAccepted)
+		})
+	})
+
+	for _, path := range []string{"/api/metrics-test/1", "/api/metrics-test/2", "/nowhere"} {
+		router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, path, nil))
+	}
+
+	body := scrapeMetrics(t)
+	assert.Contains(t, body, `ai_assistant_http_request_duration_seconds_count{method="GET",route="/api/metrics-test/{id}",status="202"} 2`)
+	assert.Contains(t, body, `ai_assistant_http_request_duration_seconds_count{method="GET",route="unmatched",status="404"} 1`)
+	assert.NotContains(t, body, `route="/api/metrics-test/1"`)
+}
+
+func TestMetricsCountAIRequests(t *testing.T) {
+	metrics.ObserveAIRequest("test-provider", "test-model", "generate", time.Now(), nil)
+	metrics.ObserveAIRequest("test-provider", "test-model", "generate", time.Now(), errors.New("overloaded"))
+	metrics.AddAITokens("test-provider", "test-model", 12, 30)
+
+	body := scrapeMetrics(t)
+	assert.Contains(t, body, `ai_assistant_ai_request_duration_seconds_count{model="test-model",operation="generate",provider="test-provider"} 2`)
+	assert.Contains(t, body, `ai_assistant_ai_request_errors_total{model="test-model",operation="generate",provider="test-provider"} 1`)
+	assert.Contains(t, body, `ai_assistant_ai_tokens_total{direction="input",model="test-model",provider="test-provider"} 12`)
+	assert.Contains(t, body, `ai_assistant_ai_tokens_total{direction="output",model="test-model",provider="test-provider"} 30`)
+}
@@ -0,0 +1,49 @@
+package handlers_test
+
+import (
+	"testing"
+	"testing/fstest"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"ai-assistant/migrations"
+	"ai-assistant/pkg/migrate"
+)
+
+func TestEmbeddedMigrations(t *testing.T) {
+	list, err := migrate.Load(migrations.FS)
+	require.NoError(t, err)
+	require.NotEmpty(t, list)
+
+	for i, m := range list {
+		assert.Equal(t, int64(i+1), m.Version, "versions are consecutive")
+		assert.NotEmpty(t, m.Down, "%04d_%s has a down migration", m.Version, m.Name)
+	}
+	assert.Equal(t, "initial_schema", list[0].Name)
+}
+
+func TestLoadMigrations(t *testing.T) {
+	list, err := migrate.Load(fstest.MapFS{
+		"0002_add_index.up.sql":  {Data: []byte("CREATE INDEX i ON t (a);")},
+		"0001_create_t.up.sql":   {Data: []byte("CREATE TABLE t (a INT);")},
+		"0001_create_t.down.sql": {Data: []byte("DROP TABLE t;")},
+		"embed.go":               {Data: []byte("package migrations")},
+	})
+	require.NoError(t, err)
+	require.Len(t, list, 2)
+	assert.Equal(t, "create_t", list[0].Name)
+	assert.Equal(t, "DROP TABLE t;", list[0].Down)
+	assert.Equal(t, int64(2), list[1].Version)
+	assert.Empty(t, list[1].Down)
+	assert.NotEqual(t, list[0].Checksum(), list[1].Checksum())
+
+	invalid := []fstest.MapFS{
+		{"0001_create_t.down.sql": {Data: []byte("DROP TABLE t;")}},
+		{"0001_a.up.sql": {Data: []byte("SELECT 1;")}, "0001_b.up.sql": {Data: []byte("SELECT 2;")}},
+		{"create_t.sql": {Data: []byte("SELECT 1;")}},
+	}
+	for _, fsys := range invalid {
+		_, err := migrate.Load(fsys)
+		assert.Error(t, err)
+	}
+}
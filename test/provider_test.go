@@ -0,0 +1,174 @@
+package handlers_test
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"net"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/emersion/go-imap/backend/memory"
+	imapserver "github.com/emersion/go-imap/server"
+	gosmtp "github.com/emersion/go-smtp"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"ai-assistant/internal/app/config"
+	"ai-assistant/internal/services/email"
+	"ai-assistant/internal/services/email/imap"
+	"ai-assistant/internal/services/email/mailmime"
+	"ai-assistant/internal/services/email/smtp"
+)
+
+// smtpRecorder is a go-smtp backend that keeps every delivered envelope.
+type smtpRecorder struct {
+	mu       sync.Mutex
+	from     string
+	rcpts    []string
+	messages [][]byte
+}
+
+func (r *smtpRecorder) NewSession(*gosmtp.Conn) (gosmtp.Session, error) {
+	return &smtpSession{r: r}, nil
+}
+
+type smtpSession struct{ r *smtpRecorder }
+
+func (s *smtpSession) Reset()        {}
+func (s *smtpSession) Logout() error { return nil }
+
+func (s *smtpSession) Mail(from string, _ *gosmtp.MailOptions) error {
+	s.r.mu.Lock()
+	defer s.r.mu.Unlock()
+	s.r.from = from
+	return nil
+}
+
+func (s *smtpSession) Rcpt(to string, _ *gosmtp.RcptOptions) error {
+	s.r.mu.Lock()
+	defer s.r.mu.Unlock()
+	s.r.rcpts = append(s.r.rcpts, to)
+	return nil
+}
+
+func (s *smtpSession) Data(r io.Reader) error {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return err
+	}
+	s.r.mu.Lock()
+	defer s.r.mu.Unlock()
+	s.r.messages = append(s.r.messages, data)
+	return nil
+}
+
+func listen(t *testing.T) net.Listener {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	return l
+}
+
+func TestSMTPService_Send(t *testing.T) {
+	recorder := &smtpRecorder{}
+	server := gosmtp.NewServer(recorder)
+	server.Domain = "localhost"
+	l := listen(t)
+	go server.Serve(l)
+	defer server.Close()
+
+	host, port, _ := net.SplitHostPort(l.Addr().String())
+	var sender email.Sender = smtp.NewSMTPService(&config.Config{Email: config.EmailConfig{
+		SMTP: config.SMTPConfig{Host: host, Port: port, From: "me@example.com", TLSMode: "none"},
+	}})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	result, err := sender.Send(ctx, &mailmime.Outgoing{
+		To:      []string{"Jane Doe <jane@example.com>"},
+		Bcc:     []string{"audit@example.com"},
+		Subject: "Hello",
+		Text:    "Hi Jane",
+	}, email.SendOptions{})
+	require.NoError(t, err)
+	assert.NotEmpty(t, result.ID)
+
+	recorder.mu.Lock()
+	defer recorder.mu.Unlock()
+	assert.Equal(t, "me@example.com", recorder.from)
+	assert.Equal(t, []string{"jane@example.com", "audit@example.com"}, recorder.rcpts)
+	require.Len(t, recorder.messages, 1)
+
+	header, root, err := mailmime.Parse(bytes.NewReader(recorder.messages[0]))
+	require.NoError(t, err)
+	assert.Equal(t, "Hello", header.Get("Subject"))
+	assert.Empty(t, header.Get("Bcc"))
+	assert.Equal(t, "Hi Jane", strings.TrimSpace(mailmime.Extract(root).Text))
+}
+
+func TestIMAPService_ListFetchModify(t *testing.T) {
+	be := memory.New()
+	user, err := be.Login(nil, "username", "password")
+	require.NoError(t, err)
+	raw := "From: Bob <bob@example.com>\r\n" +
+		"To: me@example.com\r\n" +
+		"Subject: Lunch\r\n" +
+		"Date: Mon, 02 Jan 2006 15:04:05 +0000\r\n" +
+		"Message-ID: <lunch@example.com>\r\n" +
+		"Content-Type: text/plain; charset=utf-8\r\n" +
+		"\r\n" +
+		"Noon?\r\n"
+	inbox, err := user.GetMailbox("INBOX")
+	require.NoError(t, err)
+	require.NoError(t, inbox.CreateMessage(nil, time.Now(), bytes.NewBufferString(raw)))
+
+	server := imapserver.New(be)
+	server.AllowInsecureAuth = true
+	l := listen(t)
+	go server.Serve(l)
+	defer server.Close()
+
+	var mailbox email.MailboxProvider = imap.NewIMAPService(&config.Config{Email: config.EmailConfig{
+		IMAP: config.IMAPConfig{Addr: l.Addr().String(), Username: "username", Password: "password", Mailbox: "INBOX"},
+	}})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	unread, err := mailbox.List(ctx, email.ListOptions{Labels: []string{email.LabelUnread}})
+	require.NoError(t, err)
+	require.Len(t, unread.Messages, 1)
+
+	page, err := mailbox.List(ctx, email.ListOptions{MaxResults: 1})
+	require.NoError(t, err)
+	require.Len(t, page.Messages, 1)
+	assert.Equal(t, unread.Messages[0].ID, page.Messages[0].ID, "newest message first")
+	assert.NotEmpty(t, page.NextPageToken)
+
+	rest, err := mailbox.List(ctx, email.ListOptions{MaxResults: 1, PageToken: page.NextPageToken})
+	require.NoError(t, err)
+	require.Len(t, rest.Messages, 1)
+	assert.NotEqual(t, page.Messages[0].ID, rest.Messages[0].ID)
+
+	msg, err := mailbox.Fetch(ctx, unread.Messages[0].ID)
+	require.NoError(t, err)
+	assert.Equal(t, "Lunch", msg.Subject)
+	assert.Equal(t, "Bob <bob@example.com>", msg.From)
+	assert.Equal(t, "<lunch@example.com>", msg.HeaderID)
+	assert.Equal(t, "Noon?", strings.TrimSpace(msg.Body))
+	assert.False(t, msg.IsRead)
+	assert.True(t, email.HasLabel(msg.Labels, email.LabelUnread))
+
+	require.NoError(t, mailbox.ModifyLabels(ctx, msg.ID, []string{email.LabelStarred}, []string{email.LabelUnread}))
+
+	msg, err = mailbox.Fetch(ctx, msg.ID)
+	require.NoError(t, err)
+	assert.True(t, msg.IsRead)
+	assert.True(t, email.HasLabel(msg.Labels, email.LabelStarred))
+
+	unread, err = mailbox.List(ctx, email.ListOptions{Labels: []string{email.LabelUnread}})
+	require.NoError(t, err)
+	assert.Empty(t, unread.Messages)
+}
@@ -0,0 +1,142 @@
+package handlers_test
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"ai-assistant/internal/handlers"
+	"ai-assistant/internal/models"
+	"ai-assistant/internal/services/email/gmail"
+)
+
+const (
+	pushAudience       = "https://app.example.com/api/gmail/push"
+	pushServiceAccount = "push@project.iam.gserviceaccount.com"
+)
+
+type MockPushUsecase struct {
+	mock.Mock
+}
+
+func (m *MockPushUsecase) Watch(ctx context.Context, userID string) (*models.GmailWatch, error) {
+	args := m.Called(ctx, userID)
+	return args.Get(0).(*models.GmailWatch), args.Error(1)
+}
+
+func (m *MockPushUsecase) StopWatch(ctx context.Context, userID string) error {
+	return m.Called(ctx, userID).Error(0)
+}
+
+func (m *MockPushUsecase) HandleNotification(ctx context.Context, n *gmail.Notification) error {
+	return m.Called(ctx, n).Error(0)
+}
+
+// jwksServer serves key under kid the way Google's certs endpoint does.
+func jwksServer(t *testing.T, kid string, key *rsa.PublicKey) *httptest.Server {
+	t.Helper()
+	set := map[string]interface{}{
+		"keys": []map[string]string{{
+			"kid": kid,
+			"kty": "RSA",
+			"alg": "RS256",
+			"use": "sig",
+			"n":   base64.RawURLEncoding.EncodeToString(key.N.Bytes()),
+			"e":   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(key.E)).Bytes()),
+		}},
+	}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(set)
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func signPushToken(t *testing.T, key *rsa.PrivateKey, kid string, mutate func(jwt.MapClaims)) string {
+	t.Helper()
+	now := time.Now()
+	claims := jwt.MapClaims{
+		"iss":            "https://accounts.google.com",
+		"aud":            pushAudience,
+		"email":          pushServiceAccount,
+		"email_verified": true,
+		"sub":            "1234567890",
+		"iat":            now.Unix(),
+		"exp":            now.Add(time.Hour).Unix(),
+	}
+	if mutate != nil {
+		mutate(claims)
+	}
+	token := jwt.NewWithClaims(jwt.SigningMethodRS256, claims)
+	token.Header["kid"] = kid
+	signed, err := token.SignedString(key)
+	require.NoError(t, err)
+	return signed
+}
+
+func pushBody(data string) string {
+	encoded := base64.StdEncoding.EncodeToString([]byte(data))
+	return `{"message":{"data":"` + encoded + `","messageId":"1"},"subscription":"projects/p/subscriptions/gmail"}`
+}
+
+func TestPushHandler_VerifiesToken(t *testing.T) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+	otherKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+
+	server := jwksServer(t, "k1", &key.PublicKey)
+	verifier := gmail.NewPushVerifier(gmail.NewJWKS(server.URL, server.Client()), pushAudience, pushServiceAccount)
+	valid := pushBody(`{"emailAddress":"alice@example.com","historyId":9876}`)
+
+	tests := []struct {
+		name           string
+		token          string
+		body           string
+		expectedStatus int
+	}{
+		{"valid", "Bearer " + signPushToken(t, key, "k1", nil), valid, http.StatusNoContent},
+		{"missing token", "", valid, http.StatusUnauthorized},
+		{"wrong audience", "Bearer " + signPushToken(t, key, "k1", func(c jwt.MapClaims) { c["aud"] = "https://evil.example.com" }), valid, http.StatusUnauthorized},
+		{"expired", "Bearer " + signPushToken(t, key, "k1", func(c jwt.MapClaims) { c["exp"] = time.Now().Add(-time.Minute).Unix() }), valid, http.StatusUnauthorized},
+		{"wrong issuer", "Bearer " + signPushToken(t, key, "k1", func(c jwt.MapClaims) { c["iss"] = "https://issuer.example.com" }), valid, http.StatusUnauthorized},
+		{"other service account", "Bearer " + signPushToken(t, key, "k1", func(c jwt.MapClaims) { c["email"] = "someone@example.com" }), valid, http.StatusUnauthorized},
+		{"forged signature", "Bearer " + signPushToken(t, otherKey, "k1", nil), valid, http.StatusUnauthorized},
+		{"unknown key", "Bearer " + signPushToken(t, key, "k2", nil), valid, http.StatusUnauthorized},
+		{"bad payload", "Bearer " + signPushToken(t, key, "k1", nil), pushBody(`{"emailAddress":"alice@example.com"}`), http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockUsecase := new(MockPushUsecase)
+			mockUsecase.On("HandleNotification", mock.Anything, &gmail.Notification{EmailAddress: "alice@example.com", HistoryID: 9876}).Return(nil)
+			handler := handlers.NewPushHandler(mockUsecase, verifier)
+
+			req := httptest.NewRequest(http.MethodPost, "/api/gmail/push", strings.NewReader(tt.body))
+			if tt.token != "" {
+				req.Header.Set("Authorization", tt.token)
+			}
+			rr := httptest.NewRecorder()
+			handler.Push(rr, req)
+
+			assert.Equal(t, tt.expectedStatus, rr.Code)
+			if tt.expectedStatus == http.StatusNoContent {
+				mockUsecase.AssertExpectations(t)
+			} else {
+				mockUsecase.AssertNotCalled(t, "HandleNotification", mock.Anything, mock.Anything)
+			}
+		})
+	}
+}
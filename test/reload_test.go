@@ -0,0 +1,150 @@
+package handlers_test
+
+import (
+	"context"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"ai-assistant/internal/app/config"
+	"ai-assistant/internal/middleware"
+	"ai-assistant/internal/models"
+	"ai-assistant/internal/services/auth"
+	"ai-assistant/pkg/logger"
+)
+
+const reloadConfig = `
+development:
+  server:
+    port: 9000
+  logging:
+    level: info
+  cors:
+    allowed_origins: ["https://app.example.com"]
+`
+
+func TestReloadSwapsReloadableSettings(t *testing.T) {
+	minimalEnv(t)
+	path := writeConfigFile(t, reloadConfig)
+	t.Setenv("CONFIG_FILE", path)
+	cfg, err := config.Load(nil)
+	require.NoError(t, err)
+
+	buf := captureLogs(t, slog.LevelInfo)
+	reloader := config.NewReloader(cfg, nil, logger.New())
+	var reloaded *config.Config
+	reloader.OnReload(func(next *config.Config) { reloaded = next })
+
+	require.NoError(t, os.WriteFile(path, []byte(`
+development:
+  server:
+    port: 9001
+  logging:
+    level: debug
+  cors:
+    allowed_origins: ["https://app.example.com", "https://admin.example.com"]
+  rate_limit:
+    ai_requests_per_minute: 30
+  features:
+    mailbox_grounding: false
+`), 0o600))
+	require.NoError(t, reloader.Reload())
+
+	current := reloader.Current()
+	assert.Same(t, current, reloaded)
+	assert.Equal(t, "debug", current.Logging.Level)
+	assert.Equal(t, []string{"https://app.example.com", "https://admin.example.com"}, current.CORS.AllowedOrigins)
+	assert.Equal(t, 30, current.RateLimit.AIRequestsPerMinute)
+	assert.False(t, current.Features.MailboxGrounding)
+	assert.True(t, current.Features.AIClassification)
+	// The listener is already bound to the old port.
+	assert.Equal(t, "9000", current.Server.Port)
+	assert.Equal(t, "info", cfg.Logging.Level, "the running configuration is not modified")
+
+	var messages []string
+	for _, record := range logRecords(t, buf) {
+		messages = append(messages, record["msg"].(string))
+		switch record["msg"] {
+		case "Configuration reloaded":
+			assert.ElementsMatch(t, []any{"logging.level", "cors.allowed_origins", "rate_limit.ai_requests_per_minute", "features.mailbox_grounding"}, record["changed"])
+		case "Configuration changes need a restart to apply":
+			assert.Equal(t, []any{"server.port"}, record["keys"])
+		}
+	}
+	assert.ElementsMatch(t, []string{"Configuration reloaded", "Configuration changes need a restart to apply"}, messages)
+}
+
+func TestReloadKeepsConfigurationWhenInvalid(t *testing.T) {
+	minimalEnv(t)
+	path := writeConfigFile(t, reloadConfig)
+	t.Setenv("CONFIG_FILE", path)
+	cfg, err := config.Load(nil)
+	require.NoError(t, err)
+
+	captureLogs(t, slog.LevelInfo)
+	reloader := config.NewReloader(cfg, nil, logger.New())
+	called := false
+	reloader.OnReload(func(*config.Config) { called = true })
+
+	require.NoError(t, os.WriteFile(path, []byte("development:\n  logging:\n    level: verbose\n"), 0o600))
+	err = reloader.Reload()
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "LOG_LEVEL")
+	assert.Same(t, cfg, reloader.Current())
+	assert.False(t, called)
+}
+
+func TestDiffNamesChangedKeys(t *testing.T) {
+	a, b := config.Default(), config.Default()
+	assert.Empty(t, config.Diff(a, b))
+
+	b.Email.SMTP.Host = "smtp.example.com"
+	b.AI.AllowedProviders = []string{"gemini"}
+	assert.Equal(t, []string{"ai.allowed_providers", "email.smtp.host"}, config.Diff(a, b))
+}
+
+func TestCORSFollowsAllowedOrigins(t *testing.T) {
+	origins := []string{"https://app.example.com"}
+	handler := middleware.CORS(func() []string { return origins })(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+
+	request := func(origin string) string {
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		req.Header.Set("Origin", origin)
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		return rec.Header().Get("Access-Control-Allow-Origin")
+	}
+
+	assert.Equal(t, "https://app.example.com", request("https://app.example.com"))
+	assert.Empty(t, request("https://evil.example.com"))
+
+	origins = []string{"*"}
+	assert.Equal(t, "*", request("https://evil.example.com"))
+}
+
+func TestRateLimiterLimitsEachUser(t *testing.T) {
+	limiter := middleware.NewRateLimiter(1, 2)
+	handler := limiter.Middleware()(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+
+	request := func(userID string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "/api/ai/chat", nil)
+		req = req.WithContext(context.WithValue(req.Context(), auth.UserContextKey, &models.AuthUser{ID: userID}))
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		return rec
+	}
+
+	assert.Equal(t, http.StatusOK, request("alice").Code)
+	assert.Equal(t, http.StatusOK, request("alice").Code)
+	limited := request("alice")
+	assert.Equal(t, http.StatusTooManyRequests, limited.Code)
+	assert.Equal(t, "60", limited.Header().Get("Retry-After"))
+	assert.Equal(t, http.StatusOK, request("bob").Code)
+
+	limiter.SetLimit(0, 0)
+	assert.Equal(t, http.StatusOK, request("alice").Code)
+}
@@ -0,0 +1,414 @@
+package handlers_test
+
+import (
+	"context"
+	"database/sql"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"ai-assistant/internal/models"
+	"ai-assistant/internal/repository"
+	"ai-assistant/internal/repository/memory"
+	"ai-assistant/internal/services/email/search"
+	"ai-assistant/migrations"
+	"ai-assistant/pkg/database"
+	"ai-assistant/pkg/migrate"
+)
+
+type repositories struct {
+	users         repository.UserRepositoryInterface
+	emails        repository.EmailRepositoryInterface
+	conversations repository.AIConversationRepositoryInterface
+	outbound      repository.OutboundRepositoryInterface
+}
+
+// TestRepositoryContract runs the same checks against the in-memory and
+// the PostgreSQL repositories. The PostgreSQL run needs TEST_DATABASE_URL
+// pointing at a database it may migrate and empty.
+func TestRepositoryContract(t *testing.T) {
+	t.Run("memory", func(t *testing.T) {
+		runRepositoryContract(t, func(t *testing.T) repositories {
+			store := memory.New()
+			return repositories{store.Users(), store.Emails(), store.AIConversations(), store.Outbound()}
+		})
+	})
+
+	t.Run("postgres", func(t *testing.T) {
+		sqlDB := openTestDatabase(t)
+		db := database.Wrap(sqlDB)
+		runRepositoryContract(t, func(t *testing.T) repositories {
+			_, err := sqlDB.Exec(`TRUNCATE users, ai_conversations CASCADE`)
+			require.NoError(t, err)
+			return repositories{
+				repository.NewUserRepository(db),
+				repository.NewEmailRepository(db),
+				repository.NewAIConversationRepository(db),
+				repository.NewOutboundRepository(db),
+			}
+		})
+	})
+}
+
+// openTestDatabase connects to TEST_DATABASE_URL and migrates it, or skips
+// the test when it is not set.
+func openTestDatabase(t *testing.T) *sql.DB {
+	t.Helper()
+	url := os.Getenv("TEST_DATABASE_URL")
+	if url == "" {
+		t.Skip("TEST_DATABASE_URL is not set")
+	}
+	sqlDB, err := sql.Open("postgres", url)
+	require.NoError(t, err)
+	t.Cleanup(func() { sqlDB.Close() })
+
+	migrator, err := migrate.New(sqlDB, migrations.FS)
+	require.NoError(t, err)
+	_, err = migrator.Up(context.Background(), 0)
+	require.NoError(t, err)
+	return sqlDB
+}
+
+var contractTime = time.Date(2024, 3, 1, 9, 0, 0, 0, time.UTC)
+
+func contractUser(id string) *models.User {
+	return &models.User{ID: id, Email: id + "@example.com", CreatedAt: contractTime, UpdatedAt: contractTime}
+}
+
+func contractEmail(id, userID string, minutes int) *models.Email {
+	subject := "Subject " + id
+	body := "Body of " + id
+	return &models.Email{
+		ID:        id,
+		MessageID: "msg-" + id,
+		Subject:   &subject,
+		From:      "alice@example.com",
+		To:        []string{userID + "@example.com"},
+		Body:      &body,
+		Labels:    []string{"INBOX", "UNREAD"},
+		CreatedAt: contractTime.Add(time.Duration(minutes) * time.Minute),
+		UserID:    userID,
+	}
+}
+
+func emailIDs(emails []*models.Email) []string {
+	var ids []string
+	for _, e := range emails {
+		ids = append(ids, e.ID)
+	}
+	return ids
+}
+
+func runRepositoryContract(t *testing.T, open func(t *testing.T) repositories) {
+	ctx := context.Background()
+
+	t.Run("users", func(t *testing.T) {
+		r := open(t)
+		require.NoError(t, r.users.Create(ctx, contractUser("u1")))
+
+		user, err := r.users.GetByEmail(ctx, "u1@example.com")
+		require.NoError(t, err)
+		require.NotNil(t, user)
+		assert.Equal(t, "u1", user.ID)
+		assert.WithinDuration(t, contractTime, user.CreatedAt, 0)
+
+		missing, err := r.users.GetByID(ctx, "nobody")
+		assert.NoError(t, err)
+		assert.Nil(t, missing)
+
+		assert.ErrorIs(t, r.users.Create(ctx, contractUser("u1")), repository.ErrDuplicate)
+		sameEmail := contractUser("u2")
+		sameEmail.Email = "u1@example.com"
+		assert.ErrorIs(t, r.users.Create(ctx, sameEmail), repository.ErrDuplicate)
+
+		require.NoError(t, r.users.Create(ctx, contractUser("u2")))
+		renamed := contractUser("u2")
+		renamed.Email = "u1@example.com"
+		assert.ErrorIs(t, r.users.Update(ctx, renamed), repository.ErrDuplicate)
+		renamed.Email = "new@example.com"
+		require.NoError(t, r.users.Update(ctx, renamed))
+		user, err = r.users.GetByID(ctx, "u2")
+		require.NoError(t, err)
+		assert.Equal(t, "new@example.com", user.Email)
+		assert.True(t, user.UpdatedAt.After(contractTime))
+
+		assert.NoError(t, r.users.Update(ctx, contractUser("nobody")))
+		assert.NoError(t, r.users.Delete(ctx, "nobody"))
+	})
+
+	t.Run("emails", func(t *testing.T) {
+		r := open(t)
+		require.NoError(t, r.users.Create(ctx, contractUser("u1")))
+
+		assert.ErrorIs(t, r.emails.Create(ctx, contractEmail("e0", "nobody", 0)), repository.ErrInvalidReference)
+		for i, id := range []string{"e1", "e2", "e3"} {
+			require.NoError(t, r.emails.Create(ctx, contractEmail(id, "u1", i)))
+		}
+		duplicate := contractEmail("e4", "u1", 4)
+		duplicate.MessageID = "msg-e1"
+		assert.ErrorIs(t, r.emails.Create(ctx, duplicate), repository.ErrDuplicate)
+
+		emails, err := r.emails.GetByUserID(ctx, "u1", 10, 0)
+		require.NoError(t, err)
+		assert.Equal(t, []string{"e3", "e2", "e1"}, emailIDs(emails))
+		emails, err = r.emails.GetByUserID(ctx, "u1", 1, 1)
+		require.NoError(t, err)
+		assert.Equal(t, []string{"e2"}, emailIDs(emails))
+		emails, err = r.emails.GetByUserID(ctx, "u1", 10, 5)
+		require.NoError(t, err)
+		assert.Empty(t, emails)
+
+		require.NoError(t, r.emails.UpdateReadStatus(ctx, "e1", true))
+		email, err := r.emails.GetByID(ctx, "e1")
+		require.NoError(t, err)
+		assert.True(t, email.IsRead)
+		assert.Equal(t, []string{"u1@example.com"}, email.To)
+		assert.Nil(t, email.ThreadID)
+
+		missing, err := r.emails.GetByID(ctx, "nope")
+		assert.NoError(t, err)
+		assert.Nil(t, missing)
+
+		require.NoError(t, r.users.Delete(ctx, "u1"))
+		email, err = r.emails.GetByID(ctx, "e1")
+		require.NoError(t, err)
+		assert.Nil(t, email, "emails are deleted with their user")
+	})
+
+	t.Run("attachments and renderings", func(t *testing.T) {
+		r := open(t)
+		require.NoError(t, r.users.Create(ctx, contractUser("u1")))
+
+		attachments := []*models.EmailAttachment{
+			{ID: "b", EmailID: "e1", Filename: "b.pdf", MimeType: "application/pdf", Size: 2, CreatedAt: contractTime},
+			{ID: "a", EmailID: "e1", Filename: "a.pdf", MimeType: "application/pdf", Size: 1, CreatedAt: contractTime},
+			{ID: "a", EmailID: "e1", Filename: "a.pdf", MimeType: "application/pdf", Size: 1, CreatedAt: contractTime},
+		}
+		err := r.emails.CreateWithAttachments(ctx, contractEmail("e1", "u1", 0), attachments)
+		assert.ErrorIs(t, err, repository.ErrDuplicate)
+		email, err := r.emails.GetByID(ctx, "e1")
+		require.NoError(t, err)
+		assert.Nil(t, email, "nothing is stored when an attachment fails")
+
+		require.NoError(t, r.emails.CreateWithAttachments(ctx, contractEmail("e1", "u1", 0), attachments[:2]))
+		list, err := r.emails.GetAttachments(ctx, "e1")
+		require.NoError(t, err)
+		require.Len(t, list, 2)
+		assert.Equal(t, "a", list[0].ID, "ties are ordered by ID")
+
+		require.NoError(t, r.emails.SetAttachmentStorageKey(ctx, "a", "blob/a"))
+		attachment, err := r.emails.GetAttachment(ctx, "e1", "a")
+		require.NoError(t, err)
+		require.NotNil(t, attachment.StorageKey)
+		assert.Equal(t, "blob/a", *attachment.StorageKey)
+		attachment, err = r.emails.GetAttachment(ctx, "other", "a")
+		assert.NoError(t, err)
+		assert.Nil(t, attachment)
+
+		assert.ErrorIs(t, r.emails.CreateAttachment(ctx, &models.EmailAttachment{ID: "c", EmailID: "nope", CreatedAt: contractTime}), repository.ErrInvalidReference)
+
+		rendering := &models.SanitizedEmail{EmailID: "e1", RemoteImages: "block", Version: "1", HTML: "<p>1</p>", CreatedAt: contractTime}
+		require.NoError(t, r.emails.SaveRendering(ctx, rendering))
+		rendering.Version, rendering.HTML = "2", "<p>2</p>"
+		require.NoError(t, r.emails.SaveRendering(ctx, rendering))
+		cached, err := r.emails.GetRendering(ctx, "e1", "block")
+		require.NoError(t, err)
+		assert.Equal(t, "2", cached.Version)
+		assert.Equal(t, "<p>2</p>", cached.HTML)
+		cached, err = r.emails.GetRendering(ctx, "e1", "proxy")
+		assert.NoError(t, err)
+		assert.Nil(t, cached)
+		assert.ErrorIs(t, r.emails.SaveRendering(ctx, &models.SanitizedEmail{EmailID: "nope", RemoteImages: "block", CreatedAt: contractTime}), repository.ErrInvalidReference)
+	})
+
+	t.Run("labels and threads", func(t *testing.T) {
+		r := open(t)
+		require.NoError(t, r.users.Create(ctx, contractUser("u1")))
+		thread := "t1"
+		header := "<first@example.com>"
+		first := contractEmail("e1", "u1", 0)
+		first.ThreadID, first.HeaderID = &thread, &header
+		reply := contractEmail("e2", "u1", 5)
+		reply.ThreadID = &thread
+		reply.From = "bob@example.com"
+		reply.Labels = []string{"INBOX"}
+		reply.IsRead = true
+		single := contractEmail("e3", "u1", 2)
+		for _, e := range []*models.Email{first, reply, single} {
+			require.NoError(t, r.emails.Create(ctx, e))
+		}
+
+		threads, err := r.emails.ListThreads(ctx, "u1", "", 10, 0)
+		require.NoError(t, err)
+		require.Len(t, threads, 2)
+		assert.Equal(t, "t1", threads[0].ID)
+		assert.Equal(t, "Subject e1", threads[0].Subject)
+		assert.Equal(t, []string{"alice@example.com", "bob@example.com"}, threads[0].Participants)
+		assert.Equal(t, 2, threads[0].MessageCount)
+		assert.Equal(t, 1, threads[0].UnreadCount)
+		assert.Equal(t, "Body of e2", threads[0].Snippet)
+		assert.Equal(t, []string{"INBOX", "UNREAD"}, threads[0].Labels)
+		assert.Equal(t, "e3", threads[1].ID)
+
+		messages, err := r.emails.GetByThreadID(ctx, "u1", "t1")
+		require.NoError(t, err)
+		assert.Equal(t, []string{"e1", "e2"}, emailIDs(messages))
+
+		found, err := r.emails.FindThreadID(ctx, "u1", []string{"<unknown>", header})
+		require.NoError(t, err)
+		assert.Equal(t, "t1", found)
+
+		n, err := r.emails.ModifyThreadLabels(ctx, "u1", "t1", nil, []string{"UNREAD"})
+		require.NoError(t, err)
+		assert.Equal(t, int64(2), n)
+		threads, err = r.emails.ListThreads(ctx, "u1", "UNREAD", 10, 0)
+		require.NoError(t, err)
+		require.Len(t, threads, 1)
+		assert.Equal(t, "e3", threads[0].ID)
+
+		ok, err := r.emails.ModifyLabels(ctx, "u1", "e3", []string{"Work", "INBOX"}, nil)
+		require.NoError(t, err)
+		assert.True(t, ok)
+		email, err := r.emails.GetByID(ctx, "e3")
+		require.NoError(t, err)
+		assert.ElementsMatch(t, []string{"INBOX", "UNREAD", "Work"}, email.Labels)
+		ok, err = r.emails.ModifyLabels(ctx, "other-user", "e3", nil, []string{"Work"})
+		require.NoError(t, err)
+		assert.False(t, ok)
+
+		require.NoError(t, r.emails.ReplaceLabel(ctx, "u1", "Work", "Projects"))
+		require.NoError(t, r.emails.RemoveLabel(ctx, "u1", "INBOX"))
+		email, err = r.emails.GetByID(ctx, "e3")
+		require.NoError(t, err)
+		assert.ElementsMatch(t, []string{"UNREAD", "Projects"}, email.Labels)
+	})
+
+	t.Run("search", func(t *testing.T) {
+		r := open(t)
+		require.NoError(t, r.users.Create(ctx, contractUser("u1")))
+		invoice := contractEmail("e1", "u1", 0)
+		subject := "Invoice for March"
+		invoice.Subject = &subject
+		invoice.IsRead = true
+		other := contractEmail("e2", "u1", 1)
+		other.From = "bob@example.com"
+		other.Subject = nil
+		for _, e := range []*models.Email{invoice, other} {
+			require.NoError(t, r.emails.Create(ctx, e))
+		}
+
+		query := func(raw string) []string {
+			q, err := search.Parse(raw)
+			require.NoError(t, err)
+			results, err := r.emails.Search(ctx, "u1", q, 10, 0)
+			require.NoError(t, err)
+			var ids []string
+			for _, result := range results {
+				ids = append(ids, result.ID)
+			}
+			return ids
+		}
+		assert.Equal(t, []string{"e1"}, query("invoice"))
+		assert.Equal(t, []string{"e2", "e1"}, query("from:example.com"))
+		assert.Equal(t, []string{"e2"}, query("is:unread"))
+		assert.Equal(t, []string{"e1"}, query("-subject:april"), "a NULL subject matches neither way")
+		assert.Empty(t, query("invoice -march"))
+	})
+
+	t.Run("search snippets are escaped", func(t *testing.T) {
+		r := open(t)
+		require.NoError(t, r.users.Create(ctx, contractUser("u1")))
+		e := contractEmail("e1", "u1", 0)
+		body := "Your invoice <img src=x onerror=alert(1)> is \x02ready\x03 & attached"
+		e.Body = &body
+		require.NoError(t, r.emails.Create(ctx, e))
+
+		snippet := func(raw string) string {
+			q, err := search.Parse(raw)
+			require.NoError(t, err)
+			results, err := r.emails.Search(ctx, "u1", q, 10, 0)
+			require.NoError(t, err)
+			require.Len(t, results, 1)
+			return results[0].Snippet
+		}
+		highlighted := snippet("invoice")
+		assert.Contains(t, highlighted, "<mark>invoice</mark>")
+		assert.Contains(t, highlighted, "&lt;img src=x onerror=alert(1)&gt;")
+		assert.NotContains(t, highlighted, "<img")
+		assert.NotContains(t, highlighted, "<mark>ready")
+		assert.Equal(t, "Your invoice &lt;img src=x onerror=alert(1)&gt; is ready &amp; attached", snippet("from:example.com"))
+	})
+
+	t.Run("outbound", func(t *testing.T) {
+		r := open(t)
+		require.NoError(t, r.users.Create(ctx, contractUser("u1")))
+		for i, id := range []string{"o1", "o2"} {
+			require.NoError(t, r.outbound.Create(ctx, &models.OutboundEmail{
+				ID: id, UserID: "u1", Provider: "gmail", MessageID: "<" + id + "@example.com>",
+				From: "u1@example.com", To: []string{"bob@example.com"}, Cc: []string{}, Bcc: []string{},
+				Subject: "Hi", Status: models.OutboundStatusQueued,
+				CreatedAt: contractTime.Add(time.Duration(i) * time.Minute), UpdatedAt: contractTime,
+			}))
+		}
+
+		threadID, providerID := "thread-1", "gmail-1"
+		require.NoError(t, r.outbound.SetSendResult(ctx, "o1", models.OutboundStatusSent, &providerID, &threadID, nil))
+		require.NoError(t, r.outbound.SetSendResult(ctx, "o1", models.OutboundStatusSent, nil, nil, nil))
+		sent, err := r.outbound.GetByProviderMessageID(ctx, "gmail", "gmail-1")
+		require.NoError(t, err)
+		require.NotNil(t, sent)
+		assert.Equal(t, "thread-1", *sent.ThreadID, "a missing thread ID keeps the recorded one")
+
+		require.NoError(t, r.outbound.AdvanceStatus(ctx, "o1", models.OutboundStatusQueued, []models.OutboundStatus{models.OutboundStatusDelivered}))
+		all, err := r.outbound.GetByUserID(ctx, "u1", "", 10, 0)
+		require.NoError(t, err)
+		require.Len(t, all, 2)
+		assert.Equal(t, "o2", all[0].ID)
+		assert.Equal(t, models.OutboundStatusSent, all[1].Status)
+
+		queued, err := r.outbound.GetByUserID(ctx, "u1", models.OutboundStatusQueued, 10, 0)
+		require.NoError(t, err)
+		require.Len(t, queued, 1)
+		assert.Equal(t, "o2", queued[0].ID)
+
+		eventID := "evt-1"
+		event := &models.OutboundEvent{ID: "e1", OutboundEmailID: "o1", Status: models.OutboundStatusDelivered, ProviderEventID: &eventID, OccurredAt: contractTime}
+		added, err := r.outbound.AddEvent(ctx, event)
+		require.NoError(t, err)
+		assert.True(t, added)
+		again := *event
+		again.ID = "e2"
+		added, err = r.outbound.AddEvent(ctx, &again)
+		require.NoError(t, err)
+		assert.False(t, added, "a redelivered provider event is recorded once")
+
+		events, err := r.outbound.GetEvents(ctx, []string{"o1", "o2"})
+		require.NoError(t, err)
+		require.Len(t, events, 1)
+		assert.Equal(t, "e1", events[0].ID)
+	})
+
+	t.Run("ai conversations", func(t *testing.T) {
+		r := open(t)
+		for i, id := range []string{"c1", "c2", "c3"} {
+			require.NoError(t, r.conversations.Create(ctx, &models.AIConversation{
+				ID: id, Prompt: "p", Response: "r", Provider: "gemini",
+				CreatedAt: contractTime.Add(time.Duration(i) * time.Minute),
+			}))
+		}
+		assert.ErrorIs(t, r.conversations.Create(ctx, &models.AIConversation{ID: "c1", Provider: "claude", CreatedAt: contractTime}), repository.ErrDuplicate)
+
+		list, err := r.conversations.GetByProvider(ctx, "gemini", 2)
+		require.NoError(t, err)
+		require.Len(t, list, 2)
+		assert.Equal(t, "c3", list[0].ID)
+		assert.Equal(t, "c2", list[1].ID)
+
+		require.NoError(t, r.conversations.Delete(ctx, "c3"))
+		conversation, err := r.conversations.GetByID(ctx, "c3")
+		assert.NoError(t, err)
+		assert.Nil(t, conversation)
+	})
+}
@@ -0,0 +1,194 @@
+package handlers_test
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"ai-assistant/internal/models"
+	"ai-assistant/internal/repository/memory"
+	"ai-assistant/internal/services/ai/embedding"
+	"ai-assistant/internal/usecase"
+	"ai-assistant/pkg/errors"
+	"ai-assistant/pkg/logger"
+)
+
+func TestEmbedding_Split(t *testing.T) {
+	body := strings.Repeat("renewal pricing ", 100)
+	chunks := embedding.Split("Contract", body, 200, 40)
+
+	require.Greater(t, len(chunks), 1)
+	for _, chunk := range chunks {
+		assert.True(t, strings.HasPrefix(chunk, "Subject: Contract\n"))
+		assert.LessOrEqual(t, len(chunk), 200+len("Subject: Contract\n"))
+	}
+	assert.Equal(t, []string{"Subject: Hello"}, embedding.Split("Hello", "", 200, 40))
+	assert.Empty(t, embedding.Split("", "  ", 200, 40))
+}
+
+func TestEmbedding_LocalRetrieval(t *testing.T) {
+	ctx := context.Background()
+	embedder := embedding.NewLocalEmbedder(256)
+	index := embedding.NewMemoryIndex()
+
+	docs := map[string]string{
+		"email_vendor": "Subject: Renewal\nOur renewal pricing for next year is 12% higher per seat.",
+		"email_lunch":  "Subject: Lunch\nShall we get tacos on Friday?",
+		"email_other":  "Subject: Renewal\nRenewal pricing for another user.",
+	}
+	for id, text := range docs {
+		vectors, err := embedder.EmbedDocuments(ctx, []string{text})
+		require.NoError(t, err)
+		userID := "user1"
+		if id == "email_other" {
+			userID = "user2"
+		}
+		require.NoError(t, index.ReplaceEmail(ctx, id, embedder.Model(), []embedding.Chunk{
+			{ID: id + "_0", EmailID: id, UserID: userID, Content: text, Model: embedder.Model(), Vector: vectors[0]},
+		}))
+	}
+
+	query, err := embedder.EmbedQuery(ctx, "what did the vendor say about renewal pricing?")
+	require.NoError(t, err)
+
+	matches, err := index.Search(ctx, "user1", embedder.Model(), query, 5)
+	require.NoError(t, err)
+	require.Len(t, matches, 2, "only the user's own chunks are searched")
+	assert.Equal(t, "email_vendor", matches[0].EmailID)
+	assert.Greater(t, matches[0].Score, matches[1].Score)
+
+	// Re-indexing an email replaces its chunks.
+	require.NoError(t, index.ReplaceEmail(ctx, "email_vendor", embedder.Model(), nil))
+	count, err := index.Count(ctx, "user1", embedder.Model())
+	require.NoError(t, err)
+	assert.Equal(t, 1, count)
+}
+
+type stubProvider struct{ prompt string }
+
+func (p *stubProvider) GenerateResponse(ctx context.Context, prompt string) (string, error) {
+	p.prompt = prompt
+	return "The vendor quoted a 12% increase [1].", nil
+}
+
+func (p *stubProvider) Close() error { return nil }
+
+type stubRetriever struct{ citations []models.Citation }
+
+func (r *stubRetriever) Retrieve(ctx context.Context, userID, question string, k int) ([]models.Citation, error) {
+	return r.citations, nil
+}
+
+func TestAIUsecase_MailboxGrounding(t *testing.T) {
+	date := time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC)
+	retriever := &stubRetriever{citations: []models.Citation{
+		{EmailID: "email_vendor", From: "sales@vendor.com", Date: date, Snippet: "renewal pricing is 12% higher", Score: 0.9},
+		{EmailID: "email_vendor", From: "sales@vendor.com", Date: date, Snippet: "effective from April", Score: 0.7},
+		{EmailID: "email_legal", From: "legal@example.com", Date: date, Snippet: "contract renews in May", Score: 0.5},
+	}}
+	provider := &stubProvider{}
+	ai := usecase.NewAIUsecase(provider, nil, nil, retriever)
+
+	resp, err := ai.ProcessAIRequest(context.Background(), "user1", &models.AIRequest{
+		Prompt:    "What did the vendor say about renewal pricing?",
+		Grounding: models.GroundingMailbox,
+	})
+	require.NoError(t, err)
+
+	assert.Equal(t, "gemini", resp.Provider)
+	require.Len(t, resp.Citations, 2, "citations are deduplicated by email")
+	assert.Equal(t, "email_vendor", resp.Citations[0].EmailID)
+	assert.Equal(t, "email_legal", resp.Citations[1].EmailID)
+
+	assert.Contains(t, provider.prompt, "[1] From: sales@vendor.com | Date: 2024-03-01\nrenewal pricing is 12% higher")
+	assert.Contains(t, provider.prompt, "[3] From: legal@example.com")
+	assert.Contains(t, provider.prompt, "Question: What did the vendor say about renewal pricing?")
+
+	_, err = ai.ProcessAIRequest(context.Background(), "user1", &models.AIRequest{Prompt: "hi", Grounding: "web"})
+	assert.Error(t, err)
+
+	// The feature flag is reloadable; switched off, grounded requests fail
+	// and classification is refused.
+	ai.SetFeatures(false, false)
+	_, err = ai.ProcessAIRequest(context.Background(), "user1", &models.AIRequest{Prompt: "hi", Grounding: models.GroundingMailbox})
+	var appErr *errors.AppError
+	require.ErrorAs(t, err, &appErr)
+	assert.Equal(t, 503, appErr.Code)
+	_, err = ai.ClassifyEmail(context.Background(), &models.Email{}, []string{"Invoices"})
+	assert.Error(t, err)
+	ai.SetFeatures(true, true)
+	_, err = ai.ProcessAIRequest(context.Background(), "user1", &models.AIRequest{Prompt: "hi", Grounding: models.GroundingMailbox})
+	assert.NoError(t, err)
+}
+
+// countingStore counts how often the index is asked whether a user has
+// vectors.
+type countingStore struct {
+	embedding.Store
+	mu     sync.Mutex
+	counts int
+}
+
+func (s *countingStore) Count(ctx context.Context, userID, model string) (int, error) {
+	s.mu.Lock()
+	s.counts++
+	s.mu.Unlock()
+	return s.Store.Count(ctx, userID, model)
+}
+
+func TestRetrieval_BackfillsInBackground(t *testing.T) {
+	ctx := context.Background()
+	store := memory.New()
+	for _, id := range []string{"alice", "bob"} {
+		require.NoError(t, store.Users().Create(ctx, &models.User{ID: id, Email: id + "@example.com"}))
+	}
+	date := time.Date(2024, 3, 1, 9, 0, 0, 0, time.UTC)
+	for i, body := range []string{"Our renewal pricing is 12% higher per seat.", "Shall we get tacos on Friday?"} {
+		require.NoError(t, store.Emails().Create(ctx, &models.Email{
+			ID: fmt.Sprintf("email_%d", i), MessageID: fmt.Sprintf("m%d", i), Subject: stringPtr("Note"),
+			From: "carol@example.com", Body: stringPtr(body), CreatedAt: date.Add(time.Duration(i) * time.Hour), UserID: "alice",
+		}))
+	}
+
+	embedder := embedding.NewLocalEmbedder(256)
+	index := &countingStore{Store: embedding.NewMemoryIndex()}
+	retrieval := usecase.NewRetrievalUsecase(store.Emails(), embedder, index, logger.New())
+
+	// Nothing is indexed yet: the latest emails are cited, and the question
+	// does not wait for them to be embedded.
+	citations, err := retrieval.Retrieve(ctx, "alice", "renewal pricing", 5)
+	require.NoError(t, err)
+	require.Len(t, citations, 2)
+	assert.Equal(t, "email_1", citations[0].EmailID)
+	assert.Equal(t, "Subject: Note\nShall we get tacos on Friday?", citations[0].Snippet)
+	count, err := index.Count(ctx, "alice", embedder.Model())
+	require.NoError(t, err)
+	assert.Zero(t, count)
+
+	workers, stop := context.WithCancel(ctx)
+	defer stop()
+	retrieval.Start(workers)
+	require.Eventually(t, func() bool {
+		count, err := index.Count(ctx, "alice", embedder.Model())
+		return err == nil && count == 2
+	}, 5*time.Second, 10*time.Millisecond)
+
+	citations, err = retrieval.Retrieve(ctx, "alice", "renewal pricing", 1)
+	require.NoError(t, err)
+	require.Len(t, citations, 1)
+	assert.Equal(t, "email_0", citations[0].EmailID)
+	assert.Greater(t, citations[0].Score, 0.0)
+
+	// Bob has no mail, so nothing gets indexed; after the first sync the
+	// index is not asked again.
+	index.counts = 0
+	for i := 0; i < 3; i++ {
+		retrieval.RequestBackfill(ctx, "bob")
+	}
+	assert.Equal(t, 1, index.counts)
+}
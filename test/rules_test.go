@@ -0,0 +1,63 @@
+package handlers_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"ai-assistant/internal/models"
+	"ai-assistant/internal/services/email/rules"
+)
+
+func TestRules_Match(t *testing.T) {
+	subject := "Your receipt #1234"
+	body := "Thanks for your order. The invoice is attached."
+	email := &models.Email{From: "Shop <Billing@shop.example>", Subject: &subject, Body: &body}
+	label := []models.RuleAction{{Type: models.RuleActionLabel, Label: "Receipts"}}
+
+	tests := []struct {
+		name       string
+		conditions models.RuleConditions
+		category   string
+		want       bool
+	}{
+		{"from substring ignores case", models.RuleConditions{From: "billing@shop"}, "", true},
+		{"from mismatch", models.RuleConditions{From: "alice@"}, "", false},
+		{"subject regex", models.RuleConditions{SubjectRegex: `(?i)receipt #\d+`}, "", true},
+		{"any keyword", models.RuleConditions{BodyKeywords: []string{"refund", "INVOICE"}}, "", true},
+		{"all keywords", models.RuleConditions{BodyKeywords: []string{"refund", "invoice"}, AllKeywords: true}, "", false},
+		{"keywords match whole words", models.RuleConditions{BodyKeywords: []string{"voice"}}, "", false},
+		{"every condition must hold", models.RuleConditions{From: "billing@", SubjectRegex: "newsletter"}, "", false},
+		{"category", models.RuleConditions{Category: "Receipt"}, "receipt", true},
+		{"category not classified", models.RuleConditions{Category: "receipt"}, "", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m, err := rules.Compile(&models.Rule{Name: "r", Conditions: tt.conditions, Actions: label})
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, m.Match(email, tt.category))
+		})
+	}
+}
+
+func TestRules_CompileRejectsInvalidRules(t *testing.T) {
+	valid := models.RuleConditions{From: "a@example.com"}
+	tests := []struct {
+		name string
+		rule models.Rule
+	}{
+		{"no name", models.Rule{Conditions: valid, Actions: []models.RuleAction{{Type: models.RuleActionArchive}}}},
+		{"no conditions", models.Rule{Name: "r", Actions: []models.RuleAction{{Type: models.RuleActionArchive}}}},
+		{"no actions", models.Rule{Name: "r", Conditions: valid}},
+		{"bad regex", models.Rule{Name: "r", Conditions: models.RuleConditions{SubjectRegex: "("}, Actions: []models.RuleAction{{Type: models.RuleActionArchive}}}},
+		{"label without name", models.Rule{Name: "r", Conditions: valid, Actions: []models.RuleAction{{Type: models.RuleActionLabel}}}},
+		{"forward without recipient", models.Rule{Name: "r", Conditions: valid, Actions: []models.RuleAction{{Type: models.RuleActionForward, To: "nobody"}}}},
+		{"unknown action", models.Rule{Name: "r", Conditions: valid, Actions: []models.RuleAction{{Type: "delete"}}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := rules.Compile(&tt.rule)
+			assert.Error(t, err)
+		})
+	}
+}
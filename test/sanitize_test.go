@@ -0,0 +1,176 @@
+package handlers_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"ai-assistant/internal/handlers"
+	"ai-assistant/internal/models"
+	"ai-assistant/internal/repository/memory"
+	"ai-assistant/internal/services/blob/filesystem"
+	"ai-assistant/internal/services/email/sanitize"
+	"ai-assistant/internal/services/imageproxy"
+	"ai-assistant/internal/services/inlineimage"
+	"ai-assistant/internal/usecase"
+)
+
+func TestSanitizeRemovesActiveContent(t *testing.T) {
+	tests := []struct {
+		name      string
+		input     string
+		forbidden []string
+		want      string
+	}{
+		{"script", `<p>hi</p><script>alert(1)</script>`, []string{"script", "alert"}, "<p>hi</p>"},
+		{"event handler", `<div onclick="steal()" onmouseover=x>a</div>`, []string{"onclick", "onmouseover", "steal"}, "<div>a</div>"},
+		{"javascript link", `<a href=" javascript:alert(1)">x</a>`, []string{"javascript"}, "<a>x</a>"},
+		{"form", `<form action="https://evil"><input name=pw>Password<button>Go</button></form>`, []string{"form", "input", "button", "evil"}, "Password"},
+		{"iframe and object", `<iframe src="https://evil"></iframe><object data=x></object><embed src=y>`, []string{"iframe", "object", "embed"}, ""},
+		{"meta refresh", `<meta http-equiv="refresh" content="0;url=https://evil"><p>x</p>`, []string{"meta", "evil"}, "<p>x</p>"},
+		{"svg", `<svg onload=alert(1)><script>1</script></svg>ok`, []string{"svg", "alert"}, "ok"},
+		{"dangerous css", `<div style="position:fixed;top:0;background:url(https://t/p.gif);width:expression(alert(1));color:red">x</div>`, []string{"position", "url(", "expression"}, `<div style="color: red">x</div>`},
+		{"escaped css", `<div style="background:u\72l(https://t)">x</div>`, []string{"t)"}, "<div>x</div>"},
+		{"stylesheet", `<style>.a{color:blue;background-image:url(https://t)}</style><p class="a" id="b">x</p>`, []string{"class", "id=", "url("}, `<p style="color: blue">x</p>`},
+		{"safe link", `<a href="https://example.com" target="_top">x</a>`, []string{"_top"}, `<a href="https://example.com" target="_blank" rel="noopener noreferrer nofollow">x</a>`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := sanitize.Sanitize(tt.input, sanitize.Options{})
+			require.NoError(t, err)
+			for _, f := range tt.forbidden {
+				assert.NotContains(t, result.HTML, f)
+			}
+			assert.Equal(t, tt.want, result.HTML)
+		})
+	}
+}
+
+func TestSanitizeImages(t *testing.T) {
+	input := `<p>Logo <img src="cid:logo%40example.com" alt="Logo"></p>` +
+		`<img src="https://tracker.example/p.gif" width="1"><img src="//cdn.example/a.png">` +
+		`<img src="data:image/png;base64,iVBORw0KGgo="><img src="data:image/svg+xml;base64,PHN2Zz4=">` +
+		`<img src="cid:missing">`
+	inline := func(cid string) string {
+		if cid == "logo@example.com" {
+			return "https://api.example/api/emails/e1/attachments/a1"
+		}
+		return ""
+	}
+
+	blocked, err := sanitize.Sanitize(input, sanitize.Options{InlineImage: inline})
+	require.NoError(t, err)
+	assert.Equal(t, 2, blocked.BlockedImages)
+	assert.Contains(t, blocked.HTML, `src="https://api.example/api/emails/e1/attachments/a1"`)
+	assert.Contains(t, blocked.HTML, `src="data:image/png;base64,iVBORw0KGgo="`)
+	assert.NotContains(t, blocked.HTML, "tracker")
+	assert.NotContains(t, blocked.HTML, "svg")
+	assert.NotContains(t, blocked.HTML, "missing")
+	assert.Contains(t, blocked.Text, "Logo")
+
+	proxy := imageproxy.New("secret", "https://api.example", nil)
+	proxied, err := sanitize.Sanitize(input, sanitize.Options{InlineImage: inline, RemoteImage: proxy.URL})
+	require.NoError(t, err)
+	assert.Equal(t, 0, proxied.BlockedImages)
+	assert.Contains(t, proxied.HTML, `src="https://api.example/api/images/proxy?sig=`)
+	assert.Contains(t, proxied.HTML, url.QueryEscape("https://cdn.example/a.png"))
+}
+
+func TestImageProxy(t *testing.T) {
+	remote := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/pixel.gif":
+			w.Header().Set("Content-Type", "image/gif")
+			w.Write([]byte("GIF89a"))
+		case "/image.svg":
+			w.Header().Set("Content-Type", "image/svg+xml")
+			w.Write([]byte("<svg></svg>"))
+		}
+	}))
+	defer remote.Close()
+
+	// The test server is on loopback, which the default client refuses.
+	_, err := imageproxy.New("secret", "https://api.example", nil).Fetch(context.Background(), remote.URL+"/pixel.gif")
+	assert.ErrorIs(t, err, imageproxy.ErrNotAllowed)
+
+	proxy := imageproxy.New("secret", "https://api.example", remote.Client())
+	handler := handlers.NewImageProxyHandler(proxy)
+
+	serve := func(proxied string) *httptest.ResponseRecorder {
+		u, err := url.Parse(proxied)
+		require.NoError(t, err)
+		rec := httptest.NewRecorder()
+		handler.Proxy(rec, httptest.NewRequest(http.MethodGet, u.RequestURI(), nil))
+		return rec
+	}
+
+	rec := serve(proxy.URL(remote.URL + "/pixel.gif"))
+	require.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, "image/gif", rec.Header().Get("Content-Type"))
+	assert.Equal(t, "nosniff", rec.Header().Get("X-Content-Type-Options"))
+	assert.Equal(t, "GIF89a", rec.Body.String())
+
+	rec = serve(proxy.URL(remote.URL + "/image.svg"))
+	assert.Equal(t, http.StatusUnsupportedMediaType, rec.Code)
+
+	forged := strings.Replace(proxy.URL(remote.URL+"/pixel.gif"), "pixel.gif", "other.gif", 1)
+	rec = serve(forged)
+	assert.Equal(t, http.StatusForbidden, rec.Code)
+}
+
+func TestInlineImagesLoadThroughSignedURLs(t *testing.T) {
+	ctx := context.Background()
+	store := memory.New()
+	require.NoError(t, store.Users().Create(ctx, &models.User{ID: "alice", Email: "alice@example.com"}))
+	html := `<p>Logo <img src="cid:logo@example.com"></p>`
+	require.NoError(t, store.Emails().Create(ctx, &models.Email{
+		ID: "e1", MessageID: "m1", From: "carol@example.com", HTMLBody: &html, CreatedAt: time.Now(), UserID: "alice",
+	}))
+	blobs, err := filesystem.NewStore(t.TempDir())
+	require.NoError(t, err)
+	for _, a := range []*models.EmailAttachment{
+		{ID: "logo", EmailID: "e1", Filename: "logo.png", MimeType: "image/png", ContentID: stringPtr("<logo@example.com>")},
+		{ID: "page", EmailID: "e1", Filename: "page.html", MimeType: "text/html"},
+	} {
+		key := "attachments/alice/e1/" + a.ID
+		require.NoError(t, blobs.Put(ctx, key, []byte("content of "+a.ID), a.MimeType))
+		a.StorageKey = &key
+		a.CreatedAt = time.Now()
+		require.NoError(t, store.Emails().CreateAttachment(ctx, a))
+	}
+
+	signer := inlineimage.New("secret", "https://api.example")
+	rendering, err := usecase.NewSanitizeUsecase(store.Emails(), signer, nil).GetSanitizedEmail(ctx, "alice", "e1", true)
+	require.NoError(t, err)
+	start := strings.Index(rendering.HTML, `src="`) + len(`src="`)
+	src := strings.ReplaceAll(rendering.HTML[start:start+strings.Index(rendering.HTML[start:], `"`)], "&amp;", "&")
+	assert.True(t, strings.HasPrefix(src, "https://api.example/api/images/inline/e1/logo?exp="), src)
+
+	router := chi.NewRouter()
+	handlers.NewInlineImageHandler(usecase.NewEmailUsecase(store.Emails(), nil, nil, nil, blobs, nil), signer).RegisterRoutes(router)
+	serve := func(signed string) *httptest.ResponseRecorder {
+		u, err := url.Parse(signed)
+		require.NoError(t, err)
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, strings.TrimPrefix(u.RequestURI(), "/api/images"), nil))
+		return rec
+	}
+
+	// No API token is needed, as for an <img> tag.
+	rec := serve(src)
+	require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+	assert.Equal(t, "image/png", rec.Header().Get("Content-Type"))
+	assert.Equal(t, "content of logo", rec.Body.String())
+
+	assert.Equal(t, http.StatusForbidden, serve(strings.Replace(src, "/logo?", "/page?", 1)).Code)
+	assert.Equal(t, http.StatusForbidden, serve(signer.URL("e1", "logo", time.Now().Add(-time.Minute))).Code, "expired")
+	assert.Equal(t, http.StatusNotFound, serve(signer.URL("e1", "page", time.Now().Add(time.Hour))).Code, "not an image")
+}
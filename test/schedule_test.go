@@ -0,0 +1,180 @@
+package handlers_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"ai-assistant/internal/handlers"
+	"ai-assistant/internal/models"
+	"ai-assistant/internal/repository"
+	"ai-assistant/internal/usecase"
+	"ai-assistant/pkg/database"
+	"ai-assistant/pkg/errors"
+)
+
+type MockScheduleUsecase struct {
+	mock.Mock
+}
+
+func (m *MockScheduleUsecase) QueueEmail(ctx context.Context, userID, from string, req *models.SendEmailRequest) (*models.ScheduledEmail, error) {
+	args := m.Called(ctx, userID, from, req)
+	scheduled, _ := args.Get(0).(*models.ScheduledEmail)
+	return scheduled, args.Error(1)
+}
+
+func (m *MockScheduleUsecase) ListScheduled(ctx context.Context, userID, status string, limit, offset int) ([]*models.ScheduledEmail, error) {
+	args := m.Called(ctx, userID, status, limit, offset)
+	emails, _ := args.Get(0).([]*models.ScheduledEmail)
+	return emails, args.Error(1)
+}
+
+func (m *MockScheduleUsecase) GetScheduled(ctx context.Context, userID, id string) (*models.ScheduledEmail, error) {
+	args := m.Called(ctx, userID, id)
+	scheduled, _ := args.Get(0).(*models.ScheduledEmail)
+	return scheduled, args.Error(1)
+}
+
+func (m *MockScheduleUsecase) UpdateScheduled(ctx context.Context, userID, id string, req *models.SendEmailRequest) (*models.ScheduledEmail, error) {
+	args := m.Called(ctx, userID, id, req)
+	scheduled, _ := args.Get(0).(*models.ScheduledEmail)
+	return scheduled, args.Error(1)
+}
+
+func (m *MockScheduleUsecase) CancelScheduled(ctx context.Context, userID, id string) (*models.ScheduledEmail, error) {
+	args := m.Called(ctx, userID, id)
+	scheduled, _ := args.Get(0).(*models.ScheduledEmail)
+	return scheduled, args.Error(1)
+}
+
+func TestParseSendAt(t *testing.T) {
+	now := time.Date(2026, 10, 19, 12, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name     string
+		sendAt   string
+		timeZone string
+		expected time.Time
+		wantErr  bool
+	}{
+		{"RFC 3339", "2026-10-20T09:00:00+02:00", "", time.Date(2026, 10, 20, 7, 0, 0, 0, time.UTC), false},
+		{"local time in zone", "2026-10-20T09:00", "America/New_York", time.Date(2026, 10, 20, 13, 0, 0, 0, time.UTC), false},
+		{"across DST change", "2026-11-02T09:00", "America/New_York", time.Date(2026, 11, 2, 14, 0, 0, 0, time.UTC), false},
+		{"local time without zone", "2026-10-20T09:00", "", time.Time{}, true},
+		{"unknown zone", "2026-10-20T09:00", "Mars/Olympus", time.Time{}, true},
+		{"in the past", "2026-10-19T11:00:00Z", "", time.Time{}, true},
+		{"too far ahead", "2028-01-01T00:00:00Z", "", time.Time{}, true},
+		{"garbage", "tomorrow", "UTC", time.Time{}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := usecase.ParseSendAt(tt.sendAt, tt.timeZone, now)
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.True(t, tt.expected.Equal(got), "got %s", got)
+		})
+	}
+}
+
+func TestScheduleHandler(t *testing.T) {
+	scheduled := &models.ScheduledEmail{ID: "sched_1", Status: models.ScheduledStatusScheduled}
+
+	t.Run("send is accepted into the queue", func(t *testing.T) {
+		mockUsecase := new(MockScheduleUsecase)
+		mockUsecase.On("QueueEmail", mock.Anything, "user123", "", mock.MatchedBy(func(req *models.SendEmailRequest) bool {
+			return req.SendAt == "2026-10-20T09:00" && req.TimeZone == "Europe/Berlin"
+		})).Return(scheduled, nil)
+
+		router := chi.NewRouter()
+		router.Use(mockAuthMiddleware)
+		handlers.NewScheduleHandler(mockUsecase).RegisterRoutes(router)
+
+		body, _ := json.Marshal(map[string]interface{}{
+			"to": []string{"a@example.com"}, "subject": "Hi", "body": "Hello",
+			"sendAt": "2026-10-20T09:00", "timeZone": "Europe/Berlin",
+		})
+		rr := httptest.NewRecorder()
+		router.ServeHTTP(rr, httptest.NewRequest("POST", "/send", bytes.NewReader(body)))
+
+		assert.Equal(t, http.StatusAccepted, rr.Code)
+		assert.Contains(t, rr.Body.String(), `"id":"sched_1"`)
+		mockUsecase.AssertExpectations(t)
+	})
+
+	t.Run("cancel after dispatch conflicts", func(t *testing.T) {
+		mockUsecase := new(MockScheduleUsecase)
+		mockUsecase.On("CancelScheduled", mock.Anything, "user123", "sched_1").
+			Return(nil, errors.ErrConflict("Email is already sent"))
+
+		router := chi.NewRouter()
+		router.Use(mockAuthMiddleware)
+		handlers.NewScheduleHandler(mockUsecase).RegisterRoutes(router)
+
+		rr := httptest.NewRecorder()
+		router.ServeHTTP(rr, httptest.NewRequest("POST", "/scheduled/sched_1/cancel", nil))
+
+		assert.Equal(t, http.StatusConflict, rr.Code)
+		mockUsecase.AssertExpectations(t)
+	})
+}
+
+// TestScheduledQueueIgnoresLocalTimeZone checks that dispatchers whose
+// local time zone is not UTC claim messages when they are due, not hours
+// early or late. It needs TEST_DATABASE_URL.
+func TestScheduledQueueIgnoresLocalTimeZone(t *testing.T) {
+	sqlDB := openTestDatabase(t)
+	db := database.Wrap(sqlDB)
+	ctx := context.Background()
+
+	for _, zone := range []string{"Asia/Tokyo", "America/Los_Angeles"} {
+		t.Run(zone, func(t *testing.T) {
+			loc, err := time.LoadLocation(zone)
+			require.NoError(t, err)
+			local := time.Local
+			time.Local = loc
+			t.Cleanup(func() { time.Local = local })
+
+			_, err = sqlDB.Exec(`TRUNCATE users CASCADE`)
+			require.NoError(t, err)
+			require.NoError(t, repository.NewUserRepository(db).Create(ctx, &models.User{ID: "u1", Email: "u1@example.com"}))
+
+			scheduledRepo := repository.NewScheduledEmailRepository(db)
+			now := time.Now()
+			for id, sendAt := range map[string]time.Time{"due": now.Add(-time.Minute), "later": now.Add(time.Hour)} {
+				require.NoError(t, scheduledRepo.Create(ctx, &models.ScheduledEmail{
+					ID: id, UserID: "u1", From: "u1@example.com", To: []string{"bob@example.com"},
+					Subject: id, Body: id, SendAt: sendAt, Status: models.ScheduledStatusScheduled,
+					CreatedAt: now, UpdatedAt: now,
+				}))
+			}
+
+			claimed, err := scheduledRepo.ClaimDue(ctx, time.Now())
+			require.NoError(t, err)
+			require.NotNil(t, claimed)
+			assert.Equal(t, "due", claimed.ID)
+			assert.WithinDuration(t, now.Add(-time.Minute), claimed.SendAt, time.Second)
+			claimed, err = scheduledRepo.ClaimDue(ctx, time.Now())
+			require.NoError(t, err)
+			assert.Nil(t, claimed, "the other message is an hour away")
+
+			n, err := scheduledRepo.FailStale(ctx, time.Now().Add(-time.Minute), "interrupted")
+			require.NoError(t, err)
+			assert.Zero(t, n, "the claim is fresh")
+			n, err = scheduledRepo.FailStale(ctx, time.Now().Add(time.Minute), "interrupted")
+			require.NoError(t, err)
+			assert.Equal(t, int64(1), n)
+		})
+	}
+}
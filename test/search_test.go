@@ -0,0 +1,43 @@
+package handlers_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"ai-assistant/internal/services/email/search"
+)
+
+func TestSearch_ParseQuery(t *testing.T) {
+	q, err := search.Parse(`from:alice@example.com subject:"quarterly report" budget -is:unread has:attachment label:Work -"out of office" after:2024/01/31 foo:bar`)
+	require.NoError(t, err)
+
+	assert.Equal(t, []search.Term{
+		{Field: search.FieldFrom, Value: "alice@example.com"},
+		{Field: search.FieldSubject, Value: "quarterly report", Phrase: true},
+		{Field: search.FieldText, Value: "budget"},
+		{Field: search.FieldIs, Value: search.IsUnread, Negate: true},
+		{Field: search.FieldHas, Value: search.HasAttachment},
+		{Field: search.FieldLabel, Value: "Work"},
+		{Field: search.FieldText, Value: "out of office", Phrase: true, Negate: true},
+		{Field: search.FieldAfter, Value: "2024/01/31", Date: time.Date(2024, 1, 31, 0, 0, 0, 0, time.UTC)},
+		{Field: search.FieldText, Value: "foo:bar"},
+	}, q.Terms)
+	assert.True(t, q.HasText())
+}
+
+func TestSearch_ParseQueryErrors(t *testing.T) {
+	for _, raw := range []string{
+		"",
+		"   ",
+		`subject:"unterminated`,
+		"is:important",
+		"has:drive",
+		"before:yesterday",
+		"from:",
+	} {
+		_, err := search.Parse(raw)
+		assert.Error(t, err, raw)
+	}
+}
@@ -0,0 +1,59 @@
+package handlers_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"ai-assistant/internal/services/email/templates"
+)
+
+func TestTemplateRender(t *testing.T) {
+	tmpl, err := templates.Parse("Invoice {{.number}}\nfor {{.name}}", `<html><head><style>
+		p { color: #333; margin: 0 }
+		.total { font-weight: bold }
+		#footer > a { color: gray }
+		a:hover { color: red }
+		@media (max-width: 600px) { p { font-size: 12px } }
+	</style></head><body>
+	<h1>Hello {{.name}}</h1>
+	<p class="total" style="color: black">You owe {{.amount}}.</p>
+	<ul>{{range .items}}<li>{{.}}</li>{{end}}</ul>
+	<div id="footer"><a href="https://example.com/pay">Pay now</a></div>
+	</body></html>`)
+	require.NoError(t, err)
+
+	rendered, err := tmpl.Render(map[string]interface{}{
+		"number": "42",
+		"name":   "<Bob>",
+		"amount": "$10",
+		"items":  []string{"Widget", "Gadget"},
+	})
+	require.NoError(t, err)
+
+	assert.Equal(t, "Invoice 42 for <Bob>", rendered.Subject)
+
+	// Data is escaped; rules are inlined with the element's own style winning.
+	assert.Contains(t, rendered.HTML, "Hello &lt;Bob&gt;")
+	assert.Contains(t, rendered.HTML, `<p class="total" style="color: black; margin: 0; font-weight: bold">`)
+	assert.Contains(t, rendered.HTML, `<a href="https://example.com/pay" style="color: gray">`)
+	// What cannot be inlined stays in <style>.
+	assert.Contains(t, rendered.HTML, "a:hover {color: red}")
+	assert.Contains(t, rendered.HTML, "@media (max-width: 600px)")
+	assert.NotContains(t, rendered.HTML, ".total {")
+
+	assert.Equal(t, "Hello <Bob>\n\nYou owe $10.\n\n- Widget\n- Gadget\n\nPay now (https://example.com/pay)", rendered.Text)
+}
+
+func TestTemplateRender_MissingData(t *testing.T) {
+	tmpl, err := templates.Parse("Hi {{.name}}", "<p>{{.body}}</p>")
+	require.NoError(t, err)
+
+	_, err = tmpl.Render(map[string]interface{}{"name": "Bob"})
+	assert.ErrorContains(t, err, `"body"`)
+}
+
+func TestTemplateParse_Invalid(t *testing.T) {
+	_, err := templates.Parse("Hi {{.name", "<p></p>")
+	assert.Error(t, err)
+}
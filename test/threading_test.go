@@ -0,0 +1,54 @@
+package handlers_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"ai-assistant/internal/services/email/mailmime"
+	"ai-assistant/internal/services/email/threading"
+)
+
+func TestThreading_DeriveIDSharedByConversation(t *testing.T) {
+	root := threading.DeriveID("<a@example.com>", "", nil)
+	reply := threading.DeriveID("<b@example.com>", "<a@example.com>", []string{"<a@example.com>"})
+	replyToReply := threading.DeriveID("<c@example.com>", "<b@example.com>", []string{"<a@example.com>", "<b@example.com>"})
+	other := threading.DeriveID("<z@example.com>", "", nil)
+
+	assert.NotEmpty(t, root)
+	assert.Equal(t, root, reply)
+	assert.Equal(t, root, replyToReply)
+	assert.NotEqual(t, root, other)
+	assert.Empty(t, threading.DeriveID("", "", nil))
+
+	assert.Equal(t, []string{"<b@example.com>", "<a@example.com>"},
+		threading.Ancestors("<b@example.com>", []string{"<a@example.com>", "<b@example.com>"}))
+}
+
+func TestThreading_StripQuoted(t *testing.T) {
+	orig := &mailmime.Original{
+		From: "Bob <bob@example.com>",
+		Date: time.Date(2024, 5, 1, 9, 0, 0, 0, time.UTC),
+		Text: "Are we still on for Friday?\n\n> earlier quote",
+	}
+	reply := "Yes, see you then.\n\n" + mailmime.QuoteText(orig)
+
+	tests := []struct {
+		name string
+		in   string
+		want string
+	}{
+		{"own composer", reply, "Yes, see you then."},
+		{"wrapped attribution", "Sounds good\r\n\r\nOn Wed, May 1, 2024 at 9:00 AM Bob <bob@example.com>\r\nwrote:\r\n> hi\r\n", "Sounds good"},
+		{"outlook", "Approved.\n\n-----Original Message-----\nFrom: Bob\nSent: Monday\n\nPlease approve.", "Approved."},
+		{"inline quote kept", "> Can you make it?\nYes.\n> And Friday?\nAlso yes.", "> Can you make it?\nYes.\n> And Friday?\nAlso yes."},
+		{"only quote", "> nothing new", "> nothing new"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, threading.StripQuoted(tt.in))
+		})
+	}
+
+	assert.Equal(t, "Hello wo…", threading.Snippet("Hello\n\n  world", 8))
+}
@@ -0,0 +1,96 @@
+package handlers_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/propagation"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"ai-assistant/internal/middleware"
+	"ai-assistant/pkg/tracing"
+)
+
+// recordSpans installs a tracer provider recording every span until the
+// test ends.
+func recordSpans(t *testing.T) *tracetest.SpanRecorder {
+	t.Helper()
+	recorder := tracetest.NewSpanRecorder()
+	provider := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder))
+
+	previousProvider, previousPropagator := otel.GetTracerProvider(), otel.GetTextMapPropagator()
+	otel.SetTracerProvider(provider)
+	otel.SetTextMapPropagator(propagation.TraceContext{})
+	t.Cleanup(func() {
+		provider.Shutdown(context.Background())
+		otel.SetTracerProvider(previousProvider)
+		otel.SetTextMapPropagator(previousPropagator)
+	})
+	return recorder
+}
+
+func TestTracingContinuesTheCallersTrace(t *testing.T) {
+	recorder := recordSpans(t)
+
+	var outboundTraceparent string
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		outboundTraceparent = r.Header.Get("traceparent")
+	}))
+	defer upstream.Close()
+	client := &http.Client{Transport: tracing.Transport(nil)}
+
+	router := chi.NewRouter()
+	router.Use(middleware.Tracing())
+	router.Get("/api/things/{id}", func(w http.ResponseWriter, r *http.Request) {
+		ctx, span := tracing.Start(r.Context(), "ThingUsecase.Get")
+		defer span.End()
+
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, upstream.URL, nil)
+		require.NoError(t, err)
+		resp, err := client.Do(req)
+		require.NoError(t, err)
+		resp.Body.Close()
+	})
+
+	const traceID = "4bf92f3577b34da6a3ce929d0e0e4736"
+	req := httptest.NewRequest(http.MethodGet, "/api/things/42", nil)
+	req.Header.Set("traceparent", "00-"+traceID+"-00f067aa0ba902b7-01")
+	router.ServeHTTP(httptest.NewRecorder(), req)
+
+	spans := recorder.Ended()
+	require.Len(t, spans, 3)
+	names := map[string]sdktrace.ReadOnlySpan{}
+	for _, span := range spans {
+		assert.Equal(t, traceID, span.SpanContext().TraceID().String())
+		names[span.Name()] = span
+	}
+
+	server, ok := names["GET /api/things/{id}"]
+	require.True(t, ok, "server span is named after the route pattern")
+	assert.Equal(t, "00f067aa0ba902b7", server.Parent().SpanID().String())
+
+	usecase, ok := names["ThingUsecase.Get"]
+	require.True(t, ok)
+	assert.Equal(t, server.SpanContext().SpanID(), usecase.Parent().SpanID())
+
+	assert.True(t, strings.HasPrefix(outboundTraceparent, "00-"+traceID+"-"))
+}
+
+func TestTracingEndRecordsErrors(t *testing.T) {
+	recorder := recordSpans(t)
+
+	_, span := tracing.Start(context.Background(), "failing")
+	tracing.End(span, assert.AnError)
+
+	spans := recorder.Ended()
+	require.Len(t, spans, 1)
+	assert.Equal(t, "Error", spans[0].Status().Code.String())
+	assert.Equal(t, assert.AnError.Error(), spans[0].Status().Description)
+}
@@ -0,0 +1,141 @@
+package handlers_test
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"sync"
+	"testing"
+
+	"github.com/lib/pq"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"ai-assistant/pkg/database"
+)
+
+// recordingConnector is a database/sql driver that logs the statements it
+// is given, failing any that fail returns an error for.
+type recordingConnector struct {
+	mu   sync.Mutex
+	log  []string
+	fail func(query string) error
+}
+
+func (c *recordingConnector) record(stmt string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.log = append(c.log, stmt)
+}
+
+func (c *recordingConnector) statements() []string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return append([]string(nil), c.log...)
+}
+
+func (c *recordingConnector) Connect(context.Context) (driver.Conn, error) {
+	return &recordingConn{c}, nil
+}
+func (c *recordingConnector) Driver() driver.Driver { return nil }
+
+type recordingConn struct{ c *recordingConnector }
+
+func (rc *recordingConn) Prepare(string) (driver.Stmt, error) {
+	return nil, errors.New("not supported")
+}
+func (rc *recordingConn) Close() error { return nil }
+func (rc *recordingConn) Begin() (driver.Tx, error) {
+	rc.c.record("BEGIN")
+	return rc, nil
+}
+func (rc *recordingConn) Commit() error   { rc.c.record("COMMIT"); return nil }
+func (rc *recordingConn) Rollback() error { rc.c.record("ROLLBACK"); return nil }
+
+func (rc *recordingConn) ExecContext(_ context.Context, query string, _ []driver.NamedValue) (driver.Result, error) {
+	rc.c.record(query)
+	if rc.c.fail != nil {
+		if err := rc.c.fail(query); err != nil {
+			return nil, err
+		}
+	}
+	return driver.RowsAffected(1), nil
+}
+
+func newRecordingDB(fail func(string) error) (*database.DB, *recordingConnector) {
+	c := &recordingConnector{fail: fail}
+	db := sql.OpenDB(c)
+	db.SetMaxOpenConns(1)
+	return database.Wrap(db), c
+}
+
+func TestWithTxNestsInSavepoints(t *testing.T) {
+	db, c := newRecordingDB(nil)
+	ctx := context.Background()
+
+	err := db.WithTx(ctx, func(ctx context.Context) error {
+		db.ExecContext(ctx, "INSERT a")
+		innerErr := db.WithTx(ctx, func(ctx context.Context) error {
+			db.ExecContext(ctx, "INSERT b")
+			return errors.New("inner failed")
+		})
+		assert.EqualError(t, innerErr, "inner failed")
+		db.ExecContext(ctx, "INSERT c")
+		return db.WithTx(ctx, func(ctx context.Context) error {
+			_, err := db.ExecContext(ctx, "INSERT d")
+			return err
+		})
+	})
+	require.NoError(t, err)
+	assert.Equal(t, []string{
+		"BEGIN", "INSERT a",
+		"SAVEPOINT sp_1", "INSERT b", "ROLLBACK TO SAVEPOINT sp_1",
+		"INSERT c",
+		"SAVEPOINT sp_2", "INSERT d", "RELEASE SAVEPOINT sp_2",
+		"COMMIT",
+	}, c.statements())
+}
+
+func TestWithTxRetriesSerializationFailures(t *testing.T) {
+	failures := 1
+	db, c := newRecordingDB(func(query string) error {
+		if query == "UPDATE x" && failures > 0 {
+			failures--
+			return &pq.Error{Code: "40001", Message: "could not serialize access"}
+		}
+		return nil
+	})
+
+	runs := 0
+	err := db.WithTx(context.Background(), func(ctx context.Context) error {
+		runs++
+		_, err := db.ExecContext(ctx, "UPDATE x")
+		return err
+	})
+	require.NoError(t, err)
+	assert.Equal(t, 2, runs)
+	assert.Equal(t, []string{"BEGIN", "UPDATE x", "ROLLBACK", "BEGIN", "UPDATE x", "COMMIT"}, c.statements())
+}
+
+func TestWithTxRollsBack(t *testing.T) {
+	db, c := newRecordingDB(nil)
+
+	err := db.WithTx(context.Background(), func(ctx context.Context) error {
+		db.ExecContext(ctx, "INSERT a")
+		return errors.New("failed")
+	})
+	assert.EqualError(t, err, "failed")
+	assert.Equal(t, []string{"BEGIN", "INSERT a", "ROLLBACK"}, c.statements())
+
+	ctx, cancel := context.WithCancel(context.Background())
+	err = db.WithTx(ctx, func(ctx context.Context) error {
+		cancel()
+		return nil
+	})
+	assert.ErrorIs(t, err, context.Canceled)
+	assert.NotContains(t, c.statements()[3:], "COMMIT")
+
+	// Outside WithTx statements run on the pool.
+	db.ExecContext(context.Background(), "INSERT b")
+	assert.Equal(t, "INSERT b", c.statements()[len(c.statements())-1])
+}
@@ -0,0 +1,90 @@
+package handlers_test
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"ai-assistant/internal/handlers"
+	"ai-assistant/internal/models"
+	"ai-assistant/internal/services/email/resend"
+)
+
+type MockDeliveryRecorder struct {
+	mock.Mock
+}
+
+func (m *MockDeliveryRecorder) RecordDeliveryEvent(ctx context.Context, event *models.DeliveryEvent) error {
+	return m.Called(ctx, event).Error(0)
+}
+
+var webhookKey = []byte("resend-webhook-test-secret")
+
+func svixSignature(key []byte, id string, ts time.Time, body string) string {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(id + "." + strconv.FormatInt(ts.Unix(), 10) + "." + body))
+	return "v1," + base64.StdEncoding.EncodeToString(mac.Sum(nil))
+}
+
+func TestWebhookHandler_Resend(t *testing.T) {
+	verifier, err := resend.NewWebhookVerifier("whsec_" + base64.StdEncoding.EncodeToString(webhookKey))
+	require.NoError(t, err)
+
+	delivered := `{"type":"email.delivered","created_at":"2026-10-19T10:00:00.000Z","data":{"email_id":"re_123","to":["bob@example.com"],"subject":"Hi"}}`
+	clicked := `{"type":"email.clicked","created_at":"2026-10-19T10:00:00.000Z","data":{"email_id":"re_123"}}`
+	now := time.Now()
+
+	tests := []struct {
+		name           string
+		body           string
+		timestamp      time.Time
+		signature      string
+		expectRecord   bool
+		expectedStatus int
+	}{
+		{"valid", delivered, now, svixSignature(webhookKey, "msg_1", now, delivered), true, http.StatusNoContent},
+		{"rotated secret", delivered, now, svixSignature([]byte("old"), "msg_1", now, delivered) + " " + svixSignature(webhookKey, "msg_1", now, delivered), true, http.StatusNoContent},
+		{"untracked type", clicked, now, svixSignature(webhookKey, "msg_1", now, clicked), false, http.StatusNoContent},
+		{"wrong secret", delivered, now, svixSignature([]byte("other"), "msg_1", now, delivered), false, http.StatusUnauthorized},
+		{"tampered body", strings.Replace(delivered, "re_123", "re_999", 1), now, svixSignature(webhookKey, "msg_1", now, delivered), false, http.StatusUnauthorized},
+		{"stale timestamp", delivered, now.Add(-10 * time.Minute), svixSignature(webhookKey, "msg_1", now.Add(-10*time.Minute), delivered), false, http.StatusUnauthorized},
+		{"missing signature", delivered, now, "", false, http.StatusUnauthorized},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			recorder := new(MockDeliveryRecorder)
+			recorder.On("RecordDeliveryEvent", mock.Anything, mock.MatchedBy(func(e *models.DeliveryEvent) bool {
+				return e.Provider == "resend" && e.ProviderEventID == "msg_1" &&
+					e.ProviderMessageID == "re_123" && e.Status == models.OutboundStatusDelivered
+			})).Return(nil)
+			handler := handlers.NewWebhookHandler(recorder, verifier)
+
+			req := httptest.NewRequest(http.MethodPost, "/api/webhooks/resend", strings.NewReader(tt.body))
+			req.Header.Set("svix-id", "msg_1")
+			req.Header.Set("svix-timestamp", strconv.FormatInt(tt.timestamp.Unix(), 10))
+			if tt.signature != "" {
+				req.Header.Set("svix-signature", tt.signature)
+			}
+			rr := httptest.NewRecorder()
+			handler.Resend(rr, req)
+
+			assert.Equal(t, tt.expectedStatus, rr.Code)
+			if tt.expectRecord {
+				recorder.AssertExpectations(t)
+			} else {
+				recorder.AssertNotCalled(t, "RecordDeliveryEvent", mock.Anything, mock.Anything)
+			}
+		})
+	}
+}